@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+)
+
+// client reads and changes one household's lists and meal plans, either in
+// the database or through the server's JSON API. Both give back the API's
+// resources, so the output is the same either way.
+type client interface {
+	Lists(ctx context.Context) ([]models.APIShoppingList, error)
+	Items(ctx context.Context, listID string) ([]models.APIShoppingListItem, error)
+	AddItem(ctx context.Context, listID, text string) (models.APIShoppingListItem, error)
+	TickItem(ctx context.Context, listID, itemID string, ticked bool) (models.APIShoppingListItem, error)
+	MealPlan(ctx context.Context, week string) (models.APIMealPlan, error)
+	// SetMeal saves meal.Meal in its slot, if the slot is still at
+	// meal.Version, linking it to the recipe with that title
+	SetMeal(ctx context.Context, meal models.APIMeal) (models.APIMeal, error)
+	Close(ctx context.Context) error
+}
+
+// localClient works on the database directly, as the user it was opened for
+type localClient struct {
+	store db.Store
+	data  db.DBInterface
+	slots []string
+	now   func() time.Time
+}
+
+// newLocalClient opens the data of the household of the user with email.
+// Without an email, the only household is used, acting as its first user;
+// with no households at all, the data saved before households existed is.
+func newLocalClient(ctx context.Context, store db.Store, email string, slots []string, now func() time.Time) (*localClient, error) {
+	user, err := localUser(ctx, store, email)
+	if err != nil {
+		return nil, err
+	}
+	var data db.DBInterface = store
+	if user.HouseholdID != "" {
+		data = store.ForHousehold(user.HouseholdID)
+	}
+	return &localClient{store: store, data: db.WithHistory(data, user), slots: slots, now: now}, nil
+}
+
+// localUser finds the user changes are made as
+func localUser(ctx context.Context, store db.Store, email string) (models.User, error) {
+	if email != "" {
+		user, err := store.GetUserByEmail(ctx, email)
+		if errors.Is(err, db.ErrNotFound) {
+			return user, fmt.Errorf("no user with the email %s", email)
+		}
+		return user, err
+	}
+
+	households, err := store.GetHouseholds(ctx)
+	if err != nil {
+		return models.User{}, err
+	}
+	switch len(households) {
+	case 0:
+		return models.User{}, nil
+	case 1:
+	default:
+		return models.User{}, errors.New("there is more than one household: choose a user with --user")
+	}
+	users, err := store.GetHouseholdUsers(ctx, households[0].IDHex)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(users) == 0 {
+		return models.User{HouseholdID: households[0].IDHex}, nil
+	}
+	models.SortUsers(users)
+	return users[0], nil
+}
+
+// list returns the data of the list with IDHex listID
+func (c *localClient) list(listID string) db.DBInterface {
+	return c.data.ForShoppingList(listID)
+}
+
+func (c *localClient) Lists(ctx context.Context) ([]models.APIShoppingList, error) {
+	lists, err := c.data.GetShoppingLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]models.APIShoppingList, 0, len(lists))
+	for _, list := range lists {
+		resources = append(resources, models.NewAPIShoppingList(list))
+	}
+	return resources, nil
+}
+
+func (c *localClient) Items(ctx context.Context, listID string) ([]models.APIShoppingListItem, error) {
+	shoppingList, err := c.list(listID).GetShoppingList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]models.APIShoppingListItem, 0, len(shoppingList))
+	for _, item := range shoppingList {
+		items = append(items, models.NewAPIShoppingListItem(listID, item))
+	}
+	return items, nil
+}
+
+func (c *localClient) AddItem(ctx context.Context, listID, text string) (models.APIShoppingListItem, error) {
+	item, err := c.list(listID).AddShoppingListItem(ctx, text)
+	if err != nil {
+		return models.APIShoppingListItem{}, err
+	}
+	return models.NewAPIShoppingListItem(listID, item), nil
+}
+
+func (c *localClient) TickItem(ctx context.Context, listID, itemID string, ticked bool) (models.APIShoppingListItem, error) {
+	item, err := db.TickItem(ctx, c.list(listID), itemID, ticked, c.now())
+	if err != nil {
+		return models.APIShoppingListItem{}, err
+	}
+	return models.NewAPIShoppingListItem(listID, item), nil
+}
+
+func (c *localClient) MealPlan(ctx context.Context, week string) (models.APIMealPlan, error) {
+	mealPlan, err := c.data.GetMealPlan(ctx, week)
+	if err != nil {
+		return models.APIMealPlan{}, err
+	}
+	return models.NewAPIMealPlan(mealPlan, c.slots), nil
+}
+
+func (c *localClient) SetMeal(ctx context.Context, meal models.APIMeal) (models.APIMeal, error) {
+	var recipeID string
+	if meal.Meal != "" {
+		library, err := c.data.GetRecipes(ctx)
+		if err != nil {
+			return meal, err
+		}
+		recipe, _ := recipes.FindByTitle(library, meal.Meal)
+		recipeID = recipe.IDHex
+	}
+	saved, err := c.data.EditMeal(ctx, meal.Week, models.MealSlotInput{
+		Day:      meal.Day,
+		Slot:     meal.Slot,
+		Meal:     meal.Meal,
+		RecipeID: recipeID,
+		Version:  meal.Version,
+	})
+	saved.Week = meal.Week
+	if errors.Is(err, db.ErrConflict) {
+		return models.NewAPIMeal(saved), errors.New("the meal has been changed since it was read")
+	}
+	return models.NewAPIMeal(saved), err
+}
+
+func (c *localClient) Close(ctx context.Context) error {
+	c.store.Close()
+	return nil
+}
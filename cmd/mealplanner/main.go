@@ -0,0 +1,440 @@
+// Command mealplanner reads and changes the shopping lists and meal plan from
+// a terminal or a cron job, either in the database the server is configured
+// with or through a running server's JSON API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/config"
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+const usage = `Usage: mealplanner [flags] <command> [arguments]
+
+Commands:
+  lists                                     Show the shopping lists
+  list show [--list NAME]                   Show the items on a list
+  list add [--list NAME] ITEM...            Add items, e.g. list add "bin bags"
+  list tick [--list NAME] [--untick] ITEM   Tick an item, by name or ID
+  plan show [--week WEEK]                   Show a week's meals, e.g. --week 2026-W42
+  meal set [--week WEEK] [--slot SLOT] DAY MEAL
+                                            Set a meal, e.g. meal set monday "curry"
+
+Without --server, the database is opened using the server's settings
+(GO_ENV, STORAGE_DRIVER and so on). With --server, the server's JSON API is
+used, signing in with MEALPLANNER_TOKEN, or with --user and
+MEALPLANNER_PASSWORD.
+
+Flags:
+`
+
+// errUsage is returned for commands that are not run as usage describes
+var errUsage = errors.New("invalid usage")
+
+// app holds what commands read and write, so that tests can replace them
+type app struct {
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+	now    func() time.Time
+	// openStore opens the database for local mode, returning the settings
+	// it was opened with
+	openStore func(ctx context.Context) (db.Store, *config.Config, error)
+}
+
+// command is a subcommand, run with the client, a printer and the arguments
+// after its name. ctx is cancelled when the command is interrupted or runs
+// out of time.
+type command func(a *app, ctx context.Context, c client, p printer, args []string) error
+
+var commands = map[string]command{
+	"lists":     (*app).lists,
+	"list show": (*app).listShow,
+	"list add":  (*app).listAdd,
+	"list tick": (*app).listTick,
+	"plan show": (*app).planShow,
+	"meal set":  (*app).mealSet,
+}
+
+func main() {
+	a := &app{
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		getenv:    os.Getenv,
+		now:       time.Now,
+		openStore: openConfiguredStore,
+	}
+	err := a.run(os.Args[1:])
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "mealplanner: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// openConfiguredStore opens the database the server is configured to use
+func openConfiguredStore(ctx context.Context) (db.Store, *config.Config, error) {
+	cfg := config.LoadConfig()
+	store, err := db.Open(ctx, cfg)
+	return store, cfg, err
+}
+
+// run parses the global flags and runs the command named by the arguments
+func (a *app) run(args []string) error {
+	flags := a.flagSet("mealplanner", usage)
+	format := flags.String("format", formatTable, "output format: table, json or text")
+	server := flags.String("server", a.getenv("MEALPLANNER_SERVER"), "URL of the server to use, instead of the database (MEALPLANNER_SERVER)")
+	token := flags.String("token", a.getenv("MEALPLANNER_TOKEN"), "session token for --server (MEALPLANNER_TOKEN)")
+	user := flags.String("user", a.getenv("MEALPLANNER_USER"), "email of the user to act as (MEALPLANNER_USER)")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	run, args := findCommand(flags.Args())
+	if run == nil {
+		if len(args) > 0 {
+			fmt.Fprintf(a.stderr, "Unknown command %q\n\n", strings.Join(args, " "))
+		}
+		flags.Usage()
+		return errUsage
+	}
+	p, err := newPrinter(a.stdout, *format)
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C cancels the command, as does running for longer than a
+	// request to the server, or to the database, may take
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var c client
+	timeout := remoteTimeout
+	if *server != "" {
+		c, err = newRemoteClient(ctx, *server, *token, *user, a.getenv("MEALPLANNER_PASSWORD"))
+	} else {
+		c, timeout, err = a.openLocal(ctx, *user)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.Close(ctx); err != nil {
+			fmt.Fprintf(a.stderr, "mealplanner: %s\n", err)
+		}
+	}()
+
+	commandCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		commandCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return run(a, commandCtx, c, p, args)
+}
+
+// openLocal opens the database as the user with email, returning how long
+// the database is configured to give a request
+func (a *app) openLocal(ctx context.Context, email string) (client, time.Duration, error) {
+	store, cfg, err := a.openStore(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	c, err := newLocalClient(ctx, store, email, cfg.MealSlots, a.now)
+	if err != nil {
+		store.Close()
+		return nil, 0, err
+	}
+	return c, cfg.RequestTimeout, nil
+}
+
+// findCommand returns the command named by the first one or two arguments,
+// and the arguments after its name
+func findCommand(args []string) (command, []string) {
+	if len(args) >= 2 {
+		if run, ok := commands[args[0]+" "+args[1]]; ok {
+			return run, args[2:]
+		}
+	}
+	if len(args) >= 1 {
+		if run, ok := commands[args[0]]; ok {
+			return run, args[1:]
+		}
+	}
+	return nil, args
+}
+
+// flagSet returns an empty set of flags that reports errors rather than
+// exiting
+func (a *app) flagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(a.stderr)
+	flags.Usage = func() {
+		fmt.Fprint(a.stderr, usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses a command's flags, which may come before, between or after
+// its arguments, and returns the arguments. "--" ends the flags.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return nil, parseError(err)
+		}
+		rest := flags.Args()
+		if parsed := args[:len(args)-len(rest)]; len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return positional, nil
+}
+
+// parseError turns a flag parsing error, which the flag package has already
+// reported, into errUsage. Asking for help is returned as flag.ErrHelp.
+func parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return errUsage
+}
+
+// badUsage reports how a command is used and returns errUsage
+func badUsage(flags *flag.FlagSet) error {
+	flags.Usage()
+	return errUsage
+}
+
+// lists shows the household's shopping lists
+func (a *app) lists(ctx context.Context, c client, p printer, args []string) error {
+	flags := a.flagSet("lists", "Usage: mealplanner lists\n")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return badUsage(flags)
+	}
+	lists, err := c.Lists(ctx)
+	if err != nil {
+		return err
+	}
+	return p.lists(lists)
+}
+
+// listShow shows the items of a shopping list
+func (a *app) listShow(ctx context.Context, c client, p printer, args []string) error {
+	flags := a.flagSet("list show", "Usage: mealplanner list show [--list NAME]\n")
+	listName := flags.String("list", "", "name or ID of the list, instead of the first")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return badUsage(flags)
+	}
+	list, err := chooseList(ctx, c, *listName)
+	if err != nil {
+		return err
+	}
+	items, err := c.Items(ctx, list.ID)
+	if err != nil {
+		return err
+	}
+	return p.items(items)
+}
+
+// listAdd adds each argument to a shopping list as an item
+func (a *app) listAdd(ctx context.Context, c client, p printer, args []string) error {
+	flags := a.flagSet("list add", "Usage: mealplanner list add [--list NAME] ITEM...\n")
+	listName := flags.String("list", "", "name or ID of the list, instead of the first")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return badUsage(flags)
+	}
+	list, err := chooseList(ctx, c, *listName)
+	if err != nil {
+		return err
+	}
+	var added []models.APIShoppingListItem
+	for _, text := range args {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		item, err := c.AddItem(ctx, list.ID, text)
+		if err != nil {
+			return fmt.Errorf("adding %q: %w", text, err)
+		}
+		added = append(added, item)
+	}
+	if len(added) == 1 {
+		return p.item(added[0])
+	}
+	return p.items(added)
+}
+
+// listTick ticks, or with --untick unticks, the item named by the arguments
+func (a *app) listTick(ctx context.Context, c client, p printer, args []string) error {
+	flags := a.flagSet("list tick", "Usage: mealplanner list tick [--list NAME] [--untick] ITEM\n")
+	listName := flags.String("list", "", "name or ID of the list, instead of the first")
+	untick := flags.Bool("untick", false, "untick the item instead")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return badUsage(flags)
+	}
+	list, err := chooseList(ctx, c, *listName)
+	if err != nil {
+		return err
+	}
+	items, err := c.Items(ctx, list.ID)
+	if err != nil {
+		return err
+	}
+	item, err := findItem(items, strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	item, err = c.TickItem(ctx, list.ID, item.ID, !*untick)
+	if err != nil {
+		return err
+	}
+	return p.item(item)
+}
+
+// planShow shows the meals of a week
+func (a *app) planShow(ctx context.Context, c client, p printer, args []string) error {
+	flags := a.flagSet("plan show", "Usage: mealplanner plan show [--week WEEK]\n")
+	week := flags.String("week", models.ISOWeek(a.now()), "ISO week, e.g. 2026-W42")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return badUsage(flags)
+	}
+	if _, err := models.ParseISOWeek(*week); err != nil {
+		return err
+	}
+	plan, err := c.MealPlan(ctx, *week)
+	if err != nil {
+		return err
+	}
+	return p.mealPlan(plan)
+}
+
+// mealSet sets the meal in one slot of a day. The rest of the arguments after
+// the day are the meal, so quoting it is optional; "" clears the slot.
+func (a *app) mealSet(ctx context.Context, c client, p printer, args []string) error {
+	flags := a.flagSet("meal set", "Usage: mealplanner meal set [--week WEEK] [--slot SLOT] DAY MEAL\n")
+	week := flags.String("week", models.ISOWeek(a.now()), "ISO week, e.g. 2026-W42")
+	slot := flags.String("slot", "Dinner", "meal slot")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return badUsage(flags)
+	}
+	if _, err := models.ParseISOWeek(*week); err != nil {
+		return err
+	}
+	plan, err := c.MealPlan(ctx, *week)
+	if err != nil {
+		return err
+	}
+	meal, err := findMeal(plan, args[0], *slot)
+	if err != nil {
+		return err
+	}
+	meal.Meal = strings.TrimSpace(strings.Join(args[1:], " "))
+	saved, err := c.SetMeal(ctx, meal)
+	if err != nil {
+		return err
+	}
+	return p.meal(saved)
+}
+
+// chooseList returns the list with the name or ID given, ignoring case, or
+// the first list that is not archived when name is ""
+func chooseList(ctx context.Context, c client, name string) (models.APIShoppingList, error) {
+	lists, err := c.Lists(ctx)
+	if err != nil {
+		return models.APIShoppingList{}, err
+	}
+	for _, list := range lists {
+		if (name == "" && !list.Archived) || list.ID == name || (name != "" && strings.EqualFold(list.Name, name)) {
+			return list, nil
+		}
+	}
+	if name == "" && len(lists) > 0 {
+		return lists[0], nil
+	}
+	return models.APIShoppingList{}, fmt.Errorf("no shopping list called %q", name)
+}
+
+// findItem returns the item with the ID given, or whose name or text is
+// query, ignoring case
+func findItem(items []models.APIShoppingListItem, query string) (models.APIShoppingListItem, error) {
+	var found []models.APIShoppingListItem
+	for _, item := range items {
+		if item.ID == query {
+			return item, nil
+		}
+		if strings.EqualFold(item.Item, query) || strings.EqualFold(item.Text, query) {
+			found = append(found, item)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return models.APIShoppingListItem{}, fmt.Errorf("no item %q on the list", query)
+	case 1:
+		return found[0], nil
+	}
+	return models.APIShoppingListItem{}, fmt.Errorf("%q matches %d items: use its ID", query, len(found))
+}
+
+// findMeal returns the meal plan's slot on day, ignoring case
+func findMeal(plan models.APIMealPlan, day, slot string) (models.APIMeal, error) {
+	knownDay := false
+	var slots []string
+	for _, meal := range plan.Meals {
+		if !strings.EqualFold(meal.Day, day) {
+			continue
+		}
+		if strings.EqualFold(meal.Slot, slot) {
+			return meal, nil
+		}
+		knownDay = true
+		slots = append(slots, meal.Slot)
+	}
+	if !knownDay {
+		return models.APIMeal{}, fmt.Errorf("unknown day %q", day)
+	}
+	return models.APIMeal{}, fmt.Errorf("unknown meal slot %q (expected one of %s)", slot, strings.Join(slots, ", "))
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/config"
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/handlers"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNow is in the ISO week 2026-W42
+var testNow = time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+
+// newTestApp runs commands against store, with env as the environment
+func newTestApp(store db.Store, env map[string]string) (*app, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &app{
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(key string) string { return env[key] },
+		now:    func() time.Time { return testNow },
+		openStore: func(context.Context) (db.Store, *config.Config, error) {
+			return store, &config.Config{MealSlots: models.DefaultMealSlots}, nil
+		},
+	}, &stdout, &stderr
+}
+
+// runCommand runs the command line and returns what it printed
+func runCommand(t *testing.T, a *app, stdout *bytes.Buffer, args ...string) string {
+	t.Helper()
+	stdout.Reset()
+	require.NoError(t, a.run(args))
+	return stdout.String()
+}
+
+// newRemoteServer serves the JSON API routes the client uses
+func newRemoteServer(store db.Store) *httptest.Server {
+	h := handlers.New(store)
+	h.TemplatePath = "../../pkg/templates/index.html"
+	mux := http.NewServeMux()
+	private := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, h.RequireLogin(handler))
+	}
+	api := handlers.APIPrefix
+	mux.HandleFunc("POST "+api+"/session", h.APISessionHandler)
+	private("DELETE "+api+"/session", h.APIEndSessionHandler)
+	private("GET "+api+"/meal-plans/{week}", h.APIMealPlanHandler)
+	private("PUT "+api+"/meal-plans/{week}/meals/{day}/{slot}", h.APIMealHandler)
+	private("GET "+api+"/lists", h.APIListsHandler)
+	private("GET "+api+"/lists/{id}/items", h.APIItemsHandler)
+	private("POST "+api+"/lists/{id}/items", h.APICreateItemHandler)
+	private("PATCH "+api+"/lists/{id}/items/{item}", h.APIUpdateItemHandler)
+	return httptest.NewServer(mux)
+}
+
+func TestLocalCommands(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryDB()
+	_, err := db.Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	a, stdout, _ := newTestApp(store, nil)
+
+	out := runCommand(t, a, stdout, "list", "add", "bin bags", "2kg potatoes")
+	assert.Contains(t, out, "bin bags")
+	assert.Contains(t, out, "2 kg potatoes")
+
+	out = runCommand(t, a, stdout, "--format", "text", "list", "tick", "Bin Bags")
+	assert.Equal(t, "[x] bin bags\n", out)
+
+	out = runCommand(t, a, stdout, "--format", "text", "list", "show")
+	assert.Equal(t, "[x] bin bags\n[ ] 2 kg potatoes\n", out)
+
+	out = runCommand(t, a, stdout, "--format", "json", "meal", "set", "monday", "fish", "pie")
+	var meal models.APIMeal
+	require.NoError(t, json.Unmarshal([]byte(out), &meal))
+	assert.Equal(t, models.APIMeal{Week: "2026-W42", Day: "Monday", Slot: "Dinner", Meal: "fish pie", Version: 1}, meal)
+	runCommand(t, a, stdout, "meal", "set", "--slot", "lunch", "tuesday", "Soup")
+
+	out = runCommand(t, a, stdout, "--format", "text", "plan", "show", "--week", "2026-W42")
+	assert.Equal(t, "Monday Dinner: fish pie\nTuesday Lunch: Soup\n", out)
+	out = runCommand(t, a, stdout, "plan", "show")
+	assert.Contains(t, out, "Week 2026-W42, from 2026-10-12")
+	assert.Regexp(t, `Monday\s+Dinner\s+fish pie`, out)
+
+	households, err := store.GetHouseholds(ctx)
+	require.NoError(t, err)
+	history, err := store.ForHousehold(households[0].IDHex).GetChanges(ctx, "", 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, "Sam", history[0].Who(), "changes are made as the household's user")
+}
+
+func TestCommandErrors(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryDB()
+	_, err := db.Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	a, _, stderr := newTestApp(store, nil)
+
+	assert.ErrorIs(t, a.run([]string{"list", "sing"}), errUsage)
+	assert.Contains(t, stderr.String(), `Unknown command "list sing"`)
+	assert.ErrorIs(t, a.run([]string{"list", "add"}), errUsage)
+	assert.ErrorIs(t, a.run([]string{"list", "show", "--colour"}), errUsage)
+
+	assert.EqualError(t, a.run([]string{"--format", "yaml", "lists"}), `unknown format "yaml" (expected table, json or text)`)
+	assert.EqualError(t, a.run([]string{"list", "show", "--list", "Hardware"}), `no shopping list called "Hardware"`)
+	assert.EqualError(t, a.run([]string{"list", "tick", "milk"}), `no item "milk" on the list`)
+	assert.EqualError(t, a.run([]string{"meal", "set", "someday", "Soup"}), `unknown day "someday"`)
+	assert.EqualError(t, a.run([]string{"meal", "set", "--slot", "Elevenses", "monday", "Cake"}),
+		`unknown meal slot "Elevenses" (expected one of Breakfast, Lunch, Dinner, Snacks)`)
+	assert.Error(t, a.run([]string{"plan", "show", "--week", "last week"}))
+	assert.EqualError(t, a.run([]string{"--user", "alex@example.com", "lists"}), "no user with the email alex@example.com")
+
+	_, err = db.Register(ctx, store, "alex@example.com", "Alex", "correct-horse", "Flat")
+	require.NoError(t, err)
+	assert.EqualError(t, a.run([]string{"lists"}), "there is more than one household: choose a user with --user")
+	assert.NoError(t, a.run([]string{"--user", "alex@example.com", "lists"}))
+}
+
+func TestRemoteCommands(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryDB()
+	_, err := db.Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	server := newRemoteServer(store)
+	defer server.Close()
+
+	env := map[string]string{"MEALPLANNER_SERVER": server.URL, "MEALPLANNER_USER": "sam@example.com", "MEALPLANNER_PASSWORD": "correct-horse"}
+	a, stdout, _ := newTestApp(nil, env)
+	a.openStore = func(context.Context) (db.Store, *config.Config, error) {
+		t.Fatal("remote commands do not open the database")
+		return nil, nil, nil
+	}
+
+	out := runCommand(t, a, stdout, "--format", "text", "list", "add", "bin bags")
+	assert.Equal(t, "[ ] bin bags\n", out)
+	out = runCommand(t, a, stdout, "--format", "text", "list", "tick", "bin bags")
+	assert.Equal(t, "[x] bin bags\n", out)
+	out = runCommand(t, a, stdout, "--format", "json", "list", "show")
+	var items []models.APIShoppingListItem
+	require.NoError(t, json.Unmarshal([]byte(out), &items))
+	require.Len(t, items, 1)
+	assert.True(t, items[0].Ticked)
+
+	runCommand(t, a, stdout, "meal", "set", "monday", "curry")
+	out = runCommand(t, a, stdout, "--format", "text", "plan", "show")
+	assert.Equal(t, "Monday Dinner: curry\n", out)
+
+	// A token, as a cron job would keep, is used instead of signing in
+	user, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	token, err := db.StartSession(ctx, store, user, time.Now())
+	require.NoError(t, err)
+	delete(env, "MEALPLANNER_PASSWORD")
+	env["MEALPLANNER_TOKEN"] = token
+	out = runCommand(t, a, stdout, "--format", "text", "lists")
+	assert.Equal(t, "Shopping list\n", out)
+
+	env["MEALPLANNER_TOKEN"] = "not-a-token"
+	err = a.run([]string{"lists"})
+	assert.EqualError(t, err, "Sign in required (401)")
+
+	delete(env, "MEALPLANNER_TOKEN")
+	err = a.run([]string{"lists"})
+	assert.True(t, strings.HasPrefix(err.Error(), "signing in to "+server.URL+" needs"), err.Error())
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// Output formats for --format
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatText  = "text"
+)
+
+// printer writes results in the chosen format: aligned columns with a
+// header, the API's JSON, or plain lines for scripts
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// newPrinter checks format is one of the output formats
+func newPrinter(w io.Writer, format string) (printer, error) {
+	switch format {
+	case formatTable, formatJSON, formatText:
+		return printer{w: w, format: format}, nil
+	}
+	return printer{}, fmt.Errorf("unknown format %q (expected %s, %s or %s)", format, formatTable, formatJSON, formatText)
+}
+
+// table writes rows under header as aligned columns
+func (p printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// json writes v as indented JSON
+func (p printer) json(v any) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// lines writes one line per string
+func (p printer) lines(lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(p.w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lists prints shopping lists
+func (p printer) lists(lists []models.APIShoppingList) error {
+	switch p.format {
+	case formatJSON:
+		return p.json(lists)
+	case formatText:
+		var lines []string
+		for _, list := range lists {
+			lines = append(lines, list.Name)
+		}
+		return p.lines(lines)
+	}
+	var rows [][]string
+	for _, list := range lists {
+		rows = append(rows, []string{list.Name, yesNo(list.Archived), list.ID})
+	}
+	return p.table([]string{"NAME", "ARCHIVED", "ID"}, rows)
+}
+
+// items prints the items of a list
+func (p printer) items(items []models.APIShoppingListItem) error {
+	switch p.format {
+	case formatJSON:
+		return p.json(items)
+	case formatText:
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, itemLine(item))
+		}
+		return p.lines(lines)
+	}
+	var rows [][]string
+	for _, item := range items {
+		rows = append(rows, []string{checkbox(item.Ticked), item.Text, item.Category, item.ID})
+	}
+	return p.table([]string{"", "ITEM", "CATEGORY", "ID"}, rows)
+}
+
+// item prints one item, as added or ticked
+func (p printer) item(item models.APIShoppingListItem) error {
+	if p.format == formatJSON {
+		return p.json(item)
+	}
+	return p.items([]models.APIShoppingListItem{item})
+}
+
+// mealPlan prints a week's meals. Plain text leaves out empty slots.
+func (p printer) mealPlan(plan models.APIMealPlan) error {
+	switch p.format {
+	case formatJSON:
+		return p.json(plan)
+	case formatText:
+		var lines []string
+		for _, meal := range plan.Meals {
+			if meal.Meal != "" {
+				lines = append(lines, mealLine(meal))
+			}
+		}
+		return p.lines(lines)
+	}
+	fmt.Fprintf(p.w, "Week %s, from %s\n", plan.Week, plan.WeekStart)
+	var rows [][]string
+	for _, meal := range plan.Meals {
+		rows = append(rows, []string{meal.Day, meal.Slot, meal.Meal})
+	}
+	return p.table([]string{"DAY", "SLOT", "MEAL"}, rows)
+}
+
+// meal prints one meal, as set
+func (p printer) meal(meal models.APIMeal) error {
+	switch p.format {
+	case formatJSON:
+		return p.json(meal)
+	case formatText:
+		return p.lines([]string{mealLine(meal)})
+	}
+	return p.table([]string{"WEEK", "DAY", "SLOT", "MEAL"}, [][]string{{meal.Week, meal.Day, meal.Slot, meal.Meal}})
+}
+
+// itemLine is an item as a line of plain text, e.g. "[x] 2 kg potatoes"
+func itemLine(item models.APIShoppingListItem) string {
+	return checkbox(item.Ticked) + " " + item.Text
+}
+
+// mealLine is a meal as a line of plain text, e.g. "Monday Dinner: Curry"
+func mealLine(meal models.APIMeal) string {
+	return meal.Day + " " + meal.Slot + ": " + meal.Meal
+}
+
+func checkbox(ticked bool) string {
+	if ticked {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
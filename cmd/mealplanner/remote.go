@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/handlers"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// remoteTimeout is how long a request to the server may take
+const remoteTimeout = 30 * time.Second
+
+// remoteClient works through the JSON API of a running server
+type remoteClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+	// signedIn is set when the client started the session, and so ends it
+	signedIn bool
+}
+
+// newRemoteClient connects to the server at serverURL with a session token,
+// or else signs in with email and password
+func newRemoteClient(ctx context.Context, serverURL, token, email, password string) (*remoteClient, error) {
+	c := &remoteClient{
+		baseURL: strings.TrimSuffix(serverURL, "/") + handlers.APIPrefix,
+		token:   token,
+		http:    &http.Client{Timeout: remoteTimeout},
+	}
+	if token != "" {
+		return c, nil
+	}
+	if email == "" || password == "" {
+		return nil, fmt.Errorf("signing in to %s needs MEALPLANNER_TOKEN, or --email and MEALPLANNER_PASSWORD", serverURL)
+	}
+	var session models.APISession
+	if err := c.do(ctx, "POST", "/session", models.APISessionInput{Email: email, Password: password}, &session); err != nil {
+		return nil, err
+	}
+	c.token = session.Token
+	c.signedIn = true
+	return c, nil
+}
+
+// do sends a request with body encoded as JSON, decoding the response into
+// out. Error responses are returned as errors holding the server's message.
+func (c *remoteClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr models.APIError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s (%d)", apiErr.Error, resp.StatusCode)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *remoteClient) Lists(ctx context.Context) ([]models.APIShoppingList, error) {
+	var lists []models.APIShoppingList
+	err := c.do(ctx, "GET", "/lists", nil, &lists)
+	return lists, err
+}
+
+func (c *remoteClient) Items(ctx context.Context, listID string) ([]models.APIShoppingListItem, error) {
+	var items []models.APIShoppingListItem
+	err := c.do(ctx, "GET", "/lists/"+url.PathEscape(listID)+"/items", nil, &items)
+	return items, err
+}
+
+func (c *remoteClient) AddItem(ctx context.Context, listID, text string) (models.APIShoppingListItem, error) {
+	var item models.APIShoppingListItem
+	err := c.do(ctx, "POST", "/lists/"+url.PathEscape(listID)+"/items", models.APIShoppingListItemInput{Text: &text}, &item)
+	return item, err
+}
+
+func (c *remoteClient) TickItem(ctx context.Context, listID, itemID string, ticked bool) (models.APIShoppingListItem, error) {
+	var item models.APIShoppingListItem
+	path := "/lists/" + url.PathEscape(listID) + "/items/" + url.PathEscape(itemID)
+	err := c.do(ctx, "PATCH", path, models.APIShoppingListItemInput{Ticked: &ticked}, &item)
+	return item, err
+}
+
+func (c *remoteClient) MealPlan(ctx context.Context, week string) (models.APIMealPlan, error) {
+	var plan models.APIMealPlan
+	err := c.do(ctx, "GET", "/meal-plans/"+url.PathEscape(week), nil, &plan)
+	return plan, err
+}
+
+func (c *remoteClient) SetMeal(ctx context.Context, meal models.APIMeal) (models.APIMeal, error) {
+	var saved models.APIMeal
+	path := "/meal-plans/" + url.PathEscape(meal.Week) + "/meals/" + url.PathEscape(meal.Day) + "/" + url.PathEscape(meal.Slot)
+	err := c.do(ctx, "PUT", path, models.APIMealInput{Meal: meal.Meal, Version: &meal.Version}, &saved)
+	return saved, err
+}
+
+// Close ends the session if the client started it
+func (c *remoteClient) Close(ctx context.Context) error {
+	if !c.signedIn {
+		return nil
+	}
+	return c.do(ctx, "DELETE", "/session", nil, nil)
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/config"
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+)
+
+const usage = `Usage: %s [command]
+
+Commands:
+  serve               Run the web server (the default)
+  migrate [up]        Apply pending database migrations, then exit
+  migrate status      List the database migrations and which are applied
+  migrate down [--to VERSION]
+                      Undo the migrations newer than VERSION (by default the latest)
+  sync [flags]        Make one MongoDB database match another (see sync --help)
+  copy-prod-to-test   Sync the test database with production
+  verify [--repair]   Check that every shopping list's order holds each item once
+  seed                Add the demo account and its sample data
+  backup [--out FILE] Write a copy of the whole database to FILE
+
+Every command uses the database chosen by GO_ENV, STORAGE_DRIVER and the
+other settings, read from the environment and .env.
+`
+
+// command is a subcommand of the server binary. ctx is cancelled when the
+// command is interrupted.
+type command func(ctx context.Context, cfg *config.Config, args []string) error
+
+// commands are the subcommands, by name
+var commands = map[string]command{
+	"serve":             serve,
+	"migrate":           migrate,
+	"sync":              syncDatabases,
+	"copy-prod-to-test": syncDatabases,
+	"verify":            verify,
+	"seed":              seed,
+	"backup":            backup,
+}
+
+// run runs the subcommand named by args[0], or serve when there is none
+func run(args []string) error {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	switch name {
+	case "help", "-h", "-help", "--help":
+		fmt.Printf(usage, filepath.Base(os.Args[0]))
+		return nil
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Printf(usage, filepath.Base(os.Args[0]))
+		return fmt.Errorf("unknown command %q", name)
+	}
+	// Ctrl-C cancels the command, stopping a long sync, backup or migration
+	// part way rather than killing the process mid-write
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := cmd(ctx, config.LoadConfig(), args); !errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return nil
+}
+
+// migrate applies the pending database migrations, lists them with
+// "status", or undoes them with "down", then exits. Only MongoDB has
+// versioned migrations; SQLite brings its tables up to date when opened.
+func migrate(ctx context.Context, cfg *config.Config, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	to := flags.Int("to", -1, "undo the migrations newer than this version (by default, only the latest)")
+	switch action {
+	case "up", "status", "down":
+	default:
+		return fmt.Errorf("unknown migrate action %q (expected up, status or down)", action)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 || (action != "down" && *to != -1) {
+		return fmt.Errorf("migrate %s takes no arguments, got %q", action, args)
+	}
+	if cfg.StorageDriver != config.DriverMongoDB && cfg.StorageDriver != "" {
+		return fmt.Errorf("the %s backend has no versioned migrations: its tables are brought up to date when it is opened", cfg.StorageDriver)
+	}
+
+	store, err := db.Open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("migrating %s database %s: %w", cfg.StorageDriver, cfg.DatabaseName, err)
+	}
+	defer store.Close()
+
+	switch action {
+	case "status":
+		statuses, err := db.MigrationStatuses(ctx, store)
+		if err != nil {
+			return err
+		}
+		printMigrations(statuses)
+		return nil
+	case "down":
+		if *to == -1 {
+			*to, err = previousMigration(ctx, store)
+			if err != nil {
+				return err
+			}
+		}
+		undone, err := db.MigrateDown(ctx, store, *to)
+		for _, status := range undone {
+			fmt.Printf("Undid migration %d: %s\n", status.Version, status.Description)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("The %s database %s is at migration %d\n", cfg.StorageDriver, cfg.DatabaseName, *to)
+		return nil
+	}
+	if err := applyMigrations(ctx, store); err != nil {
+		return err
+	}
+	fmt.Printf("The %s database %s is up to date\n", cfg.StorageDriver, cfg.DatabaseName)
+	return nil
+}
+
+// startupMigrations applies the pending migrations when the server starts,
+// or only warns about them when AUTO_MIGRATE is false
+func startupMigrations(ctx context.Context, cfg *config.Config, store db.Store) error {
+	if cfg.AutoMigrate {
+		return applyMigrations(ctx, store)
+	}
+	statuses, err := db.MigrationStatuses(ctx, store)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied() {
+			pending++
+		}
+	}
+	if pending > 0 {
+		fmt.Printf("Warning: %d database migrations are pending; run the migrate command to apply them\n", pending)
+	}
+	return nil
+}
+
+// applyMigrations applies the pending migrations of store, printing each
+func applyMigrations(ctx context.Context, store db.Store) error {
+	applied, err := db.MigrateUp(ctx, store)
+	for _, status := range applied {
+		fmt.Printf("Applied migration %d: %s\n", status.Version, status.Description)
+	}
+	return err
+}
+
+// previousMigration returns the version of the applied migration before the
+// latest, which migrate down goes back to by default
+func previousMigration(ctx context.Context, store db.Store) (int, error) {
+	statuses, err := db.MigrationStatuses(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+	var applied []int
+	for _, status := range statuses {
+		if status.Applied() {
+			applied = append(applied, status.Version)
+		}
+	}
+	if len(applied) == 0 {
+		return 0, fmt.Errorf("there are no applied migrations to undo")
+	}
+	if len(applied) == 1 {
+		return 0, nil
+	}
+	return applied[len(applied)-2], nil
+}
+
+// printMigrations prints a table of migrations and when each was applied
+func printMigrations(statuses []db.MigrationStatus) {
+	if len(statuses) == 0 {
+		fmt.Println("This storage backend has no versioned migrations")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tAPPLIED\tDESCRIPTION")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.Applied() {
+			applied = status.AppliedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, applied, status.Description)
+	}
+	w.Flush()
+}
+
+// syncDatabases makes one MongoDB database match another, by default the
+// test database match production. Only the documents that differ are
+// written, after asking unless --yes or --dry-run is given.
+func syncDatabases(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	from := flags.String("from", config.DatabaseNameFor("production"), "database to copy from")
+	to := flags.String("to", config.DatabaseNameFor("development"), "database to copy to")
+	fromURI := flags.String("from-uri", cfg.MongoURI, "MongoDB deployment to copy from")
+	toURI := flags.String("to-uri", cfg.MongoURI, "MongoDB deployment to copy to")
+	collections := flags.String("collections", strings.Join(db.SyncCollections, ","), "comma-separated collections to copy")
+	dryRun := flags.Bool("dry-run", false, "show what would change without changing anything")
+	anonymise := flags.Bool("anonymise", false, "scramble lists, meals, recipes and user details on the way, and reset passwords to the demo password")
+	key := flags.String("anonymise-key", "", "key to scramble with, so repeated syncs scramble alike (random by default)")
+	batchSize := flags.Int("batch-size", db.DefaultSyncBatchSize, "documents to write at a time")
+	yes := flags.Bool("yes", false, "do not ask before overwriting the destination")
+	force := flags.Bool("force", false, "allow overwriting the production database")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("sync takes no arguments, got %q", flags.Args())
+	}
+	if *fromURI == "" || *toURI == "" {
+		return fmt.Errorf("sync copies MongoDB databases: set GO_SHOPPING_MONGO_ATLAS_URI or give --from-uri and --to-uri")
+	}
+	if *to == config.DatabaseNameFor("production") && !*dryRun && !*force {
+		return fmt.Errorf("not overwriting the production database %s without --force", *to)
+	}
+
+	options := db.SyncOptions{DryRun: *dryRun, BatchSize: *batchSize}
+	for _, name := range strings.Split(*collections, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			options.Collections = append(options.Collections, name)
+		}
+	}
+	if len(options.Collections) == 0 {
+		return fmt.Errorf("no collections to sync")
+	}
+	if *anonymise {
+		options.AnonymiseKey = *key
+		if options.AnonymiseKey == "" {
+			options.AnonymiseKey = time.Now().Format(time.RFC3339Nano)
+		}
+	}
+	if !*dryRun && !*yes && !confirm(fmt.Sprintf("This will overwrite %s in %s. Are you sure?", strings.Join(options.Collections, ", "), *to)) {
+		return fmt.Errorf("cancelled")
+	}
+
+	source, err := db.ConnectMongo(ctx, *fromURI)
+	if err != nil {
+		return fmt.Errorf("connecting to MongoDB: %w", err)
+	}
+	defer source.Disconnect(ctx)
+	dest := source
+	if *toURI != *fromURI {
+		if dest, err = db.ConnectMongo(ctx, *toURI); err != nil {
+			return fmt.Errorf("connecting to MongoDB: %w", err)
+		}
+		defer dest.Disconnect(ctx)
+	}
+
+	summaries, err := db.Sync(ctx, source.Database(*from), dest.Database(*to), options)
+	printSyncSummaries(summaries, *dryRun)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("Dry run: %s was not changed\n", *to)
+	} else {
+		fmt.Printf("%s now matches %s\n", *to, *from)
+	}
+	return nil
+}
+
+// printSyncSummaries prints a table of what a sync changed in each collection
+func printSyncSummaries(summaries []db.SyncSummary, dryRun bool) {
+	if len(summaries) == 0 {
+		return
+	}
+	verb := "Synced"
+	if dryRun {
+		verb = "Would sync"
+	}
+	fmt.Printf("%s:\n", verb)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "COLLECTION\tADDED\tCHANGED\tREMOVED\tUNCHANGED\t")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t\n", s.Collection, s.Added, s.Changed, s.Removed, s.Unchanged)
+	}
+	w.Flush()
+}
+
+// confirm asks a yes or no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s (y/N): ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "y")
+}
+
+// verify checks, and with --repair fixes, the order of every household's
+// shopping lists, failing when it finds drift it was not asked to repair
+func verify(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "put right what is found")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("verify takes no arguments, got %q", flags.Args())
+	}
+
+	store, err := db.Open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", cfg.StorageDriver, err)
+	}
+	defer store.Close()
+
+	check, verb := store.VerifyIntegrity, "Found"
+	if *repair {
+		check, verb = store.Repair, "Repaired"
+	}
+	report, err := check(ctx)
+	if err != nil {
+		return err
+	}
+	for _, problem := range report.Problems {
+		fmt.Printf("%s %s\n", verb, problem)
+	}
+	fmt.Printf("Checked %d shopping lists in %s: %d out of order\n", report.Lists, cfg.DatabaseName, len(report.Problems))
+	if !report.OK() && !*repair {
+		return fmt.Errorf("%d shopping lists are out of order: run verify --repair to fix them", len(report.Problems))
+	}
+	return nil
+}
+
+// seed registers the demo account, with its sample data, in the database.
+// Production is only seeded with --force.
+func seed(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	force := flags.Bool("force", false, "seed the production database too")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if cfg.IsProduction() && !*force {
+		return fmt.Errorf("not seeding the production database %s without --force", cfg.DatabaseName)
+	}
+	if cfg.StorageDriver == config.DriverMemory {
+		return fmt.Errorf("the memory storage driver keeps nothing, so there is nothing to seed")
+	}
+
+	store, err := db.Open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", cfg.StorageDriver, err)
+	}
+	defer store.Close()
+	if _, err := db.SeedDemoAccount(ctx, store); err != nil {
+		return err
+	}
+	fmt.Printf("Seeded %s: sign in as %s with the password %s\n", cfg.DatabaseName, db.DemoEmail, db.DemoPassword)
+	return nil
+}
+
+// backup writes a copy of the database to the file given by --out, by
+// default in ./backups named after the database and the time
+func backup(ctx context.Context, cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := flags.String("out", "", "file to write the backup to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		extension := ".jsonl"
+		if cfg.StorageDriver == config.DriverSQLite {
+			extension = ".db"
+		}
+		*out = filepath.Join("backups", cfg.DatabaseName+"-"+time.Now().Format("20060102-150405")+extension)
+	}
+
+	store, err := db.Open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", cfg.StorageDriver, err)
+	}
+	defer store.Close()
+
+	// Written beside the backup, then renamed, so a failed backup leaves no
+	// file that looks complete
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(*out), filepath.Base(*out)+".*.partial")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := db.Backup(ctx, store, file); err != nil {
+		file.Close()
+		return fmt.Errorf("backing up %s: %w", cfg.DatabaseName, err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), *out); err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %s to %s\n", cfg.DatabaseName, *out)
+	return nil
+}
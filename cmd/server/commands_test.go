@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/config"
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqliteConfig configures a development SQLite database in a temporary
+// directory
+func sqliteConfig(t *testing.T) *config.Config {
+	return &config.Config{
+		Environment:   "development",
+		DatabaseName:  config.DatabaseNameFor("development"),
+		StorageDriver: config.DriverSQLite,
+		SQLitePath:    filepath.Join(t.TempDir(), "test.db"),
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	assert.EqualError(t, run([]string{"deploy"}), `unknown command "deploy"`)
+	assert.NoError(t, run([]string{"help"}))
+}
+
+func TestSeedAndBackup(t *testing.T) {
+	ctx := context.Background()
+	cfg := sqliteConfig(t)
+	require.NoError(t, seed(context.Background(), cfg, nil))
+	assert.Error(t, seed(context.Background(), cfg, nil), "the demo account is only added once")
+
+	out := filepath.Join(t.TempDir(), "backups", "copy.db")
+	require.NoError(t, backup(context.Background(), cfg, []string{"--out", out}))
+	entries, err := os.ReadDir(filepath.Dir(out))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "nothing is left behind but the backup")
+
+	restored, err := db.NewSQLiteDB(ctx, out)
+	require.NoError(t, err)
+	defer restored.Close()
+	user, err := restored.GetUserByEmail(ctx, db.DemoEmail)
+	require.NoError(t, err)
+	items, err := restored.ForHousehold(user.HouseholdID).GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, items)
+}
+
+func TestVerifyCommand(t *testing.T) {
+	cfg := sqliteConfig(t)
+	require.NoError(t, seed(context.Background(), cfg, nil))
+	require.NoError(t, verify(context.Background(), cfg, nil))
+
+	store, err := db.NewSQLiteDB(context.Background(), cfg.SQLitePath)
+	require.NoError(t, err)
+	_, err = store.DB.Exec(`UPDATE shopping_list_items SET sort_position = NULL`)
+	require.NoError(t, err)
+	store.Close()
+
+	assert.ErrorContains(t, verify(context.Background(), cfg, nil), "out of order: run verify --repair")
+	require.NoError(t, verify(context.Background(), cfg, []string{"--repair"}))
+	assert.NoError(t, verify(context.Background(), cfg, nil))
+}
+
+func TestMigrateCommand(t *testing.T) {
+	cfg := sqliteConfig(t)
+	for _, args := range [][]string{nil, {"up"}, {"status"}, {"down"}} {
+		assert.EqualError(t, migrate(context.Background(), cfg, args), "the sqlite backend has no versioned migrations: its tables are brought up to date when it is opened", args)
+	}
+	assert.NoFileExists(t, cfg.SQLitePath, "the database is not opened")
+	assert.EqualError(t, migrate(context.Background(), cfg, []string{"sideways"}), `unknown migrate action "sideways" (expected up, status or down)`)
+	assert.Error(t, migrate(context.Background(), cfg, []string{"up", "--to", "1"}))
+}
+
+func TestCommandsRefuse(t *testing.T) {
+	cfg := sqliteConfig(t)
+	cfg.Environment = "production"
+	assert.EqualError(t, seed(context.Background(), cfg, nil), "not seeding the production database GoShopping-test without --force")
+	assert.EqualError(t, syncDatabases(context.Background(), cfg, []string{"--yes"}), "sync copies MongoDB databases: set GO_SHOPPING_MONGO_ATLAS_URI or give --from-uri and --to-uri")
+	cfg.MongoURI = "mongodb://localhost:1"
+	assert.EqualError(t, syncDatabases(context.Background(), cfg, []string{"--from", "GoShopping-test", "--to", "GoShopping", "--yes"}), "not overwriting the production database GoShopping without --force")
+	assert.EqualError(t, syncDatabases(context.Background(), cfg, []string{"--collections", " , ", "--yes"}), "no collections to sync")
+
+	memory := &config.Config{Environment: "development", DatabaseName: "GoShopping-test", StorageDriver: config.DriverMemory}
+	out := filepath.Join(t.TempDir(), "memory.jsonl")
+	assert.ErrorIs(t, backup(context.Background(), memory, []string{"--out", out}), db.ErrNoBackup)
+	assert.NoFileExists(t, out)
+	assert.Error(t, migrate(context.Background(), memory, []string{"now"}))
+}
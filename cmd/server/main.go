@@ -1,50 +1,193 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/JonClarke84/mealplannergo/pkg/config"
 	"github.com/JonClarke84/mealplannergo/pkg/db"
 	"github.com/JonClarke84/mealplannergo/pkg/handlers"
 )
 
+// walkOrderInterval is how often the walk order is relearned from ticks
+const walkOrderInterval = 10 * time.Minute
+
+// shutdownTimeout is how long the server waits for requests under way to
+// finish once interrupted
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	// Load configuration
-	cfg := config.LoadConfig()
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve runs the web server until it fails or ctx is cancelled
+func serve(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("serve takes no arguments, got %q", args)
+	}
 
 	// Display environment information
 	fmt.Printf("Starting server in %s environment\n", cfg.Environment)
-	fmt.Printf("Using database: %s\n", cfg.DatabaseName)
+	fmt.Printf("Using %s storage with database: %s\n", cfg.StorageDriver, cfg.DatabaseName)
 
 	// Initialize database connection
-	mongoDB, err := db.NewMongoDB(cfg.MongoURI, cfg.DatabaseName)
+	store, err := db.Open(ctx, cfg)
 	if err != nil {
-		fmt.Printf("Error connecting to MongoDB: %s\n", err)
-		os.Exit(1)
+		return fmt.Errorf("connecting to %s: %w", cfg.StorageDriver, err)
+	}
+	defer store.Close()
+	if err := startupMigrations(ctx, cfg, store); err != nil {
+		return err
 	}
-	defer mongoDB.Close()
 
 	// Initialize handlers
-	h := handlers.New(mongoDB)
+	h := handlers.New(store)
+	h.MealSlots = cfg.MealSlots
+	h.SecureCookies = cfg.SecureCookies
+	h.RequestTimeout = cfg.RequestTimeout
 
-	// Define routes
-	http.HandleFunc("/", h.HomeHandler)
-	http.HandleFunc("/meal", h.MealHandler)
-	http.HandleFunc("/shopping-list", h.ShoppingListHandler)
-	http.HandleFunc("/shopping-list/tick", h.ShoppingListTickHandler)
-	http.HandleFunc("/shopping-list/sort", h.ShoppingListSortHandler)
-	http.HandleFunc("/shopping-list/edit", h.ShoppingListEditHandler)
+	if cfg.IsDemo() {
+		if _, err := db.SeedDemoAccount(ctx, store); err != nil {
+			return fmt.Errorf("seeding demo data: %w", err)
+		}
+		h.LoginHint = fmt.Sprintf("Sign in as %s with the password %s", db.DemoEmail, db.DemoPassword)
+		fmt.Println("Demo mode: loaded sample data, changes are not saved")
+		fmt.Println(h.LoginHint)
+	}
 
-	// Serve static files
-	publicFileServer := http.FileServer(http.Dir("./public"))
-	http.Handle("/public/", http.StripPrefix("/public/", publicFileServer))
+	// Relearn the order items are picked up in as new ticks are recorded
+	stopLearner := db.StartWalkOrderLearner(ctx, store, walkOrderInterval)
+	defer stopLearner()
 
 	// Start server
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: newRouter(h)}
+	failed := make(chan error, 1)
+	go func() { failed <- server.ListenAndServe() }()
 	fmt.Printf("Server starting on port %s...\n", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, nil); err != nil {
-		fmt.Printf("Error starting server: %s\n", err)
-		os.Exit(1)
+	select {
+	case err := <-failed:
+		return fmt.Errorf("starting server: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Println("Server stopping...")
+	// The context is already cancelled, so requests under way are given
+	// their own time to finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("stopping server: %w", err)
+	}
+	return nil
+}
+
+// newRouter registers every route. Everything but signing in, the OpenAPI
+// document and the static files requires a signed-in user; JSON API clients
+// send their session token as a bearer token. Each request has
+// h.RequestTimeout to finish, but for the event streams, which stay open.
+func newRouter(h *handlers.Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	public := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, h.WithDeadline(handler))
+	}
+	private := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, h.WithDeadline(h.RequireLogin(handler)))
+	}
+
+	private("/", h.HomeHandler)
+	private("/meal", h.MealHandler)
+	private("/shopping-list", h.ShoppingListHandler)
+	private("/shopping-list/tick", h.ShoppingListTickHandler)
+	private("/shopping-list/sort", h.ShoppingListSortHandler)
+	private("/shopping-list/edit", h.ShoppingListEditHandler)
+	private("/shopping-list/build", h.ShoppingListBuildHandler)
+	private("/shopping-list/merge", h.ShoppingListMergeHandler)
+	private("/shopping-list/auto-sort", h.ShoppingListAutoSortHandler)
+	private("/shopping-list/remove-meal", h.ShoppingListRemoveMealHandler)
+	private("/shopping-list/category", h.ShoppingListCategoryHandler)
+	private("/lists", h.ShoppingListsHandler)
+	private("/lists/{id}", h.ShoppingListManageHandler)
+	private("/lists/{id}/archive", h.ShoppingListArchiveHandler)
+	private("/lists/{id}/items", h.ShoppingListHandler)
+	private("/lists/{id}/items/tick", h.ShoppingListTickHandler)
+	private("/lists/{id}/items/edit", h.ShoppingListEditHandler)
+	private("/lists/{id}/items/category", h.ShoppingListCategoryHandler)
+	private("/lists/{id}/items/move", h.ShoppingListMoveHandler)
+	private("/lists/{id}/sort", h.ShoppingListSortHandler)
+	private("/lists/{id}/build", h.ShoppingListBuildHandler)
+	private("/lists/{id}/merge", h.ShoppingListMergeHandler)
+	private("/lists/{id}/auto-sort", h.ShoppingListAutoSortHandler)
+	private("/lists/{id}/remove-meal", h.ShoppingListRemoveMealHandler)
+	mux.Handle("/lists/{id}/events", h.RequireLogin(http.HandlerFunc(h.ShoppingListEventsHandler)))
+	private("/lists/{id}/history", h.ShoppingListHistoryHandler)
+	private("/lists/{id}/history/{change}/undo", h.ShoppingListUndoHandler)
+	private("/lists/{id}/history/{change}/redo", h.ShoppingListRedoHandler)
+	private("/recipes", h.RecipesHandler)
+	private("/recipes/{id}", h.RecipeHandler)
+	private("/stores", h.StoresHandler)
+	private("/stores/{name}", h.StoreHandler)
+	private("/household", h.HouseholdHandler)
+	private("/household/invites", h.HouseholdInvitesHandler)
+	private("/household/members/{id}", h.HouseholdMemberHandler)
+
+	// JSON API, for scripts and other clients. Requests are checked against
+	// the OpenAPI document before they reach the handlers.
+	spec := h.OpenAPI()
+	for _, route := range apiRoutes(h) {
+		handler := h.ValidateAPI(spec, route.pattern, route.handler)
+		if route.public {
+			public(route.pattern, handler)
+		} else {
+			private(route.pattern, handler)
+		}
+	}
+	public("GET /api/openapi.json", h.OpenAPIHandler)
+	private("/api/", h.APINotFoundHandler)
+
+	public("/login", h.LoginHandler)
+	public("/register", h.RegisterHandler)
+	public("/join", h.JoinHandler)
+	public("/logout", h.LogoutHandler)
+
+	// Serve static files
+	publicFileServer := http.FileServer(http.Dir("./public"))
+	mux.Handle("/public/", http.StripPrefix("/public/", publicFileServer))
+
+	return mux
+}
+
+// apiRoute is a JSON API route. Public routes can be used before signing in.
+type apiRoute struct {
+	pattern string
+	handler http.HandlerFunc
+	public  bool
+}
+
+// apiRoutes lists the JSON API routes, which must match the operations in
+// the OpenAPI document
+func apiRoutes(h *handlers.Handler) []apiRoute {
+	api := handlers.APIPrefix
+	return []apiRoute{
+		{"POST " + api + "/session", h.APISessionHandler, true},
+		{"DELETE " + api + "/session", h.APIEndSessionHandler, false},
+		{"GET " + api + "/meal-plans/{week}", h.APIMealPlanHandler, false},
+		{"PUT " + api + "/meal-plans/{week}/meals/{day}/{slot}", h.APIMealHandler, false},
+		{"GET " + api + "/lists", h.APIListsHandler, false},
+		{"POST " + api + "/lists", h.APICreateListHandler, false},
+		{"GET " + api + "/lists/{id}", h.APIListHandler, false},
+		{"PATCH " + api + "/lists/{id}", h.APIUpdateListHandler, false},
+		{"DELETE " + api + "/lists/{id}", h.APIDeleteListHandler, false},
+		{"GET " + api + "/lists/{id}/items", h.APIItemsHandler, false},
+		{"POST " + api + "/lists/{id}/items", h.APICreateItemHandler, false},
+		{"GET " + api + "/lists/{id}/items/{item}", h.APIItemHandler, false},
+		{"PATCH " + api + "/lists/{id}/items/{item}", h.APIUpdateItemHandler, false},
+		{"DELETE " + api + "/lists/{id}/items/{item}", h.APIDeleteItemHandler, false},
+		{"PUT " + api + "/lists/{id}/order", h.APIOrderHandler, false},
 	}
-}
\ No newline at end of file
+}
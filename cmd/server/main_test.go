@@ -1,28 +1,54 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/JonClarke84/mealplannergo/pkg/db"
 	"github.com/JonClarke84/mealplannergo/pkg/db/tests"
 	"github.com/JonClarke84/mealplannergo/pkg/handlers"
 	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/openapi"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// setupTestServer creates a test HTTP server with mocked database
+// setupTestServer creates a test HTTP server with mocked database. History is
+// off, so the mock is only called as the handlers call it.
 func setupTestServer(t *testing.T) (*httptest.Server, *tests.MockDB) {
-	mockDB := new(tests.MockDB)
-	
-	h := handlers.New(mockDB)
+	mockDB := tests.NewMockDB()
+	return newTestServer(mockDB, false), mockDB
+}
+
+// setupMemoryTestServer creates a test HTTP server backed by a real in-memory store
+func setupMemoryTestServer(t *testing.T) (*httptest.Server, *db.MemoryDB) {
+	store := db.NewMemoryDB()
+	return newTestServer(store, true), store
+}
+
+// newTestServer registers every route against the given store, recording
+// changes when history is set. Requests are made as an owner with no
+// household, so they use the data saved before households existed.
+func newTestServer(store db.Store, history bool) *httptest.Server {
+	h := handlers.New(store)
+	h.History = history
 	// Override template path to use test template
 	h.TemplatePath = "./testdata/test_template.html"
-	
+	h.RecipesTemplatePath = "./testdata/test_recipes_template.html"
+	h.StoresTemplatePath = "./testdata/test_stores_template.html"
+	h.LoginTemplatePath = "./testdata/test_login_template.html"
+
 	// Setup routes
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", h.HomeHandler)
@@ -31,10 +57,20 @@ func setupTestServer(t *testing.T) (*httptest.Server, *tests.MockDB) {
 	mux.HandleFunc("/shopping-list/tick", h.ShoppingListTickHandler)
 	mux.HandleFunc("/shopping-list/sort", h.ShoppingListSortHandler)
 	mux.HandleFunc("/shopping-list/edit", h.ShoppingListEditHandler)
-	
-	server := httptest.NewServer(mux)
-	
-	return server, mockDB
+	mux.HandleFunc("/shopping-list/build", h.ShoppingListBuildHandler)
+	mux.HandleFunc("/shopping-list/merge", h.ShoppingListMergeHandler)
+	mux.HandleFunc("/shopping-list/auto-sort", h.ShoppingListAutoSortHandler)
+	mux.HandleFunc("/shopping-list/remove-meal", h.ShoppingListRemoveMealHandler)
+	mux.HandleFunc("/shopping-list/category", h.ShoppingListCategoryHandler)
+	mux.HandleFunc("/recipes", h.RecipesHandler)
+	mux.HandleFunc("/recipes/{id}", h.RecipeHandler)
+	mux.HandleFunc("/stores", h.StoresHandler)
+	mux.HandleFunc("/stores/{name}", h.StoreHandler)
+
+	owner := models.User{Role: models.RoleOwner}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(handlers.WithUser(r.Context(), owner)))
+	}))
 }
 
 func TestHomeRoute(t *testing.T) {
@@ -60,8 +96,10 @@ func TestHomeRoute(t *testing.T) {
 		},
 	}
 	
-	mockDB.On("GetShoppingList").Return(shoppingList, nil)
-	mockDB.On("GetMealPlan").Return(mealPlan, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return(shoppingList, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
+	mockDB.On("GetMealPlan", mock.Anything, models.ISOWeek(time.Now())).Return(mealPlan, nil)
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
 	
 	resp, err := http.Get(server.URL + "/")
 	assert.NoError(t, err)
@@ -75,9 +113,12 @@ func TestMealRoute(t *testing.T) {
 	server, mockDB := setupTestServer(t)
 	defer server.Close()
 	
-	mockDB.On("UpdateMeal", "Monday", "New Meal").Return(nil)
+	edit := models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "New Meal", Version: 2}
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+	mockDB.On("EditMeal", mock.Anything, "2026-W42", edit).Return(models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "New Meal", Version: 3}, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, nil)
 	
-	formData := "Monday=New+Meal"
+	formData := "week=2026-W42&day=Monday&slot=Lunch&meal=New+Meal&version=2"
 	resp, err := http.Post(server.URL+"/meal", "application/x-www-form-urlencoded", strings.NewReader(formData))
 	assert.NoError(t, err)
 	defer resp.Body.Close()
@@ -97,7 +138,9 @@ func TestShoppingListAddRoute(t *testing.T) {
 		Ticked: false,
 	}
 	
-	mockDB.On("AddShoppingListItem", "New Item").Return(newItem, nil)
+	mockDB.On("AddShoppingListItem", mock.Anything, "New Item").Return(newItem, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{newItem}, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
 	
 	formData := "item=New+Item"
 	resp, err := http.Post(server.URL+"/shopping-list", "application/x-www-form-urlencoded", strings.NewReader(formData))
@@ -119,7 +162,8 @@ func TestShoppingListTickRoute(t *testing.T) {
 		Ticked: true,
 	}
 	
-	mockDB.On("TickShoppingListItem", "123", true).Return(updatedItem, nil)
+	mockDB.On("TickShoppingListItem", mock.Anything, "123", true).Return(updatedItem, nil)
+	mockDB.On("RecordTick", mock.Anything, mock.MatchedBy(func(event models.TickEvent) bool { return event.Item == "test item" })).Return(nil)
 	
 	formData := "123=on"
 	resp, err := http.Post(server.URL+"/shopping-list/tick", "application/x-www-form-urlencoded", strings.NewReader(formData))
@@ -145,11 +189,12 @@ func TestShoppingListSortRoute(t *testing.T) {
 		},
 	}
 	
-	mockDB.On("SortShoppingList", mock.MatchedBy(func(o []models.Order) bool {
+	mockDB.On("ReorderShoppingList", mock.Anything, mock.MatchedBy(func(o []models.Order) bool {
 		return len(o) == 2 && o[0].ID == "123" && o[1].ID == "456"
-	})).Return(nil)
+	}), 0).Return(nil)
 	
-	orderUpdate := models.OrderUpdate{Order: orders}
+	version := 0
+	orderUpdate := models.OrderUpdate{Order: orders, Version: &version}
 	jsonData, _ := json.Marshal(orderUpdate)
 	
 	resp, err := http.Post(
@@ -175,13 +220,409 @@ func TestShoppingListEditRoute(t *testing.T) {
 		Ticked: false,
 	}
 	
-	mockDB.On("UpdateShoppingListItem", "123", "Updated Item").Return(updatedItem, nil)
+	mockDB.On("EditShoppingListItem", mock.Anything, models.ShoppingListItem{IDHex: "123", Item: "Updated Item", Version: 1}).Return(updatedItem, nil)
 	
-	formData := "123=Updated+Item"
+	formData := "123=Updated+Item&version=1"
 	resp, err := http.Post(server.URL+"/shopping-list/edit", "application/x-www-form-urlencoded", strings.NewReader(formData))
 	assert.NoError(t, err)
 	defer resp.Body.Close()
 	
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	mockDB.AssertExpectations(t)
-}
\ No newline at end of file
+}
+func TestShoppingListRoutesWithMemoryDB(t *testing.T) {
+	server, store := setupMemoryTestServer(t)
+	defer server.Close()
+
+	postForm := func(path, formData string) *http.Response {
+		resp, err := http.Post(server.URL+path, "application/x-www-form-urlencoded", strings.NewReader(formData))
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := postForm("/shopping-list", "item=Milk")
+	resp.Body.Close()
+	resp = postForm("/shopping-list", "item=Bread")
+	resp.Body.Close()
+
+	list, err := store.GetShoppingList(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	resp = postForm("/shopping-list/edit", list[0].IDHex+"=Oat+milk&version=0")
+	resp.Body.Close()
+	resp = postForm("/shopping-list/tick", list[0].IDHex+"=on")
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Oat milk (checked)")
+	assert.Contains(t, string(body), "Bread")
+}
+
+func TestRecipeRoutesWithMemoryDB(t *testing.T) {
+	server, store := setupMemoryTestServer(t)
+	defer server.Close()
+
+	form := "title=Chilli&servings=4&ingredients=500g+mince%0A1+tin+kidney+beans&method=Brown+the+mince&tags=spicy"
+	resp, err := http.Post(server.URL+"/recipes", "application/x-www-form-urlencoded", strings.NewReader(form))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	library, err := store.GetRecipes(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, library, 1) {
+		resp, err = http.Get(server.URL + "/recipes/" + library[0].IDHex)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), "500 g mince")
+
+		// Typing the recipe's title into a slot links the meal to it
+		resp, err = http.Post(server.URL+"/meal", "application/x-www-form-urlencoded",
+			strings.NewReader("week=2026-W42&day=Monday&slot=Dinner&meal=chilli&version=0"))
+		assert.NoError(t, err)
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Contains(t, string(body), `<a href="/recipes/`+library[0].IDHex+`">`)
+	}
+
+	resp, err = http.Get(server.URL + "/recipes/000000000000000000000000")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+
+// setupRouterTestServer serves newRouter, as main does, over an in-memory
+// store, with a client that keeps cookies and does not follow redirects
+func setupRouterTestServer(t *testing.T) (*httptest.Server, *http.Client) {
+	h := handlers.New(db.NewMemoryDB())
+	h.TemplatePath = "./testdata/test_template.html"
+	h.RecipesTemplatePath = "./testdata/test_recipes_template.html"
+	h.StoresTemplatePath = "./testdata/test_stores_template.html"
+	h.LoginTemplatePath = "./testdata/test_login_template.html"
+	h.HouseholdTemplatePath = "./testdata/test_household_template.html"
+	h.ListsTemplatePath = "./testdata/test_lists_template.html"
+	h.HistoryTemplatePath = "./testdata/test_history_template.html"
+	h.SecureCookies = false
+	server := httptest.NewServer(newRouter(h))
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return server, client
+}
+
+func TestPrivateRoutesRequireLogin(t *testing.T) {
+	server, client := setupRouterTestServer(t)
+	defer server.Close()
+
+	for _, path := range []string{
+		"/", "/meal", "/shopping-list", "/shopping-list/tick", "/shopping-list/sort",
+		"/shopping-list/edit", "/shopping-list/build", "/shopping-list/merge",
+		"/shopping-list/auto-sort", "/shopping-list/remove-meal", "/shopping-list/category",
+		"/recipes", "/recipes/000000000000000000000000", "/stores", "/stores/Corner%20shop",
+		"/household", "/household/invites", "/household/members/000000000000000000000000",
+		"/lists", "/lists/000000000000000000000000", "/lists/000000000000000000000000/archive",
+		"/lists/000000000000000000000000/items", "/lists/000000000000000000000000/items/tick",
+		"/lists/000000000000000000000000/items/edit", "/lists/000000000000000000000000/items/category",
+		"/lists/000000000000000000000000/items/move", "/lists/000000000000000000000000/sort",
+		"/lists/000000000000000000000000/build", "/lists/000000000000000000000000/merge",
+		"/lists/000000000000000000000000/auto-sort", "/lists/000000000000000000000000/remove-meal",
+		"/lists/000000000000000000000000/events",
+		"/lists/000000000000000000000000/history",
+		"/lists/000000000000000000000000/history/000000000000000000000000/undo",
+		"/lists/000000000000000000000000/history/000000000000000000000000/redo",
+	} {
+		resp, err := client.Post(server.URL+path, "application/x-www-form-urlencoded", strings.NewReader(""))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusSeeOther, resp.StatusCode, path)
+		assert.Equal(t, "/login", resp.Header.Get("Location"), path)
+	}
+
+	for _, path := range []string{"/login", "/register", "/join"} {
+		resp, err := client.Get(server.URL + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+}
+
+func TestLoginFlow(t *testing.T) {
+	server, client := setupRouterTestServer(t)
+	defer server.Close()
+
+	form := url.Values{"email": {"sam@example.com"}, "name": {"Sam"}, "password": {"correct-horse"}, "household": {"Home"}}
+	resp, err := client.PostForm(server.URL+"/register", form)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	resp, err = client.PostForm(server.URL+"/shopping-list", url.Values{"item": {"potatoes"}})
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "potatoes")
+
+	resp, err = client.Post(server.URL+"/logout", "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	resp, err = client.Get(server.URL + "/")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode, "signed out")
+
+	form = url.Values{"email": {"sam@example.com"}, "password": {"correct-horse"}}
+	resp, err = client.PostForm(server.URL+"/login", form)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	resp, err = client.Get(server.URL + "/")
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "potatoes")
+	assert.Contains(t, string(body), "Signed in as Sam")
+}
+
+func TestShoppingListRoutes(t *testing.T) {
+	server, client := setupRouterTestServer(t)
+	defer server.Close()
+
+	form := url.Values{"email": {"sam@example.com"}, "name": {"Sam"}, "password": {"correct-horse"}, "household": {"Home"}}
+	resp, err := client.PostForm(server.URL+"/register", form)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = client.Get(server.URL + "/")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	match := regexp.MustCompile(`data-list="([0-9a-f]{24})"`).FindStringSubmatch(string(body))
+	if !assert.Len(t, match, 2) {
+		return
+	}
+
+	resp, err = client.PostForm(server.URL+"/lists/"+match[1]+"/items", url.Values{"item": {"potatoes"}})
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "potatoes")
+
+	resp, err = client.PostForm(server.URL+"/lists", url.Values{"name": {"Hardware"}})
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "Hardware")
+
+	resp, err = client.Get(server.URL + "/lists/000000000000000000000000/items")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// apiRequest sends a JSON API request with the bearer token, decoding the
+// response into out when it is not nil
+func apiRequest(t *testing.T, server *httptest.Server, token, method, path string, body any, out any) *http.Response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		reader = strings.NewReader(string(encoded))
+	}
+	req, err := http.NewRequest(method, server.URL+handlers.APIPrefix+path, reader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+func TestAPIRoutes(t *testing.T) {
+	server, client := setupRouterTestServer(t)
+	defer server.Close()
+
+	form := url.Values{"email": {"sam@example.com"}, "name": {"Sam"}, "password": {"correct-horse"}, "household": {"Home"}}
+	resp, err := client.PostForm(server.URL+"/register", form)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	var apiErr models.APIError
+	resp = apiRequest(t, server, "", "GET", "/lists", nil, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "API requests are not redirected")
+	assert.Equal(t, "Sign in required", apiErr.Error)
+	resp = apiRequest(t, server, "", "POST", "/session", models.APISessionInput{Email: "sam@example.com", Password: "wrong"}, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	var session models.APISession
+	resp = apiRequest(t, server, "", "POST", "/session", models.APISessionInput{Email: "sam@example.com", Password: "correct-horse"}, &session)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.NotEmpty(t, session.Token)
+	assert.Equal(t, "Sam", session.User.Name)
+	assert.Equal(t, models.RoleOwner, session.User.Role)
+
+	var lists []models.APIShoppingList
+	resp = apiRequest(t, server, session.Token, "GET", "/lists", nil, &lists)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if !assert.Len(t, lists, 1) {
+		return
+	}
+
+	var item models.APIShoppingListItem
+	resp = apiRequest(t, server, session.Token, "POST", "/lists/"+lists[0].ID+"/items", map[string]any{"text": "2kg potatoes"}, &item)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, handlers.APIPrefix+"/lists/"+lists[0].ID+"/items/"+item.ID, resp.Header.Get("Location"))
+	assert.Equal(t, "potatoes", item.Item)
+
+	var meal models.APIMeal
+	resp = apiRequest(t, server, session.Token, "PUT", "/meal-plans/2026-W42/meals/Monday/Dinner", map[string]any{"meal": "Fish pie", "version": 0}, &meal)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, meal.Version)
+	var plan models.APIMealPlan
+	resp = apiRequest(t, server, session.Token, "GET", "/meal-plans/2026-W42", nil, &plan)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, plan.Meals, meal)
+
+	resp = apiRequest(t, server, session.Token, "GET", "/no-such-thing", nil, &apiErr)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	resp = apiRequest(t, server, session.Token, "DELETE", "/session", nil, nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = apiRequest(t, server, session.Token, "GET", "/lists", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "signed out")
+}
+
+// TestOpenAPIMatchesRouter fails when the OpenAPI document and the JSON API
+// routes drift apart: every operation must be routed to its own handler, and
+// every route must be documented
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	h := handlers.New(db.NewMemoryDB())
+	mux := newRouter(h)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/openapi.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the document is public")
+	var spec openapi.Document
+	if !assert.NoError(t, json.NewDecoder(resp.Body).Decode(&spec)) {
+		return
+	}
+	assert.Equal(t, openapi.Version, spec.OpenAPI)
+
+	documented := make(map[string]bool)
+	for path, item := range spec.Paths {
+		for method := range item {
+			pattern := strings.ToUpper(method) + " " + path
+			documented[pattern] = true
+
+			// Fill in the path parameters to see which route serves it
+			target := path
+			for _, name := range openapi.PathParams(path) {
+				target = strings.Replace(target, "{"+name+"}", "x", 1)
+			}
+			req := httptest.NewRequest(strings.ToUpper(method), target, nil)
+			_, routed := mux.Handler(req)
+			assert.Equal(t, pattern, routed, "documented operation is not routed")
+		}
+	}
+
+	routed := make(map[string]bool)
+	for _, route := range apiRoutes(h) {
+		routed[route.pattern] = true
+		assert.True(t, documented[route.pattern], "route %s is not in the OpenAPI document", route.pattern)
+	}
+	assert.Len(t, routed, len(documented))
+}
+
+func TestAPIRequestValidation(t *testing.T) {
+	server, client := setupRouterTestServer(t)
+	defer server.Close()
+
+	form := url.Values{"email": {"sam@example.com"}, "name": {"Sam"}, "password": {"correct-horse"}, "household": {"Home"}}
+	resp, err := client.PostForm(server.URL+"/register", form)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	var apiErr models.APIError
+	resp = apiRequest(t, server, "", "POST", "/session", map[string]any{"email": "sam@example.com"}, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "Invalid request: body.password is required", apiErr.Error)
+
+	var session models.APISession
+	apiRequest(t, server, "", "POST", "/session", models.APISessionInput{Email: "sam@example.com", Password: "correct-horse"}, &session)
+	var lists []models.APIShoppingList
+	apiRequest(t, server, session.Token, "GET", "/lists", nil, &lists)
+	if !assert.Len(t, lists, 1) {
+		return
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    any
+		message string
+	}{
+		{"wrong type", "POST", "/lists/" + lists[0].ID + "/items", map[string]any{"text": 2}, "body.text must be a string"},
+		{"unknown field", "PATCH", "/lists/" + lists[0].ID, map[string]any{"title": "Food"}, `body has unknown field "title"`},
+		{"not an object", "POST", "/lists", []string{"Food"}, "body must be an object"},
+		{"array of the wrong type", "PUT", "/lists/" + lists[0].ID + "/order", map[string]any{"order": []int{1}, "version": 1}, "body.order[0] must be a string"},
+		{"fractional version", "PUT", "/meal-plans/2026-W42/meals/Monday/Dinner", map[string]any{"meal": "Soup", "version": 1.5}, "body.version must be an integer"},
+		{"unknown slot", "PUT", "/meal-plans/2026-W42/meals/Monday/Elevenses", map[string]any{"meal": "Cake", "version": 0}, "slot must be one of [Breakfast Lunch Dinner Snacks]"},
+		{"bad week", "GET", "/meal-plans/last-week", nil, `week must match ^\d{4}-W\d{2}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiErr models.APIError
+			resp := apiRequest(t, server, session.Token, tt.method, tt.path, tt.body, &apiErr)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, "Invalid request: "+tt.message, apiErr.Error)
+		})
+	}
+
+	var item models.APIShoppingListItem
+	resp = apiRequest(t, server, session.Token, "POST", "/lists/"+lists[0].ID+"/items", map[string]any{"text": "milk", "ticked": nil}, &item)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode, "valid bodies reach the handler")
+	assert.Equal(t, "milk", item.Item)
+}
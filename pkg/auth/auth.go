@@ -0,0 +1,82 @@
+// Package auth hashes passwords and creates session tokens
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword returns a bcrypt hash of password, which must be at least
+// models.MinPasswordLength characters
+func HashPassword(password string) (string, error) {
+	if len(password) < models.MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", models.MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewSessionToken returns a random token to identify a signed-in browser
+func NewSessionToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// inviteCodeAlphabet leaves out letters and digits that are easily confused
+// when read out, such as O and 0
+const inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTVWXYZ23456789"
+
+// NewInviteCode returns a random code, such as "K7PQM-2XWRT", that is short
+// enough to read out or type on a phone
+func NewInviteCode() (string, error) {
+	const length = 10
+	code := make([]byte, 0, length+1)
+	for i := 0; i < length; i++ {
+		if i == length/2 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(inviteCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code = append(code, inviteCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// NormaliseInviteCode returns code in upper case without spaces or dashes,
+// so it matches however it is typed
+func NormaliseInviteCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// HashToken returns the hash a session token is stored under
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.NotContains(t, hash, "correct horse")
+	assert.True(t, CheckPassword(hash, "correct horse"))
+	assert.False(t, CheckPassword(hash, "Correct horse"))
+	assert.False(t, CheckPassword("not a hash", "correct horse"))
+
+	_, err = HashPassword("short")
+	assert.Error(t, err)
+}
+
+func TestSessionTokens(t *testing.T) {
+	first, err := NewSessionToken()
+	require.NoError(t, err)
+	second, err := NewSessionToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Len(t, first, 43)
+
+	assert.Equal(t, HashToken(first), HashToken(first))
+	assert.NotEqual(t, HashToken(first), HashToken(second))
+	assert.NotContains(t, HashToken(first), first)
+}
+
+func TestInviteCodes(t *testing.T) {
+	first, err := NewInviteCode()
+	require.NoError(t, err)
+	second, err := NewInviteCode()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Regexp(t, `^[A-Z2-9]{5}-[A-Z2-9]{5}$`, first)
+
+	assert.Equal(t, "K7PQM2XWRT", NormaliseInviteCode(" k7pqm-2xw rt "))
+}
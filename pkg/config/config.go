@@ -3,48 +3,163 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/JonClarke84/mealplannergo/pkg/models"
 	"github.com/joho/godotenv"
 )
 
+// Supported values for STORAGE_DRIVER
+const (
+	DriverMongoDB = "mongodb"
+	DriverSQLite  = "sqlite"
+	DriverMemory  = "memory"
+)
+
 type Config struct {
-	MongoURI    string
-	DatabaseName string
-	Environment string
-	Port        string
+	MongoURI      string
+	DatabaseName  string
+	Environment   string
+	Port          string
+	StorageDriver string
+	SQLitePath    string
+	MealSlots     []string
+	// SecureCookies sends the session cookie over HTTPS only
+	SecureCookies bool
+	// AutoMigrate applies pending database migrations when the server starts
+	AutoMigrate bool
+	// RequestTimeout is how long a request may take before its database
+	// calls are cancelled, or zero for no limit
+	RequestTimeout time.Duration
 }
 
+// DefaultRequestTimeout is used when REQUEST_TIMEOUT is not set
+const DefaultRequestTimeout = 10 * time.Second
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	// Load .env file if it exists (ignore error if file doesn't exist)
 	_ = godotenv.Load()
 
 	env := getEnv("GO_ENV", "production")
-	
+
 	// Determine database name based on environment
-	var dbName string
-	switch env {
-	case "development", "test":
-		dbName = "GoShopping-test"
-	case "production":
-		dbName = "GoShopping"
-	default:
+	dbName := DatabaseNameFor(env)
+	if dbName == "" {
 		log.Printf("Warning: Unknown environment '%s', defaulting to production", env)
-		dbName = "GoShopping"
 		env = "production"
+		dbName = DatabaseNameFor(env)
+	}
+
+	// Demo mode always runs from seeded in-memory data
+	driver := getEnv("STORAGE_DRIVER", DriverMongoDB)
+	if env == "demo" {
+		driver = DriverMemory
+	}
+	switch driver {
+	case DriverMongoDB, DriverSQLite, DriverMemory:
+	default:
+		log.Fatalf("Error: unknown STORAGE_DRIVER '%s' (expected %s, %s or %s)", driver, DriverMongoDB, DriverSQLite, DriverMemory)
 	}
 
 	mongoURI := os.Getenv("GO_SHOPPING_MONGO_ATLAS_URI")
-	if mongoURI == "" {
+	if mongoURI == "" && driver == DriverMongoDB {
 		log.Fatal("Error: GO_SHOPPING_MONGO_ATLAS_URI environment variable is not set")
 	}
 
 	return &Config{
-		MongoURI:    mongoURI,
-		DatabaseName: dbName,
-		Environment: env,
-		Port:        getEnv("PORT", "8080"),
+		MongoURI:       mongoURI,
+		DatabaseName:   dbName,
+		Environment:    env,
+		Port:           getEnv("PORT", "8080"),
+		StorageDriver:  driver,
+		SQLitePath:     getEnv("SQLITE_PATH", "./data/"+dbName+".db"),
+		MealSlots:      getMealSlots(),
+		SecureCookies:  getSecureCookies(env),
+		AutoMigrate:    getAutoMigrate(),
+		RequestTimeout: getRequestTimeout(),
+	}
+}
+
+// DatabaseNameFor returns the name of the database the environment env uses,
+// or "" for an unknown environment
+func DatabaseNameFor(env string) string {
+	switch env {
+	case "development", "test":
+		return "GoShopping-test"
+	case "production":
+		return "GoShopping"
+	case "demo":
+		return "GoShopping-demo"
 	}
+	return ""
+}
+
+// getSecureCookies reads SECURE_COOKIES, which defaults to true in production
+// where the app is served over HTTPS
+func getSecureCookies(env string) bool {
+	value := os.Getenv("SECURE_COOKIES")
+	if value == "" {
+		return env == "production"
+	}
+	secure, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Error: SECURE_COOKIES must be true or false, got '%s'", value)
+	}
+	return secure
+}
+
+// getAutoMigrate reads AUTO_MIGRATE, which defaults to true
+func getAutoMigrate() bool {
+	value := os.Getenv("AUTO_MIGRATE")
+	if value == "" {
+		return true
+	}
+	migrate, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Error: AUTO_MIGRATE must be true or false, got '%s'", value)
+	}
+	return migrate
+}
+
+// getRequestTimeout reads REQUEST_TIMEOUT, a duration such as "10s", or 0
+// for no limit
+func getRequestTimeout() time.Duration {
+	value := os.Getenv("REQUEST_TIMEOUT")
+	if value == "" {
+		return DefaultRequestTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		log.Fatalf("Error: REQUEST_TIMEOUT must be a duration such as 10s, got '%s'", value)
+	}
+	return timeout
+}
+
+// getMealSlots reads the comma-separated MEAL_SLOTS setting, e.g. "Breakfast,Lunch,Dinner"
+func getMealSlots() []string {
+	value := os.Getenv("MEAL_SLOTS")
+	if value == "" {
+		return models.DefaultMealSlots
+	}
+
+	var slots []string
+	for _, slot := range strings.Split(value, ",") {
+		slot = strings.TrimSpace(slot)
+		if slot == "" {
+			continue
+		}
+		if err := models.ValidateMealSlot(slot); err != nil {
+			log.Fatalf("Error: MEAL_SLOTS: %s", err)
+		}
+		slots = append(slots, slot)
+	}
+	if len(slots) == 0 {
+		return models.DefaultMealSlots
+	}
+	return slots
 }
 
 // getEnv gets environment variable with a default fallback
@@ -60,7 +175,12 @@ func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development" || c.Environment == "test"
 }
 
+// IsDemo returns true if running the seeded, in-memory demo
+func (c *Config) IsDemo() bool {
+	return c.Environment == "demo"
+}
+
 // IsProduction returns true if running in production environment
 func (c *Config) IsProduction() bool {
 	return c.Environment == "production"
-}
\ No newline at end of file
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/auth"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// ErrInvalidLogin is returned by Login when the email or password is wrong.
+// It does not say which, so it cannot be used to find out who has an account.
+var ErrInvalidLogin = errors.New("incorrect email or password")
+
+// ErrInvalidInvite is returned by Join when the invite code is wrong, used or
+// expired
+var ErrInvalidInvite = errors.New("invite code is not valid or has expired")
+
+// ErrLastOwner is returned by SetRole rather than leave a household with no
+// owner
+var ErrLastOwner = errors.New("a household needs at least one owner")
+
+// Register creates a household called householdName with a new user in it,
+// who owns it. Neither is kept if either cannot be created.
+func Register(ctx context.Context, store AccountStore, email, name, password, householdName string) (models.User, error) {
+	user, err := newUser(ctx, store, email, name, password, models.RoleOwner)
+	if err != nil {
+		return models.User{}, err
+	}
+	_, user, err = store.CreateOwnedHousehold(ctx, models.Household{Name: householdName}, user)
+	return user, err
+}
+
+// Join creates a user in the household the invite code is for, with the
+// invite's role. Each code can only be used once: it is claimed before the
+// user is created, and put back if they cannot be.
+func Join(ctx context.Context, store AccountStore, code, email, name, password string, now time.Time) (models.User, error) {
+	invite, err := store.ClaimInvite(ctx, auth.HashToken(auth.NormaliseInviteCode(code)))
+	if err == ErrNotFound || (err == nil && invite.Expired(now)) {
+		return models.User{}, ErrInvalidInvite
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user, err := newUser(ctx, store, email, name, password, invite.Role)
+	if err == nil {
+		user.HouseholdID = invite.HouseholdID
+		user, err = store.CreateUser(ctx, user)
+	}
+	if err != nil {
+		// Put the invite back even if the request has gone away
+		if err := store.CreateInvite(context.WithoutCancel(ctx), invite); err != nil {
+			fmt.Printf("Error putting back invite: %s\n", err)
+		}
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// newUser returns a user ready to be added to a household, checking it can be
+// created before anything is stored
+func newUser(ctx context.Context, store AccountStore, email, name, password string, role models.Role) (models.User, error) {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user := models.User{Email: email, Name: name, PasswordHash: hash, HouseholdID: "pending", Role: role}
+	if err := user.Validate(); err != nil {
+		return models.User{}, err
+	}
+	if _, err := store.GetUserByEmail(ctx, email); err == nil {
+		return models.User{}, ErrExists
+	}
+	return user, nil
+}
+
+// CreateInvite returns a code that lets someone join householdID with role,
+// until models.InviteLifetime after now
+func CreateInvite(ctx context.Context, store AccountStore, householdID string, role models.Role, now time.Time) (string, error) {
+	if _, err := models.ParseRole(string(role)); err != nil {
+		return "", err
+	}
+	code, err := auth.NewInviteCode()
+	if err != nil {
+		return "", err
+	}
+	invite := models.Invite{
+		CodeHash:    auth.HashToken(auth.NormaliseInviteCode(code)),
+		HouseholdID: householdID,
+		Role:        role,
+		ExpiresAt:   now.Add(models.InviteLifetime),
+	}
+	if err := store.CreateInvite(ctx, invite); err != nil {
+		return "", fmt.Errorf("creating invite: %w", err)
+	}
+	return code, nil
+}
+
+// SetRole changes the role of the user with IDHex userID in householdID. It
+// returns ErrNotFound when they are not in that household.
+func SetRole(ctx context.Context, store AccountStore, householdID, userID string, role models.Role) error {
+	users, err := store.GetHouseholdUsers(ctx, householdID)
+	if err != nil {
+		return err
+	}
+	found := false
+	owners := 0 // after the change
+	if role == models.RoleOwner {
+		owners++
+	}
+	for _, user := range users {
+		if user.IDHex == userID {
+			found = true
+		} else if user.Role == models.RoleOwner {
+			owners++
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return store.SetUserRole(ctx, userID, role)
+}
+
+// Login returns the user with email if password is theirs
+func Login(ctx context.Context, store AccountStore, email, password string) (models.User, error) {
+	user, err := store.GetUserByEmail(ctx, email)
+	if err == ErrNotFound {
+		return models.User{}, ErrInvalidLogin
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return models.User{}, ErrInvalidLogin
+	}
+	return user, nil
+}
+
+// StartSession signs user in, returning the token their browser sends back
+func StartSession(ctx context.Context, store AccountStore, user models.User, now time.Time) (string, error) {
+	token, err := auth.NewSessionToken()
+	if err != nil {
+		return "", err
+	}
+	session := models.Session{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.IDHex,
+		ExpiresAt: now.Add(models.SessionLifetime),
+	}
+	if err := store.CreateSession(ctx, session); err != nil {
+		return "", fmt.Errorf("starting session: %w", err)
+	}
+	return token, nil
+}
+
+// Authenticate returns the user signed in with token. It returns ErrNotFound
+// when there is no such session or it has expired.
+func Authenticate(ctx context.Context, store AccountStore, token string, now time.Time) (models.User, error) {
+	if token == "" {
+		return models.User{}, ErrNotFound
+	}
+	tokenHash := auth.HashToken(token)
+	session, err := store.GetSession(ctx, tokenHash)
+	if err != nil {
+		return models.User{}, err
+	}
+	if session.Expired(now) {
+		if err := store.DeleteSession(ctx, tokenHash); err != nil {
+			return models.User{}, err
+		}
+		return models.User{}, ErrNotFound
+	}
+	return store.GetUser(ctx, session.UserID)
+}
+
+// EndSession signs out the browser with token
+func EndSession(ctx context.Context, store AccountStore, token string) error {
+	if token == "" {
+		return nil
+	}
+	return store.DeleteSession(ctx, auth.HashToken(token))
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+
+	user, err := Register(ctx, store, "Sam@Example.com", "Sam", "correct-horse", "The Smiths")
+	require.NoError(t, err)
+	assert.Equal(t, "sam@example.com", user.Email)
+	assert.NotEqual(t, "correct-horse", user.PasswordHash)
+	household, err := store.GetHousehold(ctx, user.HouseholdID)
+	require.NoError(t, err)
+	assert.Equal(t, "The Smiths", household.Name)
+
+	_, err = Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Another")
+	assert.ErrorIs(t, err, ErrExists)
+	_, err = Register(ctx, store, "alex@example.com", "Alex", "short", "Another")
+	assert.Error(t, err)
+	households, err := store.GetHouseholds(ctx)
+	require.NoError(t, err)
+	assert.Len(t, households, 1, "no household is left behind by a failed registration")
+
+	loggedIn, err := Login(ctx, store, "SAM@example.com", "correct-horse")
+	require.NoError(t, err)
+	assert.Equal(t, user, loggedIn)
+	_, err = Login(ctx, store, "sam@example.com", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+	_, err = Login(ctx, store, "nobody@example.com", "correct-horse")
+	assert.ErrorIs(t, err, ErrInvalidLogin)
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	user, err := Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	token, err := StartSession(ctx, store, user, now)
+	require.NoError(t, err)
+	_, err = store.GetSession(ctx, token)
+	assert.ErrorIs(t, err, ErrNotFound, "only a hash of the token is stored")
+
+	signedIn, err := Authenticate(ctx, store, token, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, user, signedIn)
+	_, err = Authenticate(ctx, store, "forged", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = Authenticate(ctx, store, "", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = Authenticate(ctx, store, token, now.Add(models.SessionLifetime))
+	assert.ErrorIs(t, err, ErrNotFound, "the session has expired")
+	_, err = Authenticate(ctx, store, token, now)
+	assert.ErrorIs(t, err, ErrNotFound, "expired sessions are deleted")
+
+	token, err = StartSession(ctx, store, user, now)
+	require.NoError(t, err)
+	require.NoError(t, EndSession(ctx, store, token))
+	_, err = Authenticate(ctx, store, token, now)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestInviteAndJoin(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	owner, err := Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleOwner, owner.Role)
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	code, err := CreateInvite(ctx, store, owner.HouseholdID, models.RoleViewer, now)
+	require.NoError(t, err)
+	_, err = CreateInvite(ctx, store, owner.HouseholdID, "admin", now)
+	assert.Error(t, err)
+
+	_, err = Join(ctx, store, "WRONG-CODE", "kid@example.com", "Kid", "correct-horse", now)
+	assert.ErrorIs(t, err, ErrInvalidInvite)
+	_, err = Join(ctx, store, code, "sam@example.com", "Sam", "correct-horse", now)
+	assert.ErrorIs(t, err, ErrExists, "a failed join leaves the invite usable")
+
+	kid, err := Join(ctx, store, " "+strings.ToLower(code)+" ", "kid@example.com", "Kid", "correct-horse", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, owner.HouseholdID, kid.HouseholdID)
+	assert.Equal(t, models.RoleViewer, kid.Role)
+
+	_, err = Join(ctx, store, code, "other@example.com", "Other", "correct-horse", now)
+	assert.ErrorIs(t, err, ErrInvalidInvite, "each code is used once")
+
+	code, err = CreateInvite(ctx, store, owner.HouseholdID, models.RoleMember, now)
+	require.NoError(t, err)
+	_, err = Join(ctx, store, code, "late@example.com", "Late", "correct-horse", now.Add(models.InviteLifetime))
+	assert.ErrorIs(t, err, ErrInvalidInvite, "the invite has expired")
+}
+
+func TestJoinRace(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	owner, err := Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	code, err := CreateInvite(ctx, store, owner.HouseholdID, models.RoleMember, now)
+	require.NoError(t, err)
+
+	// However many people join with the same code at once, one gets in
+	const joins = 5
+	errs := make(chan error, joins)
+	for i := 0; i < joins; i++ {
+		go func(i int) {
+			_, err := Join(ctx, store, code, fmt.Sprintf("kid%d@example.com", i), "Kid", "correct-horse", now)
+			errs <- err
+		}(i)
+	}
+	joined := 0
+	for i := 0; i < joins; i++ {
+		if err := <-errs; err == nil {
+			joined++
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidInvite)
+		}
+	}
+	assert.Equal(t, 1, joined)
+	members, err := store.GetHouseholdUsers(ctx, owner.HouseholdID)
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+}
+
+func TestSetRole(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	owner, err := Register(ctx, store, "sam@example.com", "Sam", "correct-horse", "Home")
+	require.NoError(t, err)
+	code, err := CreateInvite(ctx, store, owner.HouseholdID, models.RoleMember, time.Now())
+	require.NoError(t, err)
+	partner, err := Join(ctx, store, code, "alex@example.com", "Alex", "correct-horse", time.Now())
+	require.NoError(t, err)
+	stranger, err := Register(ctx, store, "kim@example.com", "Kim", "correct-horse", "Elsewhere")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, SetRole(ctx, store, owner.HouseholdID, owner.IDHex, models.RoleMember), ErrLastOwner)
+	assert.ErrorIs(t, SetRole(ctx, store, owner.HouseholdID, stranger.IDHex, models.RoleViewer), ErrNotFound)
+
+	require.NoError(t, SetRole(ctx, store, owner.HouseholdID, partner.IDHex, models.RoleOwner))
+	require.NoError(t, SetRole(ctx, store, owner.HouseholdID, owner.IDHex, models.RoleMember))
+	updated, err := store.GetUser(ctx, owner.IDHex)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleMember, updated.Role)
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrNoBackup is returned by Backup for stores that are not saved anywhere,
+// such as MemoryDB
+var ErrNoBackup = errors.New("this storage backend keeps nothing to back up")
+
+// backuper is a store that can write a copy of all of its data
+type backuper interface {
+	Backup(ctx context.Context, w io.Writer) error
+}
+
+// Backup writes a copy of everything in store, every household's data and
+// accounts alike, to w
+func Backup(ctx context.Context, store Store, w io.Writer) error {
+	b, ok := store.(backuper)
+	if !ok {
+		return ErrNoBackup
+	}
+	return b.Backup(ctx, w)
+}
+
+// Backup writes every document in the database as a line of JSON holding the
+// collection's name and the document in canonical extended JSON, so that
+// types such as ObjectIDs and dates survive a restore with mongoimport or
+// the driver
+func (m *MongoDB) Backup(ctx context.Context, w io.Writer) error {
+	database := m.Client.Database(m.DatabaseName)
+	collections, err := database.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return fmt.Errorf("listing collections: %w", err)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		cursor, err := database.Collection(collection).Find(ctx, bson.D{})
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", collection, err)
+		}
+		for cursor.Next(ctx) {
+			line, err := bson.MarshalExtJSON(bson.D{
+				{Key: "collection", Value: collection},
+				{Key: "document", Value: cursor.Current},
+			}, true, false)
+			if err != nil {
+				cursor.Close(ctx)
+				return fmt.Errorf("encoding %s document: %w", collection, err)
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// Backup writes a consistent copy of the database file, made with VACUUM
+// INTO so that writes may carry on while it is taken
+func (s *SQLiteDB) Backup(ctx context.Context, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "mealplanner-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.db")
+	if _, err := s.DB.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("copying database: %w", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// categorise returns items with a category guessed for those without one,
+// using the learned dictionary of store
+func categorise(ctx context.Context, store DBInterface, items ...models.ShoppingListItem) ([]models.ShoppingListItem, error) {
+	dictionary, err := store.GetCategoryDictionary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].Category == "" {
+			items[i].Category = models.GuessCategory(items[i].Item, dictionary)
+		}
+	}
+	return items, nil
+}
+
+// AssignCategory sets the category of a shopping list item and remembers it,
+// so items with the same name are put in that category from now on
+func AssignCategory(ctx context.Context, store DBInterface, itemId string, category string) (models.ShoppingListItem, error) {
+	category = strings.TrimSpace(category)
+	item, err := store.SetShoppingListItemCategory(ctx, itemId, category)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if item.IDHex == "" {
+		return models.ShoppingListItem{}, ErrNotFound
+	}
+	if err := store.LearnCategory(ctx, item.Item, category); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	return item, nil
+}
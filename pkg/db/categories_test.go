@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignCategoryLearns(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	candles, err := store.AddShoppingListItem(ctx, "Candles")
+	require.NoError(t, err)
+	assert.Empty(t, candles.Category)
+
+	candles, err = AssignCategory(ctx, store, candles.IDHex, " Seasonal ")
+	require.NoError(t, err)
+	assert.Equal(t, "Seasonal", candles.Category)
+
+	// The next item with that name goes into the learned category, even once
+	// the first has been ticked off
+	_, err = store.TickShoppingListItem(ctx, candles.IDHex, true)
+	require.NoError(t, err)
+	again, err := store.AddShoppingListItem(ctx, "6 candles")
+	require.NoError(t, err)
+	assert.Equal(t, "Seasonal", again.Category)
+
+	// Learning overrides the built-in dictionary
+	milk, err := store.AddShoppingListItem(ctx, "Milk")
+	require.NoError(t, err)
+	assert.Equal(t, "Dairy & eggs", milk.Category)
+	_, err = AssignCategory(ctx, store, milk.IDHex, "Drinks")
+	require.NoError(t, err)
+	_, err = store.TickShoppingListItem(ctx, milk.IDHex, true)
+	require.NoError(t, err)
+	milk, err = store.AddShoppingListItem(ctx, "milk")
+	require.NoError(t, err)
+	assert.Equal(t, "Drinks", milk.Category)
+
+	_, err = AssignCategory(ctx, store, "000000000000000000000000", "Drinks")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// itemConflict returns the stored item with IDHex and ErrConflict after an
+// edit of it was rejected, or ErrNotFound if it is no longer on the list.
+// Backends use it to implement EditShoppingListItem and
+// ReplaceShoppingListItem.
+func itemConflict(ctx context.Context, store DBInterface, IDHex string) (models.ShoppingListItem, error) {
+	item, err := store.GetShoppingListItemFromIDHex(ctx, IDHex)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if item.IDHex == "" {
+		return models.ShoppingListItem{}, ErrNotFound
+	}
+	return item, ErrConflict
+}
+
+// listConflict returns the stored list with IDHex and ErrConflict after an
+// edit of it was rejected, or ErrNotFound if there is no such list. Backends
+// use it to implement EditShoppingList.
+func listConflict(ctx context.Context, store DBInterface, IDHex string) (models.ShoppingList, error) {
+	lists, err := store.GetShoppingLists(ctx)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	list, found := models.FindShoppingList(lists, IDHex)
+	if !found {
+		return models.ShoppingList{}, ErrNotFound
+	}
+	return list, ErrConflict
+}
+
+// mealConflict returns the stored slot and ErrConflict after an edit of it
+// was rejected, or ErrNotFound if the week has no such day. Backends use it
+// to implement EditMeal.
+func mealConflict(ctx context.Context, store DBInterface, week string, day string, slot string) (models.MealSlotInput, error) {
+	mealPlan, err := store.GetMealPlan(ctx, week)
+	if err != nil {
+		return models.MealSlotInput{}, err
+	}
+	for _, meal := range mealPlan.Meals {
+		if meal.Day == day {
+			input := meal.Input(slot)
+			input.Week = week
+			return input, ErrConflict
+		}
+	}
+	return models.MealSlotInput{}, ErrNotFound
+}
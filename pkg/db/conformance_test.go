@@ -0,0 +1,1527 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storeFactory returns a freshly initialised, empty store for a single test
+type storeFactory func(t *testing.T) Store
+
+// runConformance runs every Store method against the store returned by
+// newStore, so each backend is held to the same behaviour. The data methods
+// are run both on data saved before households existed and on a household's.
+func runConformance(t *testing.T, newStore storeFactory) {
+	ctx := context.Background()
+	t.Run("Unowned", func(t *testing.T) {
+		runDataConformance(t, func(t *testing.T) DBInterface {
+			return newStore(t)
+		})
+	})
+	t.Run("Household", func(t *testing.T) {
+		runDataConformance(t, func(t *testing.T) DBInterface {
+			store := newStore(t)
+			testHousehold(t, store, "First")
+			return testHousehold(t, store, "Second")
+		})
+	})
+
+	t.Run("CreateHousehold", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.CreateHousehold(ctx, models.Household{Name: " "})
+		assert.Error(t, err)
+
+		household, err := store.CreateHousehold(ctx, models.Household{Name: " The Smiths "})
+		require.NoError(t, err)
+		assert.Equal(t, household.ID.Hex(), household.IDHex)
+		assert.Equal(t, "The Smiths", household.Name)
+
+		found, err := store.GetHousehold(ctx, household.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, household, found)
+
+		_, err = store.CreateHousehold(ctx, models.Household{Name: "Allotment"})
+		require.NoError(t, err)
+		households, err := store.GetHouseholds(ctx)
+		require.NoError(t, err)
+		require.Len(t, households, 2)
+		assert.Equal(t, "Allotment", households[0].Name)
+
+		_, err = store.GetHousehold(ctx, primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("CreateUser", func(t *testing.T) {
+		store := newStore(t)
+		household, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+		require.NoError(t, err)
+
+		user, err := store.CreateUser(ctx, models.User{Email: " Sam@Example.com ", Name: "Sam", PasswordHash: "hash", HouseholdID: household.IDHex, Role: models.RoleOwner})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.Hex(), user.IDHex)
+		assert.Equal(t, "sam@example.com", user.Email)
+
+		found, err := store.GetUser(ctx, user.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, user, found)
+		found, err = store.GetUserByEmail(ctx, "SAM@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, user, found)
+
+		_, err = store.CreateUser(ctx, models.User{Email: "sam@example.com", PasswordHash: "other", HouseholdID: household.IDHex, Role: models.RoleOwner})
+		assert.ErrorIs(t, err, ErrExists)
+		_, err = store.CreateUser(ctx, models.User{Email: "not an email", PasswordHash: "hash", HouseholdID: household.IDHex, Role: models.RoleOwner})
+		assert.Error(t, err)
+
+		_, err = store.GetUser(ctx, primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.GetUserByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("CreateOwnedHousehold", func(t *testing.T) {
+		store := newStore(t)
+		addItems(t, store, "Milk")
+		owner := models.User{Email: "Sam@Example.com", Name: "Sam", PasswordHash: "hash", Role: models.RoleOwner}
+
+		household, user, err := store.CreateOwnedHousehold(ctx, models.Household{Name: " Home "}, owner)
+		require.NoError(t, err)
+		assert.Equal(t, "Home", household.Name)
+		assert.Equal(t, household.IDHex, user.HouseholdID)
+		assert.Equal(t, "sam@example.com", user.Email)
+		found, err := store.GetUser(ctx, user.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, user, found)
+		assert.Equal(t, []string{"Milk"}, itemNames(t, store.ForHousehold(household.IDHex)), "the first household takes over the data")
+
+		// Neither is added when the owner cannot be
+		_, _, err = store.CreateOwnedHousehold(ctx, models.Household{Name: "Allotment"}, owner)
+		assert.ErrorIs(t, err, ErrExists)
+		_, _, err = store.CreateOwnedHousehold(ctx, models.Household{Name: "Allotment"}, models.User{Email: "not an email", PasswordHash: "hash", Role: models.RoleOwner})
+		assert.Error(t, err)
+		_, _, err = store.CreateOwnedHousehold(ctx, models.Household{Name: " "}, models.User{Email: "alex@example.com", PasswordHash: "hash", Role: models.RoleOwner})
+		assert.Error(t, err)
+		households, err := store.GetHouseholds(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Household{household}, households)
+		_, err = store.GetUserByEmail(ctx, "alex@example.com")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("HouseholdUsers", func(t *testing.T) {
+		store := newStore(t)
+		home, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+		require.NoError(t, err)
+		other, err := store.CreateHousehold(ctx, models.Household{Name: "Other"})
+		require.NoError(t, err)
+		for _, user := range []models.User{
+			{Email: "sam@example.com", Name: "Sam", PasswordHash: "hash", HouseholdID: home.IDHex, Role: models.RoleOwner},
+			{Email: "alex@example.com", Name: "alex", PasswordHash: "hash", HouseholdID: home.IDHex, Role: models.RoleViewer},
+			{Email: "kim@example.com", Name: "Kim", PasswordHash: "hash", HouseholdID: other.IDHex, Role: models.RoleOwner},
+		} {
+			_, err := store.CreateUser(ctx, user)
+			require.NoError(t, err)
+		}
+		_, err = store.CreateUser(ctx, models.User{Email: "jo@example.com", PasswordHash: "hash", HouseholdID: home.IDHex, Role: "admin"})
+		assert.Error(t, err)
+
+		users, err := store.GetHouseholdUsers(ctx, home.IDHex)
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		assert.Equal(t, "alex", users[0].Name)
+		assert.Equal(t, models.RoleViewer, users[0].Role)
+
+		require.NoError(t, store.SetUserRole(ctx, users[0].IDHex, models.RoleMember))
+		alex, err := store.GetUser(ctx, users[0].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleMember, alex.Role)
+		assert.Error(t, store.SetUserRole(ctx, users[0].IDHex, "admin"))
+		assert.ErrorIs(t, store.SetUserRole(ctx, primitive.NewObjectID().Hex(), models.RoleMember), ErrNotFound)
+	})
+
+	t.Run("Invites", func(t *testing.T) {
+		store := newStore(t)
+		household, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+		require.NoError(t, err)
+		expiresAt := time.Date(2026, 10, 24, 10, 0, 0, 0, time.UTC)
+		invite := models.Invite{CodeHash: "abc", HouseholdID: household.IDHex, Role: models.RoleMember, ExpiresAt: expiresAt}
+
+		require.NoError(t, store.CreateInvite(ctx, invite))
+		found, err := store.GetInvite(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, household.IDHex, found.HouseholdID)
+		assert.Equal(t, models.RoleMember, found.Role)
+		assert.True(t, expiresAt.Equal(found.ExpiresAt))
+
+		claimed, err := store.ClaimInvite(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, found, claimed)
+		_, err = store.GetInvite(ctx, "abc")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.ClaimInvite(ctx, "abc")
+		assert.ErrorIs(t, err, ErrNotFound, "an invite is claimed once")
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		store := newStore(t)
+		household, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+		require.NoError(t, err)
+		user, err := store.CreateUser(ctx, models.User{Email: "sam@example.com", PasswordHash: "hash", HouseholdID: household.IDHex, Role: models.RoleOwner})
+		require.NoError(t, err)
+		expiresAt := time.Date(2026, 11, 17, 10, 0, 0, 0, time.UTC)
+		session := models.Session{TokenHash: "abc", UserID: user.IDHex, ExpiresAt: expiresAt}
+
+		require.NoError(t, store.CreateSession(ctx, session))
+		found, err := store.GetSession(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, user.IDHex, found.UserID)
+		assert.True(t, expiresAt.Equal(found.ExpiresAt))
+
+		require.NoError(t, store.DeleteSession(ctx, "abc"))
+		_, err = store.GetSession(ctx, "abc")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, store.DeleteSession(ctx, "abc"), "deleting a missing session is not an error")
+	})
+
+	t.Run("HouseholdIsolation", func(t *testing.T) {
+		store := newStore(t)
+		ours := testHousehold(t, store, "Ours")
+		theirs := testHousehold(t, store, "Theirs")
+
+		milk := addItems(t, ours, "Milk")[0]
+		require.NoError(t, ours.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Curry"))
+		recipe, err := ours.AddRecipe(ctx, testRecipe("Chilli"))
+		require.NoError(t, err)
+		require.NoError(t, ours.LearnCategory(ctx, "milk", "Dairy & eggs"))
+		_, err = ours.SaveStore(ctx, models.Store{Name: "Corner shop"})
+		require.NoError(t, err)
+		require.NoError(t, ours.RecordTick(ctx, models.TickEvent{Item: "milk", TickedAt: time.Now()}))
+		require.NoError(t, ours.SaveWalkOrder(ctx, map[string]float64{"milk": 0}))
+
+		assert.Empty(t, itemNames(t, theirs))
+		item, err := theirs.GetShoppingListItemFromIDHex(ctx, milk.IDHex)
+		require.NoError(t, err)
+		assert.Empty(t, item.IDHex)
+		mealPlan, err := theirs.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Empty(t, mealPlan.Meals[0].SlotMeal("Dinner"))
+		library, err := theirs.GetRecipes(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, library)
+		_, err = theirs.GetRecipe(ctx, recipe.IDHex)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, theirs.DeleteRecipe(ctx, recipe.IDHex), ErrNotFound)
+		dictionary, err := theirs.GetCategoryDictionary(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, dictionary)
+		stores, err := theirs.GetStores(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stores)
+		events, err := theirs.GetTickEvents(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+		order, err := theirs.GetWalkOrder(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, order)
+
+		// Writing the same keys in another household leaves ours alone
+		require.NoError(t, theirs.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Pizza"))
+		require.NoError(t, theirs.LearnCategory(ctx, "milk", "Drinks"))
+		require.NoError(t, theirs.SaveWalkOrder(ctx, map[string]float64{}))
+		_, err = theirs.TickShoppingListItem(ctx, milk.IDHex, true)
+		require.NoError(t, err)
+
+		mealPlan, err = ours.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal("Dinner"))
+		dictionary, err = ours.GetCategoryDictionary(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Dairy & eggs", dictionary["milk"])
+		order, err = ours.GetWalkOrder(ctx)
+		require.NoError(t, err)
+		assert.Len(t, order, 1)
+		item, err = ours.GetShoppingListItemFromIDHex(ctx, milk.IDHex)
+		require.NoError(t, err)
+		assert.False(t, item.Ticked)
+	})
+
+	t.Run("FirstHouseholdClaimsUnownedData", func(t *testing.T) {
+		store := newStore(t)
+		addItems(t, store, "Milk")
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Curry"))
+		_, err := store.AddRecipe(ctx, testRecipe("Chilli"))
+		require.NoError(t, err)
+		require.NoError(t, store.LearnCategory(ctx, "milk", "Dairy & eggs"))
+
+		first := testHousehold(t, store, "First")
+		second := testHousehold(t, store, "Second")
+
+		assert.Equal(t, []string{"Milk"}, itemNames(t, first))
+		mealPlan, err := first.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal("Dinner"))
+		library, err := first.GetRecipes(ctx)
+		require.NoError(t, err)
+		assert.Len(t, library, 1)
+		dictionary, err := first.GetCategoryDictionary(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Dairy & eggs", dictionary["milk"])
+
+		assert.Empty(t, itemNames(t, second))
+		assert.Empty(t, itemNames(t, store), "claimed data is no longer unowned")
+	})
+
+	t.Run("CancelledAccountContext", func(t *testing.T) {
+		store := newStore(t)
+		household, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+		require.NoError(t, err)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err = store.CreateHousehold(cancelled, models.Household{Name: "Allotment"})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.GetHouseholds(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.CreateUser(cancelled, models.User{Email: "sam@example.com", Name: "Sam", PasswordHash: "hash", HouseholdID: household.IDHex, Role: models.RoleOwner})
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.GetSession(cancelled, "token")
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.VerifyIntegrity(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// None of the cancelled changes were made
+		households, err := store.GetHouseholds(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Household{household}, households)
+		_, err = store.GetUserByEmail(ctx, "sam@example.com")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+// testHousehold creates a household in store and returns its data
+func testHousehold(t *testing.T, store Store, name string) DBInterface {
+	t.Helper()
+	household, err := store.CreateHousehold(context.Background(), models.Household{Name: name})
+	require.NoError(t, err)
+	return store.ForHousehold(household.IDHex)
+}
+
+// runDataConformance runs every DBInterface method against the store returned
+// by newStore
+func runDataConformance(t *testing.T, newStore func(t *testing.T) DBInterface) {
+	ctx := context.Background()
+	t.Run("EmptyShoppingList", func(t *testing.T) {
+		store := newStore(t)
+
+		list, err := store.GetShoppingList(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("AddShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+
+		item, err := store.AddShoppingListItem(ctx, "Milk")
+		require.NoError(t, err)
+		assert.False(t, item.ID.IsZero(), "ID should be generated")
+		assert.Equal(t, item.ID.Hex(), item.IDHex, "IDHex should match ID")
+		assert.Equal(t, "Milk", item.Item)
+		assert.False(t, item.Ticked)
+
+		list, err := store.GetShoppingList(ctx)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, item, list[0])
+	})
+
+	t.Run("AddShoppingListItemEmptyName", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.AddShoppingListItem(ctx, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("AddShoppingListItemParsesQuantity", func(t *testing.T) {
+		store := newStore(t)
+
+		item, err := store.AddShoppingListItem(ctx, "2kg potatoes (for roasting)")
+		require.NoError(t, err)
+		assert.Equal(t, "potatoes", item.Item)
+		assert.Equal(t, 2.0, item.Quantity)
+		assert.Equal(t, "kg", item.Unit)
+		assert.Equal(t, "for roasting", item.Note)
+	})
+
+	t.Run("AddShoppingListItemMergesDuplicates", func(t *testing.T) {
+		store := newStore(t)
+		flour, err := store.AddShoppingListItem(ctx, "500g flour")
+		require.NoError(t, err)
+		addItems(t, store, "6 eggs")
+
+		merged, err := store.AddShoppingListItem(ctx, "1 kg Flour")
+		require.NoError(t, err)
+		assert.Equal(t, flour.IDHex, merged.IDHex)
+		assert.Equal(t, "1.5 kg flour", merged.Text())
+		assert.Equal(t, []string{"flour", "eggs"}, itemNames(t, store))
+
+		// Ticked items are already in the basket, so a new line is added
+		_, err = store.TickShoppingListItem(ctx, flour.IDHex, true)
+		require.NoError(t, err)
+		_, err = store.AddShoppingListItem(ctx, "flour")
+		require.NoError(t, err)
+		// Amounts that cannot be added are kept apart
+		_, err = store.AddShoppingListItem(ctx, "1 bag eggs")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"flour", "eggs", "flour", "eggs"}, itemNames(t, store))
+	})
+
+	t.Run("InsertShoppingListItemWithSources", func(t *testing.T) {
+		store := newStore(t)
+		sources := []models.MealSource{
+			{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "abc123", Meal: "Chilli"},
+			{Week: "2026-W42", Day: "Friday", Slot: "Dinner", RecipeID: "def456", Meal: "Bolognese"},
+		}
+
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "500 g mince", Sources: sources})
+		require.NoError(t, err)
+		assert.False(t, item.ID.IsZero(), "ID should be generated")
+
+		stored, err := store.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, item, stored)
+		assert.Equal(t, sources, stored.Sources)
+	})
+
+	t.Run("InsertShoppingListItemWithQuantity", func(t *testing.T) {
+		store := newStore(t)
+
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "potatoes", Quantity: 1.5, Unit: "kg", Note: "for roasting"})
+		require.NoError(t, err)
+
+		stored, err := store.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, item, stored)
+		assert.Equal(t, "1.5 kg potatoes (for roasting)", stored.Text())
+	})
+
+	t.Run("UpdateShoppingListItemDetails", func(t *testing.T) {
+		store := newStore(t)
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "milk", Quantity: 2, Unit: "l"})
+		require.NoError(t, err)
+		_, err = store.TickShoppingListItem(ctx, item.IDHex, true)
+		require.NoError(t, err)
+
+		updated, err := store.UpdateShoppingListItemDetails(ctx, models.ShoppingListItem{IDHex: item.IDHex, Item: "oat milk", Note: "barista"})
+		require.NoError(t, err)
+		assert.Equal(t, "oat milk", updated.Item)
+		assert.Zero(t, updated.Quantity)
+		assert.Empty(t, updated.Unit)
+		assert.Equal(t, "barista", updated.Note)
+		assert.True(t, updated.Ticked, "ticked state should be kept")
+
+		stored, err := store.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, updated, stored)
+	})
+
+	t.Run("EditShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "milk"})
+		require.NoError(t, err)
+		assert.Zero(t, item.Version)
+
+		edited, err := store.EditShoppingListItem(ctx, models.ShoppingListItem{IDHex: item.IDHex, Item: "oat milk", Version: item.Version})
+		require.NoError(t, err)
+		assert.Equal(t, "oat milk", edited.Item)
+		assert.Equal(t, 1, edited.Version)
+
+		// Another edit made from the copy read before the first is rejected
+		stored, err := store.EditShoppingListItem(ctx, models.ShoppingListItem{IDHex: item.IDHex, Item: "soya milk", Version: item.Version})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, edited, stored, "the stored item should be returned")
+
+		// Every change to the item counts, not only edits
+		ticked, err := store.TickShoppingListItem(ctx, item.IDHex, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, ticked.Version)
+		_, err = store.EditShoppingListItem(ctx, models.ShoppingListItem{IDHex: item.IDHex, Item: "soya milk", Version: edited.Version})
+		assert.ErrorIs(t, err, ErrConflict)
+
+		_, err = store.EditShoppingListItem(ctx, models.ShoppingListItem{IDHex: "000000000000000000000000", Item: "eggs"})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("ReplaceShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+		source := models.MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "abc123"}
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "Mince", Sources: []models.MealSource{source}})
+		require.NoError(t, err)
+
+		restore := models.ShoppingListItem{IDHex: item.IDHex, Item: "Beef mince", Quantity: 500, Unit: "g", Ticked: true, Category: "Meat", Version: item.Version}
+		restored, err := store.ReplaceShoppingListItem(ctx, restore)
+		require.NoError(t, err)
+		assert.Equal(t, "Beef mince", restored.Item)
+		assert.Equal(t, 500.0, restored.Quantity)
+		assert.True(t, restored.Ticked)
+		assert.Equal(t, "Meat", restored.Category)
+		assert.Nil(t, restored.Sources)
+		assert.Equal(t, 1, restored.Version, "all of it is one change")
+
+		stored, err := store.ReplaceShoppingListItem(ctx, restore)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, restored, stored, "the stored item should be returned")
+
+		_, err = store.ReplaceShoppingListItem(ctx, models.ShoppingListItem{IDHex: "000000000000000000000000", Item: "eggs"})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("AddShoppingListItemGuessesCategory", func(t *testing.T) {
+		store := newStore(t)
+		require.NoError(t, store.LearnCategory(ctx, "Halloumi", "Dairy & eggs"))
+
+		milk, err := store.AddShoppingListItem(ctx, "2 l semi-skimmed milk")
+		require.NoError(t, err)
+		assert.Equal(t, "Dairy & eggs", milk.Category)
+
+		halloumi, err := store.AddShoppingListItem(ctx, "halloumi")
+		require.NoError(t, err)
+		assert.Equal(t, "Dairy & eggs", halloumi.Category)
+
+		bulbs, err := store.AddShoppingListItem(ctx, "light bulbs")
+		require.NoError(t, err)
+		assert.Empty(t, bulbs.Category)
+	})
+
+	t.Run("SetShoppingListItemCategory", func(t *testing.T) {
+		store := newStore(t)
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "Candles", Category: "Household"})
+		require.NoError(t, err)
+		stored, err := store.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, "Household", stored.Category)
+
+		updated, err := store.SetShoppingListItemCategory(ctx, item.IDHex, "Seasonal")
+		require.NoError(t, err)
+		assert.Equal(t, "Seasonal", updated.Category)
+		assert.Equal(t, "Candles", updated.Item)
+	})
+
+	t.Run("LearnCategory", func(t *testing.T) {
+		store := newStore(t)
+
+		dictionary, err := store.GetCategoryDictionary(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, dictionary)
+
+		require.NoError(t, store.LearnCategory(ctx, "Oat Milk", "Drinks"))
+		require.NoError(t, store.LearnCategory(ctx, "2 x Candles", "Household"))
+		require.NoError(t, store.LearnCategory(ctx, "oat milk", "Dairy & eggs"))
+		dictionary, err = store.GetCategoryDictionary(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"oat milk": "Dairy & eggs", "candles": "Household"}, dictionary)
+
+		require.NoError(t, store.LearnCategory(ctx, "Candles", ""))
+		dictionary, err = store.GetCategoryDictionary(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"oat milk": "Dairy & eggs"}, dictionary)
+
+		assert.Error(t, store.LearnCategory(ctx, " ", "Drinks"))
+	})
+
+	t.Run("SaveStore", func(t *testing.T) {
+		store := newStore(t)
+
+		stores, err := store.GetStores(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stores)
+
+		saved, err := store.SaveStore(ctx, models.Store{Name: " Corner shop ", CategoryOrder: []string{"Drinks", "Bakery"}})
+		require.NoError(t, err)
+		assert.Equal(t, "Corner shop", saved.Name)
+		_, err = store.SaveStore(ctx, models.Store{Name: "Big supermarket"})
+		require.NoError(t, err)
+		// Saving under the same name, in any case, replaces the store
+		_, err = store.SaveStore(ctx, models.Store{Name: "corner Shop", CategoryOrder: []string{"Produce"}})
+		require.NoError(t, err)
+
+		stores, err = store.GetStores(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Store{
+			{Name: "Big supermarket", CategoryOrder: []string{}},
+			{Name: "corner Shop", CategoryOrder: []string{"Produce"}},
+		}, stores)
+
+		_, err = store.SaveStore(ctx, models.Store{Name: ""})
+		assert.Error(t, err)
+	})
+
+	t.Run("DeleteStore", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.SaveStore(ctx, models.Store{Name: "Corner shop"})
+		require.NoError(t, err)
+
+		require.NoError(t, store.DeleteStore(ctx, "CORNER SHOP"))
+		stores, err := store.GetStores(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, stores)
+
+		assert.ErrorIs(t, store.DeleteStore(ctx, "Corner shop"), ErrNotFound)
+	})
+
+	t.Run("RecordTick", func(t *testing.T) {
+		store := newStore(t)
+		start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+		require.NoError(t, store.RecordTick(ctx, models.TickEvent{Item: "milk", TickedAt: start.Add(time.Minute)}))
+		require.NoError(t, store.RecordTick(ctx, models.TickEvent{Item: "bananas", TickedAt: start}))
+
+		events, err := store.GetTickEvents(ctx)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, "bananas", events[0].Item)
+		assert.True(t, start.Equal(events[0].TickedAt))
+		assert.Equal(t, "milk", events[1].Item)
+		assert.True(t, start.Add(time.Minute).Equal(events[1].TickedAt))
+	})
+
+	t.Run("SaveWalkOrder", func(t *testing.T) {
+		store := newStore(t)
+		order, err := store.GetWalkOrder(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, order)
+
+		require.NoError(t, store.SaveWalkOrder(ctx, map[string]float64{"bananas": 0, "milk": 1}))
+		require.NoError(t, store.SaveWalkOrder(ctx, map[string]float64{"bread": 0.25, "milk": 0.5}))
+		order, err = store.GetWalkOrder(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"bread": 0.25, "milk": 0.5}, order)
+
+		require.NoError(t, store.SaveWalkOrder(ctx, nil))
+		order, err = store.GetWalkOrder(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, order)
+	})
+
+	t.Run("SetShoppingListItemSources", func(t *testing.T) {
+		store := newStore(t)
+		source := models.MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "abc123"}
+
+		item, err := store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "Mince", Sources: []models.MealSource{source}})
+		require.NoError(t, err)
+
+		updated, err := store.SetShoppingListItemSources(ctx, item.IDHex, nil)
+		require.NoError(t, err)
+		assert.Nil(t, updated.Sources)
+
+		updated, err = store.SetShoppingListItemSources(ctx, item.IDHex, []models.MealSource{source})
+		require.NoError(t, err)
+		assert.Equal(t, []models.MealSource{source}, updated.Sources)
+	})
+
+	t.Run("GetShoppingListPreservesInsertionOrder", func(t *testing.T) {
+		store := newStore(t)
+
+		addItems(t, store, "Eggs", "Bread", "Cheese")
+
+		assert.Equal(t, []string{"Eggs", "Bread", "Cheese"}, itemNames(t, store))
+	})
+
+	t.Run("GetShoppingListItemFromIDHex", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs", "Bread")
+
+		item, err := store.GetShoppingListItemFromIDHex(ctx, items[1].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, items[1], item)
+
+		missing, err := store.GetShoppingListItemFromIDHex(ctx, "000000000000000000000000")
+		require.NoError(t, err)
+		assert.Equal(t, models.ShoppingListItem{}, missing)
+	})
+
+	t.Run("UpdateShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs", "Bread")
+
+		updated, err := store.UpdateShoppingListItem(ctx, items[0].IDHex, "Free range eggs")
+		require.NoError(t, err)
+		assert.Equal(t, items[0].IDHex, updated.IDHex)
+		assert.Equal(t, "Free range eggs", updated.Item)
+
+		assert.Equal(t, []string{"Free range eggs", "Bread"}, itemNames(t, store))
+	})
+
+	t.Run("TickShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs")
+
+		ticked, err := store.TickShoppingListItem(ctx, items[0].IDHex, true)
+		require.NoError(t, err)
+		assert.True(t, ticked.Ticked)
+
+		unticked, err := store.TickShoppingListItem(ctx, items[0].IDHex, false)
+		require.NoError(t, err)
+		assert.False(t, unticked.Ticked)
+	})
+
+	t.Run("DeleteShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs", "Bread", "Cheese")
+
+		require.NoError(t, store.DeleteShoppingListItem(ctx, items[1].IDHex))
+
+		assert.Equal(t, []string{"Eggs", "Cheese"}, itemNames(t, store))
+	})
+
+	t.Run("SortShoppingList", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs", "Bread", "Cheese")
+
+		err := store.SortShoppingList(ctx, []models.Order{
+			{ID: items[2].IDHex, Position: 1},
+			{ID: items[0].IDHex, Position: 2},
+			{ID: items[1].IDHex, Position: 3},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Cheese", "Eggs", "Bread"}, itemNames(t, store))
+
+		// New items are appended after the sorted ones
+		addItems(t, store, "Butter")
+		assert.Equal(t, []string{"Cheese", "Eggs", "Bread", "Butter"}, itemNames(t, store))
+	})
+
+	t.Run("SortShoppingListInvalidID", func(t *testing.T) {
+		store := newStore(t)
+
+		addItems(t, store, "Eggs")
+
+		err := store.SortShoppingList(ctx, []models.Order{{ID: "not-an-id", Position: 1}})
+		assert.EqualError(t, err, "invalid object ID: not-an-id")
+		assert.Equal(t, []string{"Eggs"}, itemNames(t, store))
+	})
+
+	t.Run("ReorderShoppingList", func(t *testing.T) {
+		store := newStore(t)
+		items := addItems(t, store, "Eggs", "Bread", "Cheese")
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		version := lists[0].Version
+
+		// Two people reorder the list they both read: the first order is
+		// saved, and the second is turned away rather than overwrite it
+		first := []models.Order{{ID: items[2].IDHex, Position: 1}, {ID: items[0].IDHex, Position: 2}, {ID: items[1].IDHex, Position: 3}}
+		second := []models.Order{{ID: items[1].IDHex, Position: 1}, {ID: items[2].IDHex, Position: 2}, {ID: items[0].IDHex, Position: 3}}
+		require.NoError(t, store.ReorderShoppingList(ctx, first, version))
+		assert.ErrorIs(t, store.ReorderShoppingList(ctx, second, version), ErrConflict)
+		assert.Equal(t, []string{"Cheese", "Eggs", "Bread"}, itemNames(t, store))
+
+		lists, err = store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, version+1, lists[0].Version)
+		require.NoError(t, store.ReorderShoppingList(ctx, second, version+1))
+		assert.Equal(t, []string{"Bread", "Cheese", "Eggs"}, itemNames(t, store))
+	})
+
+	t.Run("MergeShoppingListItems", func(t *testing.T) {
+		store := newStore(t)
+		items := addItems(t, store, "Eggs", "Bread", "Cheese")
+		chilli := models.MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "abc", Meal: "Chilli"}
+		merged := items[0]
+		merged.Item, merged.Quantity, merged.Note, merged.Sources = "Eggs", 12, "free range", []models.MealSource{chilli}
+		_, err := store.TickShoppingListItem(ctx, items[2].IDHex, true)
+		require.NoError(t, err)
+
+		// The cheese changed after it was read, so the merge fails part way
+		// through and nothing of it is saved
+		err = store.MergeShoppingListItems(ctx, []models.ShoppingListItem{merged}, []models.ShoppingListItem{items[1], items[2]})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, []string{"Eggs", "Bread", "Cheese"}, itemNames(t, store))
+		eggs, err := store.GetShoppingListItemFromIDHex(ctx, items[0].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, items[0].Version, eggs.Version)
+		assert.Empty(t, eggs.Sources)
+
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		version := lists[0].Version
+		require.NoError(t, store.MergeShoppingListItems(ctx, []models.ShoppingListItem{merged}, []models.ShoppingListItem{items[1]}))
+		assert.Equal(t, []string{"Eggs", "Cheese"}, itemNames(t, store))
+		eggs, err = store.GetShoppingListItemFromIDHex(ctx, items[0].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, "12 Eggs (free range)", eggs.Text())
+		assert.Equal(t, []models.MealSource{chilli}, eggs.Sources)
+		assert.Equal(t, items[0].Version+1, eggs.Version)
+		lists, err = store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, lists[0].Version, version)
+	})
+
+	t.Run("AddShoppingListIdToShoppingListOrder", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs", "Bread")
+
+		// Items left out of a new order follow the rest, rather than vanish
+		require.NoError(t, store.SortShoppingList(ctx, []models.Order{{ID: items[1].IDHex, Position: 1}}))
+		assert.Equal(t, []string{"Bread", "Eggs"}, itemNames(t, store))
+
+		// An item already in the order keeps its place, and is not repeated
+		require.NoError(t, store.AddShoppingListIdToShoppingListOrder(ctx, items[1].IDHex))
+		assert.Equal(t, []string{"Bread", "Eggs"}, itemNames(t, store))
+	})
+
+	t.Run("SortOrderIntegrity", func(t *testing.T) {
+		store := newStore(t)
+
+		items := addItems(t, store, "Eggs", "Bread", "Cheese")
+		require.NoError(t, store.DeleteShoppingListItem(ctx, items[1].IDHex))
+		require.NoError(t, store.SortShoppingList(ctx, []models.Order{
+			{ID: items[2].IDHex, Position: 1},
+			{ID: primitive.NewObjectID().Hex(), Position: 2},
+			{ID: items[2].IDHex, Position: 3},
+		}))
+		assert.Equal(t, []string{"Cheese", "Eggs"}, itemNames(t, store))
+
+		if root, ok := store.(Store); ok {
+			report, err := root.VerifyIntegrity(ctx)
+			require.NoError(t, err)
+			assert.True(t, report.OK(), "%v", report.Problems)
+			assert.GreaterOrEqual(t, report.Lists, 1)
+		}
+	})
+
+	t.Run("GetShoppingListsCreatesFirstList", func(t *testing.T) {
+		store := newStore(t)
+
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		require.Len(t, lists, 1)
+		assert.Equal(t, models.DefaultShoppingListName, lists[0].Name)
+		assert.Equal(t, lists[0].ID.Hex(), lists[0].IDHex)
+		assert.False(t, lists[0].Archived)
+
+		// Items added without choosing a list go on the first one
+		addItems(t, store, "Eggs")
+		assert.Equal(t, []string{"Eggs"}, itemNames(t, store.ForShoppingList(lists[0].IDHex)))
+	})
+
+	t.Run("CreateShoppingList", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.CreateShoppingList(ctx, " ")
+		assert.Error(t, err)
+
+		hardware, err := store.CreateShoppingList(ctx, " Hardware ")
+		require.NoError(t, err)
+		assert.Equal(t, "Hardware", hardware.Name)
+		assert.Equal(t, hardware.ID.Hex(), hardware.IDHex)
+		party, err := store.CreateShoppingList(ctx, "Party")
+		require.NoError(t, err)
+
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		require.Len(t, lists, 3)
+		assert.Equal(t, models.DefaultShoppingListName, lists[0].Name, "the first list is made before any other")
+		assert.Equal(t, hardware.IDHex, lists[1].IDHex)
+		assert.Equal(t, party.IDHex, lists[2].IDHex)
+	})
+
+	t.Run("RenameAndArchiveShoppingList", func(t *testing.T) {
+		store := newStore(t)
+		list, err := store.CreateShoppingList(ctx, "Hardware")
+		require.NoError(t, err)
+
+		renamed, err := store.RenameShoppingList(ctx, list.IDHex, "DIY")
+		require.NoError(t, err)
+		assert.Equal(t, "DIY", renamed.Name)
+		_, err = store.RenameShoppingList(ctx, list.IDHex, "")
+		assert.Error(t, err)
+
+		archived, err := store.ArchiveShoppingList(ctx, list.IDHex, true)
+		require.NoError(t, err)
+		assert.True(t, archived.Archived)
+		assert.Equal(t, "DIY", archived.Name)
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, archived.IDHex, lists[1].IDHex)
+		assert.True(t, lists[1].Archived)
+
+		restored, err := store.ArchiveShoppingList(ctx, list.IDHex, false)
+		require.NoError(t, err)
+		assert.False(t, restored.Archived)
+
+		_, err = store.RenameShoppingList(ctx, primitive.NewObjectID().Hex(), "Nowhere")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.ArchiveShoppingList(ctx, "not-an-id", true)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("EditShoppingList", func(t *testing.T) {
+		store := newStore(t)
+		list, err := store.CreateShoppingList(ctx, "Hardware")
+		require.NoError(t, err)
+
+		edited, err := store.EditShoppingList(ctx, models.ShoppingList{IDHex: list.IDHex, Name: " DIY ", Archived: true, Version: list.Version})
+		require.NoError(t, err)
+		assert.Equal(t, "DIY", edited.Name)
+		assert.True(t, edited.Archived)
+		assert.Equal(t, list.Version+1, edited.Version)
+
+		// An edit made from the copy read before the first is rejected
+		stored, err := store.EditShoppingList(ctx, models.ShoppingList{IDHex: list.IDHex, Name: "Tools", Version: list.Version})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, edited, stored, "the stored list should be returned")
+
+		_, err = store.EditShoppingList(ctx, models.ShoppingList{IDHex: list.IDHex, Name: " ", Version: edited.Version})
+		assert.Error(t, err)
+		_, err = store.EditShoppingList(ctx, models.ShoppingList{IDHex: primitive.NewObjectID().Hex(), Name: "Nowhere"})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("ForShoppingList", func(t *testing.T) {
+		store := newStore(t)
+		addItems(t, store, "Eggs")
+		list, err := store.CreateShoppingList(ctx, "Hardware")
+		require.NoError(t, err)
+
+		hardware := store.ForShoppingList(list.IDHex)
+		assert.Equal(t, []string{}, itemNames(t, hardware))
+		items := addItems(t, hardware, "Nails", "Glue")
+		_, err = hardware.TickShoppingListItem(ctx, items[0].IDHex, true)
+		require.NoError(t, err)
+		require.NoError(t, hardware.SortShoppingList(ctx, []models.Order{{ID: items[1].IDHex, Position: 1}, {ID: items[0].IDHex, Position: 2}}))
+
+		assert.Equal(t, []string{"Glue", "Nails"}, itemNames(t, hardware))
+		assert.Equal(t, []string{"Eggs"}, itemNames(t, store))
+		missing, err := store.GetShoppingListItemFromIDHex(ctx, items[0].IDHex)
+		require.NoError(t, err)
+		assert.Empty(t, missing.IDHex, "items belong to one list")
+
+		_, err = store.ForShoppingList(primitive.NewObjectID().Hex()).GetShoppingList(ctx)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.ForShoppingList("not-an-id").AddShoppingListItem(ctx, "Eggs")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("MoveShoppingListItem", func(t *testing.T) {
+		store := newStore(t)
+		items := addItems(t, store, "Eggs", "Nails", "Bread")
+		list, err := store.CreateShoppingList(ctx, "Hardware")
+		require.NoError(t, err)
+		hardware := store.ForShoppingList(list.IDHex)
+		addItems(t, hardware, "Glue")
+		_, err = store.TickShoppingListItem(ctx, items[1].IDHex, true)
+		require.NoError(t, err)
+
+		moved, err := store.MoveShoppingListItem(ctx, items[1].IDHex, list.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, items[1].IDHex, moved.IDHex)
+		assert.True(t, moved.Ticked, "details move with the item")
+		assert.Equal(t, []string{"Eggs", "Bread"}, itemNames(t, store))
+		assert.Equal(t, []string{"Glue", "Nails"}, itemNames(t, hardware))
+
+		// Each list keeps its own order
+		require.NoError(t, store.SortShoppingList(ctx, []models.Order{{ID: items[2].IDHex, Position: 1}, {ID: items[0].IDHex, Position: 2}}))
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		_, err = hardware.MoveShoppingListItem(ctx, items[1].IDHex, lists[0].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bread", "Eggs", "Nails"}, itemNames(t, store))
+		assert.Equal(t, []string{"Glue"}, itemNames(t, hardware))
+
+		_, err = store.MoveShoppingListItem(ctx, items[0].IDHex, lists[0].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bread", "Eggs", "Nails"}, itemNames(t, store), "moving to the same list changes nothing")
+
+		_, err = store.MoveShoppingListItem(ctx, primitive.NewObjectID().Hex(), list.IDHex)
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.MoveShoppingListItem(ctx, items[0].IDHex, primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, []string{"Bread", "Eggs", "Nails"}, itemNames(t, store))
+	})
+
+	t.Run("ShoppingListVersion", func(t *testing.T) {
+		store := newStore(t)
+		hardware, err := store.CreateShoppingList(ctx, "Hardware")
+		require.NoError(t, err)
+		versions := func() (int, int) {
+			t.Helper()
+			lists, err := store.GetShoppingLists(ctx)
+			require.NoError(t, err)
+			require.Len(t, lists, 2)
+			return lists[0].Version, lists[1].Version
+		}
+
+		items := addItems(t, store, "Eggs", "Bread")
+		first, other := versions()
+		assert.Equal(t, 2, first, "adding items changes the list")
+		assert.Zero(t, other)
+
+		// Changes to an item are counted by the item, not the list
+		_, err = store.TickShoppingListItem(ctx, items[0].IDHex, true)
+		require.NoError(t, err)
+		_, err = store.RenameShoppingList(ctx, hardware.IDHex, "DIY")
+		require.NoError(t, err)
+		first, _ = versions()
+		assert.Equal(t, 2, first)
+
+		require.NoError(t, store.SortShoppingList(ctx, []models.Order{{ID: items[1].IDHex, Position: 1}, {ID: items[0].IDHex, Position: 2}}))
+		first, _ = versions()
+		assert.Equal(t, 3, first)
+
+		_, err = store.MoveShoppingListItem(ctx, items[0].IDHex, hardware.IDHex)
+		require.NoError(t, err)
+		first, other = versions()
+		assert.Equal(t, 4, first)
+		assert.Equal(t, 1, other)
+
+		require.NoError(t, store.DeleteShoppingListItem(ctx, items[1].IDHex))
+		first, _ = versions()
+		assert.Equal(t, 5, first)
+	})
+
+	t.Run("DeleteShoppingList", func(t *testing.T) {
+		store := newStore(t)
+		addItems(t, store, "Eggs")
+		list, err := store.CreateShoppingList(ctx, "Hardware")
+		require.NoError(t, err)
+		addItems(t, store.ForShoppingList(list.IDHex), "Nails")
+
+		require.NoError(t, store.DeleteShoppingList(ctx, list.IDHex))
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		assert.Len(t, lists, 1)
+		_, err = store.ForShoppingList(list.IDHex).GetShoppingList(ctx)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, []string{"Eggs"}, itemNames(t, store))
+
+		assert.ErrorIs(t, store.DeleteShoppingList(ctx, list.IDHex), ErrNotFound)
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		store := newStore(t)
+		lists, err := store.GetShoppingLists(ctx)
+		require.NoError(t, err)
+		listID := lists[0].IDHex
+		now := time.Now().UTC().Truncate(time.Millisecond)
+
+		first, err := store.RecordChange(ctx, models.Change{
+			ListID:   listID,
+			UserID:   "user1",
+			UserName: "Sam",
+			Time:     now,
+			Action:   models.ChangeDelete,
+			Before:   models.ChangeValue{Item: &models.ShoppingListItem{IDHex: "abc", Item: "Eggs"}, Order: []string{"abc", "def"}},
+			After:    models.ChangeValue{Order: []string{"def"}},
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, first.IDHex)
+		_, err = store.RecordChange(ctx, models.Change{ListID: listID, Time: now.Add(time.Second), Action: models.ChangeSort})
+		require.NoError(t, err)
+		meal, err := store.RecordChange(ctx, models.Change{
+			Time:   now,
+			Action: models.ChangeMeal,
+			After:  models.ChangeValue{Meal: &models.MealSlotInput{Week: "2026-W42", Day: "Monday", Slot: "Dinner", Meal: "Chilli"}},
+		})
+		require.NoError(t, err)
+
+		found, err := store.GetChange(ctx, first.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, first, found)
+
+		changes, err := store.GetChanges(ctx, listID, 10)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, models.ChangeSort, changes[0].Action, "newest first")
+		assert.Equal(t, first, changes[1])
+		changes, err = store.GetChanges(ctx, listID, 1)
+		require.NoError(t, err)
+		assert.Len(t, changes, 1)
+		changes, err = store.GetChanges(ctx, "", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Change{meal}, changes)
+
+		first.Undone = true
+		first.Before.Item.IDHex = "ghi"
+		require.NoError(t, store.UpdateChange(ctx, first))
+		found, err = store.GetChange(ctx, first.IDHex)
+		require.NoError(t, err)
+		assert.True(t, found.Undone)
+		assert.Equal(t, "ghi", found.Before.Item.IDHex)
+
+		missing := primitive.NewObjectID().Hex()
+		_, err = store.GetChange(ctx, missing)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, store.UpdateChange(ctx, models.Change{IDHex: missing}), ErrNotFound)
+	})
+
+	t.Run("HistoryUndoDelete", func(t *testing.T) {
+		store := WithHistory(newStore(t), models.User{Name: "Sam"})
+		items := addItems(t, store, "Eggs", "Milk", "Bread")
+
+		require.NoError(t, store.DeleteShoppingListItem(ctx, items[1].IDHex))
+		deleted := LastChange(store)
+		require.NotNil(t, deleted)
+		assert.Equal(t, "Sam", deleted.UserName)
+		assert.Equal(t, "deleted Milk", deleted.Summary())
+
+		undone, err := Undo(ctx, store, deleted.IDHex)
+		require.NoError(t, err)
+		assert.True(t, undone.Undone)
+		assert.Equal(t, []string{"Eggs", "Milk", "Bread"}, itemNames(t, store), "the item goes back in its place")
+		_, err = Undo(ctx, store, deleted.IDHex)
+		require.NoError(t, err, "undoing twice changes nothing")
+		assert.Equal(t, []string{"Eggs", "Milk", "Bread"}, itemNames(t, store))
+
+		_, err = Redo(ctx, store, deleted.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Eggs", "Bread"}, itemNames(t, store))
+		_, err = Undo(ctx, store, deleted.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Eggs", "Milk", "Bread"}, itemNames(t, store))
+
+		changes, err := store.GetChanges(ctx, deleted.ListID, 10)
+		require.NoError(t, err)
+		assert.Len(t, changes, 4, "three adds and the delete; undoing is not recorded")
+	})
+
+	t.Run("HistoryUndoEdits", func(t *testing.T) {
+		store := WithHistory(newStore(t), models.User{})
+		items := addItems(t, store, "Eggs")
+
+		tickedItem, err := store.TickShoppingListItem(ctx, items[0].IDHex, true)
+		require.NoError(t, err)
+		ticked := LastChange(store)
+		require.NotNil(t, ticked)
+		assert.Equal(t, "Someone", ticked.Who())
+		assert.Equal(t, "ticked Eggs", ticked.Summary())
+
+		edit := tickedItem
+		edit.Item = "Free range eggs"
+		_, err = store.EditShoppingListItem(ctx, edit)
+		require.NoError(t, err)
+		edited := LastChange(store)
+		assert.Equal(t, "changed Eggs to Free range eggs", edited.Summary())
+
+		// The tick cannot be undone until the later edit is
+		_, err = Undo(ctx, store, ticked.IDHex)
+		assert.ErrorIs(t, err, ErrConflict)
+		_, err = Undo(ctx, store, edited.IDHex)
+		require.NoError(t, err)
+		_, err = Undo(ctx, store, ticked.IDHex)
+		require.NoError(t, err)
+		item, err := store.GetShoppingListItemFromIDHex(ctx, items[0].IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, "Eggs", item.Item)
+		assert.False(t, item.Ticked)
+
+		_, err = Redo(ctx, store, ticked.IDHex)
+		require.NoError(t, err)
+		item, err = store.GetShoppingListItemFromIDHex(ctx, items[0].IDHex)
+		require.NoError(t, err)
+		assert.True(t, item.Ticked)
+	})
+
+	t.Run("HistoryUndoSort", func(t *testing.T) {
+		store := WithHistory(newStore(t), models.User{})
+		items := addItems(t, store, "Eggs", "Milk")
+
+		require.NoError(t, store.SortShoppingList(ctx, []models.Order{{ID: items[1].IDHex, Position: 1}, {ID: items[0].IDHex, Position: 2}}))
+		sorted := LastChange(store)
+		require.NotNil(t, sorted)
+		assert.Equal(t, models.ChangeSort, sorted.Action)
+
+		_, err := Undo(ctx, store, sorted.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Eggs", "Milk"}, itemNames(t, store))
+
+		addItems(t, store, "Bread")
+		_, err = Redo(ctx, store, sorted.IDHex)
+		assert.ErrorIs(t, err, ErrConflict, "the list has changed since")
+		assert.Equal(t, []string{"Eggs", "Milk", "Bread"}, itemNames(t, store))
+	})
+
+	t.Run("HistoryUndoMeal", func(t *testing.T) {
+		store := WithHistory(newStore(t), models.User{})
+		_, err := store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Dinner", Meal: "Chilli"})
+		require.NoError(t, err)
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Chilli"))
+		changes, err := store.GetChanges(ctx, "", 10)
+		require.NoError(t, err)
+		require.Len(t, changes, 1, "saving the same meal is not a change")
+		assert.Equal(t, "set Monday Dinner to Chilli", changes[0].Summary())
+
+		_, err = Undo(ctx, store, changes[0].IDHex)
+		require.NoError(t, err)
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Empty(t, mealPlan.Meals[0].SlotMeal("Dinner"))
+
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Curry"))
+		_, err = Redo(ctx, store, changes[0].IDHex)
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("GetMealPlanCreatesEmptyWeek", func(t *testing.T) {
+		store := newStore(t)
+
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, "2026-W42", mealPlan.Week)
+		assert.True(t, mealPlan.WeekStart.Equal(time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC)),
+			"WeekStart should be the Monday of the week, got %s", mealPlan.WeekStart)
+		require.Len(t, mealPlan.Meals, len(models.WeekDays))
+		for i, day := range models.WeekDays {
+			assert.Equal(t, day, mealPlan.Meals[i].Day)
+			assert.Empty(t, mealPlan.Meals[i].Slots)
+		}
+	})
+
+	t.Run("GetMealPlanInvalidWeek", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetMealPlan(ctx, "last week")
+		assert.Error(t, err)
+	})
+
+	t.Run("UpdateMeal", func(t *testing.T) {
+		store := newStore(t)
+
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Tuesday", "Dinner", "Spaghetti bolognese"))
+
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, "Tuesday", mealPlan.Meals[1].Day)
+		assert.Equal(t, "Spaghetti bolognese", mealPlan.Meals[1].SlotMeal("Dinner"))
+		assert.Empty(t, mealPlan.Meals[0].SlotMeal("Dinner"), "other days should be untouched")
+	})
+
+	t.Run("UpdateMealSlotsAreIndependent", func(t *testing.T) {
+		store := newStore(t)
+
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Breakfast", "Porridge"))
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Lunch", "Sandwiches"))
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Lasagne"))
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Lunch", "Soup"))
+
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"Breakfast": "Porridge",
+			"Lunch":     "Soup",
+			"Dinner":    "Lasagne",
+		}, mealPlan.Meals[0].Slots)
+	})
+
+	t.Run("UpdateMealInvalidSlot", func(t *testing.T) {
+		store := newStore(t)
+
+		assert.Error(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "$bad.slot", "Toast"))
+	})
+
+	t.Run("UpdateMealUnknownDay", func(t *testing.T) {
+		store := newStore(t)
+
+		assert.ErrorIs(t, store.UpdateMeal(ctx, "2026-W42", "Funday", "Dinner", "Toast"), ErrNotFound)
+		assert.ErrorIs(t, store.SetMealRecipe(ctx, "2026-W42", "Funday", "Dinner", "abc123"), ErrNotFound)
+		_, err := store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Funday", Slot: "Dinner", Meal: "Toast"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Funday", Slot: "Dinner", Meal: "Toast", Version: 3})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		for _, meal := range mealPlan.Meals {
+			assert.Empty(t, meal.Slots, meal.Day)
+		}
+	})
+
+	t.Run("UpdateMealKeepsWeeksSeparate", func(t *testing.T) {
+		store := newStore(t)
+
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Curry"))
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W43", "Monday", "Dinner", "Lasagne"))
+
+		thisWeek, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		nextWeek, err := store.GetMealPlan(ctx, "2026-W43")
+		require.NoError(t, err)
+
+		assert.Equal(t, "Curry", thisWeek.Meals[0].SlotMeal("Dinner"))
+		assert.Equal(t, "Lasagne", nextWeek.Meals[0].SlotMeal("Dinner"))
+	})
+
+	t.Run("SetMealRecipe", func(t *testing.T) {
+		store := newStore(t)
+
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Tuesday", "Dinner", "Chilli"))
+		require.NoError(t, store.SetMealRecipe(ctx, "2026-W42", "Tuesday", "Dinner", "abc123"))
+
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, "Chilli", mealPlan.Meals[1].SlotMeal("Dinner"))
+		assert.Equal(t, "abc123", mealPlan.Meals[1].SlotRecipe("Dinner"))
+		assert.Empty(t, mealPlan.Meals[1].SlotRecipe("Lunch"))
+
+		require.NoError(t, store.SetMealRecipe(ctx, "2026-W42", "Tuesday", "Dinner", ""))
+		mealPlan, err = store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Empty(t, mealPlan.Meals[1].SlotRecipe("Dinner"))
+		assert.Equal(t, "Chilli", mealPlan.Meals[1].SlotMeal("Dinner"), "unlinking keeps the meal text")
+	})
+
+	t.Run("EditMeal", func(t *testing.T) {
+		store := newStore(t)
+
+		saved, err := store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Dinner", Meal: "Chilli", RecipeID: "abc123"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, saved.Version)
+
+		mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+		require.NoError(t, err)
+		assert.Equal(t, "Chilli", mealPlan.Meals[0].SlotMeal("Dinner"))
+		assert.Equal(t, "abc123", mealPlan.Meals[0].SlotRecipe("Dinner"))
+		assert.Equal(t, 1, mealPlan.Meals[0].SlotVersion("Dinner"))
+		assert.Zero(t, mealPlan.Meals[0].SlotVersion("Lunch"))
+
+		// A second edit from the empty slot is rejected, with the stored slot
+		stored, err := store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Dinner", Meal: "Curry"})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, "Chilli", stored.Meal)
+		assert.Equal(t, "abc123", stored.RecipeID)
+		assert.Equal(t, 1, stored.Version)
+
+		saved, err = store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Dinner", Meal: "Curry", Version: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 2, saved.Version)
+
+		// Other slots and writes without a version are counted separately
+		_, err = store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "Soup"})
+		require.NoError(t, err)
+		require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Lasagne"))
+		_, err = store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Dinner", Meal: "Fish pie", Version: 2})
+		assert.ErrorIs(t, err, ErrConflict)
+
+		_, err = store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Someday", Slot: "Dinner", Meal: "Toast"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.EditMeal(ctx, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "$bad.slot", Meal: "Toast"})
+		assert.Error(t, err)
+	})
+
+	t.Run("SetMealRecipeInvalidSlot", func(t *testing.T) {
+		store := newStore(t)
+
+		assert.Error(t, store.SetMealRecipe(ctx, "2026-W42", "Monday", "$bad.slot", "abc123"))
+	})
+
+	t.Run("AddRecipe", func(t *testing.T) {
+		store := newStore(t)
+
+		recipe, err := store.AddRecipe(ctx, testRecipe("Chilli"))
+		require.NoError(t, err)
+		assert.False(t, recipe.ID.IsZero(), "ID should be generated")
+		assert.Equal(t, recipe.ID.Hex(), recipe.IDHex, "IDHex should match ID")
+
+		stored, err := store.GetRecipe(ctx, recipe.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, recipe, stored)
+	})
+
+	t.Run("AddRecipeInvalid", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.AddRecipe(ctx, recipes.Recipe{Title: " "})
+		assert.Error(t, err)
+	})
+
+	t.Run("GetRecipesSortedByTitle", func(t *testing.T) {
+		store := newStore(t)
+
+		for _, title := range []string{"Risotto", "apple crumble", "Chilli"} {
+			_, err := store.AddRecipe(ctx, testRecipe(title))
+			require.NoError(t, err)
+		}
+
+		library, err := store.GetRecipes(ctx)
+		require.NoError(t, err)
+		titles := []string{}
+		for _, recipe := range library {
+			titles = append(titles, recipe.Title)
+		}
+		assert.Equal(t, []string{"apple crumble", "Chilli", "Risotto"}, titles)
+	})
+
+	t.Run("GetRecipeNotFound", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetRecipe(ctx, "000000000000000000000000")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("UpdateRecipe", func(t *testing.T) {
+		store := newStore(t)
+
+		recipe, err := store.AddRecipe(ctx, testRecipe("Chilli"))
+		require.NoError(t, err)
+
+		edited := testRecipe("Veggie chilli")
+		edited.IDHex = recipe.IDHex
+		edited.Servings = 6
+		edited.Tags = []string{"vegetarian"}
+		updated, err := store.UpdateRecipe(ctx, edited)
+		require.NoError(t, err)
+		assert.Equal(t, recipe.ID, updated.ID)
+
+		stored, err := store.GetRecipe(ctx, recipe.IDHex)
+		require.NoError(t, err)
+		assert.Equal(t, "Veggie chilli", stored.Title)
+		assert.Equal(t, 6, stored.Servings)
+		assert.Equal(t, []string{"vegetarian"}, stored.Tags)
+		assert.Equal(t, edited.Ingredients, stored.Ingredients)
+	})
+
+	t.Run("UpdateRecipeNotFound", func(t *testing.T) {
+		store := newStore(t)
+
+		missing := testRecipe("Chilli")
+		missing.IDHex = "000000000000000000000000"
+		_, err := store.UpdateRecipe(ctx, missing)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("DeleteRecipe", func(t *testing.T) {
+		store := newStore(t)
+
+		recipe, err := store.AddRecipe(ctx, testRecipe("Chilli"))
+		require.NoError(t, err)
+
+		require.NoError(t, store.DeleteRecipe(ctx, recipe.IDHex))
+		_, err = store.GetRecipe(ctx, recipe.IDHex)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, store.DeleteRecipe(ctx, recipe.IDHex), ErrNotFound)
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		store := newStore(t)
+		items := addItems(t, store, "Milk", "Eggs")
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := store.GetShoppingList(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.GetShoppingLists(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.AddShoppingListItem(cancelled, "Bread")
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.TickShoppingListItem(cancelled, items[0].IDHex, true)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, store.DeleteShoppingListItem(cancelled, items[1].IDHex), context.Canceled)
+		assert.ErrorIs(t, store.SortShoppingList(cancelled, []models.Order{
+			{ID: items[1].IDHex, Position: 1},
+			{ID: items[0].IDHex, Position: 2},
+		}), context.Canceled)
+		_, err = store.GetMealPlan(cancelled, "2026-W42")
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = store.GetRecipes(cancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// None of the cancelled changes were made
+		list, err := store.GetShoppingList(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, items, list)
+	})
+}
+
+// testRecipe returns a valid recipe with the given title
+func testRecipe(title string) recipes.Recipe {
+	return recipes.Recipe{
+		Title:    title,
+		Servings: 4,
+		Ingredients: []recipes.Ingredient{
+			{Name: "mince", Quantity: 500, Unit: "g"},
+			{Name: "kidney beans", Quantity: 1, Unit: "tin"},
+			{Name: "salt"},
+		},
+		Method: []string{"Brown the mince", "Simmer with the beans"},
+		Tags:   []string{"batch", "spicy"},
+	}
+}
+
+// addItems adds each named item to the store and returns them in order
+func addItems(t *testing.T, store DBInterface, names ...string) []models.ShoppingListItem {
+	t.Helper()
+	var items []models.ShoppingListItem
+	for _, name := range names {
+		item, err := store.AddShoppingListItem(context.Background(), name)
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	return items
+}
+
+// itemNames returns the names of the items in the store's shopping list
+func itemNames(t *testing.T, store DBInterface) []string {
+	t.Helper()
+	list, err := store.GetShoppingList(context.Background())
+	require.NoError(t, err)
+	names := []string{}
+	for _, item := range list {
+		names = append(names, item.Item)
+	}
+	return names
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Store {
+		store, err := NewSQLiteDB(context.Background(), filepath.Join(t.TempDir(), "conformance.db"))
+		require.NoError(t, err)
+		t.Cleanup(store.Close)
+		return store
+	})
+}
+
+// TestMongoDBConformance runs the suite against a real MongoDB deployment when
+// GO_SHOPPING_MONGO_TEST_URI is set. Each test uses a throwaway database.
+func TestMongoDBConformance(t *testing.T) {
+	uri := os.Getenv("GO_SHOPPING_MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("Skipping as GO_SHOPPING_MONGO_TEST_URI is not set")
+	}
+
+	runConformance(t, func(t *testing.T) Store {
+		dbName := fmt.Sprintf("GoShopping-conformance-%d", time.Now().UnixNano())
+		store, err := NewMongoDB(context.Background(), uri, dbName)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			store.Client.Database(dbName).Drop(context.Background())
+			store.Close()
+		})
+		_, err = store.MigrateUp(context.Background())
+		require.NoError(t, err)
+		return store
+	})
+}
+
+func TestMemoryDBConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Store {
+		return NewMemoryDB()
+	})
+}
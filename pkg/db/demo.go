@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+)
+
+// The demo household's account, shown on the sign-in page in demo mode
+const (
+	DemoEmail    = "demo@example.com"
+	DemoPassword = "demo-password"
+)
+
+// demoShoppingList and demoMeals are loaded into the store in demo mode
+var demoShoppingList = []string{"Milk x2", "6 eggs", "Bread (wholemeal)", "500g mince", "Passata", "Spaghetti", "Bananas"}
+
+// demoTrips are past shopping trips, in the order items were ticked off, so
+// the walk order has something to learn from
+var demoTrips = [][]string{
+	{"spaghetti", "passata", "mince", "milk", "eggs", "bread", "bananas"},
+	{"passata", "mince", "eggs", "milk", "bread", "bananas"},
+}
+
+// demoStore is walked from the back of the shop to the tills
+var demoStore = models.Store{Name: "Corner shop", CategoryOrder: []string{"Cupboard", "Meat & fish", "Dairy & eggs", "Bakery", "Produce"}}
+
+var demoMeals = map[string]string{
+	"Monday":    "Chicken stir fry",
+	"Tuesday":   "Spaghetti bolognese",
+	"Wednesday": "Fish pie",
+	"Thursday":  "Vegetable curry",
+	"Friday":    "Homemade pizza",
+	"Saturday":  "Roast chicken",
+	"Sunday":    "Leftovers",
+}
+
+// demoRecipes are added to the recipe library and linked to the demo meals
+// with the same title
+var demoRecipes = []recipes.Recipe{
+	{
+		Title:    "Spaghetti bolognese",
+		Servings: 4,
+		Ingredients: []recipes.Ingredient{
+			{Name: "mince", Quantity: 500, Unit: "g"},
+			{Name: "passata", Quantity: 500, Unit: "g"},
+			{Name: "onion", Quantity: 1},
+			{Name: "spaghetti", Quantity: 400, Unit: "g"},
+		},
+		Method: []string{"Brown the mince with the onion", "Add the passata and simmer for 30 minutes", "Serve with the cooked spaghetti"},
+		Tags:   []string{"family", "pasta"},
+	},
+	{
+		Title:    "Fish pie",
+		Servings: 4,
+		Ingredients: []recipes.Ingredient{
+			{Name: "fish pie mix", Quantity: 400, Unit: "g"},
+			{Name: "potatoes", Quantity: 1, Unit: "kg"},
+			{Name: "milk", Quantity: 300, Unit: "ml"},
+		},
+		Method: []string{"Boil and mash the potatoes", "Poach the fish in the milk", "Top the fish with the mash and bake for 25 minutes"},
+		Tags:   []string{"fish"},
+	},
+}
+
+// SeedDemoAccount registers the demo account and fills its household with
+// sample data (see SeedDemoData)
+func SeedDemoAccount(ctx context.Context, store Store) (models.User, error) {
+	user, err := Register(ctx, store, DemoEmail, "Demo", DemoPassword, "Demo household")
+	if err != nil {
+		return models.User{}, fmt.Errorf("registering demo account: %w", err)
+	}
+	if err := SeedDemoData(ctx, store.ForHousehold(user.HouseholdID)); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// SeedDemoData fills store with sample recipes, a plan for this week and a
+// shopping list
+func SeedDemoData(ctx context.Context, store DBInterface) error {
+	library := []recipes.Recipe{}
+	for _, recipe := range demoRecipes {
+		newRecipe, err := store.AddRecipe(ctx, recipe.Clone())
+		if err != nil {
+			return fmt.Errorf("seeding recipe %q: %w", recipe.Title, err)
+		}
+		library = append(library, newRecipe)
+	}
+
+	week := models.ISOWeek(time.Now())
+	for day, meal := range demoMeals {
+		if err := store.UpdateMeal(ctx, week, day, models.LegacyMealSlot, meal); err != nil {
+			return fmt.Errorf("seeding meal for %s: %w", day, err)
+		}
+		if recipe, found := recipes.FindByTitle(library, meal); found {
+			if err := store.SetMealRecipe(ctx, week, day, models.LegacyMealSlot, recipe.IDHex); err != nil {
+				return fmt.Errorf("seeding recipe for %s: %w", day, err)
+			}
+		}
+	}
+
+	for _, text := range demoShoppingList {
+		if _, err := store.AddShoppingListItem(ctx, text); err != nil {
+			return fmt.Errorf("seeding shopping list item %q: %w", text, err)
+		}
+	}
+	if _, err := store.SaveStore(ctx, demoStore); err != nil {
+		return fmt.Errorf("seeding store %q: %w", demoStore.Name, err)
+	}
+	for i, trip := range demoTrips {
+		start := time.Now().AddDate(0, 0, -7*(len(demoTrips)-i))
+		for j, item := range trip {
+			if err := store.RecordTick(ctx, models.TickEvent{Item: item, TickedAt: start.Add(time.Duration(j) * time.Minute)}); err != nil {
+				return fmt.Errorf("seeding tick for %q: %w", item, err)
+			}
+		}
+	}
+
+	// Start with a couple of items already in the basket
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return err
+	}
+	for _, item := range shoppingList[:2] {
+		if _, err := store.TickShoppingListItem(ctx, item.IDHex, true); err != nil {
+			return fmt.Errorf("seeding ticked item %q: %w", item.Item, err)
+		}
+	}
+
+	return nil
+}
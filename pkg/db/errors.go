@@ -0,0 +1,14 @@
+package db
+
+import "errors"
+
+// ErrNotFound is returned when the record asked for does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned when a record would duplicate one that must be unique,
+// such as a second user with the same email
+var ErrExists = errors.New("already exists")
+
+// ErrConflict is returned when an edit was made to an out of date copy of a
+// record that someone else has changed since
+var ErrConflict = errors.New("changed since it was read")
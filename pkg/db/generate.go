@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+)
+
+// GenerateShoppingList adds the ingredients of every recipe planned in week to
+// the shopping list and returns the items added. Quantities of the same
+// ingredient in the same unit are summed across meals, and ingredients
+// already on the list are skipped.
+func GenerateShoppingList(ctx context.Context, store DBInterface, week string) ([]models.ShoppingListItem, error) {
+	mealPlan, err := store.GetMealPlan(ctx, week)
+	if err != nil {
+		return nil, err
+	}
+	library, err := store.GetRecipes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	onList := make(map[string]bool)
+	for _, item := range shoppingList {
+		// Items saved as a single string may still start with a quantity
+		onList[ingredientKey(recipes.ParseIngredient(item.Item).Name)] = true
+	}
+
+	planned, err := categorise(ctx, store, planIngredients(mealPlan, library)...)
+	if err != nil {
+		return nil, err
+	}
+
+	added := []models.ShoppingListItem{}
+	for _, item := range planned {
+		if onList[ingredientKey(item.Item)] {
+			continue
+		}
+		newItem, err := store.InsertShoppingListItem(ctx, item)
+		if err != nil {
+			return added, fmt.Errorf("adding %q: %w", item.Item, err)
+		}
+		added = append(added, newItem)
+	}
+	return added, nil
+}
+
+// planIngredients returns the ingredients of the recipes linked to the meals
+// of mealPlan as shopping list items, in the order they are first needed.
+// Quantities of the same ingredient are added where their units can be
+// converted (see models.MergeShoppingListItems). Recipes no longer in the
+// library are skipped.
+func planIngredients(mealPlan models.MealPlan, library []recipes.Recipe) []models.ShoppingListItem {
+	byID := make(map[string]recipes.Recipe, len(library))
+	for _, recipe := range library {
+		byID[recipe.IDHex] = recipe
+	}
+
+	var planned []models.ShoppingListItem
+	for _, meal := range mealPlan.Meals {
+		// Map order is random, so visit the day's slots alphabetically
+		slots := make([]string, 0, len(meal.Recipes))
+		for slot := range meal.Recipes {
+			slots = append(slots, slot)
+		}
+		sort.Strings(slots)
+
+		for _, slot := range slots {
+			recipe, exists := byID[meal.Recipes[slot]]
+			if !exists {
+				continue
+			}
+			source := models.MealSource{
+				Week:     mealPlan.Week,
+				Day:      meal.Day,
+				Slot:     slot,
+				RecipeID: recipe.IDHex,
+				Meal:     recipe.Title,
+			}
+			for _, ingredient := range recipe.Ingredients {
+				planned = addPlanned(planned, models.ShoppingListItem{
+					Item:     ingredient.Name,
+					Quantity: ingredient.Quantity,
+					Unit:     ingredient.Unit,
+					Sources:  []models.MealSource{source},
+				})
+			}
+		}
+	}
+	return planned
+}
+
+// addPlanned merges item into the first planned item it can be added to, or
+// appends it
+func addPlanned(planned []models.ShoppingListItem, item models.ShoppingListItem) []models.ShoppingListItem {
+	for i := range planned {
+		if merged, ok := models.MergeShoppingListItems(planned[i], item); ok {
+			planned[i] = merged
+			return planned
+		}
+	}
+	return append(planned, item)
+}
+
+// ingredientKey normalises an ingredient name for comparison
+func ingredientKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
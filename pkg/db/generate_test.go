@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// planRecipe adds recipe to the library and links it to a slot of week
+func planRecipe(t *testing.T, store DBInterface, week, day, slot string, recipe recipes.Recipe) recipes.Recipe {
+	t.Helper()
+	ctx := context.Background()
+	recipe, err := store.AddRecipe(ctx, recipe)
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateMeal(ctx, week, day, slot, recipe.Title))
+	require.NoError(t, store.SetMealRecipe(ctx, week, day, slot, recipe.IDHex))
+	return recipe
+}
+
+func TestGenerateShoppingList(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	chilli := planRecipe(t, store, "2026-W42", "Monday", "Dinner", recipes.Recipe{
+		Title: "Chilli",
+		Ingredients: []recipes.Ingredient{
+			{Name: "mince", Quantity: 200, Unit: "g"},
+			{Name: "kidney beans", Quantity: 1, Unit: "tin"},
+			{Name: "onion", Quantity: 1},
+		},
+	})
+	bolognese := planRecipe(t, store, "2026-W42", "Wednesday", "Dinner", recipes.Recipe{
+		Title: "Bolognese",
+		Ingredients: []recipes.Ingredient{
+			{Name: "Mince", Quantity: 300, Unit: "g"},
+			{Name: "spaghetti", Quantity: 400, Unit: "g"},
+			{Name: "onion", Quantity: 2},
+		},
+	})
+	// A meal typed as free text adds nothing
+	require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Friday", "Dinner", "Takeaway"))
+	// Other weeks are not included
+	planRecipe(t, store, "2026-W43", "Monday", "Dinner", recipes.Recipe{
+		Title:       "Soup",
+		Ingredients: []recipes.Ingredient{{Name: "leeks", Quantity: 3}},
+	})
+	_, err := store.AddShoppingListItem(ctx, "Kidney beans")
+	require.NoError(t, err)
+
+	added, err := GenerateShoppingList(ctx, store, "2026-W42")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"500 g mince", "3 onion", "400 g spaghetti"}, itemNamesOf(added))
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kidney beans", "500 g mince", "3 onion", "400 g spaghetti"}, itemNamesOf(list))
+	assert.Equal(t, "mince", list[1].Item)
+	assert.Equal(t, 500.0, list[1].Quantity)
+
+	mince := added[0]
+	require.Len(t, mince.Sources, 2)
+	assert.Equal(t, models.MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: chilli.IDHex, Meal: "Chilli"}, mince.Sources[0])
+	assert.Equal(t, bolognese.IDHex, mince.Sources[1].RecipeID)
+	assert.Equal(t, "Chilli, Bolognese", mince.SourceSummary())
+
+	// Building again adds nothing, as everything is already on the list
+	added, err = GenerateShoppingList(ctx, store, "2026-W42")
+	require.NoError(t, err)
+	assert.Empty(t, added)
+}
+
+func TestGenerateShoppingListSkipsDeletedRecipes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	recipe := planRecipe(t, store, "2026-W42", "Monday", "Lunch", recipes.Recipe{
+		Title:       "Soup",
+		Ingredients: []recipes.Ingredient{{Name: "leeks", Quantity: 3}},
+	})
+	require.NoError(t, store.DeleteRecipe(ctx, recipe.IDHex))
+
+	added, err := GenerateShoppingList(ctx, store, "2026-W42")
+	require.NoError(t, err)
+	assert.Empty(t, added)
+}
+
+func TestGenerateShoppingListConvertsUnits(t *testing.T) {
+	store := NewMemoryDB()
+	planRecipe(t, store, "2026-W42", "Monday", "Breakfast", recipes.Recipe{
+		Title: "Porridge",
+		Ingredients: []recipes.Ingredient{
+			{Name: "milk", Quantity: 300, Unit: "ml"},
+			{Name: "coriander", Quantity: 1, Unit: "bunch"},
+		},
+	})
+	planRecipe(t, store, "2026-W42", "Tuesday", "Breakfast", recipes.Recipe{
+		Title: "Pancakes",
+		Ingredients: []recipes.Ingredient{
+			{Name: "milk", Quantity: 1, Unit: "cup"},
+			{Name: "coriander", Quantity: 20, Unit: "g"},
+		},
+	})
+
+	added, err := GenerateShoppingList(context.Background(), store, "2026-W42")
+	require.NoError(t, err)
+	// Units that cannot be converted are kept apart
+	assert.Equal(t, []string{"550 ml milk", "1 bunch coriander", "20 g coriander"}, itemNamesOf(added))
+	assert.Len(t, added[0].Sources, 2)
+}
+
+// itemNamesOf returns items as text, e.g. "500 g mince"
+func itemNamesOf(items []models.ShoppingListItem) []string {
+	names := []string{}
+	for _, item := range items {
+		names = append(names, item.Text())
+	}
+	return names
+}
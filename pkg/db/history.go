@@ -0,0 +1,540 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// historyDB records the changes made through it in the household's history.
+// See WithHistory.
+type historyDB struct {
+	DBInterface
+	user models.User
+	// list is the IDHex of the shopping list item calls use, or "" for the
+	// household's first list
+	list string
+	// last is the change most recently recorded through this store
+	last *models.Change
+}
+
+// WithHistory returns a view of store that records every change made through
+// it to shopping list items, their order and the meal plan as a
+// models.Change made by user, so that it can be undone. Moving items between
+// lists and changes to the lists themselves are not recorded.
+func WithHistory(store DBInterface, user models.User) DBInterface {
+	return &historyDB{DBInterface: withoutHistory(store), user: user}
+}
+
+// withoutHistory returns the store that a store from WithHistory records the
+// changes to
+func withoutHistory(store DBInterface) DBInterface {
+	if history, ok := store.(*historyDB); ok {
+		return history.DBInterface
+	}
+	return store
+}
+
+// LastChange returns the change most recently recorded through store, or
+// nil if there is none or store did not come from WithHistory
+func LastChange(store DBInterface) *models.Change {
+	if history, ok := store.(*historyDB); ok {
+		return history.last
+	}
+	return nil
+}
+
+// ForShoppingList returns a view of this household whose shopping list item
+// calls use the list with IDHex listID, recording changes as made by the
+// same user
+func (h *historyDB) ForShoppingList(listID string) DBInterface {
+	return &historyDB{DBInterface: h.DBInterface.ForShoppingList(listID), user: h.user, list: listID}
+}
+
+// record adds a change to the history. The change has already been made, so
+// failing to record it is logged rather than returned.
+func (h *historyDB) record(ctx context.Context, listID string, action string, before, after models.ChangeValue) {
+	change, err := h.DBInterface.RecordChange(ctx, models.Change{
+		ListID:   listID,
+		UserID:   h.user.IDHex,
+		UserName: h.user.DisplayName(),
+		Time:     time.Now().UTC(),
+		Action:   action,
+		Before:   before,
+		After:    after,
+	})
+	if err != nil {
+		fmt.Printf("Error recording %s change: %s\n", action, err)
+		return
+	}
+	h.last = &change
+}
+
+// recordList adds a change to the list item calls use
+func (h *historyDB) recordList(ctx context.Context, action string, before, after models.ChangeValue) {
+	listID := h.list
+	if listID == "" {
+		lists, err := h.GetShoppingLists(ctx)
+		if err != nil || len(lists) == 0 {
+			fmt.Printf("Error recording %s change: no shopping list\n", action)
+			return
+		}
+		listID = lists[0].IDHex
+	}
+	h.record(ctx, listID, action, before, after)
+}
+
+// recordItem adds a change to one item of the list item calls use
+func (h *historyDB) recordItem(ctx context.Context, action string, before, after *models.ShoppingListItem) {
+	h.recordList(ctx, action, models.ChangeValue{Item: before}, models.ChangeValue{Item: after})
+}
+
+// item returns the item with IDHex, or nil if it is not on the list
+func (h *historyDB) item(ctx context.Context, IDHex string) *models.ShoppingListItem {
+	item, err := h.GetShoppingListItemFromIDHex(ctx, IDHex)
+	if err != nil || item.IDHex == "" {
+		return nil
+	}
+	return &item
+}
+
+// meal returns one slot of a day of the given week, or nil if there is no
+// such day
+func (h *historyDB) meal(ctx context.Context, week string, day string, slot string) *models.MealSlotInput {
+	mealPlan, err := h.GetMealPlan(ctx, week)
+	if err != nil {
+		return nil
+	}
+	for _, meal := range mealPlan.Meals {
+		if meal.Day == day {
+			input := meal.Input(slot)
+			input.Week = week
+			return &input
+		}
+	}
+	return nil
+}
+
+// AddShoppingListItem adds an item, or adds to a matching one, and records it
+func (h *historyDB) AddShoppingListItem(ctx context.Context, itemName string) (models.ShoppingListItem, error) {
+	shoppingList, _ := h.GetShoppingList(ctx)
+	item, err := h.DBInterface.AddShoppingListItem(ctx, itemName)
+	if err != nil {
+		return item, err
+	}
+	h.recordItem(ctx, models.ChangeAdd, findItem(shoppingList, item.IDHex), &item)
+	return item, nil
+}
+
+// InsertShoppingListItem adds a new item and records it
+func (h *historyDB) InsertShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	inserted, err := h.DBInterface.InsertShoppingListItem(ctx, item)
+	if err != nil {
+		return inserted, err
+	}
+	h.recordItem(ctx, models.ChangeAdd, nil, &inserted)
+	return inserted, nil
+}
+
+// UpdateShoppingListItem renames an item and records it
+func (h *historyDB) UpdateShoppingListItem(ctx context.Context, itemId string, newItem string) (models.ShoppingListItem, error) {
+	before := h.item(ctx, itemId)
+	item, err := h.DBInterface.UpdateShoppingListItem(ctx, itemId, newItem)
+	h.recordItemUpdate(ctx, models.ChangeEdit, before, item, err)
+	return item, err
+}
+
+// UpdateShoppingListItemDetails changes an item's details and records it
+func (h *historyDB) UpdateShoppingListItemDetails(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	before := h.item(ctx, item.IDHex)
+	updated, err := h.DBInterface.UpdateShoppingListItemDetails(ctx, item)
+	h.recordItemUpdate(ctx, models.ChangeEdit, before, updated, err)
+	return updated, err
+}
+
+// EditShoppingListItem changes an item's details, unless it has changed
+// since it was read, and records it
+func (h *historyDB) EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	before := h.item(ctx, item.IDHex)
+	edited, err := h.DBInterface.EditShoppingListItem(ctx, item)
+	h.recordItemUpdate(ctx, models.ChangeEdit, before, edited, err)
+	return edited, err
+}
+
+// ReplaceShoppingListItem changes an item, unless it has changed since it
+// was read, and records it as a tick when that is all that changed
+func (h *historyDB) ReplaceShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	before := h.item(ctx, item.IDHex)
+	replaced, err := h.DBInterface.ReplaceShoppingListItem(ctx, item)
+	action := models.ChangeEdit
+	if before != nil && before.Ticked != replaced.Ticked {
+		ticked := *before
+		ticked.Ticked = replaced.Ticked
+		if sameItemState(ticked, replaced) {
+			action = models.ChangeTick
+		}
+	}
+	h.recordItemUpdate(ctx, action, before, replaced, err)
+	return replaced, err
+}
+
+// SetShoppingListItemCategory changes an item's category and records it
+func (h *historyDB) SetShoppingListItemCategory(ctx context.Context, itemId string, category string) (models.ShoppingListItem, error) {
+	before := h.item(ctx, itemId)
+	item, err := h.DBInterface.SetShoppingListItemCategory(ctx, itemId, category)
+	h.recordItemUpdate(ctx, models.ChangeEdit, before, item, err)
+	return item, err
+}
+
+// TickShoppingListItem ticks or unticks an item and records it
+func (h *historyDB) TickShoppingListItem(ctx context.Context, itemId string, ticked bool) (models.ShoppingListItem, error) {
+	before := h.item(ctx, itemId)
+	item, err := h.DBInterface.TickShoppingListItem(ctx, itemId, ticked)
+	h.recordItemUpdate(ctx, models.ChangeTick, before, item, err)
+	return item, err
+}
+
+// SetShoppingListItemSources changes the meals an item is needed for and
+// records it
+func (h *historyDB) SetShoppingListItemSources(ctx context.Context, itemId string, sources []models.MealSource) (models.ShoppingListItem, error) {
+	before := h.item(ctx, itemId)
+	item, err := h.DBInterface.SetShoppingListItemSources(ctx, itemId, sources)
+	h.recordItemUpdate(ctx, models.ChangeEdit, before, item, err)
+	return item, err
+}
+
+// recordItemUpdate records a change to an item that was on the list, if it
+// was made
+func (h *historyDB) recordItemUpdate(ctx context.Context, action string, before *models.ShoppingListItem, after models.ShoppingListItem, err error) {
+	if err != nil || before == nil || after.IDHex == "" {
+		return
+	}
+	h.recordItem(ctx, action, before, &after)
+}
+
+// DeleteShoppingListItem removes an item and records it, along with the
+// order of the list, so that undoing it puts the item back in its place
+func (h *historyDB) DeleteShoppingListItem(ctx context.Context, itemIDHex string) error {
+	shoppingList, _ := h.GetShoppingList(ctx)
+	if err := h.DBInterface.DeleteShoppingListItem(ctx, itemIDHex); err != nil {
+		return err
+	}
+	h.recordDelete(ctx, shoppingList, itemOrder(shoppingList), itemIDHex)
+	return nil
+}
+
+// MergeShoppingListItems merges items and records an edit of each merged
+// item, then the removal of each removed one, so that each can be undone
+func (h *historyDB) MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error {
+	shoppingList, _ := h.GetShoppingList(ctx)
+	if err := h.DBInterface.MergeShoppingListItems(ctx, merged, removed); err != nil {
+		return err
+	}
+	after, _ := h.GetShoppingList(ctx)
+	for _, item := range merged {
+		if saved := findItem(after, item.IDHex); saved != nil {
+			h.recordItemUpdate(ctx, models.ChangeEdit, findItem(shoppingList, item.IDHex), *saved, nil)
+		}
+	}
+	order := itemOrder(shoppingList)
+	for _, item := range removed {
+		order = h.recordDelete(ctx, shoppingList, order, item.IDHex)
+	}
+	return nil
+}
+
+// recordDelete records the removal of the item with itemIDHex from
+// shoppingList, along with the order of the list before and after, and
+// returns the order after. order is the list's order before the removal.
+func (h *historyDB) recordDelete(ctx context.Context, shoppingList []models.ShoppingListItem, order []string, itemIDHex string) []string {
+	before := findItem(shoppingList, itemIDHex)
+	if before == nil {
+		return order
+	}
+	remaining := make([]string, 0, len(order))
+	for _, IDHex := range order {
+		if IDHex != itemIDHex {
+			remaining = append(remaining, IDHex)
+		}
+	}
+	h.recordList(ctx, models.ChangeDelete, models.ChangeValue{Item: before, Order: order}, models.ChangeValue{Order: remaining})
+	return remaining
+}
+
+// SortShoppingList reorders the list and records the order before and after
+func (h *historyDB) SortShoppingList(ctx context.Context, newOrder []models.Order) error {
+	return h.recordSort(ctx, func() error {
+		return h.DBInterface.SortShoppingList(ctx, newOrder)
+	})
+}
+
+// ReorderShoppingList reorders the list if it is still at version, and
+// records the order before and after
+func (h *historyDB) ReorderShoppingList(ctx context.Context, newOrder []models.Order, version int) error {
+	return h.recordSort(ctx, func() error {
+		return h.DBInterface.ReorderShoppingList(ctx, newOrder, version)
+	})
+}
+
+// recordSort runs sort, recording the order before and after if it succeeds
+func (h *historyDB) recordSort(ctx context.Context, sort func() error) error {
+	shoppingList, _ := h.GetShoppingList(ctx)
+	if err := sort(); err != nil {
+		return err
+	}
+	sorted, _ := h.GetShoppingList(ctx)
+	h.recordList(ctx, models.ChangeSort, models.ChangeValue{Order: itemOrder(shoppingList)}, models.ChangeValue{Order: itemOrder(sorted)})
+	return nil
+}
+
+// UpdateMeal changes the meal in one slot and records it
+func (h *historyDB) UpdateMeal(ctx context.Context, week string, day string, slot string, meal string) error {
+	before := h.meal(ctx, week, day, slot)
+	if err := h.DBInterface.UpdateMeal(ctx, week, day, slot, meal); err != nil {
+		return err
+	}
+	h.recordMeal(ctx, before, h.meal(ctx, week, day, slot))
+	return nil
+}
+
+// SetMealRecipe links the meal in one slot to a recipe and records it
+func (h *historyDB) SetMealRecipe(ctx context.Context, week string, day string, slot string, recipeID string) error {
+	before := h.meal(ctx, week, day, slot)
+	if err := h.DBInterface.SetMealRecipe(ctx, week, day, slot, recipeID); err != nil {
+		return err
+	}
+	h.recordMeal(ctx, before, h.meal(ctx, week, day, slot))
+	return nil
+}
+
+// EditMeal changes one slot, unless it has changed since it was read, and
+// records it
+func (h *historyDB) EditMeal(ctx context.Context, week string, input models.MealSlotInput) (models.MealSlotInput, error) {
+	before := h.meal(ctx, week, input.Day, input.Slot)
+	saved, err := h.DBInterface.EditMeal(ctx, week, input)
+	if err != nil {
+		return saved, err
+	}
+	after := saved
+	after.Week = week
+	h.recordMeal(ctx, before, &after)
+	return saved, nil
+}
+
+// recordMeal records a change to a meal slot, if it changed
+func (h *historyDB) recordMeal(ctx context.Context, before, after *models.MealSlotInput) {
+	if before == nil || after == nil {
+		return
+	}
+	if before.Meal == after.Meal && before.RecipeID == after.RecipeID {
+		return
+	}
+	h.record(ctx, "", models.ChangeMeal, models.ChangeValue{Meal: before}, models.ChangeValue{Meal: after})
+}
+
+// findItem returns the item with IDHex in shoppingList, or nil
+func findItem(shoppingList []models.ShoppingListItem, IDHex string) *models.ShoppingListItem {
+	for _, item := range shoppingList {
+		if item.IDHex == IDHex {
+			return &item
+		}
+	}
+	return nil
+}
+
+// itemOrder returns the IDHex of each item, in order
+func itemOrder(shoppingList []models.ShoppingListItem) []string {
+	order := make([]string, 0, len(shoppingList))
+	for _, item := range shoppingList {
+		order = append(order, item.IDHex)
+	}
+	return order
+}
+
+// Undo puts back what the recorded change with IDHex changed, and marks it
+// undone. It returns ErrConflict, changing nothing, if what the change
+// changed has been changed again since.
+func Undo(ctx context.Context, store DBInterface, IDHex string) (models.Change, error) {
+	return setUndone(ctx, store, IDHex, true)
+}
+
+// Redo makes an undone change again, and marks it not undone. Like Undo it
+// returns ErrConflict if what it changed has been changed since.
+func Redo(ctx context.Context, store DBInterface, IDHex string) (models.Change, error) {
+	return setUndone(ctx, store, IDHex, false)
+}
+
+// setUndone moves what a change changed from its after value to its before
+// value when undone is set, or back again when it is not. Undoing and redoing
+// are not themselves recorded.
+func setUndone(ctx context.Context, store DBInterface, IDHex string, undone bool) (models.Change, error) {
+	store = withoutHistory(store)
+	change, err := store.GetChange(ctx, IDHex)
+	if err != nil {
+		return models.Change{}, err
+	}
+	if change.Undone == undone {
+		return change, nil
+	}
+
+	target := store
+	if change.ListID != "" {
+		target = store.ForShoppingList(change.ListID)
+	}
+	from, to := &change.After, &change.Before
+	if !undone {
+		from, to = to, from
+	}
+	switch change.Action {
+	case models.ChangeSort:
+		err = restoreOrder(ctx, target, from.Order, to.Order)
+	case models.ChangeMeal:
+		err = restoreMeal(ctx, target, from.Meal, to.Meal)
+	default:
+		err = restoreItem(ctx, target, from, to)
+	}
+	if err != nil {
+		return change, err
+	}
+
+	change.Undone = undone
+	return change, store.UpdateChange(ctx, change)
+}
+
+// restoreItem changes an item from how it is in from to how it is in to,
+// adding or deleting it when it is missing from either. An item added back
+// gets a new ID, which replaces the old one in to; when from.Order is still
+// the order of the list, it goes back to its place in to.Order. An item
+// changed or deleted is written once, at the version it was checked at, so
+// a change made in between is a conflict rather than partly undone.
+func restoreItem(ctx context.Context, store DBInterface, from, to *models.ChangeValue) error {
+	var current models.ShoppingListItem
+	if from.Item != nil {
+		var err error
+		current, err = store.GetShoppingListItemFromIDHex(ctx, from.Item.IDHex)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if current.IDHex == "" || !sameItemState(current, *from.Item) {
+			return ErrConflict
+		}
+	}
+
+	switch {
+	case to.Item == nil && from.Item == nil:
+		return nil
+	case to.Item == nil:
+		// Merging nothing into the item removes it only at its version
+		return store.MergeShoppingListItems(ctx, nil, []models.ShoppingListItem{current})
+	case from.Item == nil:
+		oldID := to.Item.IDHex
+		item := *to.Item
+		item.Version = 0
+		restored, err := store.InsertShoppingListItem(ctx, item)
+		if err != nil {
+			return err
+		}
+		*to.Item = restored
+		for i := range to.Order {
+			if to.Order[i] == oldID {
+				to.Order[i] = restored.IDHex
+			}
+		}
+		return restorePosition(ctx, store, restored.IDHex, from.Order, to.Order)
+	}
+
+	item := *to.Item
+	item.IDHex = current.IDHex
+	item.Version = current.Version
+	restored, err := store.ReplaceShoppingListItem(ctx, item)
+	if err != nil {
+		return err
+	}
+	*to.Item = restored
+	return nil
+}
+
+// restorePosition moves the item with IDHex, just added to the end of the
+// list, to its place in order, as long as the rest of the list is still in
+// the order from
+func restorePosition(ctx context.Context, store DBInterface, IDHex string, from, order []string) error {
+	if order == nil {
+		return nil
+	}
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return err
+	}
+	current := itemOrder(shoppingList)
+	if len(current) == 0 || current[len(current)-1] != IDHex || !reflect.DeepEqual(current[:len(current)-1], from) {
+		return nil
+	}
+	return store.SortShoppingList(ctx, orderUpdates(order))
+}
+
+// restoreOrder puts the list in the order to, if it is still in the order
+// from
+func restoreOrder(ctx context.Context, store DBInterface, from, to []string) error {
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(itemOrder(shoppingList), from) {
+		return ErrConflict
+	}
+	return store.SortShoppingList(ctx, orderUpdates(to))
+}
+
+// orderUpdates returns the positions that put items in order
+func orderUpdates(order []string) []models.Order {
+	updates := make([]models.Order, 0, len(order))
+	for i, IDHex := range order {
+		updates = append(updates, models.Order{ID: IDHex, Position: i + 1})
+	}
+	return updates
+}
+
+// restoreMeal changes a meal slot from the meal in from to the one in to, if
+// it has not changed since. to is updated with the slot as saved.
+func restoreMeal(ctx context.Context, store DBInterface, from, to *models.MealSlotInput) error {
+	if from == nil || to == nil {
+		return ErrConflict
+	}
+	mealPlan, err := store.GetMealPlan(ctx, to.Week)
+	if err != nil {
+		return err
+	}
+	for _, meal := range mealPlan.Meals {
+		if meal.Day != to.Day {
+			continue
+		}
+		current := meal.Input(to.Slot)
+		if current.Meal != from.Meal || current.RecipeID != from.RecipeID {
+			return ErrConflict
+		}
+		saved, err := store.EditMeal(ctx, to.Week, models.MealSlotInput{
+			Day:      to.Day,
+			Slot:     to.Slot,
+			Meal:     to.Meal,
+			RecipeID: to.RecipeID,
+			Version:  current.Version,
+		})
+		if err != nil {
+			return err
+		}
+		saved.Week = to.Week
+		*to = saved
+		return nil
+	}
+	return ErrNotFound
+}
+
+// sameItemState reports whether two versions of an item have the same
+// details, tick and category
+func sameItemState(a, b models.ShoppingListItem) bool {
+	return a.Text() == b.Text() && a.Ticked == b.Ticked && a.Category == b.Category
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The order of a shopping list must hold each of its items exactly once and
+// nothing else. Every backend keeps to this when it writes, puts right any
+// drift it finds when it reads a list, and can check and repair every list
+// with VerifyIntegrity and Repair.
+
+// ListIntegrity describes how the order of a shopping list has drifted from
+// its items. Each field lists item IDs as hex.
+type ListIntegrity struct {
+	Household string
+	ListID    string
+	Name      string
+	// Missing are items not in the order, which were hidden
+	Missing []string
+	// Orphans are IDs in the order with no item
+	Orphans []string
+	// Duplicates are items in the order more than once
+	Duplicates []string
+	// Malformed counts IDs saved in the wrong form, such as hex strings in a
+	// MongoDB SortOrder
+	Malformed int
+}
+
+// OK reports whether the list's order was as it should be
+func (l ListIntegrity) OK() bool {
+	return len(l.Missing) == 0 && len(l.Orphans) == 0 && len(l.Duplicates) == 0 && l.Malformed == 0
+}
+
+// String describes what is wrong with the list
+func (l ListIntegrity) String() string {
+	var problems []string
+	for _, problem := range []struct {
+		ids  []string
+		what string
+	}{
+		{l.Missing, "missing from the order"},
+		{l.Orphans, "in the order with no item"},
+		{l.Duplicates, "in the order more than once"},
+	} {
+		if len(problem.ids) > 0 {
+			problems = append(problems, fmt.Sprintf("%d %s (%s)", len(problem.ids), problem.what, strings.Join(problem.ids, ", ")))
+		}
+	}
+	if l.Malformed > 0 {
+		problems = append(problems, fmt.Sprintf("%d saved in the wrong form", l.Malformed))
+	}
+	name := fmt.Sprintf("%q (%s)", l.Name, l.ListID)
+	if l.Household != "" {
+		name += " of household " + l.Household
+	}
+	return name + ": " + strings.Join(problems, "; ")
+}
+
+// IntegrityReport is what VerifyIntegrity found, or Repair fixed
+type IntegrityReport struct {
+	// Lists is how many shopping lists were checked
+	Lists int
+	// Problems describes each list whose order had drifted
+	Problems []ListIntegrity
+}
+
+// OK reports whether every list's order was as it should be
+func (r IntegrityReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// add checks the order of a list, recording any drift, and returns the order
+// as it should be
+func (r *IntegrityReport) add(list ListIntegrity, items []models.ShoppingListItem, order []primitive.ObjectID) []primitive.ObjectID {
+	r.Lists++
+	reconciled, drift := reconcileSortOrder(items, order)
+	drift.Household, drift.ListID, drift.Name, drift.Malformed = list.Household, list.ListID, list.Name, list.Malformed
+	if !drift.OK() {
+		r.Problems = append(r.Problems, drift)
+	}
+	return reconciled
+}
+
+// reconcileSortOrder returns order with each of items in it exactly once:
+// orphaned and repeated IDs are dropped and missing items added at the end,
+// in the order they were added to the list. It also describes the drift.
+func reconcileSortOrder(items []models.ShoppingListItem, order []primitive.ObjectID) ([]primitive.ObjectID, ListIntegrity) {
+	var drift ListIntegrity
+	exists := make(map[primitive.ObjectID]bool, len(items))
+	for _, item := range items {
+		exists[item.ID] = true
+	}
+
+	reconciled := make([]primitive.ObjectID, 0, len(items))
+	placed := make(map[primitive.ObjectID]bool, len(items))
+	for _, id := range order {
+		switch {
+		case !exists[id]:
+			drift.Orphans = append(drift.Orphans, id.Hex())
+		case placed[id]:
+			drift.Duplicates = append(drift.Duplicates, id.Hex())
+		default:
+			placed[id] = true
+			reconciled = append(reconciled, id)
+		}
+	}
+	for _, item := range items {
+		if !placed[item.ID] {
+			placed[item.ID] = true
+			drift.Missing = append(drift.Missing, item.ID.Hex())
+			reconciled = append(reconciled, item.ID)
+		}
+	}
+	return reconciled, drift
+}
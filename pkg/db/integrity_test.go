@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReconcileSortOrder(t *testing.T) {
+	a, b, c, orphan := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	items := []models.ShoppingListItem{{ID: a}, {ID: b}, {ID: c}}
+
+	order, drift := reconcileSortOrder(items, []primitive.ObjectID{c, a, b})
+	assert.Equal(t, []primitive.ObjectID{c, a, b}, order)
+	assert.True(t, drift.OK())
+
+	order, drift = reconcileSortOrder(items, []primitive.ObjectID{c, orphan, c, a})
+	assert.Equal(t, []primitive.ObjectID{c, a, b}, order, "missing items go at the end")
+	assert.Equal(t, []string{b.Hex()}, drift.Missing)
+	assert.Equal(t, []string{orphan.Hex()}, drift.Orphans)
+	assert.Equal(t, []string{c.Hex()}, drift.Duplicates)
+	assert.False(t, drift.OK())
+
+	order, drift = reconcileSortOrder(nil, []primitive.ObjectID{orphan})
+	assert.Empty(t, order)
+	assert.Equal(t, []string{orphan.Hex()}, drift.Orphans)
+
+	drift = ListIntegrity{ListID: "l1", Name: "Shopping list", Household: "h1", Missing: []string{"x"}, Malformed: 2}
+	assert.Equal(t, `"Shopping list" (l1) of household h1: 1 missing from the order (x); 2 saved in the wrong form`, drift.String())
+}
+
+func TestMemoryDBIntegrity(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	household := store.ForHousehold("h1").(*MemoryDB)
+	items := addItems(t, household, "Eggs", "Bread", "Cheese")
+	addItems(t, store, "Milk")
+
+	// Corrupt the order: Bread is missing, Cheese repeated and one ID orphaned
+	orphan := primitive.NewObjectID()
+	household.lists[0].SortOrder = []primitive.ObjectID{items[2].ID, orphan, items[0].ID, items[2].ID}
+
+	report, err := store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Lists)
+	require.Len(t, report.Problems, 1)
+	problem := report.Problems[0]
+	assert.Equal(t, "h1", problem.Household)
+	assert.Equal(t, []string{items[1].IDHex}, problem.Missing)
+	assert.Equal(t, []string{orphan.Hex()}, problem.Orphans)
+	assert.Equal(t, []string{items[2].IDHex}, problem.Duplicates)
+	assert.Len(t, household.lists[0].SortOrder, 4, "verifying changes nothing")
+
+	repaired, err := store.Repair(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, report, repaired)
+	assert.Equal(t, []primitive.ObjectID{items[2].ID, items[0].ID, items[1].ID}, household.lists[0].SortOrder)
+	report, err = store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+
+	// Reading a list repairs it too
+	household.lists[0].SortOrder = nil
+	assert.Equal(t, []string{"Eggs", "Bread", "Cheese"}, itemNames(t, household))
+	assert.Len(t, household.lists[0].SortOrder, 3)
+}
+
+func TestSQLiteIntegrity(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteDB(ctx, filepath.Join(t.TempDir(), "integrity.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	household := store.ForHousehold("h1")
+	items := addItems(t, household, "Eggs", "Bread", "Cheese", "Butter")
+
+	// Corrupt the order: Bread has no position and Butter shares Eggs'
+	_, err = store.DB.Exec(`UPDATE shopping_list_items SET sort_position = NULL WHERE id = ?`, items[1].IDHex)
+	require.NoError(t, err)
+	_, err = store.DB.Exec(`UPDATE shopping_list_items SET sort_position = 1 WHERE id = ?`, items[3].IDHex)
+	require.NoError(t, err)
+
+	report, err := store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Lists)
+	require.Len(t, report.Problems, 1)
+	assert.Equal(t, "h1", report.Problems[0].Household)
+	assert.Equal(t, []string{items[1].IDHex}, report.Problems[0].Missing)
+	assert.Equal(t, []string{items[3].IDHex}, report.Problems[0].Duplicates)
+
+	repaired, err := store.Repair(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, report, repaired)
+	assert.Equal(t, []string{"Eggs", "Butter", "Cheese", "Bread"}, itemNames(t, household))
+	report, err = store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+
+	// Reading a list repairs it too
+	_, err = store.DB.Exec(`UPDATE shopping_list_items SET sort_position = NULL WHERE id = ?`, items[0].IDHex)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Butter", "Cheese", "Bread", "Eggs"}, itemNames(t, household))
+	report, err = store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+}
+
+// TestMongoDBIntegrity checks a corrupted list against a real deployment when
+// GO_SHOPPING_MONGO_TEST_URI is set
+func TestMongoDBIntegrity(t *testing.T) {
+	ctx := context.Background()
+	uri := os.Getenv("GO_SHOPPING_MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("Skipping as GO_SHOPPING_MONGO_TEST_URI is not set")
+	}
+	dbName := fmt.Sprintf("GoShopping-integrity-%d", time.Now().UnixNano())
+	store, err := NewMongoDB(ctx, uri, dbName)
+	require.NoError(t, err)
+	defer func() {
+		store.Client.Database(dbName).Drop(ctx)
+		store.Close()
+	}()
+
+	a, b, c, orphan := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	item := func(id primitive.ObjectID, name string) bson.D {
+		return bson.D{{Key: "_id", Value: id}, {Key: "IDHex", Value: id.Hex()}, {Key: "Item", Value: name}}
+	}
+	_, err = store.Client.Database(dbName).Collection("shopping-lists").InsertOne(ctx, bson.D{
+		{Key: "Name", Value: models.DefaultShoppingListName},
+		{Key: "ShoppingList", Value: bson.A{item(a, "Eggs"), item(b, "Bread"), item(c, "Cheese")}},
+		{Key: "SortOrder", Value: bson.A{c, orphan, a.Hex(), c}},
+	})
+	require.NoError(t, err)
+
+	report, err := store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 1)
+	problem := report.Problems[0]
+	assert.Equal(t, []string{b.Hex()}, problem.Missing)
+	assert.Equal(t, []string{orphan.Hex()}, problem.Orphans)
+	assert.Equal(t, []string{c.Hex()}, problem.Duplicates)
+	assert.Equal(t, 1, problem.Malformed)
+
+	assert.Equal(t, []string{"Cheese", "Eggs", "Bread"}, itemNames(t, store), "reading repairs the list")
+	report, err = store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+
+	require.NoError(t, store.DeleteShoppingListItem(ctx, a.Hex()))
+	_, err = store.AddShoppingListItem(ctx, "Milk")
+	require.NoError(t, err)
+	report, err = store.Repair(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "deleting and adding items keeps the order intact")
+}
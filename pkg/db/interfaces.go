@@ -1,21 +1,137 @@
 package db
 
 import (
+	"context"
+
 	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
 )
 
+// Store is a storage backend. It holds the accounts of every household and
+// hands out each household's data through ForHousehold. Used directly as a
+// DBInterface it reads and writes only data saved before households existed,
+// which the first household created takes over.
+type Store interface {
+	DBInterface
+	AccountStore
+	// ForHousehold returns a DBInterface whose every call reads and writes
+	// only the data of the household with IDHex householdID
+	ForHousehold(householdID string) DBInterface
+	// VerifyIntegrity checks that the order of every shopping list holds
+	// each of its items exactly once, changing nothing
+	VerifyIntegrity(ctx context.Context) (IntegrityReport, error)
+	// Repair puts right what VerifyIntegrity finds, reporting what it fixed
+	Repair(ctx context.Context) (IntegrityReport, error)
+}
+
+// AccountStore holds households, the users in them, their sessions and
+// invites to join
+type AccountStore interface {
+	CreateHousehold(ctx context.Context, household models.Household) (models.Household, error)
+	GetHousehold(ctx context.Context, IDHex string) (models.Household, error)
+	GetHouseholds(ctx context.Context) ([]models.Household, error)
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	// CreateOwnedHousehold adds a household along with owner, its first
+	// user, or neither when either cannot be added
+	CreateOwnedHousehold(ctx context.Context, household models.Household, owner models.User) (models.Household, models.User, error)
+	GetUser(ctx context.Context, IDHex string) (models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	GetHouseholdUsers(ctx context.Context, householdID string) ([]models.User, error)
+	SetUserRole(ctx context.Context, IDHex string, role models.Role) error
+	CreateSession(ctx context.Context, session models.Session) error
+	GetSession(ctx context.Context, tokenHash string) (models.Session, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+	CreateInvite(ctx context.Context, invite models.Invite) error
+	GetInvite(ctx context.Context, codeHash string) (models.Invite, error)
+	// ClaimInvite removes the invite with a code hash and returns it, so that
+	// it is used once however many joins race for it. It returns ErrNotFound
+	// when there is no such invite.
+	ClaimInvite(ctx context.Context, codeHash string) (models.Invite, error)
+}
+
 // DBInterface defines the interface for database operations
-// This allows for easy mocking in tests
+// This allows for easy mocking in tests. Shopping list item calls use the
+// household's first list, or the one chosen with ForShoppingList.
 type DBInterface interface {
-	GetShoppingList() ([]models.ShoppingListItem, error)
-	GetShoppingListItemFromIDHex(IDHex string) (models.ShoppingListItem, error)
-	UpdateMeal(day string, meal string) error
-	AddShoppingListItem(itemName string) (models.ShoppingListItem, error)
-	AddShoppingListIdToShoppingListOrder(itemId string) error
-	UpdateShoppingListItem(itemId string, newItem string) (models.ShoppingListItem, error)
-	DeleteShoppingListItem(itemIDHex string) error
-	TickShoppingListItem(itemId string, ticked bool) (models.ShoppingListItem, error)
-	GetMealPlan() (models.MealPlan, error)
-	SortShoppingList(newOrder []models.Order) error
+	GetShoppingList(ctx context.Context) ([]models.ShoppingListItem, error)
+	GetShoppingListItemFromIDHex(ctx context.Context, IDHex string) (models.ShoppingListItem, error)
+	// UpdateMeal, SetMealRecipe and EditMeal return ErrNotFound when the week
+	// has no such day
+	UpdateMeal(ctx context.Context, week string, day string, slot string, meal string) error
+	AddShoppingListItem(ctx context.Context, itemName string) (models.ShoppingListItem, error)
+	InsertShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error)
+	SetShoppingListItemSources(ctx context.Context, itemId string, sources []models.MealSource) (models.ShoppingListItem, error)
+	AddShoppingListIdToShoppingListOrder(ctx context.Context, itemId string) error
+	UpdateShoppingListItem(ctx context.Context, itemId string, newItem string) (models.ShoppingListItem, error)
+	UpdateShoppingListItemDetails(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error)
+	// EditShoppingListItem saves the details of item like
+	// UpdateShoppingListItemDetails, but only if item.Version is the stored
+	// version. Otherwise it returns the stored item and ErrConflict.
+	EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error)
+	// ReplaceShoppingListItem saves the details, ticked status, category
+	// and sources of item, but only if item.Version is the stored version.
+	// Otherwise it returns the stored item and ErrConflict.
+	ReplaceShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error)
+	DeleteShoppingListItem(ctx context.Context, itemIDHex string) error
+	// MergeShoppingListItems saves the details and sources of each item in
+	// merged and removes the items in removed, all together or not at all.
+	// It returns ErrConflict, changing nothing, if any of them is no longer
+	// at the Version it was read at.
+	MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error
+	TickShoppingListItem(ctx context.Context, itemId string, ticked bool) (models.ShoppingListItem, error)
+	GetMealPlan(ctx context.Context, week string) (models.MealPlan, error)
+	SortShoppingList(ctx context.Context, newOrder []models.Order) error
+	// ReorderShoppingList sorts the shopping list like SortShoppingList, but
+	// only if version is the list's stored version. Otherwise it returns
+	// ErrConflict.
+	ReorderShoppingList(ctx context.Context, newOrder []models.Order, version int) error
+	SetMealRecipe(ctx context.Context, week string, day string, slot string, recipeID string) error
+	// EditMeal saves the meal and recipe of one slot of the given week, but
+	// only if input.Version is the slot's stored version. Otherwise it
+	// returns the stored slot and ErrConflict.
+	EditMeal(ctx context.Context, week string, input models.MealSlotInput) (models.MealSlotInput, error)
+	GetRecipes(ctx context.Context) ([]recipes.Recipe, error)
+	GetRecipe(ctx context.Context, IDHex string) (recipes.Recipe, error)
+	AddRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error)
+	UpdateRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error)
+	DeleteRecipe(ctx context.Context, IDHex string) error
+	SetShoppingListItemCategory(ctx context.Context, itemId string, category string) (models.ShoppingListItem, error)
+	GetCategoryDictionary(ctx context.Context) (map[string]string, error)
+	LearnCategory(ctx context.Context, itemName string, category string) error
+	GetStores(ctx context.Context) ([]models.Store, error)
+	SaveStore(ctx context.Context, store models.Store) (models.Store, error)
+	DeleteStore(ctx context.Context, name string) error
+	RecordTick(ctx context.Context, event models.TickEvent) error
+	GetTickEvents(ctx context.Context) ([]models.TickEvent, error)
+	GetWalkOrder(ctx context.Context) (map[string]float64, error)
+	SaveWalkOrder(ctx context.Context, order map[string]float64) error
+	// RecordChange adds a change to the household's history (see
+	// WithHistory), returning it with its ID set
+	RecordChange(ctx context.Context, change models.Change) (models.Change, error)
+	// UpdateChange saves the values and undone state of the recorded change
+	// with change.IDHex
+	UpdateChange(ctx context.Context, change models.Change) error
+	GetChange(ctx context.Context, IDHex string) (models.Change, error)
+	// GetChanges returns the latest limit changes to the list with IDHex
+	// listID, or to the meal plan when listID is "", newest first
+	GetChanges(ctx context.Context, listID string, limit int) ([]models.Change, error)
+	// GetShoppingLists returns every list, in the order they were made,
+	// creating the first list when there are none
+	GetShoppingLists(ctx context.Context) ([]models.ShoppingList, error)
+	CreateShoppingList(ctx context.Context, name string) (models.ShoppingList, error)
+	RenameShoppingList(ctx context.Context, IDHex string, name string) (models.ShoppingList, error)
+	ArchiveShoppingList(ctx context.Context, IDHex string, archived bool) (models.ShoppingList, error)
+	// EditShoppingList saves the name and archived status of list together,
+	// counting the change in its Version, but only if list.Version is the
+	// stored version. Otherwise it returns the stored list and ErrConflict.
+	EditShoppingList(ctx context.Context, list models.ShoppingList) (models.ShoppingList, error)
+	// DeleteShoppingList removes a list and the items on it
+	DeleteShoppingList(ctx context.Context, IDHex string) error
+	// MoveShoppingListItem moves an item from this list to the end of the
+	// list with IDHex listID, leaving the order of the rest of both lists
+	MoveShoppingListItem(ctx context.Context, itemId string, listID string) (models.ShoppingListItem, error)
+	// ForShoppingList returns a DBInterface whose shopping list item calls
+	// use the list with IDHex listID, returning ErrNotFound if there is none
+	ForShoppingList(listID string) DBInterface
 	Close()
 }
\ No newline at end of file
@@ -0,0 +1,1442 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryDB is a thread-safe, in-memory store with the same semantics as
+// MongoDB. It is used by tests and by demo mode; nothing is persisted. Each
+// household's data is a separate MemoryDB sharing the same accounts.
+type MemoryDB struct {
+	*memoryData
+	// list is the IDHex of the shopping list item calls use, or "" for the
+	// first list
+	list string
+}
+
+// memoryData is the data of one household, shared by the MemoryDBs returned
+// by ForShoppingList
+type memoryData struct {
+	mu         sync.Mutex
+	lists      []*models.ShoppingList
+	mealPlans  map[string]models.MealPlan
+	recipes    map[string]recipes.Recipe
+	categories map[string]string
+	stores     map[string]models.Store
+	tickEvents []models.TickEvent
+	walkOrder  map[string]float64
+	changes    []models.Change
+	accounts   *memoryAccounts
+}
+
+// memoryAccounts holds the accounts shared by a MemoryDB and its households
+type memoryAccounts struct {
+	mu         sync.Mutex
+	root       *MemoryDB
+	data       map[string]*MemoryDB
+	households map[string]models.Household
+	users      map[string]models.User
+	sessions   map[string]models.Session
+	invites    map[string]models.Invite
+}
+
+// Ensure MemoryDB implements Store
+var _ Store = (*MemoryDB)(nil)
+
+// NewMemoryDB creates an empty in-memory store
+func NewMemoryDB() *MemoryDB {
+	accounts := &memoryAccounts{
+		data:       make(map[string]*MemoryDB),
+		households: make(map[string]models.Household),
+		users:      make(map[string]models.User),
+		sessions:   make(map[string]models.Session),
+		invites:    make(map[string]models.Invite),
+	}
+	accounts.root = newMemoryData(accounts)
+	return accounts.root
+}
+
+// newMemoryData creates the empty data of one household
+func newMemoryData(accounts *memoryAccounts) *MemoryDB {
+	return &MemoryDB{memoryData: &memoryData{
+		mealPlans:  make(map[string]models.MealPlan),
+		recipes:    make(map[string]recipes.Recipe),
+		categories: make(map[string]string),
+		stores:     make(map[string]models.Store),
+		walkOrder:  make(map[string]float64),
+		accounts:   accounts,
+	}}
+}
+
+// ForHousehold returns the data of the household with IDHex householdID
+func (m *MemoryDB) ForHousehold(householdID string) DBInterface {
+	m.accounts.mu.Lock()
+	defer m.accounts.mu.Unlock()
+
+	return m.accounts.dataLocked(householdID)
+}
+
+// dataLocked returns the data of a household, creating it the first time.
+// The data of no household is the root store's, as in the other backends.
+// accounts.mu must be held.
+func (a *memoryAccounts) dataLocked(householdID string) *MemoryDB {
+	if householdID == "" {
+		return a.root
+	}
+	data, exists := a.data[householdID]
+	if !exists {
+		data = newMemoryData(a)
+		a.data[householdID] = data
+	}
+	return data
+}
+
+// ForShoppingList returns a view of this household whose shopping list item
+// calls use the list with IDHex listID
+func (m *MemoryDB) ForShoppingList(listID string) DBInterface {
+	return &MemoryDB{memoryData: m.memoryData, list: listID}
+}
+
+// lock takes m.mu, unless ctx is already done, so that MemoryDB gives up on
+// cancelled calls like the other backends
+func (m *MemoryDB) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	return nil
+}
+
+// lockAccounts takes m.accounts.mu, unless ctx is already done
+func (m *MemoryDB) lockAccounts(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.accounts.mu.Lock()
+	return nil
+}
+
+// listLocked returns the shopping list this MemoryDB uses, creating the first
+// list if there are none. m.mu must be held.
+func (m *MemoryDB) listLocked() (*models.ShoppingList, error) {
+	if m.list == "" {
+		if len(m.lists) == 0 {
+			m.lists = append(m.lists, newMemoryList(models.DefaultShoppingListName))
+		}
+		return m.lists[0], nil
+	}
+	for _, list := range m.lists {
+		if list.IDHex == m.list {
+			return list, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// newMemoryList returns an empty list called name
+func newMemoryList(name string) *models.ShoppingList {
+	id := primitive.NewObjectID()
+	return &models.ShoppingList{ID: id, IDHex: id.Hex(), Name: name}
+}
+
+// listSummary returns the name and state of list, without its items
+func listSummary(list *models.ShoppingList) models.ShoppingList {
+	return models.ShoppingList{ID: list.ID, IDHex: list.IDHex, Name: list.Name, Archived: list.Archived, Version: list.Version}
+}
+
+// GetShoppingLists returns every shopping list, in the order they were made,
+// creating the first list when there are none
+func (m *MemoryDB) GetShoppingLists(ctx context.Context) ([]models.ShoppingList, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	if len(m.lists) == 0 {
+		m.lists = append(m.lists, newMemoryList(models.DefaultShoppingListName))
+	}
+	lists := make([]models.ShoppingList, 0, len(m.lists))
+	for _, list := range m.lists {
+		lists = append(lists, listSummary(list))
+	}
+	return lists, nil
+}
+
+// CreateShoppingList adds an empty shopping list called name
+func (m *MemoryDB) CreateShoppingList(ctx context.Context, name string) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingList{}, err
+	}
+	defer m.mu.Unlock()
+
+	if len(m.lists) == 0 {
+		m.lists = append(m.lists, newMemoryList(models.DefaultShoppingListName))
+	}
+	list := newMemoryList(name)
+	m.lists = append(m.lists, list)
+	return listSummary(list), nil
+}
+
+// RenameShoppingList changes the name of the shopping list with IDHex
+func (m *MemoryDB) RenameShoppingList(ctx context.Context, IDHex string, name string) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	return m.updateList(ctx, IDHex, func(list *models.ShoppingList) { list.Name = name })
+}
+
+// ArchiveShoppingList archives the shopping list with IDHex, or brings it
+// back when archived is false
+func (m *MemoryDB) ArchiveShoppingList(ctx context.Context, IDHex string, archived bool) (models.ShoppingList, error) {
+	return m.updateList(ctx, IDHex, func(list *models.ShoppingList) { list.Archived = archived })
+}
+
+// EditShoppingList replaces the name and archived status of the shopping
+// list with list.IDHex, unless it has changed since list was read
+func (m *MemoryDB) EditShoppingList(ctx context.Context, list models.ShoppingList) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(list.Name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingList{}, err
+	}
+	defer m.mu.Unlock()
+
+	for _, stored := range m.lists {
+		if stored.IDHex != list.IDHex {
+			continue
+		}
+		if stored.Version != list.Version {
+			return listSummary(stored), ErrConflict
+		}
+		stored.Name = name
+		stored.Archived = list.Archived
+		stored.Version++
+		return listSummary(stored), nil
+	}
+	return models.ShoppingList{}, ErrNotFound
+}
+
+// updateList applies change to the shopping list with IDHex
+func (m *MemoryDB) updateList(ctx context.Context, IDHex string, change func(*models.ShoppingList)) (models.ShoppingList, error) {
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingList{}, err
+	}
+	defer m.mu.Unlock()
+
+	for _, list := range m.lists {
+		if list.IDHex == IDHex {
+			change(list)
+			return listSummary(list), nil
+		}
+	}
+	return models.ShoppingList{}, ErrNotFound
+}
+
+// DeleteShoppingList removes the shopping list with IDHex and its items
+func (m *MemoryDB) DeleteShoppingList(ctx context.Context, IDHex string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	for i, list := range m.lists {
+		if list.IDHex == IDHex {
+			m.lists = append(m.lists[:i:i], m.lists[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// MoveShoppingListItem moves an item from this shopping list to the end of
+// the list with IDHex listID
+func (m *MemoryDB) MoveShoppingListItem(ctx context.Context, itemId string, listID string) (models.ShoppingListItem, error) {
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	defer m.mu.Unlock()
+
+	from, err := m.listLocked()
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	var to *models.ShoppingList
+	for _, list := range m.lists {
+		if list.IDHex == listID {
+			to = list
+		}
+	}
+	if to == nil {
+		return models.ShoppingListItem{}, ErrNotFound
+	}
+
+	for i, item := range from.ShoppingList {
+		if item.IDHex != itemId {
+			continue
+		}
+		if to == from {
+			return item, nil
+		}
+		from.ShoppingList = append(from.ShoppingList[:i:i], from.ShoppingList[i+1:]...)
+		from.SortOrder = withoutID(from.SortOrder, item.ID)
+		from.Version++
+		to.ShoppingList = append(to.ShoppingList, item)
+		to.SortOrder = append(to.SortOrder, item.ID)
+		to.Version++
+		item.Sources = copySources(item.Sources)
+		return item, nil
+	}
+	return models.ShoppingListItem{}, ErrNotFound
+}
+
+// withoutID returns ids with id left out
+func withoutID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	remaining := make([]primitive.ObjectID, 0, len(ids))
+	for _, other := range ids {
+		if other != id {
+			remaining = append(remaining, other)
+		}
+	}
+	return remaining
+}
+
+// GetShoppingList retrieves the shopping list in its sort order
+func (m *MemoryDB) GetShoppingList(ctx context.Context) ([]models.ShoppingListItem, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return nil, err
+	}
+	// Put right any drift in the order before reading it
+	list.SortOrder, _ = reconcileSortOrder(list.ShoppingList, list.SortOrder)
+	shoppingList := orderShoppingList(list.ShoppingList, list.SortOrder)
+	for i := range shoppingList {
+		shoppingList[i].Sources = copySources(shoppingList[i].Sources)
+	}
+	return shoppingList, nil
+}
+
+// GetShoppingListItemFromIDHex retrieves a shopping list item by its hex ID
+func (m *MemoryDB) GetShoppingListItemFromIDHex(ctx context.Context, IDHex string) (models.ShoppingListItem, error) {
+	shoppingList, err := m.GetShoppingList(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	for _, item := range shoppingList {
+		if item.IDHex == IDHex {
+			return item, nil
+		}
+	}
+	return models.ShoppingListItem{}, nil
+}
+
+// UpdateMeal updates the meal in one slot of a day of the given week
+func (m *MemoryDB) UpdateMeal(ctx context.Context, week string, day string, slot string, meal string) error {
+	if err := models.ValidateMealSlot(slot); err != nil {
+		return err
+	}
+	return m.updateMealDay(ctx, week, day, func(updatedDay *models.Meal) {
+		updatedDay.SetSlotMeal(slot, meal)
+		updatedDay.NextSlotVersion(slot)
+	})
+}
+
+// SetMealRecipe links one slot of a day to a recipe, or unlinks it when
+// recipeID is ""
+func (m *MemoryDB) SetMealRecipe(ctx context.Context, week string, day string, slot string, recipeID string) error {
+	if err := models.ValidateMealSlot(slot); err != nil {
+		return err
+	}
+	return m.updateMealDay(ctx, week, day, func(updatedDay *models.Meal) {
+		updatedDay.SetSlotRecipe(slot, recipeID)
+		updatedDay.NextSlotVersion(slot)
+	})
+}
+
+// EditMeal saves the meal and recipe of one slot of a day of the given week,
+// unless the slot has changed since input was read
+func (m *MemoryDB) EditMeal(ctx context.Context, week string, input models.MealSlotInput) (models.MealSlotInput, error) {
+	if err := models.ValidateMealSlot(input.Slot); err != nil {
+		return models.MealSlotInput{}, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return models.MealSlotInput{}, err
+	}
+	defer m.mu.Unlock()
+
+	mealPlan, err := m.mealPlanLocked(week)
+	if err != nil {
+		return models.MealSlotInput{}, err
+	}
+	for i := range mealPlan.Meals {
+		meal := &mealPlan.Meals[i]
+		if meal.Day != input.Day {
+			continue
+		}
+		var conflict error
+		if meal.SlotVersion(input.Slot) != input.Version {
+			conflict = ErrConflict
+		} else {
+			meal.SetSlotMeal(input.Slot, input.Meal)
+			meal.SetSlotRecipe(input.Slot, input.RecipeID)
+			meal.NextSlotVersion(input.Slot)
+		}
+		stored := meal.Input(input.Slot)
+		stored.Week = week
+		return stored, conflict
+	}
+	return models.MealSlotInput{}, ErrNotFound
+}
+
+// updateMealDay applies change to a day of the given week, creating the
+// week's plan if needed. It returns ErrNotFound if the week has no such day.
+func (m *MemoryDB) updateMealDay(ctx context.Context, week string, day string, change func(*models.Meal)) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	mealPlan, err := m.mealPlanLocked(week)
+	if err != nil {
+		return err
+	}
+	for i := range mealPlan.Meals {
+		if mealPlan.Meals[i].Day == day {
+			change(&mealPlan.Meals[i])
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// AddShoppingListItem adds an item read from text such as "2kg potatoes" to
+// the end of the shopping list, or adds its quantity to a matching unticked
+// item already on the list
+func (m *MemoryDB) AddShoppingListItem(ctx context.Context, itemName string) (models.ShoppingListItem, error) {
+	return addShoppingListItem(ctx, m, itemName)
+}
+
+// InsertShoppingListItem adds a new item, along with details such as its
+// sources, to the end of the shopping list. The ID is always generated.
+func (m *MemoryDB) InsertShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	if item.Item == "" {
+		return models.ShoppingListItem{}, fmt.Errorf("item name cannot be empty")
+	}
+
+	newId := primitive.NewObjectID()
+	newItem := item
+	newItem.ID = newId
+	newItem.IDHex = newId.Hex()
+
+	newItem.Sources = copySources(item.Sources)
+	stored := newItem
+	stored.Sources = copySources(item.Sources)
+
+	// The item and its place in the order are added together
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	defer m.mu.Unlock()
+	list, err := m.listLocked()
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	list.ShoppingList = append(list.ShoppingList, stored)
+	list.SortOrder = append(list.SortOrder, newId)
+	list.Version++
+
+	return newItem, nil
+}
+
+// AddShoppingListIdToShoppingListOrder adds a new item ID to the sort order
+func (m *MemoryDB) AddShoppingListIdToShoppingListOrder(ctx context.Context, itemId string) error {
+	id, err := primitive.ObjectIDFromHex(itemId)
+	if err != nil {
+		return fmt.Errorf("invalid object ID: %s", itemId)
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return err
+	}
+	list.SortOrder, _ = reconcileSortOrder(list.ShoppingList, append(list.SortOrder, id))
+	list.Version++
+	return nil
+}
+
+// UpdateShoppingListItem updates an existing shopping list item
+func (m *MemoryDB) UpdateShoppingListItem(ctx context.Context, itemId string, newItem string) (models.ShoppingListItem, error) {
+	if err := m.updateItem(ctx, itemId, func(item *models.ShoppingListItem) {
+		item.Item = newItem
+	}); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+
+	return m.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// UpdateShoppingListItemDetails replaces the name, quantity, unit and note of
+// the shopping list item with item.IDHex
+func (m *MemoryDB) UpdateShoppingListItemDetails(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	if err := m.updateItem(ctx, item.IDHex, func(stored *models.ShoppingListItem) {
+		stored.Item = item.Item
+		stored.Quantity = item.Quantity
+		stored.Unit = item.Unit
+		stored.Note = item.Note
+	}); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+
+	return m.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// EditShoppingListItem replaces the name, quantity, unit and note of the
+// shopping list item with item.IDHex, unless it has changed since item was
+// read
+func (m *MemoryDB) EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	for i := range list.ShoppingList {
+		stored := &list.ShoppingList[i]
+		if stored.IDHex != item.IDHex {
+			continue
+		}
+		var conflict error
+		if stored.Version != item.Version {
+			conflict = ErrConflict
+		} else {
+			stored.Item = item.Item
+			stored.Quantity = item.Quantity
+			stored.Unit = item.Unit
+			stored.Note = item.Note
+			stored.Version++
+		}
+		edited := *stored
+		edited.Sources = copySources(stored.Sources)
+		return edited, conflict
+	}
+	return models.ShoppingListItem{}, ErrNotFound
+}
+
+// ReplaceShoppingListItem replaces the details, ticked status, category and
+// sources of the shopping list item with item.IDHex, unless it has changed
+// since item was read
+func (m *MemoryDB) ReplaceShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	if err := m.lock(ctx); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	for i := range list.ShoppingList {
+		stored := &list.ShoppingList[i]
+		if stored.IDHex != item.IDHex {
+			continue
+		}
+		var conflict error
+		if stored.Version != item.Version {
+			conflict = ErrConflict
+		} else {
+			stored.Item = item.Item
+			stored.Quantity = item.Quantity
+			stored.Unit = item.Unit
+			stored.Note = item.Note
+			stored.Ticked = item.Ticked
+			stored.Category = item.Category
+			stored.Sources = copySources(item.Sources)
+			stored.Version++
+		}
+		restored := *stored
+		restored.Sources = copySources(stored.Sources)
+		return restored, conflict
+	}
+	return models.ShoppingListItem{}, ErrNotFound
+}
+
+// SetShoppingListItemSources replaces the meals a shopping list item was
+// generated for
+func (m *MemoryDB) SetShoppingListItemSources(ctx context.Context, itemId string, sources []models.MealSource) (models.ShoppingListItem, error) {
+	if err := m.updateItem(ctx, itemId, func(item *models.ShoppingListItem) {
+		item.Sources = copySources(sources)
+	}); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+
+	return m.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// updateItem applies change to the item with IDHex itemId on this shopping
+// list, if it has one, and counts the change in its Version
+func (m *MemoryDB) updateItem(ctx context.Context, itemId string, change func(*models.ShoppingListItem)) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return err
+	}
+	for i := range list.ShoppingList {
+		if list.ShoppingList[i].IDHex == itemId {
+			change(&list.ShoppingList[i])
+			list.ShoppingList[i].Version++
+		}
+	}
+	return nil
+}
+
+// copySources returns a copy of sources that shares nothing with the store,
+// or nil when there are none
+func copySources(sources []models.MealSource) []models.MealSource {
+	if len(sources) == 0 {
+		return nil
+	}
+	return append([]models.MealSource{}, sources...)
+}
+
+// DeleteShoppingListItem removes an item from the shopping list
+func (m *MemoryDB) DeleteShoppingListItem(ctx context.Context, itemIDHex string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return err
+	}
+	remaining := list.ShoppingList[:0]
+	for _, item := range list.ShoppingList {
+		if item.IDHex != itemIDHex {
+			remaining = append(remaining, item)
+		}
+	}
+	if len(remaining) < len(list.ShoppingList) {
+		list.Version++
+	}
+	list.ShoppingList = remaining
+	list.SortOrder, _ = reconcileSortOrder(list.ShoppingList, list.SortOrder)
+	return nil
+}
+
+// MergeShoppingListItems saves the details and sources of the merged items
+// and removes the removed ones, as long as none has changed since it was
+// read
+func (m *MemoryDB) MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]*models.ShoppingListItem, len(list.ShoppingList))
+	for i := range list.ShoppingList {
+		stored[list.ShoppingList[i].IDHex] = &list.ShoppingList[i]
+	}
+	// Every item is checked before any is changed
+	for _, items := range [][]models.ShoppingListItem{merged, removed} {
+		for _, item := range items {
+			if current, ok := stored[item.IDHex]; !ok || current.Version != item.Version {
+				return ErrConflict
+			}
+		}
+	}
+
+	for _, item := range merged {
+		current := stored[item.IDHex]
+		current.Item = item.Item
+		current.Quantity = item.Quantity
+		current.Unit = item.Unit
+		current.Note = item.Note
+		current.Sources = copySources(item.Sources)
+		current.Version++
+	}
+	gone := make(map[string]bool, len(removed))
+	for _, item := range removed {
+		gone[item.IDHex] = true
+	}
+	remaining := list.ShoppingList[:0]
+	for _, item := range list.ShoppingList {
+		if !gone[item.IDHex] {
+			remaining = append(remaining, item)
+		}
+	}
+	list.ShoppingList = remaining
+	list.SortOrder, _ = reconcileSortOrder(list.ShoppingList, list.SortOrder)
+	list.Version++
+	return nil
+}
+
+// TickShoppingListItem sets the ticked status of a shopping list item
+func (m *MemoryDB) TickShoppingListItem(ctx context.Context, itemId string, ticked bool) (models.ShoppingListItem, error) {
+	if err := m.updateItem(ctx, itemId, func(item *models.ShoppingListItem) {
+		item.Ticked = ticked
+	}); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+
+	return m.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// GetMealPlan retrieves a copy of the meal plan for an ISO week, creating an
+// empty plan the first time a week is visited
+func (m *MemoryDB) GetMealPlan(ctx context.Context, week string) (models.MealPlan, error) {
+	if err := m.lock(ctx); err != nil {
+		return models.MealPlan{}, err
+	}
+	defer m.mu.Unlock()
+
+	mealPlan, err := m.mealPlanLocked(week)
+	if err != nil {
+		return models.MealPlan{}, err
+	}
+	meals := make([]models.Meal, len(mealPlan.Meals))
+	for i, meal := range mealPlan.Meals {
+		meals[i] = models.Meal{Day: meal.Day, Slots: make(map[string]string, len(meal.Slots))}
+		for slot, name := range meal.Slots {
+			meals[i].Slots[slot] = name
+		}
+		for slot, recipeID := range meal.Recipes {
+			meals[i].SetSlotRecipe(slot, recipeID)
+		}
+		for slot, version := range meal.Versions {
+			meals[i].SetSlotVersion(slot, version)
+		}
+	}
+	mealPlan.Meals = meals
+	return mealPlan, nil
+}
+
+// mealPlanLocked returns the stored plan for week, creating it if needed.
+// The returned plan shares its Meals with the store; m.mu must be held.
+func (m *MemoryDB) mealPlanLocked(week string) (models.MealPlan, error) {
+	if mealPlan, exists := m.mealPlans[week]; exists {
+		return mealPlan, nil
+	}
+	mealPlan, err := models.NewMealPlan(week)
+	if err != nil {
+		return models.MealPlan{}, err
+	}
+	m.mealPlans[week] = mealPlan
+	return mealPlan, nil
+}
+
+// SortShoppingList replaces the order of items in the shopping list
+func (m *MemoryDB) SortShoppingList(ctx context.Context, newOrder []models.Order) error {
+	return m.sortShoppingList(ctx, newOrder, nil)
+}
+
+// ReorderShoppingList updates the order of items in the shopping list, as
+// long as the list is still at version
+func (m *MemoryDB) ReorderShoppingList(ctx context.Context, newOrder []models.Order, version int) error {
+	return m.sortShoppingList(ctx, newOrder, &version)
+}
+
+// sortShoppingList saves newOrder as the order of the shopping list, unless
+// version is set and the list has moved on from it
+func (m *MemoryDB) sortShoppingList(ctx context.Context, newOrder []models.Order, version *int) error {
+	var newSortOrder []primitive.ObjectID
+	for _, order := range newOrder {
+		id, err := primitive.ObjectIDFromHex(order.ID)
+		if err != nil {
+			return fmt.Errorf("invalid object ID: %s", order.ID)
+		}
+		newSortOrder = append(newSortOrder, id)
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	list, err := m.listLocked()
+	if err != nil {
+		return err
+	}
+	if version != nil && list.Version != *version {
+		return ErrConflict
+	}
+	list.SortOrder, _ = reconcileSortOrder(list.ShoppingList, newSortOrder)
+	list.Version++
+	return nil
+}
+
+// GetRecipes retrieves copies of every recipe in the library, sorted by title
+func (m *MemoryDB) GetRecipes(ctx context.Context) ([]recipes.Recipe, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	library := make([]recipes.Recipe, 0, len(m.recipes))
+	for _, recipe := range m.recipes {
+		library = append(library, recipe.Clone())
+	}
+	recipes.SortByTitle(library)
+	return library, nil
+}
+
+// GetRecipe retrieves a copy of a recipe by its hex ID
+func (m *MemoryDB) GetRecipe(ctx context.Context, IDHex string) (recipes.Recipe, error) {
+	if err := m.lock(ctx); err != nil {
+		return recipes.Recipe{}, err
+	}
+	defer m.mu.Unlock()
+
+	recipe, exists := m.recipes[IDHex]
+	if !exists {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	return recipe.Clone(), nil
+}
+
+// AddRecipe adds a new recipe to the library
+func (m *MemoryDB) AddRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return recipes.Recipe{}, err
+	}
+
+	newId := primitive.NewObjectID()
+	recipe.ID = newId
+	recipe.IDHex = newId.Hex()
+
+	if err := m.lock(ctx); err != nil {
+		return recipes.Recipe{}, err
+	}
+	defer m.mu.Unlock()
+
+	m.recipes[recipe.IDHex] = recipe.Clone()
+	return recipe, nil
+}
+
+// UpdateRecipe replaces the stored recipe with the same IDHex
+func (m *MemoryDB) UpdateRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return recipes.Recipe{}, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return recipes.Recipe{}, err
+	}
+	defer m.mu.Unlock()
+
+	stored, exists := m.recipes[recipe.IDHex]
+	if !exists {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	recipe.ID = stored.ID
+	m.recipes[recipe.IDHex] = recipe.Clone()
+	return recipe, nil
+}
+
+// DeleteRecipe removes a recipe from the library
+func (m *MemoryDB) DeleteRecipe(ctx context.Context, IDHex string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if _, exists := m.recipes[IDHex]; !exists {
+		return ErrNotFound
+	}
+	delete(m.recipes, IDHex)
+	return nil
+}
+
+// SetShoppingListItemCategory sets the aisle category of a shopping list item
+func (m *MemoryDB) SetShoppingListItemCategory(ctx context.Context, itemId string, category string) (models.ShoppingListItem, error) {
+	if err := m.updateItem(ctx, itemId, func(item *models.ShoppingListItem) {
+		item.Category = category
+	}); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+
+	return m.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// GetCategoryDictionary retrieves a copy of the learned item name to
+// category dictionary
+func (m *MemoryDB) GetCategoryDictionary(ctx context.Context) (map[string]string, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	dictionary := make(map[string]string, len(m.categories))
+	for name, category := range m.categories {
+		dictionary[name] = category
+	}
+	return dictionary, nil
+}
+
+// LearnCategory remembers the category of items called itemName. An empty
+// category forgets it.
+func (m *MemoryDB) LearnCategory(ctx context.Context, itemName string, category string) error {
+	key := models.CategoryKey(itemName)
+	if key == "" {
+		return fmt.Errorf("item name cannot be empty")
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if category == "" {
+		delete(m.categories, key)
+	} else {
+		m.categories[key] = category
+	}
+	return nil
+}
+
+// GetStores retrieves copies of every store, sorted by name
+func (m *MemoryDB) GetStores(ctx context.Context) ([]models.Store, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	stores := make([]models.Store, 0, len(m.stores))
+	for _, store := range m.stores {
+		store.CategoryOrder = append([]string{}, store.CategoryOrder...)
+		stores = append(stores, store)
+	}
+	models.SortStores(stores)
+	return stores, nil
+}
+
+// SaveStore adds a store, or replaces the store with the same name
+func (m *MemoryDB) SaveStore(ctx context.Context, store models.Store) (models.Store, error) {
+	if err := store.Validate(); err != nil {
+		return models.Store{}, err
+	}
+	store.Name = strings.TrimSpace(store.Name)
+	store.CategoryOrder = append([]string{}, store.CategoryOrder...)
+
+	if err := m.lock(ctx); err != nil {
+		return models.Store{}, err
+	}
+	defer m.mu.Unlock()
+
+	m.stores[models.StoreKey(store.Name)] = store
+	return store, nil
+}
+
+// DeleteStore removes the store called name
+func (m *MemoryDB) DeleteStore(ctx context.Context, name string) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	key := models.StoreKey(name)
+	if _, exists := m.stores[key]; !exists {
+		return ErrNotFound
+	}
+	delete(m.stores, key)
+	return nil
+}
+
+// RecordTick remembers that an item was ticked off
+func (m *MemoryDB) RecordTick(ctx context.Context, event models.TickEvent) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.tickEvents = append(m.tickEvents, event)
+	return nil
+}
+
+// GetTickEvents retrieves every recorded tick, oldest first
+func (m *MemoryDB) GetTickEvents(ctx context.Context) ([]models.TickEvent, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	events := append([]models.TickEvent{}, m.tickEvents...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].TickedAt.Before(events[j].TickedAt) })
+	return events, nil
+}
+
+// GetWalkOrder retrieves a copy of the learned walk order
+func (m *MemoryDB) GetWalkOrder(ctx context.Context) (map[string]float64, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	order := make(map[string]float64, len(m.walkOrder))
+	for item, position := range m.walkOrder {
+		order[item] = position
+	}
+	return order, nil
+}
+
+// SaveWalkOrder replaces the learned walk order
+func (m *MemoryDB) SaveWalkOrder(ctx context.Context, order map[string]float64) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.walkOrder = make(map[string]float64, len(order))
+	for item, position := range order {
+		m.walkOrder[item] = position
+	}
+	return nil
+}
+
+// RecordChange adds a change to the history
+func (m *MemoryDB) RecordChange(ctx context.Context, change models.Change) (models.Change, error) {
+	change.ID = primitive.NewObjectID()
+	change.IDHex = change.ID.Hex()
+
+	if err := m.lock(ctx); err != nil {
+		return models.Change{}, err
+	}
+	defer m.mu.Unlock()
+
+	m.changes = append(m.changes, copyChange(change))
+	return change, nil
+}
+
+// UpdateChange saves the values and undone state of a recorded change
+func (m *MemoryDB) UpdateChange(ctx context.Context, change models.Change) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	change = copyChange(change)
+	for i := range m.changes {
+		if m.changes[i].IDHex == change.IDHex {
+			m.changes[i].Before = change.Before
+			m.changes[i].After = change.After
+			m.changes[i].Undone = change.Undone
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// GetChange retrieves a copy of a recorded change by its hex ID
+func (m *MemoryDB) GetChange(ctx context.Context, IDHex string) (models.Change, error) {
+	if err := m.lock(ctx); err != nil {
+		return models.Change{}, err
+	}
+	defer m.mu.Unlock()
+
+	for _, change := range m.changes {
+		if change.IDHex == IDHex {
+			return copyChange(change), nil
+		}
+	}
+	return models.Change{}, ErrNotFound
+}
+
+// GetChanges retrieves copies of the latest changes to a list, or to the
+// meal plan when listID is "", newest first
+func (m *MemoryDB) GetChanges(ctx context.Context, listID string, limit int) ([]models.Change, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	changes := []models.Change{}
+	for i := len(m.changes) - 1; i >= 0 && len(changes) < limit; i-- {
+		if m.changes[i].ListID == listID {
+			changes = append(changes, copyChange(m.changes[i]))
+		}
+	}
+	return changes, nil
+}
+
+// copyChange returns a copy of change sharing no memory with it
+func copyChange(change models.Change) models.Change {
+	for _, value := range []*models.ChangeValue{&change.Before, &change.After} {
+		if value.Item != nil {
+			item := *value.Item
+			item.Sources = copySources(item.Sources)
+			value.Item = &item
+		}
+		if value.Order != nil {
+			value.Order = append([]string{}, value.Order...)
+		}
+		if value.Meal != nil {
+			meal := *value.Meal
+			value.Meal = &meal
+		}
+	}
+	return change
+}
+
+// CreateHousehold adds a household. The first household created takes over
+// the data saved before households existed.
+func (m *MemoryDB) CreateHousehold(ctx context.Context, household models.Household) (models.Household, error) {
+	if err := household.Validate(); err != nil {
+		return models.Household{}, err
+	}
+	household.ID = primitive.NewObjectID()
+	household.IDHex = household.ID.Hex()
+	household.Name = strings.TrimSpace(household.Name)
+
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.Household{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	m.accounts.addHouseholdLocked(household)
+	return household, nil
+}
+
+// addHouseholdLocked adds a household, handing it the data saved before
+// households existed when it is the first. accounts.mu must be held.
+func (a *memoryAccounts) addHouseholdLocked(household models.Household) {
+	if len(a.households) == 0 {
+		a.root.moveDataTo(a.dataLocked(household.IDHex))
+	}
+	a.households[household.IDHex] = household
+}
+
+// CreateOwnedHousehold adds a household and owner, its first user, together,
+// so that no household is left without its owner
+func (m *MemoryDB) CreateOwnedHousehold(ctx context.Context, household models.Household, owner models.User) (models.Household, models.User, error) {
+	if err := household.Validate(); err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	household.ID = primitive.NewObjectID()
+	household.IDHex = household.ID.Hex()
+	household.Name = strings.TrimSpace(household.Name)
+	owner.Email = models.NormaliseEmail(owner.Email)
+	owner.HouseholdID = household.IDHex
+	if err := owner.Validate(); err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	owner.ID = primitive.NewObjectID()
+	owner.IDHex = owner.ID.Hex()
+
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	if m.accounts.emailTakenLocked(owner.Email) {
+		return models.Household{}, models.User{}, ErrExists
+	}
+	m.accounts.addHouseholdLocked(household)
+	m.accounts.users[owner.IDHex] = owner
+	return household, owner, nil
+}
+
+// moveDataTo hands everything in m over to the empty store other, leaving m
+// empty
+func (m *MemoryDB) moveDataTo(other *MemoryDB) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	other.mu.Lock()
+	defer other.mu.Unlock()
+
+	empty := newMemoryData(m.accounts)
+	other.lists, m.lists = m.lists, empty.lists
+	other.mealPlans, m.mealPlans = m.mealPlans, empty.mealPlans
+	other.recipes, m.recipes = m.recipes, empty.recipes
+	other.categories, m.categories = m.categories, empty.categories
+	other.stores, m.stores = m.stores, empty.stores
+	other.tickEvents, m.tickEvents = m.tickEvents, empty.tickEvents
+	other.walkOrder, m.walkOrder = m.walkOrder, empty.walkOrder
+	other.changes, m.changes = m.changes, empty.changes
+}
+
+// GetHousehold retrieves a household by its hex ID
+func (m *MemoryDB) GetHousehold(ctx context.Context, IDHex string) (models.Household, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.Household{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	household, exists := m.accounts.households[IDHex]
+	if !exists {
+		return models.Household{}, ErrNotFound
+	}
+	return household, nil
+}
+
+// GetHouseholds retrieves every household, sorted by name
+func (m *MemoryDB) GetHouseholds(ctx context.Context) ([]models.Household, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return nil, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	households := make([]models.Household, 0, len(m.accounts.households))
+	for _, household := range m.accounts.households {
+		households = append(households, household)
+	}
+	models.SortHouseholds(households)
+	return households, nil
+}
+
+// CreateUser adds a user. It returns ErrExists when the email is taken.
+func (m *MemoryDB) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	user.Email = models.NormaliseEmail(user.Email)
+	if err := user.Validate(); err != nil {
+		return models.User{}, err
+	}
+	user.ID = primitive.NewObjectID()
+	user.IDHex = user.ID.Hex()
+
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.User{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	if m.accounts.emailTakenLocked(user.Email) {
+		return models.User{}, ErrExists
+	}
+	m.accounts.users[user.IDHex] = user
+	return user, nil
+}
+
+// emailTakenLocked reports whether a user has the normalised email.
+// accounts.mu must be held.
+func (a *memoryAccounts) emailTakenLocked(email string) bool {
+	for _, user := range a.users {
+		if user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUser retrieves a user by their hex ID
+func (m *MemoryDB) GetUser(ctx context.Context, IDHex string) (models.User, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.User{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	user, exists := m.accounts.users[IDHex]
+	if !exists {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// GetUserByEmail retrieves the user with an email address, ignoring case
+func (m *MemoryDB) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	email = models.NormaliseEmail(email)
+
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.User{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	for _, user := range m.accounts.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+// GetHouseholdUsers retrieves the users in a household, sorted by name
+func (m *MemoryDB) GetHouseholdUsers(ctx context.Context, householdID string) ([]models.User, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return nil, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range m.accounts.users {
+		if user.HouseholdID == householdID {
+			users = append(users, user)
+		}
+	}
+	models.SortUsers(users)
+	return users, nil
+}
+
+// SetUserRole changes what a user may do in their household
+func (m *MemoryDB) SetUserRole(ctx context.Context, IDHex string, role models.Role) error {
+	if _, err := models.ParseRole(string(role)); err != nil {
+		return err
+	}
+
+	if err := m.lockAccounts(ctx); err != nil {
+		return err
+	}
+	defer m.accounts.mu.Unlock()
+
+	user, exists := m.accounts.users[IDHex]
+	if !exists {
+		return ErrNotFound
+	}
+	user.Role = role
+	m.accounts.users[IDHex] = user
+	return nil
+}
+
+// CreateSession remembers a signed-in browser
+func (m *MemoryDB) CreateSession(ctx context.Context, session models.Session) error {
+	if err := m.lockAccounts(ctx); err != nil {
+		return err
+	}
+	defer m.accounts.mu.Unlock()
+
+	m.accounts.sessions[session.TokenHash] = session
+	return nil
+}
+
+// GetSession retrieves the session with a token hash
+func (m *MemoryDB) GetSession(ctx context.Context, tokenHash string) (models.Session, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.Session{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	session, exists := m.accounts.sessions[tokenHash]
+	if !exists {
+		return models.Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession signs a browser out. Deleting a missing session is not an
+// error.
+func (m *MemoryDB) DeleteSession(ctx context.Context, tokenHash string) error {
+	if err := m.lockAccounts(ctx); err != nil {
+		return err
+	}
+	defer m.accounts.mu.Unlock()
+
+	delete(m.accounts.sessions, tokenHash)
+	return nil
+}
+
+// CreateInvite stores an invite to join a household
+func (m *MemoryDB) CreateInvite(ctx context.Context, invite models.Invite) error {
+	if err := m.lockAccounts(ctx); err != nil {
+		return err
+	}
+	defer m.accounts.mu.Unlock()
+
+	m.accounts.invites[invite.CodeHash] = invite
+	return nil
+}
+
+// GetInvite retrieves the invite with a code hash
+func (m *MemoryDB) GetInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.Invite{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	invite, exists := m.accounts.invites[codeHash]
+	if !exists {
+		return models.Invite{}, ErrNotFound
+	}
+	return invite, nil
+}
+
+// ClaimInvite removes the invite with a code hash and returns it
+func (m *MemoryDB) ClaimInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	if err := m.lockAccounts(ctx); err != nil {
+		return models.Invite{}, err
+	}
+	defer m.accounts.mu.Unlock()
+
+	invite, exists := m.accounts.invites[codeHash]
+	if !exists {
+		return models.Invite{}, ErrNotFound
+	}
+	delete(m.accounts.invites, codeHash)
+	return invite, nil
+}
+
+// VerifyIntegrity checks the order of every household's shopping lists
+func (m *MemoryDB) VerifyIntegrity(ctx context.Context) (IntegrityReport, error) {
+	return m.checkIntegrity(ctx, false)
+}
+
+// Repair puts right the order of every household's shopping lists
+func (m *MemoryDB) Repair(ctx context.Context) (IntegrityReport, error) {
+	return m.checkIntegrity(ctx, true)
+}
+
+// checkIntegrity checks, and when repair is set fixes, the order of every
+// list, with the data saved before households first
+func (m *MemoryDB) checkIntegrity(ctx context.Context, repair bool) (IntegrityReport, error) {
+	var report IntegrityReport
+	if err := m.lockAccounts(ctx); err != nil {
+		return report, err
+	}
+	households := []string{""}
+	data := map[string]*MemoryDB{"": m.accounts.root}
+	for household, householdData := range m.accounts.data {
+		households = append(households, household)
+		data[household] = householdData
+	}
+	m.accounts.mu.Unlock()
+	sort.Strings(households[1:])
+
+	for _, household := range households {
+		d := data[household]
+		if err := d.lock(ctx); err != nil {
+			return report, err
+		}
+		for _, list := range d.lists {
+			order := report.add(ListIntegrity{Household: household, ListID: list.IDHex, Name: list.Name}, list.ShoppingList, list.SortOrder)
+			if repair {
+				list.SortOrder = order
+			}
+		}
+		d.mu.Unlock()
+	}
+	return report, nil
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemoryDB) Close() {}
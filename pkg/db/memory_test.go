@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDBConcurrentAdds(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.AddShoppingListItem(ctx, fmt.Sprintf("Item %d", i))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 50)
+}
+
+func TestMemoryDBReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	addItems(t, store, "Milk")
+
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	list[0].Item = "Changed"
+
+	require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Curry"))
+	mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	mealPlan.Meals[0].Slots["Dinner"] = "Changed"
+
+	assert.Equal(t, []string{"Milk"}, itemNames(t, store))
+	fresh, err := store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, "Curry", fresh.Meals[0].SlotMeal("Dinner"))
+}
+
+func TestSeedDemoData(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+
+	require.NoError(t, SeedDemoData(ctx, store))
+
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, len(demoShoppingList))
+	assert.True(t, list[0].Ticked)
+	assert.False(t, list[len(list)-1].Ticked)
+
+	mealPlan, err := store.GetMealPlan(ctx, models.ISOWeek(time.Now()))
+	require.NoError(t, err)
+	for _, meal := range mealPlan.Meals {
+		assert.Equal(t, demoMeals[meal.Day], meal.SlotMeal(models.LegacyMealSlot))
+	}
+
+	library, err := store.GetRecipes(ctx)
+	require.NoError(t, err)
+	require.Len(t, library, len(demoRecipes))
+	assert.Equal(t, "Fish pie", library[0].Title)
+	assert.Equal(t, library[0].IDHex, mealPlan.Meals[2].SlotRecipe(models.LegacyMealSlot), "Wednesday's fish pie links to its recipe")
+}
+
+func TestSeedDemoAccount(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+
+	user, err := SeedDemoAccount(ctx, store)
+	require.NoError(t, err)
+	loggedIn, err := Login(ctx, store, DemoEmail, DemoPassword)
+	require.NoError(t, err)
+	assert.Equal(t, user, loggedIn)
+
+	list, err := store.ForHousehold(user.HouseholdID).GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, len(demoShoppingList))
+}
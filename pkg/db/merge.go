@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// mergeAttempts is how many times a merge is tried before giving up, when
+// the items keep changing between reading them and saving the merge
+const mergeAttempts = 5
+
+// addShoppingListItem reads text such as "500g flour" into an item and adds it
+// to the shopping list, in the category learned or guessed for its name (see
+// AssignCategory). When an unticked item for the same thing is already on the
+// list and the amounts can be added, that item is updated instead and
+// returned, as long as it has not changed since it was read; otherwise the
+// list is read again. Backends use it to implement AddShoppingListItem.
+func addShoppingListItem(ctx context.Context, store DBInterface, text string) (models.ShoppingListItem, error) {
+	item := models.ParseShoppingListItem(text)
+	if item.Item == "" {
+		return models.ShoppingListItem{}, fmt.Errorf("item name cannot be empty")
+	}
+
+	for attempt := 1; ; attempt++ {
+		existing, found, err := findMergeable(ctx, store, item)
+		if err != nil {
+			return models.ShoppingListItem{}, err
+		}
+		if !found {
+			break
+		}
+		// The item may have been changed, or removed, since it was read
+		merged, err := store.EditShoppingListItem(ctx, existing)
+		if !(errors.Is(err, ErrConflict) || errors.Is(err, ErrNotFound)) || attempt == mergeAttempts {
+			return merged, err
+		}
+	}
+	categorised, err := categorise(ctx, store, item)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	return store.InsertShoppingListItem(ctx, categorised[0])
+}
+
+// findMergeable returns the first unticked item on the shopping list that
+// item can be added to, with item added, and whether there is one
+func findMergeable(ctx context.Context, store DBInterface, item models.ShoppingListItem) (models.ShoppingListItem, bool, error) {
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, false, err
+	}
+	for _, existing := range shoppingList {
+		if existing.Ticked {
+			continue
+		}
+		if merged, ok := models.MergeShoppingListItems(existing, item); ok {
+			return merged, true, nil
+		}
+	}
+	return models.ShoppingListItem{}, false, nil
+}
+
+// MergeDuplicates combines unticked items for the same thing into the first
+// of them, adding their quantities where the units allow, and deletes the
+// rest, all in one change. If an item changes before the merge is saved, the
+// list is read and merged again. It returns the number of items removed.
+func MergeDuplicates(ctx context.Context, store DBInterface) (int, error) {
+	for attempt := 1; ; attempt++ {
+		removed, err := mergeDuplicates(ctx, store)
+		if !errors.Is(err, ErrConflict) || attempt == mergeAttempts {
+			return removed, err
+		}
+	}
+}
+
+// mergeDuplicates makes one attempt at MergeDuplicates
+func mergeDuplicates(ctx context.Context, store DBInterface) (int, error) {
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []models.ShoppingListItem
+	changed := make(map[string]bool)
+	var removed []models.ShoppingListItem
+	for _, item := range shoppingList {
+		if item.Ticked {
+			continue
+		}
+		merged := false
+		for i := range kept {
+			if combined, ok := models.MergeShoppingListItems(kept[i], item); ok {
+				kept[i] = combined
+				changed[combined.IDHex] = true
+				removed = append(removed, item)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			kept = append(kept, item)
+		}
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+
+	var merged []models.ShoppingListItem
+	for _, item := range kept {
+		if changed[item.IDHex] {
+			merged = append(merged, item)
+		}
+	}
+	if err := store.MergeShoppingListItems(ctx, merged, removed); err != nil {
+		return 0, fmt.Errorf("merging items: %w", err)
+	}
+	return len(removed), nil
+}
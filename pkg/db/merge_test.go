@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	chilli := models.MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "abc", Meal: "Chilli"}
+	pie := models.MealSource{Week: "2026-W42", Day: "Tuesday", Slot: "Dinner", RecipeID: "def", Meal: "Cottage pie"}
+	for _, item := range []models.ShoppingListItem{
+		{Item: "mince", Quantity: 500, Unit: "g", Sources: []models.MealSource{chilli}},
+		{Item: "2 tbsp cumin"},
+		{Item: "Milk", Ticked: true},
+		{Item: "Mince", Quantity: 0.5, Unit: "kg", Sources: []models.MealSource{pie}},
+		{Item: "cumin", Quantity: 0.25, Unit: "cup"},
+		{Item: "milk"},
+		{Item: "tomatoes", Quantity: 1, Unit: "tin"},
+		{Item: "tomatoes", Quantity: 400, Unit: "g"},
+	} {
+		_, err := store.InsertShoppingListItem(ctx, item)
+		require.NoError(t, err)
+	}
+
+	removed, err := MergeDuplicates(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1 kg mince", "6.167 tbsp cumin", "Milk", "milk", "1 tin tomatoes", "400 g tomatoes"}, itemNamesOf(list))
+	assert.Equal(t, []models.MealSource{chilli, pie}, list[0].Sources)
+	assert.Equal(t, "Chilli, Cottage pie", list[0].SourceSummary())
+
+	// Nothing is left to merge
+	removed, err = MergeDuplicates(ctx, store)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+// racingStore makes a change, as another person might, just before each of
+// the next races merges it is asked to save
+type racingStore struct {
+	*MemoryDB
+	race  func()
+	races int
+}
+
+func (s *racingStore) runRace() {
+	if s.races > 0 {
+		s.races--
+		s.race()
+	}
+}
+
+func (s *racingStore) EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	s.runRace()
+	return s.MemoryDB.EditShoppingListItem(ctx, item)
+}
+
+func (s *racingStore) MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error {
+	s.runRace()
+	return s.MemoryDB.MergeShoppingListItems(ctx, merged, removed)
+}
+
+func TestMergeDuplicatesRace(t *testing.T) {
+	ctx := context.Background()
+	store := &racingStore{MemoryDB: NewMemoryDB()}
+	var items []models.ShoppingListItem
+	for _, text := range []string{"500 g mince", "onions", "500 g Mince", "onions"} {
+		item, err := store.InsertShoppingListItem(ctx, models.ParseShoppingListItem(text))
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+
+	// Someone else changes the first mince, and ticks the second onions,
+	// while the list is being merged: neither change is lost
+	store.race, store.races = func() {
+		edit := items[0]
+		edit.Quantity = 700
+		_, err := store.MemoryDB.EditShoppingListItem(ctx, edit)
+		require.NoError(t, err)
+		_, err = store.MemoryDB.TickShoppingListItem(ctx, items[3].IDHex, true)
+		require.NoError(t, err)
+	}, 1
+	removed, err := MergeDuplicates(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2 kg mince", "onions", "onions"}, itemNamesOf(list))
+	assert.True(t, list[2].Ticked)
+
+	// A list that keeps changing is left as it is
+	_, err = store.InsertShoppingListItem(ctx, models.ParseShoppingListItem("1 kg mince"))
+	require.NoError(t, err)
+	store.race, store.races = func() {
+		_, err := store.MemoryDB.SetShoppingListItemCategory(ctx, list[0].IDHex, "Meat")
+		require.NoError(t, err)
+	}, mergeAttempts
+	_, err = MergeDuplicates(ctx, store)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Zero(t, store.races, "each attempt met a change")
+	list, err = store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2 kg mince", "onions", "onions", "1 kg mince"}, itemNamesOf(list))
+}
+
+func TestAddShoppingListItemRace(t *testing.T) {
+	ctx := context.Background()
+	store := &racingStore{MemoryDB: NewMemoryDB()}
+	mince, err := store.InsertShoppingListItem(ctx, models.ParseShoppingListItem("500 g mince"))
+	require.NoError(t, err)
+
+	// The mince changes between reading the list and adding to it
+	store.race, store.races = func() {
+		mince.Quantity = 700
+		_, err := store.MemoryDB.EditShoppingListItem(ctx, mince)
+		require.NoError(t, err)
+	}, 1
+	added, err := addShoppingListItem(ctx, store, "300 g mince")
+	require.NoError(t, err)
+	assert.Equal(t, "1 kg mince", added.Text())
+	assert.Equal(t, mince.IDHex, added.IDHex)
+}
+
+func TestMergeDuplicatesHistory(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	history := WithHistory(store, models.User{IDHex: "1", Name: "Sam"})
+	addItems(t, store, "eggs", "bread")
+	_, err = store.InsertShoppingListItem(ctx, models.ParseShoppingListItem("6 eggs"))
+	require.NoError(t, err)
+
+	removed, err := MergeDuplicates(ctx, history)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	changes, err := store.GetChanges(ctx, lists[0].IDHex, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, models.ChangeDelete, changes[0].Action)
+	assert.Equal(t, models.ChangeEdit, changes[1].Action)
+	assert.Equal(t, "7 eggs", changes[1].After.Item.Text())
+
+	// Each part of the merge can be undone
+	_, err = Undo(ctx, history, changes[0].IDHex)
+	require.NoError(t, err)
+	_, err = Undo(ctx, history, changes[1].IDHex)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eggs", "bread", "eggs"}, itemNames(t, store))
+}
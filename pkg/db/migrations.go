@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Migration is a numbered change to the way a backend saves its data, and
+// the change that undoes it. T is what the migration works on, such as a
+// MongoDB database.
+type Migration[T any] struct {
+	Version     int
+	Description string
+	Up          func(context.Context, T) error
+	Down        func(context.Context, T) error
+}
+
+// MigrationStatus describes a migration and when it was applied
+type MigrationStatus struct {
+	Version     int
+	Description string
+	// AppliedAt is zero while the migration is pending
+	AppliedAt time.Time
+}
+
+// Applied reports whether the migration has been applied
+func (s MigrationStatus) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// migrator is a store with versioned migrations. Stores without them, such
+// as MemoryDB and SQLiteDB, whose tables are brought up to date when opened,
+// have no migrations to run.
+type migrator interface {
+	MigrationStatuses(ctx context.Context) ([]MigrationStatus, error)
+	MigrateUp(ctx context.Context) ([]MigrationStatus, error)
+	MigrateDown(ctx context.Context, version int) ([]MigrationStatus, error)
+}
+
+// MigrationStatuses lists the migrations of store, oldest first
+func MigrationStatuses(ctx context.Context, store Store) ([]MigrationStatus, error) {
+	if m, ok := store.(migrator); ok {
+		return m.MigrationStatuses(ctx)
+	}
+	return nil, nil
+}
+
+// MigrateUp applies the pending migrations of store, oldest first, returning
+// those applied
+func MigrateUp(ctx context.Context, store Store) ([]MigrationStatus, error) {
+	if m, ok := store.(migrator); ok {
+		return m.MigrateUp(ctx)
+	}
+	return nil, nil
+}
+
+// MigrateDown undoes the applied migrations of store newer than version,
+// newest first, returning those undone
+func MigrateDown(ctx context.Context, store Store, version int) ([]MigrationStatus, error) {
+	if m, ok := store.(migrator); ok {
+		return m.MigrateDown(ctx, version)
+	}
+	return nil, nil
+}
+
+// migrationLog records which migrations a database has had applied
+type migrationLog interface {
+	applied(ctx context.Context) (map[int]time.Time, error)
+	record(ctx context.Context, status MigrationStatus) error
+	forget(ctx context.Context, version int) error
+}
+
+// migrationStatuses lists migrations with when log says each was applied
+func migrationStatuses[T any](ctx context.Context, log migrationLog, migrations []Migration[T]) ([]MigrationStatus, error) {
+	if err := checkMigrations(migrations); err != nil {
+		return nil, err
+	}
+	applied, err := log.applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading applied migrations: %w", err)
+	}
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i] = MigrationStatus{Version: migration.Version, Description: migration.Description, AppliedAt: applied[migration.Version]}
+	}
+	return statuses, nil
+}
+
+// migrateUp applies the migrations log has no record of to target, in
+// order, recording each as it succeeds
+func migrateUp[T any](ctx context.Context, target T, log migrationLog, migrations []Migration[T], now func() time.Time) ([]MigrationStatus, error) {
+	statuses, err := migrationStatuses(ctx, log, migrations)
+	if err != nil {
+		return nil, err
+	}
+	var applied []MigrationStatus
+	for i, status := range statuses {
+		if status.Applied() {
+			continue
+		}
+		if err := migrations[i].Up(ctx, target); err != nil {
+			return applied, fmt.Errorf("applying migration %d (%s): %w", status.Version, status.Description, err)
+		}
+		status.AppliedAt = now()
+		if err := log.record(ctx, status); err != nil {
+			return applied, fmt.Errorf("recording migration %d: %w", status.Version, err)
+		}
+		applied = append(applied, status)
+	}
+	return applied, nil
+}
+
+// migrateDown undoes the applied migrations of target newer than version,
+// newest first, removing each from log as it succeeds
+func migrateDown[T any](ctx context.Context, target T, log migrationLog, migrations []Migration[T], version int) ([]MigrationStatus, error) {
+	statuses, err := migrationStatuses(ctx, log, migrations)
+	if err != nil {
+		return nil, err
+	}
+	var undone []MigrationStatus
+	for i := len(statuses) - 1; i >= 0 && statuses[i].Version > version; i-- {
+		status := statuses[i]
+		if !status.Applied() {
+			continue
+		}
+		if migrations[i].Down == nil {
+			return undone, fmt.Errorf("migration %d (%s) cannot be undone", status.Version, status.Description)
+		}
+		if err := migrations[i].Down(ctx, target); err != nil {
+			return undone, fmt.Errorf("undoing migration %d (%s): %w", status.Version, status.Description, err)
+		}
+		if err := log.forget(ctx, status.Version); err != nil {
+			return undone, fmt.Errorf("recording migration %d as undone: %w", status.Version, err)
+		}
+		status.AppliedAt = time.Time{}
+		undone = append(undone, status)
+	}
+	return undone, nil
+}
+
+// checkMigrations makes sure versions are positive and increasing, so that
+// each is applied once and in order
+func checkMigrations[T any](migrations []Migration[T]) error {
+	previous := 0
+	for _, migration := range migrations {
+		if migration.Version <= previous {
+			return fmt.Errorf("migration %d (%s) is out of order", migration.Version, migration.Description)
+		}
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d (%s) has nothing to apply", migration.Version, migration.Description)
+		}
+		previous = migration.Version
+	}
+	return nil
+}
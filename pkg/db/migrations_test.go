@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeMigrationLog is a migrationLog in memory
+type fakeMigrationLog map[int]time.Time
+
+func (l fakeMigrationLog) applied(ctx context.Context) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time, len(l))
+	for version, at := range l {
+		applied[version] = at
+	}
+	return applied, nil
+}
+
+func (l fakeMigrationLog) record(ctx context.Context, status MigrationStatus) error {
+	l[status.Version] = status.AppliedAt
+	return nil
+}
+
+func (l fakeMigrationLog) forget(ctx context.Context, version int) error {
+	delete(l, version)
+	return nil
+}
+
+// testMigrations record what they do in the slice they are given
+func testMigrations() []Migration[*[]string] {
+	step := func(name string) func(context.Context, *[]string) error {
+		return func(ctx context.Context, steps *[]string) error {
+			*steps = append(*steps, name)
+			return nil
+		}
+	}
+	return []Migration[*[]string]{
+		{Version: 1, Description: "first", Up: step("up 1"), Down: step("down 1")},
+		{Version: 2, Description: "second", Up: step("up 2"), Down: step("down 2")},
+		{Version: 5, Description: "third", Up: step("up 5"), Down: step("down 5")},
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	log := fakeMigrationLog{1: now.Add(-time.Hour)}
+	migrations := testMigrations()
+	var steps []string
+
+	applied, err := migrateUp(ctx, &steps, log, migrations, func() time.Time { return now })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 2", "up 5"}, steps, "only pending migrations are applied, in order")
+	assert.Equal(t, []MigrationStatus{{2, "second", now}, {5, "third", now}}, applied)
+
+	applied, err = migrateUp(ctx, &steps, log, migrations, func() time.Time { return now })
+	require.NoError(t, err)
+	assert.Empty(t, applied, "migrations are applied once")
+
+	statuses, err := migrationStatuses(ctx, log, migrations)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied())
+	assert.Equal(t, now.Add(-time.Hour), statuses[0].AppliedAt)
+
+	steps = nil
+	undone, err := migrateDown(ctx, &steps, log, migrations, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down 5", "down 2"}, steps, "newest first")
+	assert.Equal(t, []MigrationStatus{{5, "third", time.Time{}}, {2, "second", time.Time{}}}, undone)
+	assert.Equal(t, fakeMigrationLog{1: now.Add(-time.Hour)}, log)
+
+	steps = nil
+	_, err = migrateUp(ctx, &steps, log, migrations, func() time.Time { return now })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up 2", "up 5"}, steps, "undone migrations can be applied again")
+}
+
+func TestMigrateFailures(t *testing.T) {
+	ctx := context.Background()
+	migrations := testMigrations()
+	migrations[1].Up = func(context.Context, *[]string) error { return errors.New("disk full") }
+	log := fakeMigrationLog{}
+	var steps []string
+
+	applied, err := migrateUp(ctx, &steps, log, migrations, time.Now)
+	assert.EqualError(t, err, "applying migration 2 (second): disk full")
+	assert.Len(t, applied, 1)
+	assert.Contains(t, log, 1)
+	assert.NotContains(t, log, 2, "a failed migration is not recorded")
+	assert.NotContains(t, log, 5, "later migrations wait for it")
+
+	migrations = testMigrations()
+	migrations[0].Down = nil
+	_, err = migrateDown(ctx, &steps, log, migrations, 0)
+	assert.EqualError(t, err, "migration 1 (first) cannot be undone")
+
+	migrations = testMigrations()
+	migrations[2].Version = 2
+	_, err = migrateUp(ctx, &steps, fakeMigrationLog{}, migrations, time.Now)
+	assert.EqualError(t, err, "migration 2 (third) is out of order")
+	assert.NoError(t, checkMigrations(mongoMigrations))
+}
+
+func TestNormaliseSortOrder(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+
+	order, changed := normaliseSortOrder(bson.A{a, b})
+	assert.False(t, changed)
+	assert.Equal(t, []primitive.ObjectID{a, b}, order)
+
+	order, changed = normaliseSortOrder(bson.A{a, b.Hex(), "not an id", a, int32(7)})
+	assert.True(t, changed)
+	assert.Equal(t, []primitive.ObjectID{a, b}, order)
+
+	order, changed = normaliseSortOrder(nil)
+	assert.False(t, changed)
+	assert.Empty(t, order)
+}
+
+func TestMigrateStoresWithoutMigrations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	statuses, err := MigrationStatuses(ctx, store)
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+	applied, err := MigrateUp(ctx, store)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+// TestMongoDBMigrations runs the MongoDB migrations against a real deployment
+// when GO_SHOPPING_MONGO_TEST_URI is set
+func TestMongoDBMigrations(t *testing.T) {
+	uri := os.Getenv("GO_SHOPPING_MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("Skipping as GO_SHOPPING_MONGO_TEST_URI is not set")
+	}
+	ctx := context.Background()
+	dbName := fmt.Sprintf("GoShopping-migrations-%d", time.Now().UnixNano())
+	store, err := NewMongoDB(ctx, uri, dbName)
+	require.NoError(t, err)
+	defer func() {
+		store.Client.Database(dbName).Drop(context.Background())
+		store.Close()
+	}()
+
+	lists := store.Client.Database(dbName).Collection("shopping-lists")
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	_, err = lists.InsertOne(context.Background(), bson.D{
+		{Key: "Name", Value: "Shopping list"},
+		{Key: "ShoppingList", Value: bson.A{
+			bson.D{{Key: "_id", Value: a}, {Key: "IDHex", Value: a.Hex()}, {Key: "Item", Value: "milk"}, {Key: "Order", Value: 1}},
+			bson.D{{Key: "_id", Value: b}, {Key: "IDHex", Value: b.Hex()}, {Key: "Item", Value: "bread"}, {Key: "Order", Value: 2}},
+		}},
+		{Key: "SortOrder", Value: bson.A{a, b.Hex()}},
+	})
+	require.NoError(t, err)
+
+	applied, err := store.MigrateUp(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(mongoMigrations))
+	items, err := store.GetShoppingList(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 2, "hex strings in SortOrder are read as IDs")
+	count, err := lists.CountDocuments(context.Background(), bson.D{{Key: "ShoppingList.Order", Value: bson.D{{Key: "$exists", Value: true}}}})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	undone, err := store.MigrateDown(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, undone, len(mongoMigrations))
+	count, err = lists.CountDocuments(context.Background(), bson.D{{Key: "ShoppingList.1.Order", Value: 2}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "undoing restores the items' Order")
+	statuses, err := store.MigrationStatuses(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.False(t, status.Applied())
+	}
+
+	// A database migrated by a newer version is not opened
+	latest := mongoMigrations[len(mongoMigrations)-1].Version
+	require.NoError(t, store.migrationLog().record(ctx, MigrationStatus{Version: latest + 1, Description: "from the future", AppliedAt: time.Now()}))
+	_, err = NewMongoDB(ctx, uri, dbName)
+	assert.ErrorContains(t, err, "newer than the latest this version knows of")
+}
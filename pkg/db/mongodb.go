@@ -2,79 +2,193 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// MongoDB represents a MongoDB client connection
+// MongoDB represents a MongoDB client connection. Documents belong to the
+// household named by their Household field; the MongoDB returned by
+// NewMongoDB reads and writes documents with no household.
 type MongoDB struct {
 	Client       *mongo.Client
 	DatabaseName string
+	household    string
+	// list is the IDHex of the shopping list item calls use, or "" for the
+	// household's first list
+	list string
+	// view is set on the stores returned by ForHousehold, which share Client
+	view bool
 }
 
-// Ensure MongoDB implements DBInterface
-var _ DBInterface = (*MongoDB)(nil)
+// Ensure MongoDB implements Store
+var _ Store = (*MongoDB)(nil)
 
-// NewMongoDB creates a new MongoDB connection
-func NewMongoDB(uri, databaseName string) (*MongoDB, error) {
-	// connect to MongoDB
+// householdCollections lists every collection holding household data
+var householdCollections = []string{
+	"shopping-lists", "meal-plans", "recipes", "categories", "stores", "tick-events", "walk-order", "changes",
+}
+
+// disconnectTimeout bounds how long Close waits for MongoDB to end the
+// connection
+const disconnectTimeout = 10 * time.Second
+
+// ErrNoTransactions is returned by NewMongoDB for a standalone MongoDB
+// server, which cannot run the transactions that registering, merging items
+// and moving them between lists rely on
+var ErrNoTransactions = errors.New("MongoDB is running as a standalone server, which has no transactions; run it as a replica set (a single node will do) or use MongoDB Atlas")
+
+// NewMongoDB creates a new MongoDB connection, failing with
+// ErrNoTransactions if the deployment cannot run transactions
+func NewMongoDB(ctx context.Context, uri, databaseName string) (*MongoDB, error) {
+	client, err := ConnectMongo(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Pinged your deployment. You successfully connected to MongoDB! Using database: %s\n", databaseName)
+
+	var hello mongoHello
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	if !hello.transactions() {
+		client.Disconnect(ctx)
+		return nil, ErrNoTransactions
+	}
+
+	store := &MongoDB{Client: client, DatabaseName: databaseName}
+	if err := store.checkSchema(ctx); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return store, nil
+}
+
+// mongoHello is the part of the hello command's reply that says what kind
+// of deployment answered
+type mongoHello struct {
+	// SetName is the replica set of a replica set member
+	SetName string `bson:"setName"`
+	// Msg is "isdbgrid" from the mongos router of a sharded cluster
+	Msg string `bson:"msg"`
+}
+
+// transactions reports whether the deployment can run transactions, which
+// replica sets and sharded clusters can but standalone servers cannot
+func (h mongoHello) transactions() bool {
+	return h.SetName != "" || h.Msg == "isdbgrid"
+}
+
+// ConnectMongo connects to the MongoDB deployment at uri, checking that it
+// answers
+func ConnectMongo(ctx context.Context, uri string) (*mongo.Client, error) {
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
 	opts := options.Client().ApplyURI(uri).SetServerAPIOptions(serverAPI)
 
-	client, err := mongo.Connect(context.TODO(), opts)
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	// Send a ping to confirm a successful connection
-	if err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Err(); err != nil {
+		client.Disconnect(ctx)
 		return nil, err
 	}
-	fmt.Printf("Pinged your deployment. You successfully connected to MongoDB! Using database: %s\n", databaseName)
+	return client, nil
+}
 
-	return &MongoDB{Client: client, DatabaseName: databaseName}, nil
+// householdFilter matches this store's documents, and any conditions
+func (m *MongoDB) householdFilter(conditions ...bson.E) bson.D {
+	filter := bson.D{{Key: "Household", Value: m.household}}
+	if m.household == "" {
+		filter = bson.D{{Key: "Household", Value: bson.D{{Key: "$exists", Value: false}}}}
+	}
+	return append(filter, conditions...)
 }
 
 // GetShoppingList retrieves the shopping list from the database
-func (m *MongoDB) GetShoppingList() ([]models.ShoppingListItem, error) {
+func (m *MongoDB) GetShoppingList(ctx context.Context) ([]models.ShoppingListItem, error) {
 	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
 
 	var document struct {
 		ID           primitive.ObjectID        `bson:"_id"`
 		ShoppingList []models.ShoppingListItem `bson:"ShoppingList"`
-		SortOrder    []primitive.ObjectID      `bson:"SortOrder"`
+		SortOrder    bson.A                    `bson:"SortOrder"`
 	}
 
-	err := collection.FindOne(context.Background(), bson.D{}).Decode(&document)
+	filter, err := m.listFilter(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if err := collection.FindOne(ctx, filter).Decode(&document); err != nil {
+		return nil, err
+	}
 
-	// Create a map of item ID to ShoppingListItem for easy lookup
-	itemMap := make(map[primitive.ObjectID]models.ShoppingListItem)
-	for _, item := range document.ShoppingList {
-		itemMap[item.ID] = item
+	// Put right any drift in the order, unless the list has changed since
+	var report IntegrityReport
+	order, malformed := parseSortOrder(document.SortOrder)
+	order = report.add(ListIntegrity{Malformed: malformed}, document.ShoppingList, order)
+	if !report.OK() {
+		if err := m.saveSortOrder(ctx, document.ID, document.SortOrder, order); err != nil {
+			fmt.Printf("Error repairing shopping list order: %s\n", err)
+		}
 	}
+	return orderShoppingList(document.ShoppingList, order), nil
+}
 
-	// Sort the shopping list based on the SortOrder
-	var sortedShoppingList []models.ShoppingListItem
-	for _, id := range document.SortOrder {
-		if item, exists := itemMap[id]; exists {
-			sortedShoppingList = append(sortedShoppingList, item)
+// parseSortOrder reads a SortOrder, counting the IDs that were not saved as
+// ObjectIDs. Hex strings, pushed by older versions, are still read.
+func parseSortOrder(sortOrder bson.A) ([]primitive.ObjectID, int) {
+	order := make([]primitive.ObjectID, 0, len(sortOrder))
+	malformed := 0
+	for _, value := range sortOrder {
+		switch v := value.(type) {
+		case primitive.ObjectID:
+			order = append(order, v)
+		case string:
+			malformed++
+			if id, err := primitive.ObjectIDFromHex(v); err == nil {
+				order = append(order, id)
+			}
+		default:
+			malformed++
 		}
 	}
+	return order, malformed
+}
 
-	return sortedShoppingList, nil
+// saveSortOrder replaces the SortOrder of the list with ID id, as long as it
+// is still previous
+func (m *MongoDB) saveSortOrder(ctx context.Context, id primitive.ObjectID, previous bson.A, order []primitive.ObjectID) error {
+	if previous == nil {
+		previous = bson.A{}
+	}
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "SortOrder", Value: previous}}
+	if len(previous) == 0 {
+		// A list saved without a SortOrder has none to match
+		filter = bson.D{{Key: "_id", Value: id}, {Key: "$or", Value: bson.A{
+			bson.D{{Key: "SortOrder", Value: bson.A{}}},
+			bson.D{{Key: "SortOrder", Value: bson.D{{Key: "$exists", Value: false}}}},
+		}}}
+	}
+	_, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").UpdateOne(ctx, filter,
+		bson.D{{Key: "$set", Value: bson.D{{Key: "SortOrder", Value: order}}}})
+	return err
 }
 
 // GetShoppingListItemFromIDHex retrieves a shopping list item by its hex ID
-func (m *MongoDB) GetShoppingListItemFromIDHex(IDHex string) (models.ShoppingListItem, error) {
-	shoppingList, err := m.GetShoppingList()
+func (m *MongoDB) GetShoppingListItemFromIDHex(ctx context.Context, IDHex string) (models.ShoppingListItem, error) {
+	shoppingList, err := m.GetShoppingList(ctx)
 	if err != nil {
 		var failedItem models.ShoppingListItem
 		return failedItem, err
@@ -87,22 +201,113 @@ func (m *MongoDB) GetShoppingListItemFromIDHex(IDHex string) (models.ShoppingLis
 	return models.ShoppingListItem{}, nil
 }
 
-// UpdateMeal updates a meal for a specific day
-func (m *MongoDB) UpdateMeal(day string, meal string) error {
+// UpdateMeal updates the meal in one slot of a day of the given week
+func (m *MongoDB) UpdateMeal(ctx context.Context, week string, day string, slot string, meal string) error {
+	if err := models.ValidateMealSlot(slot); err != nil {
+		return err
+	}
+	return m.updateMealDay(ctx, week, day, slot, func(updatedDay *models.Meal) error {
+		updatedDay.SetSlotMeal(slot, meal)
+		return nil
+	})
+}
+
+// SetMealRecipe links one slot of a day to a recipe, or unlinks it when
+// recipeID is ""
+func (m *MongoDB) SetMealRecipe(ctx context.Context, week string, day string, slot string, recipeID string) error {
+	if err := models.ValidateMealSlot(slot); err != nil {
+		return err
+	}
+	return m.updateMealDay(ctx, week, day, slot, func(updatedDay *models.Meal) error {
+		updatedDay.SetSlotRecipe(slot, recipeID)
+		return nil
+	})
+}
+
+// EditMeal saves the meal and recipe of one slot of a day of the given week,
+// unless the slot has changed since input was read
+func (m *MongoDB) EditMeal(ctx context.Context, week string, input models.MealSlotInput) (models.MealSlotInput, error) {
+	if err := models.ValidateMealSlot(input.Slot); err != nil {
+		return models.MealSlotInput{}, err
+	}
+	err := m.updateMealDay(ctx, week, input.Day, input.Slot, func(updatedDay *models.Meal) error {
+		if updatedDay.SlotVersion(input.Slot) != input.Version {
+			return ErrConflict
+		}
+		updatedDay.SetSlotMeal(input.Slot, input.Meal)
+		updatedDay.SetSlotRecipe(input.Slot, input.RecipeID)
+		return nil
+	})
+	if errors.Is(err, ErrConflict) {
+		return mealConflict(ctx, m, week, input.Day, input.Slot)
+	}
+	if err != nil {
+		return models.MealSlotInput{}, err
+	}
+	saved := input
+	saved.Week = week
+	saved.Version++
+	return saved, nil
+}
+
+// updateMealDay applies change to a day of the given week and writes it back,
+// counting a change to slot. The week's plan is read first (creating it if
+// needed) so the whole day is written back in its normalised, slotted form.
+// ErrNotFound is returned if the week has no such day, and ErrConflict if the
+// slot changed in between.
+func (m *MongoDB) updateMealDay(ctx context.Context, week string, day string, slot string, change func(*models.Meal) error) error {
+	mealPlan, err := m.GetMealPlan(ctx, week)
+	if err != nil {
+		return err
+	}
+	var updatedDay *models.Meal
+	for i := range mealPlan.Meals {
+		if mealPlan.Meals[i].Day == day {
+			updatedDay = &mealPlan.Meals[i]
+			break
+		}
+	}
+	if updatedDay == nil {
+		return ErrNotFound
+	}
+	version := updatedDay.SlotVersion(slot)
+	if err := change(updatedDay); err != nil {
+		return err
+	}
+	updatedDay.NextSlotVersion(slot)
+
 	collection := m.Client.Database(m.DatabaseName).Collection("meal-plans")
-	filter := bson.D{{Key: "meals", Value: bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "day", Value: day}}}}}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "meals.$.meal", Value: meal}}}}
-	_, err := collection.UpdateOne(context.Background(), filter, update)
+	filter := m.householdFilter(
+		bson.E{Key: "week", Value: week},
+		bson.E{Key: "meals", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "day", Value: day},
+			versionCondition("versions."+slot, version),
+		}}}},
+	)
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "meals.$", Value: updatedDay}}}}
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		fmt.Printf("Error updating meal: %s\n", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrConflict
 	}
 	return nil
 }
 
-// AddShoppingListItem adds a new item to the shopping list
-func (m *MongoDB) AddShoppingListItem(itemName string) (models.ShoppingListItem, error) {
-	// if itemName is empty, return an error
-	if itemName == "" {
+// AddShoppingListItem adds an item read from text such as "2kg potatoes" to
+// the end of the shopping list, or adds its quantity to a matching unticked
+// item already on the list
+func (m *MongoDB) AddShoppingListItem(ctx context.Context, itemName string) (models.ShoppingListItem, error) {
+	return addShoppingListItem(ctx, m, itemName)
+}
+
+// InsertShoppingListItem adds a new item, along with details such as its
+// sources, to the end of the shopping list. The ID is always generated.
+func (m *MongoDB) InsertShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	// if the item name is empty, return an error
+	if item.Item == "" {
 		return models.ShoppingListItem{}, fmt.Errorf("item name cannot be empty")
 	}
 
@@ -110,38 +315,50 @@ func (m *MongoDB) AddShoppingListItem(itemName string) (models.ShoppingListItem,
 	newId := primitive.NewObjectID()
 
 	// Prepare the shopping list item to be added
-	newItem := models.ShoppingListItem{
-		ID:     newId,
-		IDHex:  newId.Hex(),
-		Item:   itemName,
-		Ticked: false,
-	}
+	newItem := item
+	newItem.ID = newId
+	newItem.IDHex = newId.Hex()
 
 	// Prepare the update operation to push the new item
-	filter := bson.D{} // This filter needs to be specific to the document you're updating
-	update := bson.D{{Key: "$push", Value: bson.D{{Key: "ShoppingList", Value: newItem}}}}
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	// The item and its place at the end of the order are added by one update,
+	// so neither is saved without the other
+	update := bson.D{{Key: "$push", Value: bson.D{
+		{Key: "ShoppingList", Value: newItem},
+		{Key: "SortOrder", Value: newId},
+	}}, incListVersion}
 
 	// Execute the update operation
-	_, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").UpdateOne(context.Background(), filter, update)
+	result, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").UpdateOne(ctx, filter, update)
 	if err != nil {
 		fmt.Printf("Error adding shopping list item: %s\n", err)
-		return models.ShoppingListItem{}, err
+		return models.ShoppingListItem{}, fmt.Errorf("adding %q: %w", newItem.Item, err)
+	}
+	if result.MatchedCount == 0 {
+		return models.ShoppingListItem{}, ErrNotFound
 	}
-
-	// Add the new item to the Order
-	err = m.AddShoppingListIdToShoppingListOrder(newItem.IDHex)
 
 	return newItem, nil
 }
 
 // AddShoppingListIdToShoppingListOrder adds a new item ID to the sort order
-func (m *MongoDB) AddShoppingListIdToShoppingListOrder(itemId string) error {
+func (m *MongoDB) AddShoppingListIdToShoppingListOrder(ctx context.Context, itemId string) error {
 	// Prepare the update operation to push the new item
-	filter := bson.D{} // This filter needs to be specific to the document you're updating
-	update := bson.D{{Key: "$push", Value: bson.D{{Key: "SortOrder", Value: itemId}}}}
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := primitive.ObjectIDFromHex(itemId)
+	if err != nil {
+		return err
+	}
+	update := bson.D{{Key: "$addToSet", Value: bson.D{{Key: "SortOrder", Value: id}}}, incListVersion}
 
 	// Execute the update operation
-	_, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").UpdateOne(context.Background(), filter, update)
+	_, err = m.Client.Database(m.DatabaseName).Collection("shopping-lists").UpdateOne(ctx, filter, update)
 	if err != nil {
 		fmt.Printf("Error adding shopping list item to order: %s\n", err)
 		return err
@@ -151,20 +368,26 @@ func (m *MongoDB) AddShoppingListIdToShoppingListOrder(itemId string) error {
 }
 
 // UpdateShoppingListItem updates an existing shopping list item
-func (m *MongoDB) UpdateShoppingListItem(itemId string, newItem string) (models.ShoppingListItem, error) {
+func (m *MongoDB) UpdateShoppingListItem(ctx context.Context, itemId string, newItem string) (models.ShoppingListItem, error) {
 	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
-	filter := bson.D{{}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "ShoppingList.$[element].Item", Value: newItem}}}}
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "ShoppingList.$[element].Item", Value: newItem}}}, incItemVersion}
 	options := options.UpdateOptions{
 		ArrayFilters: &options.ArrayFilters{
 			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: itemId}}},
 		},
 	}
-	_, err := collection.UpdateOne(context.Background(), filter, update, &options)
+	if _, err := collection.UpdateOne(ctx, filter, update, &options); err != nil {
+		fmt.Printf("Error updating shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
 	var shoppingListItem models.ShoppingListItem
-	shoppingList, err := m.GetShoppingList()
+	shoppingList, err := m.GetShoppingList(ctx)
 	if err != nil {
-		fmt.Printf("Error updating shopping list item: %s\n", err)
+		fmt.Printf("Error getting shopping list: %s\n", err)
 		return shoppingListItem, err
 	}
 	for _, item := range shoppingList {
@@ -175,56 +398,264 @@ func (m *MongoDB) UpdateShoppingListItem(itemId string, newItem string) (models.
 	return shoppingListItem, nil
 }
 
+// UpdateShoppingListItemDetails replaces the name, quantity, unit and note of
+// the shopping list item with item.IDHex
+func (m *MongoDB) UpdateShoppingListItemDetails(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "ShoppingList.$[element].Item", Value: item.Item},
+		{Key: "ShoppingList.$[element].Quantity", Value: item.Quantity},
+		{Key: "ShoppingList.$[element].Unit", Value: item.Unit},
+		{Key: "ShoppingList.$[element].Note", Value: item.Note},
+	}}, incItemVersion}
+	options := options.UpdateOptions{
+		ArrayFilters: &options.ArrayFilters{
+			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: item.IDHex}}},
+		},
+	}
+	if _, err := collection.UpdateOne(ctx, filter, update, &options); err != nil {
+		fmt.Printf("Error updating shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return m.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// EditShoppingListItem replaces the name, quantity, unit and note of the
+// shopping list item with item.IDHex, unless it has changed since item was
+// read
+func (m *MongoDB) EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	// Only match the list while the item still has the version that was read
+	filter = append(filter, bson.E{Key: "ShoppingList", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+		{Key: "IDHex", Value: item.IDHex},
+		versionCondition("Version", item.Version),
+	}}}})
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "ShoppingList.$[element].Item", Value: item.Item},
+		{Key: "ShoppingList.$[element].Quantity", Value: item.Quantity},
+		{Key: "ShoppingList.$[element].Unit", Value: item.Unit},
+		{Key: "ShoppingList.$[element].Note", Value: item.Note},
+	}}, incItemVersion}
+	options := options.UpdateOptions{
+		ArrayFilters: &options.ArrayFilters{
+			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: item.IDHex}}},
+		},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update, &options)
+	if err != nil {
+		fmt.Printf("Error updating shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	if result.MatchedCount == 0 {
+		return itemConflict(ctx, m, item.IDHex)
+	}
+	return m.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// ReplaceShoppingListItem replaces the details, ticked status, category and
+// sources of the shopping list item with item.IDHex, unless it has changed
+// since item was read
+func (m *MongoDB) ReplaceShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	sources := item.Sources
+	if len(sources) == 0 {
+		sources = nil
+	}
+
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	filter = append(filter, bson.E{Key: "ShoppingList", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+		{Key: "IDHex", Value: item.IDHex},
+		versionCondition("Version", item.Version),
+	}}}})
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "ShoppingList.$[element].Item", Value: item.Item},
+		{Key: "ShoppingList.$[element].Quantity", Value: item.Quantity},
+		{Key: "ShoppingList.$[element].Unit", Value: item.Unit},
+		{Key: "ShoppingList.$[element].Note", Value: item.Note},
+		{Key: "ShoppingList.$[element].Ticked", Value: item.Ticked},
+		{Key: "ShoppingList.$[element].Category", Value: item.Category},
+		{Key: "ShoppingList.$[element].Sources", Value: sources},
+	}}, incItemVersion}
+	options := options.UpdateOptions{
+		ArrayFilters: &options.ArrayFilters{
+			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: item.IDHex}}},
+		},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update, &options)
+	if err != nil {
+		fmt.Printf("Error replacing shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	if result.MatchedCount == 0 {
+		return itemConflict(ctx, m, item.IDHex)
+	}
+	return m.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// incItemVersion is the part of an update of the items matched by the
+// "element" array filter that counts the change in their Version
+var incItemVersion = bson.E{Key: "$inc", Value: bson.D{{Key: "ShoppingList.$[element].Version", Value: 1}}}
+
+// incListVersion is the part of an update of a shopping list document that
+// counts a change to its items or their order in its Version
+var incListVersion = bson.E{Key: "$inc", Value: bson.D{{Key: "Version", Value: 1}}}
+
+// versionCondition matches documents whose version field is version. Records
+// never changed have no version field, which reads as 0.
+func versionCondition(field string, version int) bson.E {
+	if version == 0 {
+		return bson.E{Key: field, Value: bson.D{{Key: "$exists", Value: false}}}
+	}
+	return bson.E{Key: field, Value: version}
+}
+
+// SetShoppingListItemSources replaces the meals a shopping list item was
+// generated for
+func (m *MongoDB) SetShoppingListItemSources(ctx context.Context, itemId string, sources []models.MealSource) (models.ShoppingListItem, error) {
+	if len(sources) == 0 {
+		// Store no sources as null so the item reads back like one never generated
+		sources = nil
+	}
+
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "ShoppingList.$[element].Sources", Value: sources}}}, incItemVersion}
+	options := options.UpdateOptions{
+		ArrayFilters: &options.ArrayFilters{
+			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: itemId}}},
+		},
+	}
+	if _, err := collection.UpdateOne(ctx, filter, update, &options); err != nil {
+		fmt.Printf("Error updating shopping list item sources: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return m.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
 // DeleteShoppingListItem removes an item from the shopping list
-func (m *MongoDB) DeleteShoppingListItem(itemIDHex string) error {
+func (m *MongoDB) DeleteShoppingListItem(ctx context.Context, itemIDHex string) error {
 	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
 
-	filter := bson.M{}
-	update := bson.M{"$pull": bson.M{"ShoppingList": bson.M{"IDHex": itemIDHex}}}
-	if _, err := collection.UpdateOne(context.Background(), filter, update); err != nil {
-		fmt.Printf("Error deleting shopping list item: %s\n", err)
+	filter, err := m.listFilter(ctx)
+	if err != nil {
 		return err
 	}
-
-	var result struct {
-		ShoppingList []models.ShoppingListItem `bson:"ShoppingList"`
+	pull := bson.M{"ShoppingList": bson.M{"IDHex": itemIDHex}, "SortOrder": itemIDHex}
+	if id, err := primitive.ObjectIDFromHex(itemIDHex); err == nil {
+		pull["SortOrder"] = bson.M{"$in": bson.A{id, itemIDHex}}
+	}
+	// The item and its ID in the order are removed by one update
+	update := bson.M{"$pull": pull, "$inc": bson.M{"Version": 1}}
+	if _, err := collection.UpdateOne(ctx, filter, update); err != nil {
+		fmt.Printf("Error deleting shopping list item: %s\n", err)
+		return fmt.Errorf("deleting item %s: %w", itemIDHex, err)
 	}
-	if err := collection.FindOne(context.Background(), bson.M{}).Decode(&result); err != nil {
-		fmt.Printf("Error retrieving updated shopping list post-deletion: %s\n", err)
+	return nil
+}
+
+// MergeShoppingListItems saves the details and sources of the merged items
+// and removes the removed ones in a transaction, which is abandoned if any
+// has changed since it was read
+func (m *MongoDB) MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	filter, err := m.listFilter(ctx)
+	if err != nil {
 		return err
 	}
+	// atVersion matches the list while item is on it at the version read
+	atVersion := func(item models.ShoppingListItem) bson.D {
+		return append(append(bson.D{}, filter...), bson.E{Key: "ShoppingList", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "IDHex", Value: item.IDHex},
+			versionCondition("Version", item.Version),
+		}}}})
+	}
 
-	for i, item := range result.ShoppingList {
-		// Update the order to the current index
-		update := bson.M{"$set": bson.M{"ShoppingList.$.Order": i + 1}}
-		filter := bson.M{"ShoppingList.IDHex": item.IDHex}
-		if _, err := collection.UpdateOne(context.Background(), filter, update); err != nil {
-			fmt.Printf("Error updating order for item ID %s: %s\n", item.IDHex, err)
-			return err
+	err = m.transaction(ctx, func(ctx mongo.SessionContext) error {
+		for _, item := range merged {
+			sources := item.Sources
+			if len(sources) == 0 {
+				sources = nil
+			}
+			update := bson.D{{Key: "$set", Value: bson.D{
+				{Key: "ShoppingList.$[element].Item", Value: item.Item},
+				{Key: "ShoppingList.$[element].Quantity", Value: item.Quantity},
+				{Key: "ShoppingList.$[element].Unit", Value: item.Unit},
+				{Key: "ShoppingList.$[element].Note", Value: item.Note},
+				{Key: "ShoppingList.$[element].Sources", Value: sources},
+			}}, incItemVersion}
+			options := options.UpdateOptions{
+				ArrayFilters: &options.ArrayFilters{
+					Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: item.IDHex}}},
+				},
+			}
+			result, err := collection.UpdateOne(ctx, atVersion(item), update, &options)
+			if err != nil {
+				return fmt.Errorf("merging into %q: %w", item.Item, err)
+			}
+			if result.MatchedCount == 0 {
+				return ErrConflict
+			}
 		}
+		for _, item := range removed {
+			order := bson.A{item.IDHex}
+			if id, err := primitive.ObjectIDFromHex(item.IDHex); err == nil {
+				order = append(order, id)
+			}
+			update := bson.D{{Key: "$pull", Value: bson.D{
+				{Key: "ShoppingList", Value: bson.D{{Key: "IDHex", Value: item.IDHex}}},
+				{Key: "SortOrder", Value: bson.D{{Key: "$in", Value: order}}},
+			}}, incListVersion}
+			result, err := collection.UpdateOne(ctx, atVersion(item), update)
+			if err != nil {
+				return fmt.Errorf("removing merged item %s: %w", item.IDHex, err)
+			}
+			if result.MatchedCount == 0 {
+				return ErrConflict
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrConflict) {
+		fmt.Printf("Error merging shopping list items: %s\n", err)
 	}
-
-	return nil
+	return err
 }
 
 // TickShoppingListItem toggles the ticked status of a shopping list item
-func (m *MongoDB) TickShoppingListItem(itemId string, ticked bool) (models.ShoppingListItem, error) {
+func (m *MongoDB) TickShoppingListItem(ctx context.Context, itemId string, ticked bool) (models.ShoppingListItem, error) {
 	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
-	filter := bson.D{{}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "ShoppingList.$[element].Ticked", Value: ticked}}}}
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "ShoppingList.$[element].Ticked", Value: ticked}}}, incItemVersion}
 	options := options.UpdateOptions{
 		ArrayFilters: &options.ArrayFilters{
 			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: itemId}}},
 		},
 	}
-	_, err := collection.UpdateOne(context.Background(), filter, update, &options)
+	_, err = collection.UpdateOne(ctx, filter, update, &options)
 	if err != nil {
 		fmt.Printf("Error ticking shopping list item: %s\n", err)
 		var failedShoppingListItem models.ShoppingListItem
 		return failedShoppingListItem, err
 	}
 
-	shoppingListItem, err := m.GetShoppingListItemFromIDHex(itemId)
+	shoppingListItem, err := m.GetShoppingListItemFromIDHex(ctx, itemId)
 	if err != nil {
 		fmt.Printf("Error getting shopping list item: %s\n", err)
 		return shoppingListItem, err
@@ -233,23 +664,81 @@ func (m *MongoDB) TickShoppingListItem(itemId string, ticked bool) (models.Shopp
 	return shoppingListItem, nil
 }
 
-// GetMealPlan retrieves the meal plan from the database
-func (m *MongoDB) GetMealPlan() (models.MealPlan, error) {
-	// find the first document in the collection
+// GetMealPlan retrieves the meal plan for an ISO week, creating an empty plan
+// the first time a week is visited
+func (m *MongoDB) GetMealPlan(ctx context.Context, week string) (models.MealPlan, error) {
+	emptyPlan, err := models.NewMealPlan(week)
+	if err != nil {
+		return models.MealPlan{}, err
+	}
+
 	collection := m.Client.Database(m.DatabaseName).Collection("meal-plans")
-	// get the first document
-	filter := bson.D{{}}
+	filter := m.householdFilter(bson.E{Key: "week", Value: week})
 	var mealPlan models.MealPlan
-	err := collection.FindOne(context.Background(), filter).Decode(&mealPlan)
-	if err != nil {
+	err = collection.FindOne(ctx, filter).Decode(&mealPlan)
+	if err == nil {
+		return mealPlan, nil
+	}
+	if err != mongo.ErrNoDocuments {
 		fmt.Printf("Error finding meal plan: %s\n", err)
 		return mealPlan, err
 	}
-	return mealPlan, nil
+
+	// The single undated plan from before weeks were tracked becomes this week's plan
+	if week == models.ISOWeek(time.Now()) {
+		claimed, err := m.claimLegacyMealPlan(ctx, emptyPlan)
+		if err != nil {
+			return claimed, err
+		}
+		if claimed.Week != "" {
+			return claimed, nil
+		}
+	}
+
+	update := bson.D{{Key: "$setOnInsert", Value: emptyPlan}}
+	if _, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		fmt.Printf("Error creating meal plan for %s: %s\n", week, err)
+		return models.MealPlan{}, err
+	}
+	return emptyPlan, nil
+}
+
+// claimLegacyMealPlan dates the undated meal plan document, if there is one,
+// with the week of emptyPlan. It returns an empty MealPlan when there is none.
+func (m *MongoDB) claimLegacyMealPlan(ctx context.Context, emptyPlan models.MealPlan) (models.MealPlan, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("meal-plans")
+	filter := m.householdFilter(bson.E{Key: "week", Value: bson.D{{Key: "$exists", Value: false}}})
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "week", Value: emptyPlan.Week},
+		{Key: "weekStart", Value: emptyPlan.WeekStart},
+	}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var mealPlan models.MealPlan
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&mealPlan)
+	if err == mongo.ErrNoDocuments {
+		return models.MealPlan{}, nil
+	}
+	if err != nil {
+		fmt.Printf("Error claiming legacy meal plan: %s\n", err)
+	}
+	return mealPlan, err
 }
 
 // SortShoppingList updates the order of items in the shopping list
-func (m *MongoDB) SortShoppingList(newOrder []models.Order) error {
+func (m *MongoDB) SortShoppingList(ctx context.Context, newOrder []models.Order) error {
+	return m.sortShoppingList(ctx, newOrder, nil)
+}
+
+// ReorderShoppingList updates the order of items in the shopping list, as
+// long as the list is still at version
+func (m *MongoDB) ReorderShoppingList(ctx context.Context, newOrder []models.Order, version int) error {
+	return m.sortShoppingList(ctx, newOrder, &version)
+}
+
+// sortShoppingList saves newOrder as the order of the shopping list, unless
+// version is set and the list has moved on from it
+func (m *MongoDB) sortShoppingList(ctx context.Context, newOrder []models.Order, version *int) error {
 	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
 
 	// Create a new array of ObjectIDs in the new order
@@ -263,19 +752,1004 @@ func (m *MongoDB) SortShoppingList(newOrder []models.Order) error {
 	}
 
 	// Update the SortOrder in the shopping list document
-	filter := bson.D{} // This filter needs to be specific to the document you're updating
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "SortOrder", Value: newSortOrder}}}}
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return err
+	}
+	if version != nil {
+		filter = append(filter, versionCondition("Version", *version))
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "SortOrder", Value: newSortOrder}}}, incListVersion}
 
-	_, err := collection.UpdateOne(context.Background(), filter, update)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		fmt.Printf("Error updating shopping list order: %s\n", err)
 		return err
 	}
+	if version != nil && result.MatchedCount == 0 {
+		return ErrConflict
+	}
 
 	return nil
 }
 
-// Close closes the MongoDB connection
-func (m *MongoDB) Close() {
-	m.Client.Disconnect(context.TODO())
+// recipeDocument is how a recipe is stored, along with its household
+type recipeDocument struct {
+	Household      string `bson:"Household,omitempty"`
+	recipes.Recipe `bson:",inline"`
+}
+
+// GetRecipes retrieves every recipe in the library, sorted by title
+func (m *MongoDB) GetRecipes(ctx context.Context) ([]recipes.Recipe, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("recipes")
+
+	cursor, err := collection.Find(ctx, m.householdFilter())
+	if err != nil {
+		fmt.Printf("Error finding recipes: %s\n", err)
+		return nil, err
+	}
+	library := []recipes.Recipe{}
+	if err := cursor.All(ctx, &library); err != nil {
+		fmt.Printf("Error decoding recipes: %s\n", err)
+		return nil, err
+	}
+	recipes.SortByTitle(library)
+	return library, nil
+}
+
+// GetRecipe retrieves a recipe by its hex ID
+func (m *MongoDB) GetRecipe(ctx context.Context, IDHex string) (recipes.Recipe, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("recipes")
+
+	var recipe recipes.Recipe
+	err := collection.FindOne(ctx, m.householdFilter(bson.E{Key: "IDHex", Value: IDHex})).Decode(&recipe)
+	if err == mongo.ErrNoDocuments {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	if err != nil {
+		fmt.Printf("Error finding recipe: %s\n", err)
+	}
+	return recipe, err
+}
+
+// AddRecipe adds a new recipe to the library
+func (m *MongoDB) AddRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return recipes.Recipe{}, err
+	}
+
+	newId := primitive.NewObjectID()
+	recipe.ID = newId
+	recipe.IDHex = newId.Hex()
+
+	collection := m.Client.Database(m.DatabaseName).Collection("recipes")
+	if _, err := collection.InsertOne(ctx, recipeDocument{Household: m.household, Recipe: recipe}); err != nil {
+		fmt.Printf("Error adding recipe: %s\n", err)
+		return recipes.Recipe{}, err
+	}
+	return recipe, nil
+}
+
+// UpdateRecipe replaces the stored recipe with the same IDHex
+func (m *MongoDB) UpdateRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return recipes.Recipe{}, err
+	}
+	id, err := primitive.ObjectIDFromHex(recipe.IDHex)
+	if err != nil {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	recipe.ID = id
+
+	collection := m.Client.Database(m.DatabaseName).Collection("recipes")
+	result, err := collection.ReplaceOne(ctx, m.householdFilter(bson.E{Key: "IDHex", Value: recipe.IDHex}),
+		recipeDocument{Household: m.household, Recipe: recipe})
+	if err != nil {
+		fmt.Printf("Error updating recipe: %s\n", err)
+		return recipes.Recipe{}, err
+	}
+	if result.MatchedCount == 0 {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	return recipe, nil
+}
+
+// DeleteRecipe removes a recipe from the library. Meals that used it keep
+// their text.
+func (m *MongoDB) DeleteRecipe(ctx context.Context, IDHex string) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("recipes")
+	result, err := collection.DeleteOne(ctx, m.householdFilter(bson.E{Key: "IDHex", Value: IDHex}))
+	if err != nil {
+		fmt.Printf("Error deleting recipe: %s\n", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetShoppingListItemCategory sets the aisle category of a shopping list item
+func (m *MongoDB) SetShoppingListItemCategory(ctx context.Context, itemId string, category string) (models.ShoppingListItem, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	filter, err := m.listFilter(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "ShoppingList.$[element].Category", Value: category}}}, incItemVersion}
+	options := options.UpdateOptions{
+		ArrayFilters: &options.ArrayFilters{
+			Filters: []interface{}{bson.D{{Key: "element.IDHex", Value: itemId}}},
+		},
+	}
+	if _, err := collection.UpdateOne(ctx, filter, update, &options); err != nil {
+		fmt.Printf("Error updating shopping list item category: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return m.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// categoryDocument is how a learned category is stored, keyed by household
+// and item name
+type categoryDocument struct {
+	Household string `bson:"Household,omitempty"`
+	Name      string `bson:"Name"`
+	Category  string `bson:"Category"`
+}
+
+// GetCategoryDictionary retrieves the learned item name to category dictionary
+func (m *MongoDB) GetCategoryDictionary(ctx context.Context) (map[string]string, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("categories")
+
+	cursor, err := collection.Find(ctx, m.householdFilter())
+	if err != nil {
+		fmt.Printf("Error finding categories: %s\n", err)
+		return nil, err
+	}
+	var documents []categoryDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		fmt.Printf("Error decoding categories: %s\n", err)
+		return nil, err
+	}
+	dictionary := make(map[string]string, len(documents))
+	for _, document := range documents {
+		dictionary[document.Name] = document.Category
+	}
+	return dictionary, nil
+}
+
+// LearnCategory remembers the category of items called itemName. An empty
+// category forgets it.
+func (m *MongoDB) LearnCategory(ctx context.Context, itemName string, category string) error {
+	key := models.CategoryKey(itemName)
+	if key == "" {
+		return fmt.Errorf("item name cannot be empty")
+	}
+
+	collection := m.Client.Database(m.DatabaseName).Collection("categories")
+	filter := m.householdFilter(bson.E{Key: "Name", Value: key})
+	var err error
+	if category == "" {
+		_, err = collection.DeleteOne(ctx, filter)
+	} else {
+		_, err = collection.ReplaceOne(ctx, filter,
+			categoryDocument{Household: m.household, Name: key, Category: category}, options.Replace().SetUpsert(true))
+	}
+	if err != nil {
+		fmt.Printf("Error saving category: %s\n", err)
+	}
+	return err
+}
+
+// storeDocument is how a store is stored, keyed by household and
+// models.StoreKey
+type storeDocument struct {
+	Household    string `bson:"Household,omitempty"`
+	Key          string `bson:"Key"`
+	models.Store `bson:",inline"`
+}
+
+// GetStores retrieves every store, sorted by name
+func (m *MongoDB) GetStores(ctx context.Context) ([]models.Store, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("stores")
+
+	cursor, err := collection.Find(ctx, m.householdFilter())
+	if err != nil {
+		fmt.Printf("Error finding stores: %s\n", err)
+		return nil, err
+	}
+	var documents []storeDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		fmt.Printf("Error decoding stores: %s\n", err)
+		return nil, err
+	}
+	stores := make([]models.Store, 0, len(documents))
+	for _, document := range documents {
+		stores = append(stores, document.Store)
+	}
+	models.SortStores(stores)
+	return stores, nil
+}
+
+// SaveStore adds a store, or replaces the store with the same name
+func (m *MongoDB) SaveStore(ctx context.Context, store models.Store) (models.Store, error) {
+	if err := store.Validate(); err != nil {
+		return models.Store{}, err
+	}
+	store.Name = strings.TrimSpace(store.Name)
+	if store.CategoryOrder == nil {
+		store.CategoryOrder = []string{}
+	}
+
+	collection := m.Client.Database(m.DatabaseName).Collection("stores")
+	key := models.StoreKey(store.Name)
+	if _, err := collection.ReplaceOne(ctx, m.householdFilter(bson.E{Key: "Key", Value: key}),
+		storeDocument{Household: m.household, Key: key, Store: store}, options.Replace().SetUpsert(true)); err != nil {
+		fmt.Printf("Error saving store: %s\n", err)
+		return models.Store{}, err
+	}
+	return store, nil
+}
+
+// DeleteStore removes the store called name
+func (m *MongoDB) DeleteStore(ctx context.Context, name string) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("stores")
+	result, err := collection.DeleteOne(ctx, m.householdFilter(bson.E{Key: "Key", Value: models.StoreKey(name)}))
+	if err != nil {
+		fmt.Printf("Error deleting store: %s\n", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// tickDocument is how a tick event is stored, along with its household
+type tickDocument struct {
+	Household        string `bson:"Household,omitempty"`
+	models.TickEvent `bson:",inline"`
+}
+
+// RecordTick remembers that an item was ticked off
+func (m *MongoDB) RecordTick(ctx context.Context, event models.TickEvent) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("tick-events")
+	if _, err := collection.InsertOne(ctx, tickDocument{Household: m.household, TickEvent: event}); err != nil {
+		fmt.Printf("Error recording tick: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetTickEvents retrieves every recorded tick, oldest first
+func (m *MongoDB) GetTickEvents(ctx context.Context) ([]models.TickEvent, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("tick-events")
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "TickedAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, m.householdFilter(), findOptions)
+	if err != nil {
+		fmt.Printf("Error finding tick events: %s\n", err)
+		return nil, err
+	}
+	events := []models.TickEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		fmt.Printf("Error decoding tick events: %s\n", err)
+		return nil, err
+	}
+	for i := range events {
+		events[i].TickedAt = events[i].TickedAt.UTC()
+	}
+	return events, nil
+}
+
+// walkOrderDocument is how an item's learned walk position is stored, keyed
+// by household and item name
+type walkOrderDocument struct {
+	Household string  `bson:"Household,omitempty"`
+	Item      string  `bson:"Item"`
+	Position  float64 `bson:"Position"`
+}
+
+// GetWalkOrder retrieves the learned walk order
+func (m *MongoDB) GetWalkOrder(ctx context.Context) (map[string]float64, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("walk-order")
+
+	cursor, err := collection.Find(ctx, m.householdFilter())
+	if err != nil {
+		fmt.Printf("Error finding walk order: %s\n", err)
+		return nil, err
+	}
+	var documents []walkOrderDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		fmt.Printf("Error decoding walk order: %s\n", err)
+		return nil, err
+	}
+	order := make(map[string]float64, len(documents))
+	for _, document := range documents {
+		order[document.Item] = document.Position
+	}
+	return order, nil
+}
+
+// SaveWalkOrder replaces the learned walk order
+func (m *MongoDB) SaveWalkOrder(ctx context.Context, order map[string]float64) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("walk-order")
+
+	if _, err := collection.DeleteMany(ctx, m.householdFilter()); err != nil {
+		fmt.Printf("Error saving walk order: %s\n", err)
+		return err
+	}
+	if len(order) == 0 {
+		return nil
+	}
+	documents := make([]interface{}, 0, len(order))
+	for item, position := range order {
+		documents = append(documents, walkOrderDocument{Household: m.household, Item: item, Position: position})
+	}
+	if _, err := collection.InsertMany(ctx, documents); err != nil {
+		fmt.Printf("Error saving walk order: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// changeDocument is how a recorded change is stored, along with its household
+type changeDocument struct {
+	Household     string `bson:"Household,omitempty"`
+	models.Change `bson:",inline"`
+}
+
+// RecordChange adds a change to the history
+func (m *MongoDB) RecordChange(ctx context.Context, change models.Change) (models.Change, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("changes")
+	change.ID = primitive.NewObjectID()
+	change.IDHex = change.ID.Hex()
+	if _, err := collection.InsertOne(ctx, changeDocument{Household: m.household, Change: change}); err != nil {
+		fmt.Printf("Error recording change: %s\n", err)
+		return models.Change{}, err
+	}
+	return change, nil
+}
+
+// UpdateChange saves the values and undone state of a recorded change
+func (m *MongoDB) UpdateChange(ctx context.Context, change models.Change) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("changes")
+	id, err := primitive.ObjectIDFromHex(change.IDHex)
+	if err != nil {
+		return ErrNotFound
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "Before", Value: change.Before},
+		{Key: "After", Value: change.After},
+		{Key: "Undone", Value: change.Undone},
+	}}}
+	result, err := collection.UpdateOne(ctx, m.householdFilter(bson.E{Key: "_id", Value: id}), update)
+	if err != nil {
+		fmt.Printf("Error updating change: %s\n", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetChange retrieves a recorded change by its hex ID
+func (m *MongoDB) GetChange(ctx context.Context, IDHex string) (models.Change, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("changes")
+	id, err := primitive.ObjectIDFromHex(IDHex)
+	if err != nil {
+		return models.Change{}, ErrNotFound
+	}
+	var change models.Change
+	err = collection.FindOne(ctx, m.householdFilter(bson.E{Key: "_id", Value: id})).Decode(&change)
+	if err == mongo.ErrNoDocuments {
+		return models.Change{}, ErrNotFound
+	}
+	if err != nil {
+		fmt.Printf("Error finding change: %s\n", err)
+		return models.Change{}, err
+	}
+	change.IDHex = change.ID.Hex()
+	change.Time = change.Time.UTC()
+	return change, nil
+}
+
+// GetChanges retrieves the latest changes to a list, or to the meal plan when
+// listID is "", newest first
+func (m *MongoDB) GetChanges(ctx context.Context, listID string, limit int) ([]models.Change, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("changes")
+
+	list := bson.E{Key: "ListID", Value: listID}
+	if listID == "" {
+		list = bson.E{Key: "ListID", Value: bson.D{{Key: "$exists", Value: false}}}
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "Time", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, m.householdFilter(list), findOptions)
+	if err != nil {
+		fmt.Printf("Error finding changes: %s\n", err)
+		return nil, err
+	}
+	changes := []models.Change{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		fmt.Printf("Error decoding changes: %s\n", err)
+		return nil, err
+	}
+	for i := range changes {
+		changes[i].IDHex = changes[i].ID.Hex()
+		changes[i].Time = changes[i].Time.UTC()
+	}
+	return changes, nil
+}
+
+// ForHousehold returns the data of the household with IDHex householdID. It
+// shares this store's client, so only the store from NewMongoDB is closed.
+func (m *MongoDB) ForHousehold(householdID string) DBInterface {
+	return &MongoDB{Client: m.Client, DatabaseName: m.DatabaseName, household: householdID, view: true}
+}
+
+// ForShoppingList returns a view of this household whose shopping list item
+// calls use the list with IDHex listID
+func (m *MongoDB) ForShoppingList(listID string) DBInterface {
+	return &MongoDB{Client: m.Client, DatabaseName: m.DatabaseName, household: m.household, list: listID, view: true}
+}
+
+// shoppingListDocument is how a shopping list is stored, along with its
+// household
+type shoppingListDocument struct {
+	Household           string `bson:"Household,omitempty"`
+	models.ShoppingList `bson:",inline"`
+}
+
+// listFilter matches the shopping list item calls use
+func (m *MongoDB) listFilter(ctx context.Context) (bson.D, error) {
+	id, err := m.listObjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.householdFilter(bson.E{Key: "_id", Value: id}), nil
+}
+
+// listObjectID returns the ID of the shopping list item calls use, creating
+// the household's first list if it has none
+func (m *MongoDB) listObjectID(ctx context.Context) (primitive.ObjectID, error) {
+	if m.list == "" {
+		lists, err := m.GetShoppingLists(ctx)
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		return lists[0].ID, nil
+	}
+	id, err := primitive.ObjectIDFromHex(m.list)
+	if err != nil {
+		return primitive.NilObjectID, ErrNotFound
+	}
+	count, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").CountDocuments(ctx, m.householdFilter(bson.E{Key: "_id", Value: id}))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if count == 0 {
+		return primitive.NilObjectID, ErrNotFound
+	}
+	return id, nil
+}
+
+// GetShoppingLists returns every shopping list, in the order they were made,
+// creating the first list when there are none
+func (m *MongoDB) GetShoppingLists(ctx context.Context) ([]models.ShoppingList, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+
+	// Matches any of the household's lists, so only inserts when there are none
+	create := bson.D{{Key: "$setOnInsert", Value: bson.D{
+		{Key: "Name", Value: models.DefaultShoppingListName},
+		{Key: "Archived", Value: false},
+		{Key: "ShoppingList", Value: bson.A{}},
+		{Key: "SortOrder", Value: bson.A{}},
+	}}}
+	if _, err := collection.UpdateOne(ctx, m.householdFilter(), create, options.Update().SetUpsert(true)); err != nil {
+		fmt.Printf("Error creating shopping list: %s\n", err)
+		return nil, err
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.D{{Key: "ShoppingList", Value: 0}, {Key: "SortOrder", Value: 0}})
+	cursor, err := collection.Find(ctx, m.householdFilter(), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var lists []models.ShoppingList
+	for cursor.Next(ctx) {
+		var document shoppingListDocument
+		if err := cursor.Decode(&document); err != nil {
+			return nil, err
+		}
+		document.IDHex = document.ID.Hex()
+		lists = append(lists, document.ShoppingList)
+	}
+	return lists, cursor.Err()
+}
+
+// CreateShoppingList adds an empty shopping list called name
+func (m *MongoDB) CreateShoppingList(ctx context.Context, name string) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	// Make sure the household's first list comes before this one
+	if _, err := m.GetShoppingLists(ctx); err != nil {
+		return models.ShoppingList{}, err
+	}
+
+	list := models.ShoppingList{
+		ID:           primitive.NewObjectID(),
+		Name:         name,
+		ShoppingList: []models.ShoppingListItem{},
+		SortOrder:    []primitive.ObjectID{},
+	}
+	list.IDHex = list.ID.Hex()
+	document := shoppingListDocument{Household: m.household, ShoppingList: list}
+	if _, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").InsertOne(ctx, document); err != nil {
+		fmt.Printf("Error creating shopping list: %s\n", err)
+		return models.ShoppingList{}, err
+	}
+	return list, nil
+}
+
+// RenameShoppingList changes the name of the shopping list with IDHex
+func (m *MongoDB) RenameShoppingList(ctx context.Context, IDHex string, name string) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	return m.updateShoppingList(ctx, IDHex, bson.E{Key: "Name", Value: name})
+}
+
+// ArchiveShoppingList archives the shopping list with IDHex, or brings it
+// back when archived is false
+func (m *MongoDB) ArchiveShoppingList(ctx context.Context, IDHex string, archived bool) (models.ShoppingList, error) {
+	return m.updateShoppingList(ctx, IDHex, bson.E{Key: "Archived", Value: archived})
+}
+
+// EditShoppingList replaces the name and archived status of the shopping
+// list with list.IDHex, unless it has changed since list was read
+func (m *MongoDB) EditShoppingList(ctx context.Context, list models.ShoppingList) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(list.Name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	id, err := primitive.ObjectIDFromHex(list.IDHex)
+	if err != nil {
+		return models.ShoppingList{}, ErrNotFound
+	}
+	var document shoppingListDocument
+	err = m.Client.Database(m.DatabaseName).Collection("shopping-lists").FindOneAndUpdate(ctx,
+		m.householdFilter(bson.E{Key: "_id", Value: id}, versionCondition("Version", list.Version)),
+		bson.D{{Key: "$set", Value: bson.D{{Key: "Name", Value: name}, {Key: "Archived", Value: list.Archived}}}, incListVersion},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return listConflict(ctx, m, list.IDHex)
+	}
+	if err != nil {
+		fmt.Printf("Error updating shopping list: %s\n", err)
+		return models.ShoppingList{}, err
+	}
+	document.IDHex = document.ID.Hex()
+	return document.ShoppingList, nil
+}
+
+// updateShoppingList sets field on the shopping list with IDHex and returns it
+func (m *MongoDB) updateShoppingList(ctx context.Context, IDHex string, field bson.E) (models.ShoppingList, error) {
+	id, err := primitive.ObjectIDFromHex(IDHex)
+	if err != nil {
+		return models.ShoppingList{}, ErrNotFound
+	}
+	var document shoppingListDocument
+	err = m.Client.Database(m.DatabaseName).Collection("shopping-lists").FindOneAndUpdate(ctx,
+		m.householdFilter(bson.E{Key: "_id", Value: id}),
+		bson.D{{Key: "$set", Value: bson.D{field}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return models.ShoppingList{}, ErrNotFound
+	}
+	if err != nil {
+		fmt.Printf("Error updating shopping list: %s\n", err)
+		return models.ShoppingList{}, err
+	}
+	document.IDHex = document.ID.Hex()
+	return document.ShoppingList, nil
+}
+
+// DeleteShoppingList removes the shopping list with IDHex and its items
+func (m *MongoDB) DeleteShoppingList(ctx context.Context, IDHex string) error {
+	id, err := primitive.ObjectIDFromHex(IDHex)
+	if err != nil {
+		return ErrNotFound
+	}
+	result, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").DeleteOne(ctx, m.householdFilter(bson.E{Key: "_id", Value: id}))
+	if err != nil {
+		fmt.Printf("Error deleting shopping list: %s\n", err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MoveShoppingListItem moves an item from this shopping list to the end of
+// the list with IDHex listID
+func (m *MongoDB) MoveShoppingListItem(ctx context.Context, itemId string, listID string) (models.ShoppingListItem, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("shopping-lists")
+	fromID, err := m.listObjectID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	target := &MongoDB{Client: m.Client, DatabaseName: m.DatabaseName, household: m.household, list: listID}
+	toID, err := target.listObjectID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	item, err := m.GetShoppingListItemFromIDHex(ctx, itemId)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if item.IDHex == "" {
+		return models.ShoppingListItem{}, ErrNotFound
+	}
+	if fromID == toID {
+		return item, nil
+	}
+
+	// The item leaves one list and joins the other in a transaction, so it is
+	// never on both or neither
+	err = m.transaction(ctx, func(ctx mongo.SessionContext) error {
+		pull := bson.D{{Key: "$pull", Value: bson.D{
+			{Key: "ShoppingList", Value: bson.D{{Key: "IDHex", Value: itemId}}},
+			{Key: "SortOrder", Value: bson.D{{Key: "$in", Value: bson.A{item.ID, itemId}}}},
+		}}, incListVersion}
+		result, err := collection.UpdateOne(ctx, m.householdFilter(bson.E{Key: "_id", Value: fromID}, bson.E{Key: "ShoppingList.IDHex", Value: itemId}), pull)
+		if err != nil {
+			return fmt.Errorf("removing item from list %s: %w", fromID.Hex(), err)
+		}
+		if result.ModifiedCount == 0 {
+			return ErrNotFound
+		}
+		push := bson.D{{Key: "$push", Value: bson.D{
+			{Key: "ShoppingList", Value: item},
+			{Key: "SortOrder", Value: item.ID},
+		}}, incListVersion}
+		result, err = collection.UpdateOne(ctx, m.householdFilter(bson.E{Key: "_id", Value: toID}), push)
+		if err != nil {
+			return fmt.Errorf("adding item to list %s: %w", toID.Hex(), err)
+		}
+		if result.MatchedCount == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error moving shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return target.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// transaction runs fn in a transaction, so that its writes are applied
+// together or not at all. fn may be run again if the transaction meets a
+// transient error. Transactions need a replica set, as on Atlas.
+func (m *MongoDB) transaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := m.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// CreateHousehold adds a household. The first household created takes over
+// the data saved before households existed.
+func (m *MongoDB) CreateHousehold(ctx context.Context, household models.Household) (models.Household, error) {
+	if err := household.Validate(); err != nil {
+		return models.Household{}, err
+	}
+	household.ID = primitive.NewObjectID()
+	household.IDHex = household.ID.Hex()
+	household.Name = strings.TrimSpace(household.Name)
+
+	database := m.Client.Database(m.DatabaseName)
+	households, err := database.Collection("households").CountDocuments(ctx, bson.D{})
+	if err != nil {
+		return models.Household{}, err
+	}
+	if _, err := database.Collection("households").InsertOne(ctx, household); err != nil {
+		fmt.Printf("Error adding household: %s\n", err)
+		return models.Household{}, err
+	}
+	if households > 0 {
+		return household, nil
+	}
+
+	unowned := bson.D{{Key: "Household", Value: bson.D{{Key: "$exists", Value: false}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "Household", Value: household.IDHex}}}}
+	for _, collection := range householdCollections {
+		if _, err := database.Collection(collection).UpdateMany(ctx, unowned, update); err != nil {
+			fmt.Printf("Error claiming %s: %s\n", collection, err)
+			return models.Household{}, err
+		}
+	}
+	return household, nil
+}
+
+// GetHousehold retrieves a household by its hex ID
+func (m *MongoDB) GetHousehold(ctx context.Context, IDHex string) (models.Household, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("households")
+
+	var household models.Household
+	err := collection.FindOne(ctx, bson.D{{Key: "IDHex", Value: IDHex}}).Decode(&household)
+	if err == mongo.ErrNoDocuments {
+		return models.Household{}, ErrNotFound
+	}
+	return household, err
+}
+
+// GetHouseholds retrieves every household, sorted by name
+func (m *MongoDB) GetHouseholds(ctx context.Context) ([]models.Household, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("households")
+
+	cursor, err := collection.Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error finding households: %s\n", err)
+		return nil, err
+	}
+	households := []models.Household{}
+	if err := cursor.All(ctx, &households); err != nil {
+		fmt.Printf("Error decoding households: %s\n", err)
+		return nil, err
+	}
+	models.SortHouseholds(households)
+	return households, nil
+}
+
+// CreateUser adds a user. It returns ErrExists when the email is taken.
+func (m *MongoDB) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	user.Email = models.NormaliseEmail(user.Email)
+	if err := user.Validate(); err != nil {
+		return models.User{}, err
+	}
+	user.ID = primitive.NewObjectID()
+	user.IDHex = user.ID.Hex()
+
+	collection := m.Client.Database(m.DatabaseName).Collection("users")
+	_, err := collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return models.User{}, ErrExists
+	}
+	if err != nil {
+		fmt.Printf("Error adding user: %s\n", err)
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// CreateOwnedHousehold adds a household and owner, its first user, in one
+// transaction, so that no household is left without its owner
+func (m *MongoDB) CreateOwnedHousehold(ctx context.Context, household models.Household, owner models.User) (models.Household, models.User, error) {
+	var created models.Household
+	var user models.User
+	err := m.transaction(ctx, func(ctx mongo.SessionContext) error {
+		var err error
+		if created, err = m.CreateHousehold(ctx, household); err != nil {
+			return err
+		}
+		owner.HouseholdID = created.IDHex
+		user, err = m.CreateUser(ctx, owner)
+		return err
+	})
+	if err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	return created, user, nil
+}
+
+// GetUser retrieves a user by their hex ID
+func (m *MongoDB) GetUser(ctx context.Context, IDHex string) (models.User, error) {
+	return m.findUser(ctx, bson.D{{Key: "IDHex", Value: IDHex}})
+}
+
+// GetUserByEmail retrieves the user with an email address, ignoring case
+func (m *MongoDB) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return m.findUser(ctx, bson.D{{Key: "Email", Value: models.NormaliseEmail(email)}})
+}
+
+// findUser retrieves the user matching filter
+func (m *MongoDB) findUser(ctx context.Context, filter bson.D) (models.User, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("users")
+
+	var user models.User
+	err := collection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return models.User{}, ErrNotFound
+	}
+	return withDefaultRole(user), err
+}
+
+// withDefaultRole makes users from before roles, who each created their
+// household, its owners
+func withDefaultRole(user models.User) models.User {
+	if user.Role == "" {
+		user.Role = models.RoleOwner
+	}
+	return user
+}
+
+// GetHouseholdUsers retrieves the users in a household, sorted by name
+func (m *MongoDB) GetHouseholdUsers(ctx context.Context, householdID string) ([]models.User, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("users")
+
+	cursor, err := collection.Find(ctx, bson.D{{Key: "HouseholdID", Value: householdID}})
+	if err != nil {
+		fmt.Printf("Error finding users: %s\n", err)
+		return nil, err
+	}
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		fmt.Printf("Error decoding users: %s\n", err)
+		return nil, err
+	}
+	for i := range users {
+		users[i] = withDefaultRole(users[i])
+	}
+	models.SortUsers(users)
+	return users, nil
+}
+
+// SetUserRole changes what a user may do in their household
+func (m *MongoDB) SetUserRole(ctx context.Context, IDHex string, role models.Role) error {
+	if _, err := models.ParseRole(string(role)); err != nil {
+		return err
+	}
+	collection := m.Client.Database(m.DatabaseName).Collection("users")
+	result, err := collection.UpdateOne(ctx, bson.D{{Key: "IDHex", Value: IDHex}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "Role", Value: role}}}})
+	if err != nil {
+		fmt.Printf("Error setting user role: %s\n", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateSession remembers a signed-in browser
+func (m *MongoDB) CreateSession(ctx context.Context, session models.Session) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("sessions")
+	if _, err := collection.InsertOne(ctx, session); err != nil {
+		fmt.Printf("Error adding session: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetSession retrieves the session with a token hash
+func (m *MongoDB) GetSession(ctx context.Context, tokenHash string) (models.Session, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("sessions")
+
+	var session models.Session
+	err := collection.FindOne(ctx, bson.D{{Key: "_id", Value: tokenHash}}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return models.Session{}, ErrNotFound
+	}
+	session.ExpiresAt = session.ExpiresAt.UTC()
+	return session, err
+}
+
+// DeleteSession signs a browser out. Deleting a missing session is not an
+// error.
+func (m *MongoDB) DeleteSession(ctx context.Context, tokenHash string) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("sessions")
+	if _, err := collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: tokenHash}}); err != nil {
+		fmt.Printf("Error deleting session: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// CreateInvite stores an invite to join a household
+func (m *MongoDB) CreateInvite(ctx context.Context, invite models.Invite) error {
+	collection := m.Client.Database(m.DatabaseName).Collection("invites")
+	if _, err := collection.InsertOne(ctx, invite); err != nil {
+		fmt.Printf("Error adding invite: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetInvite retrieves the invite with a code hash
+func (m *MongoDB) GetInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("invites")
+
+	var invite models.Invite
+	err := collection.FindOne(ctx, bson.D{{Key: "_id", Value: codeHash}}).Decode(&invite)
+	if err == mongo.ErrNoDocuments {
+		return models.Invite{}, ErrNotFound
+	}
+	invite.ExpiresAt = invite.ExpiresAt.UTC()
+	return invite, err
+}
+
+// ClaimInvite removes the invite with a code hash and returns it
+func (m *MongoDB) ClaimInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	collection := m.Client.Database(m.DatabaseName).Collection("invites")
+
+	var invite models.Invite
+	err := collection.FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: codeHash}}).Decode(&invite)
+	if err == mongo.ErrNoDocuments {
+		return models.Invite{}, ErrNotFound
+	}
+	if err != nil {
+		fmt.Printf("Error claiming invite: %s\n", err)
+		return models.Invite{}, err
+	}
+	invite.ExpiresAt = invite.ExpiresAt.UTC()
+	return invite, nil
+}
+
+// VerifyIntegrity checks the order of every household's shopping lists
+func (m *MongoDB) VerifyIntegrity(ctx context.Context) (IntegrityReport, error) {
+	return m.checkIntegrity(ctx, false)
+}
+
+// Repair puts right the order of every household's shopping lists
+func (m *MongoDB) Repair(ctx context.Context) (IntegrityReport, error) {
+	return m.checkIntegrity(ctx, true)
+}
+
+// checkIntegrity checks, and when repair is set fixes, the order of every
+// list in the database
+func (m *MongoDB) checkIntegrity(ctx context.Context, repair bool) (IntegrityReport, error) {
+	var report IntegrityReport
+	cursor, err := m.Client.Database(m.DatabaseName).Collection("shopping-lists").Find(ctx, bson.D{},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return report, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var document struct {
+			ID           primitive.ObjectID        `bson:"_id"`
+			Household    string                    `bson:"Household"`
+			Name         string                    `bson:"Name"`
+			ShoppingList []models.ShoppingListItem `bson:"ShoppingList"`
+			SortOrder    bson.A                    `bson:"SortOrder"`
+		}
+		if err := cursor.Decode(&document); err != nil {
+			return report, err
+		}
+		order, malformed := parseSortOrder(document.SortOrder)
+		problems := len(report.Problems)
+		list := ListIntegrity{Household: document.Household, ListID: document.ID.Hex(), Name: document.Name, Malformed: malformed}
+		order = report.add(list, document.ShoppingList, order)
+		if repair && len(report.Problems) > problems {
+			if err := m.saveSortOrder(ctx, document.ID, document.SortOrder, order); err != nil {
+				return report, fmt.Errorf("repairing list %s: %w", document.ID.Hex(), err)
+			}
+		}
+	}
+	return report, cursor.Err()
+}
+
+// Close closes the MongoDB connection
+func (m *MongoDB) Close() {
+	if m.view {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer cancel()
+	m.Client.Disconnect(ctx)
 }
\ No newline at end of file
@@ -1,7 +1,16 @@
 package db
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // Test NewMongoDB function
@@ -14,6 +23,12 @@ func TestNewMongoDB(t *testing.T) {
 // In a real implementation, you would need to mock the mongo.Client and its behavior
 // or use a real test database
 
+func TestMongoHelloTransactions(t *testing.T) {
+	assert.False(t, mongoHello{}.transactions(), "a standalone server")
+	assert.True(t, mongoHello{SetName: "rs0"}.transactions(), "a replica set member")
+	assert.True(t, mongoHello{Msg: "isdbgrid"}.transactions(), "a sharded cluster")
+}
+
 func TestGetShoppingList(t *testing.T) {
 	t.Skip("Skipping as this requires a real MongoDB connection or more complex mocking")
 }
@@ -48,4 +63,67 @@ func TestGetMealPlan(t *testing.T) {
 
 func TestSortShoppingList(t *testing.T) {
 	t.Skip("Skipping as this requires a real MongoDB connection or more complex mocking")
-}
\ No newline at end of file
+}
+
+// TestMongoDBShoppingListChangesAreAtomic makes the server fail updates part
+// way through changes. It runs when GO_SHOPPING_MONGO_TEST_URI is set, as it
+// is in CI and by make test-mongo, which must name a replica set with test
+// commands enabled.
+func TestMongoDBShoppingListChangesAreAtomic(t *testing.T) {
+	ctx := context.Background()
+	uri := os.Getenv("GO_SHOPPING_MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("Skipping as GO_SHOPPING_MONGO_TEST_URI is not set")
+	}
+	dbName := fmt.Sprintf("GoShopping-atomic-%d", time.Now().UnixNano())
+	store, err := NewMongoDB(ctx, uri, dbName)
+	require.NoError(t, err)
+	defer func() {
+		store.Client.Database(dbName).Drop(ctx)
+		store.Close()
+	}()
+	items := addItems(t, store, "Eggs", "Bread")
+	hardware, err := store.CreateShoppingList(ctx, "Hardware")
+	require.NoError(t, err)
+
+	// failUpdates fails the updates after the first skip, until turned off
+	admin := store.Client.Database("admin")
+	failUpdates := func(skip int) {
+		err := admin.RunCommand(ctx, bson.D{
+			{Key: "configureFailPoint", Value: "failCommand"},
+			{Key: "mode", Value: bson.D{{Key: "skip", Value: skip}}},
+			{Key: "data", Value: bson.D{{Key: "failCommands", Value: bson.A{"update"}}, {Key: "errorCode", Value: 2}}},
+		}).Err()
+		if err != nil {
+			t.Fatalf("GO_SHOPPING_MONGO_TEST_URI needs test commands enabled (--setParameter enableTestCommands=1): %s", err)
+		}
+	}
+	stopFailing := func() {
+		require.NoError(t, admin.RunCommand(ctx, bson.D{
+			{Key: "configureFailPoint", Value: "failCommand"},
+			{Key: "mode", Value: "off"},
+		}).Err())
+	}
+
+	// The item leaves its list, then joining the other fails. Choosing the
+	// list up front leaves the move's own two updates as the only ones.
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	failUpdates(1)
+	_, err = store.ForShoppingList(lists[0].IDHex).MoveShoppingListItem(ctx, items[1].IDHex, hardware.IDHex)
+	stopFailing()
+	assert.Error(t, err)
+	assert.Equal(t, []string{"Eggs", "Bread"}, itemNames(t, store), "the item stays where it was")
+	assert.Empty(t, itemNames(t, store.ForShoppingList(hardware.IDHex)))
+
+	failUpdates(0)
+	_, err = store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "Cheese"})
+	assert.Error(t, err)
+	assert.Error(t, store.DeleteShoppingListItem(ctx, items[0].IDHex))
+	stopFailing()
+	assert.Equal(t, []string{"Eggs", "Bread"}, itemNames(t, store))
+
+	report, err := store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%v", report.Problems)
+}
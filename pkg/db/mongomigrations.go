@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoMigrations are the versioned migrations of MongoDB databases. Add new
+// ones to the end, with the next version; never change one once released.
+var mongoMigrations = []Migration[*mongo.Database]{
+	{
+		Version:     1,
+		Description: "store shopping list SortOrders as ObjectIDs",
+		Up:          normaliseSortOrders,
+		// Every version reads ObjectIDs, so there is nothing to undo
+		Down: func(context.Context, *mongo.Database) error { return nil },
+	},
+	{
+		Version:     2,
+		Description: "remove the unused Order field from shopping list items",
+		Up:          removeItemOrders,
+		Down:        restoreItemOrders,
+	},
+	{
+		Version:     3,
+		Description: "index users by email, and expire old sessions and invites",
+		Up:          createAccountIndexes,
+		Down:        dropAccountIndexes,
+	},
+	{
+		Version:     4,
+		Description: "name the shopping lists saved before lists had names",
+		Up:          nameShoppingLists,
+		// Versions without list names ignore the Name, so there is nothing
+		// to undo
+		Down: func(context.Context, *mongo.Database) error { return nil },
+	},
+	{
+		Version:     5,
+		Description: "copy the names categories, stores and walk order are keyed by into a field",
+		Up:          copyKeyedNames,
+		Down:        removeKeyedNames,
+	},
+}
+
+// keyedCollections maps the collections that were once keyed by name to the
+// field that name now lives in
+var keyedCollections = map[string]string{
+	"categories": "Name",
+	"stores":     "Key",
+	"walk-order": "Item",
+}
+
+// expiringCollections lists the collections whose documents are removed once
+// their ExpiresAt has passed
+var expiringCollections = []string{"sessions", "invites"}
+
+// MigrationStatuses lists the database's migrations, oldest first
+func (m *MongoDB) MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	return migrationStatuses(ctx, m.migrationLog(), mongoMigrations)
+}
+
+// MigrateUp applies the database's pending migrations
+func (m *MongoDB) MigrateUp(ctx context.Context) ([]MigrationStatus, error) {
+	return migrateUp(ctx, m.Client.Database(m.DatabaseName), m.migrationLog(), mongoMigrations, time.Now)
+}
+
+// MigrateDown undoes the database's migrations newer than version
+func (m *MongoDB) MigrateDown(ctx context.Context, version int) ([]MigrationStatus, error) {
+	return migrateDown(ctx, m.Client.Database(m.DatabaseName), m.migrationLog(), mongoMigrations, version)
+}
+
+// checkSchema makes sure the database has had no migrations newer than
+// those this version knows of, which may have changed how it saves data.
+// Pending migrations are left to the migrate command, or to the server as it
+// starts.
+func (m *MongoDB) checkSchema(ctx context.Context) error {
+	applied, err := m.migrationLog().applied(ctx)
+	if err != nil {
+		return fmt.Errorf("reading applied migrations: %w", err)
+	}
+	latest := mongoMigrations[len(mongoMigrations)-1].Version
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("the database %s has migration %d, newer than the latest this version knows of (%d)", m.DatabaseName, version, latest)
+		}
+	}
+	return nil
+}
+
+// migrationLog records the applied migrations in the migrations collection,
+// a document per version
+func (m *MongoDB) migrationLog() migrationLog {
+	return mongoMigrationLog{m.Client.Database(m.DatabaseName).Collection("migrations")}
+}
+
+type mongoMigrationLog struct {
+	collection *mongo.Collection
+}
+
+func (l mongoMigrationLog) applied(ctx context.Context) (map[int]time.Time, error) {
+	cursor, err := l.collection.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var documents []struct {
+		Version   int       `bson:"_id"`
+		AppliedAt time.Time `bson:"AppliedAt"`
+	}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(documents))
+	for _, document := range documents {
+		applied[document.Version] = document.AppliedAt
+	}
+	return applied, nil
+}
+
+func (l mongoMigrationLog) record(ctx context.Context, status MigrationStatus) error {
+	_, err := l.collection.ReplaceOne(ctx,
+		bson.D{{Key: "_id", Value: status.Version}},
+		bson.D{{Key: "_id", Value: status.Version}, {Key: "Description", Value: status.Description}, {Key: "AppliedAt", Value: status.AppliedAt}},
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+func (l mongoMigrationLog) forget(ctx context.Context, version int) error {
+	_, err := l.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: version}})
+	return err
+}
+
+// normaliseSortOrders rewrites the SortOrders holding hex strings, pushed by
+// older versions of AddShoppingListIdToShoppingListOrder, as ObjectIDs
+func normaliseSortOrders(ctx context.Context, database *mongo.Database) error {
+	collection := database.Collection("shopping-lists")
+	cursor, err := collection.Find(ctx, bson.D{},
+		options.Find().SetProjection(bson.D{{Key: "SortOrder", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var document struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			SortOrder bson.A             `bson:"SortOrder"`
+		}
+		if err := cursor.Decode(&document); err != nil {
+			return err
+		}
+		order, changed := normaliseSortOrder(document.SortOrder)
+		if !changed {
+			continue
+		}
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "SortOrder", Value: order}}}}
+		if _, err := collection.UpdateByID(ctx, document.ID, update); err != nil {
+			return fmt.Errorf("updating list %s: %w", document.ID.Hex(), err)
+		}
+	}
+	return cursor.Err()
+}
+
+// normaliseSortOrder returns order with hex strings parsed into ObjectIDs,
+// leaving out anything else that is not an ObjectID and any repeats, and
+// whether that changed it
+func normaliseSortOrder(order bson.A) ([]primitive.ObjectID, bool) {
+	ids := make([]primitive.ObjectID, 0, len(order))
+	seen := make(map[primitive.ObjectID]bool)
+	changed := false
+	for _, value := range order {
+		var id primitive.ObjectID
+		switch v := value.(type) {
+		case primitive.ObjectID:
+			id = v
+		case string:
+			changed = true
+			parsed, err := primitive.ObjectIDFromHex(v)
+			if err != nil {
+				continue
+			}
+			id = parsed
+		default:
+			changed = true
+			continue
+		}
+		if seen[id] {
+			changed = true
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, changed
+}
+
+// removeItemOrders unsets the Order field DeleteShoppingListItem wrote to
+// each item, which nothing reads; SortOrder is the order of a list
+func removeItemOrders(ctx context.Context, database *mongo.Database) error {
+	_, err := database.Collection("shopping-lists").UpdateMany(ctx,
+		bson.D{{Key: "ShoppingList.Order", Value: bson.D{{Key: "$exists", Value: true}}}},
+		bson.D{{Key: "$unset", Value: bson.D{{Key: "ShoppingList.$[].Order", Value: ""}}}})
+	return err
+}
+
+// restoreItemOrders numbers the items of each list from 1 in an Order
+// field, as DeleteShoppingListItem did
+func restoreItemOrders(ctx context.Context, database *mongo.Database) error {
+	numbered := bson.D{{Key: "$map", Value: bson.D{
+		{Key: "input", Value: bson.D{{Key: "$range", Value: bson.A{0, bson.D{{Key: "$size", Value: "$ShoppingList"}}}}}},
+		{Key: "as", Value: "i"},
+		{Key: "in", Value: bson.D{{Key: "$mergeObjects", Value: bson.A{
+			bson.D{{Key: "$arrayElemAt", Value: bson.A{"$ShoppingList", "$$i"}}},
+			bson.D{{Key: "Order", Value: bson.D{{Key: "$add", Value: bson.A{"$$i", 1}}}}},
+		}}}},
+	}}}
+	_, err := database.Collection("shopping-lists").UpdateMany(ctx,
+		bson.D{{Key: "ShoppingList", Value: bson.D{{Key: "$type", Value: "array"}}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "ShoppingList", Value: numbered}}}}})
+	return err
+}
+
+// createAccountIndexes makes each user's email unique, and has the server
+// remove sessions and invites once they expire
+func createAccountIndexes(ctx context.Context, database *mongo.Database) error {
+	if _, err := database.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "Email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("creating users index: %w", err)
+	}
+	for _, collection := range expiringCollections {
+		if _, err := database.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "ExpiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		}); err != nil {
+			return fmt.Errorf("creating %s index: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// dropAccountIndexes drops the indexes createAccountIndexes creates
+func dropAccountIndexes(ctx context.Context, database *mongo.Database) error {
+	if _, err := database.Collection("users").Indexes().DropOne(ctx, "Email_1"); err != nil {
+		return fmt.Errorf("dropping users index: %w", err)
+	}
+	for _, collection := range expiringCollections {
+		if _, err := database.Collection(collection).Indexes().DropOne(ctx, "ExpiresAt_1"); err != nil {
+			return fmt.Errorf("dropping %s index: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// nameShoppingLists gives the lists saved before lists had names the
+// default name
+func nameShoppingLists(ctx context.Context, database *mongo.Database) error {
+	_, err := database.Collection("shopping-lists").UpdateMany(ctx,
+		bson.D{{Key: "Name", Value: bson.D{{Key: "$exists", Value: false}}}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "Name", Value: models.DefaultShoppingListName}}}})
+	return err
+}
+
+// copyKeyedNames copies the names that categories, stores and walk order
+// documents were keyed by into a field, so that households can each have
+// their own
+func copyKeyedNames(ctx context.Context, database *mongo.Database) error {
+	for collection, field := range keyedCollections {
+		filter := bson.D{{Key: field, Value: bson.D{{Key: "$exists", Value: false}}}}
+		update := mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: field, Value: "$_id"}}}}}
+		if _, err := database.Collection(collection).UpdateMany(ctx, filter, update); err != nil {
+			return fmt.Errorf("migrating %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// removeKeyedNames removes the name fields that match the documents' keys,
+// as copyKeyedNames left them
+func removeKeyedNames(ctx context.Context, database *mongo.Database) error {
+	for collection, field := range keyedCollections {
+		filter := bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$" + field, "$_id"}}}}}
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: field, Value: ""}}}}
+		if _, err := database.Collection(collection).UpdateMany(ctx, filter, update); err != nil {
+			return fmt.Errorf("undoing %s: %w", collection, err)
+		}
+	}
+	return nil
+}
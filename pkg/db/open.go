@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JonClarke84/mealplannergo/pkg/config"
+)
+
+// Open connects to the storage backend selected by cfg.StorageDriver
+func Open(ctx context.Context, cfg *config.Config) (Store, error) {
+	switch cfg.StorageDriver {
+	case config.DriverMongoDB, "":
+		return NewMongoDB(ctx, cfg.MongoURI, cfg.DatabaseName)
+	case config.DriverSQLite:
+		return NewSQLiteDB(ctx, cfg.SQLitePath)
+	case config.DriverMemory:
+		return NewMemoryDB(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.StorageDriver)
+	}
+}
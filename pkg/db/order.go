@@ -0,0 +1,26 @@
+package db
+
+import (
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// orderShoppingList returns items in sortOrder. Items missing from sortOrder
+// go at the end, and IDs without an item are ignored; see
+// reconcileSortOrder.
+func orderShoppingList(items []models.ShoppingListItem, sortOrder []primitive.ObjectID) []models.ShoppingListItem {
+	// Create a map of item ID to ShoppingListItem for easy lookup
+	itemMap := make(map[primitive.ObjectID]models.ShoppingListItem)
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
+
+	// Sort the shopping list based on the SortOrder
+	reconciled, _ := reconcileSortOrder(items, sortOrder)
+	sortedShoppingList := make([]models.ShoppingListItem, 0, len(reconciled))
+	for _, id := range reconciled {
+		sortedShoppingList = append(sortedShoppingList, itemMap[id])
+	}
+
+	return sortedShoppingList
+}
@@ -0,0 +1,2069 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	// Register the pure-Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDB represents an embedded SQLite database stored in a single file.
+// Every row belongs to a household, named by its household_id column; the
+// SQLiteDB returned by NewSQLiteDB reads and writes rows with no household.
+type SQLiteDB struct {
+	DB        *sql.DB
+	Path      string
+	household string
+	// list is the IDHex of the shopping list item calls use, or "" for the
+	// household's first list
+	list string
+	// view is set on the stores returned by ForHousehold, which share DB
+	view bool
+}
+
+// Ensure SQLiteDB implements Store
+var _ Store = (*SQLiteDB)(nil)
+
+// sqliteSchema creates the tables used by SQLiteDB. Each item of a list has
+// its own sort_position; items found without one, or sharing one, are put in
+// order when the list is next read. Tables keyed by week or name are created
+// as they were before households, then rebuilt by migrateHouseholds.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS shopping_list_items (
+	id            TEXT PRIMARY KEY,
+	item          TEXT NOT NULL,
+	ticked        INTEGER NOT NULL DEFAULT 0,
+	sort_position INTEGER,
+	sources       TEXT NOT NULL DEFAULT '',
+	quantity      REAL NOT NULL DEFAULT 0,
+	unit          TEXT NOT NULL DEFAULT '',
+	note          TEXT NOT NULL DEFAULT '',
+	category      TEXT NOT NULL DEFAULT '',
+	version       INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS meal_plans (
+	week       TEXT PRIMARY KEY,
+	week_start TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS meal_plan_meals (
+	week     TEXT NOT NULL REFERENCES meal_plans (week) ON DELETE CASCADE,
+	day      TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (week, day)
+);
+CREATE TABLE IF NOT EXISTS meal_slots (
+	week TEXT NOT NULL,
+	day  TEXT NOT NULL,
+	slot TEXT NOT NULL,
+	meal TEXT NOT NULL DEFAULT '',
+	recipe_id TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (week, day, slot),
+	FOREIGN KEY (week, day) REFERENCES meal_plan_meals (week, day) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS recipes (
+	id          TEXT PRIMARY KEY,
+	title       TEXT NOT NULL,
+	servings    INTEGER NOT NULL DEFAULT 0,
+	ingredients TEXT NOT NULL DEFAULT '[]',
+	method      TEXT NOT NULL DEFAULT '[]',
+	tags        TEXT NOT NULL DEFAULT '[]'
+);
+CREATE TABLE IF NOT EXISTS categories (
+	name     TEXT PRIMARY KEY,
+	category TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS stores (
+	key            TEXT PRIMARY KEY,
+	name           TEXT NOT NULL,
+	category_order TEXT NOT NULL DEFAULT '[]'
+);
+CREATE TABLE IF NOT EXISTS tick_events (
+	item      TEXT NOT NULL,
+	ticked_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS walk_order (
+	item     TEXT PRIMARY KEY,
+	position REAL NOT NULL
+);
+CREATE TABLE IF NOT EXISTS changes (
+	id           TEXT PRIMARY KEY,
+	household_id TEXT NOT NULL DEFAULT '',
+	list_id      TEXT NOT NULL DEFAULT '',
+	user_id      TEXT NOT NULL DEFAULT '',
+	user_name    TEXT NOT NULL DEFAULT '',
+	changed_at   TEXT NOT NULL,
+	action       TEXT NOT NULL,
+	before_value TEXT NOT NULL DEFAULT '{}',
+	after_value  TEXT NOT NULL DEFAULT '{}',
+	undone       INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS shopping_lists (
+	id           TEXT PRIMARY KEY,
+	household_id TEXT NOT NULL DEFAULT '',
+	name         TEXT NOT NULL,
+	archived     INTEGER NOT NULL DEFAULT 0,
+	version      INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS households (
+	id   TEXT PRIMARY KEY,
+	name TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS users (
+	id            TEXT PRIMARY KEY,
+	email         TEXT NOT NULL UNIQUE,
+	name          TEXT NOT NULL DEFAULT '',
+	password_hash TEXT NOT NULL,
+	household_id  TEXT NOT NULL REFERENCES households (id)
+);
+CREATE TABLE IF NOT EXISTS sessions (
+	token_hash TEXT PRIMARY KEY,
+	user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	expires_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS invites (
+	code_hash    TEXT PRIMARY KEY,
+	household_id TEXT NOT NULL REFERENCES households (id) ON DELETE CASCADE,
+	role         TEXT NOT NULL,
+	expires_at   TEXT NOT NULL
+);
+`
+
+// householdTables are the tables rebuilt by migrateHouseholds so that
+// household_id is part of their key, and how each is created
+var householdTables = []struct{ name, columns, definition string }{
+	{"meal_plans", "week, week_start", `
+		household_id TEXT NOT NULL DEFAULT '',
+		week         TEXT NOT NULL,
+		week_start   TEXT NOT NULL,
+		PRIMARY KEY (household_id, week)`},
+	{"meal_plan_meals", "week, day, position", `
+		household_id TEXT NOT NULL DEFAULT '',
+		week         TEXT NOT NULL,
+		day          TEXT NOT NULL,
+		position     INTEGER NOT NULL,
+		PRIMARY KEY (household_id, week, day),
+		FOREIGN KEY (household_id, week) REFERENCES meal_plans (household_id, week) ON DELETE CASCADE`},
+	{"meal_slots", "week, day, slot, meal, recipe_id", `
+		household_id TEXT NOT NULL DEFAULT '',
+		week         TEXT NOT NULL,
+		day          TEXT NOT NULL,
+		slot         TEXT NOT NULL,
+		meal         TEXT NOT NULL DEFAULT '',
+		recipe_id    TEXT NOT NULL DEFAULT '',
+		version      INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (household_id, week, day, slot),
+		FOREIGN KEY (household_id, week, day) REFERENCES meal_plan_meals (household_id, week, day) ON DELETE CASCADE`},
+	{"categories", "name, category", `
+		household_id TEXT NOT NULL DEFAULT '',
+		name         TEXT NOT NULL,
+		category     TEXT NOT NULL,
+		PRIMARY KEY (household_id, name)`},
+	{"stores", "key, name, category_order", `
+		household_id   TEXT NOT NULL DEFAULT '',
+		key            TEXT NOT NULL,
+		name           TEXT NOT NULL,
+		category_order TEXT NOT NULL DEFAULT '[]',
+		PRIMARY KEY (household_id, key)`},
+	{"walk_order", "item, position", `
+		household_id TEXT NOT NULL DEFAULT '',
+		item         TEXT NOT NULL,
+		position     REAL NOT NULL,
+		PRIMARY KEY (household_id, item)`},
+}
+
+// householdDataTables lists every table holding household data
+var householdDataTables = []string{
+	"shopping_lists", "shopping_list_items", "meal_plans", "meal_plan_meals", "meal_slots",
+	"recipes", "categories", "stores", "tick_events", "walk_order", "changes",
+}
+
+// sqliteDateLayout is how week_start dates are stored
+const sqliteDateLayout = "2006-01-02"
+
+// sqliteTimeLayout is how tick times are stored, always in UTC so they sort
+// as text
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// NewSQLiteDB opens (creating if necessary) the SQLite database at path
+func NewSQLiteDB(ctx context.Context, path string) (*SQLiteDB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("creating database directory: %w", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)", path)
+	sqlDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only supports one writer at a time, so serialise access
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err := sqlDB.ExecContext(ctx, sqliteSchema); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+
+	store := &SQLiteDB{DB: sqlDB, Path: path}
+	// Columns added since the tables were first created
+	for _, column := range []struct{ table, name, definition string }{
+		{"meal_slots", "recipe_id", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "sources", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "quantity", `REAL NOT NULL DEFAULT 0`},
+		{"shopping_list_items", "unit", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "note", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "category", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "household_id", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "list_id", `TEXT NOT NULL DEFAULT ''`},
+		{"recipes", "household_id", `TEXT NOT NULL DEFAULT ''`},
+		{"tick_events", "household_id", `TEXT NOT NULL DEFAULT ''`},
+		{"shopping_list_items", "version", `INTEGER NOT NULL DEFAULT 0`},
+		{"shopping_lists", "version", `INTEGER NOT NULL DEFAULT 0`},
+		{"meal_slots", "version", `INTEGER NOT NULL DEFAULT 0`},
+		// Users from before roles each created their household
+		{"users", "role", `TEXT NOT NULL DEFAULT 'owner'`},
+	} {
+		if err := store.addColumn(ctx, column.table, column.name, column.definition); err != nil {
+			sqlDB.Close()
+			return nil, fmt.Errorf("adding %s.%s column: %w", column.table, column.name, err)
+		}
+	}
+	if err := store.migrateSingleSlotMeals(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("migrating single-slot meals: %w", err)
+	}
+	if err := store.migrateHouseholds(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("migrating to households: %w", err)
+	}
+	if err := store.migrateLegacyMeals(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("migrating undated meal plan: %w", err)
+	}
+	if err := store.migrateShoppingLists(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("migrating to named shopping lists: %w", err)
+	}
+	fmt.Printf("Opened SQLite database: %s\n", path)
+
+	return store, nil
+}
+
+// migrateHouseholds rebuilds the tables keyed by week or name, created before
+// households existed, with household_id as part of their key. Existing rows
+// keep an empty household_id, so they belong to no household until the first
+// one is created.
+func (s *SQLiteDB) migrateHouseholds(ctx context.Context) error {
+	var columns int
+	err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('meal_plans') WHERE name = 'household_id'`).Scan(&columns)
+	if err != nil || columns > 0 {
+		return err
+	}
+
+	// Tables are dropped and renamed while rows still refer to them, so
+	// foreign keys are checked only once they are all rebuilt
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range householdTables {
+		statements := []string{
+			fmt.Sprintf(`CREATE TABLE %s_new (%s)`, table.name, table.definition),
+			fmt.Sprintf(`INSERT INTO %s_new (%s) SELECT %s FROM %s`, table.name, table.columns, table.columns, table.name),
+			fmt.Sprintf(`DROP TABLE %s`, table.name),
+			fmt.Sprintf(`ALTER TABLE %s_new RENAME TO %s`, table.name, table.name),
+		}
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("rebuilding %s: %w", table.name, err)
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `PRAGMA foreign_key_check`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateShoppingLists puts the items saved before a household could have
+// more than one shopping list onto its first list
+func (s *SQLiteDB) migrateShoppingLists(ctx context.Context) error {
+	rows, err := s.DB.QueryContext(ctx, `SELECT DISTINCT household_id FROM shopping_list_items WHERE list_id = ''`)
+	if err != nil {
+		return err
+	}
+	var households []string
+	for rows.Next() {
+		var household string
+		if err := rows.Scan(&household); err != nil {
+			rows.Close()
+			return err
+		}
+		households = append(households, household)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, household := range households {
+		view := &SQLiteDB{DB: s.DB, Path: s.Path, household: household}
+		listID, err := view.listID(ctx)
+		if err != nil {
+			return err
+		}
+		if _, err := s.DB.ExecContext(ctx, `UPDATE shopping_list_items SET list_id = ? WHERE household_id = ? AND list_id = ''`, listID, household); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateLegacyMeals moves the undated meals table used before plans were kept
+// per week into the current week's plan
+func (s *SQLiteDB) migrateLegacyMeals(ctx context.Context) error {
+	var name string
+	err := s.DB.QueryRowContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'meals'`).Scan(&name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	mealPlan, err := models.NewMealPlan(models.ISOWeek(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertMealPlan(ctx, tx, mealPlan); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO meal_slots (household_id, week, day, slot, meal)
+		SELECT ?, ?, day, ?, meal FROM meals
+		WHERE meal != '' AND day IN (SELECT day FROM meal_plan_meals WHERE household_id = ? AND week = ?)`,
+		s.household, mealPlan.Week, models.LegacyMealSlot, s.household, mealPlan.Week); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE meals`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateSingleSlotMeals moves the one meal per day stored before days had
+// slots into LegacyMealSlot, then drops the old column
+func (s *SQLiteDB) migrateSingleSlotMeals(ctx context.Context) error {
+	var columns int
+	err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('meal_plan_meals') WHERE name = 'meal'`).Scan(&columns)
+	if err != nil || columns == 0 {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO meal_slots (week, day, slot, meal)
+		SELECT week, day, ?, meal FROM meal_plan_meals WHERE meal != ''`, models.LegacyMealSlot); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE meal_plan_meals DROP COLUMN meal`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addColumn adds a column to a table created before the column existed
+func (s *SQLiteDB) addColumn(ctx context.Context, table, column, definition string) error {
+	var columns int
+	err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&columns)
+	if err != nil || columns > 0 {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
+// insertMealPlan stores mealPlan unless a plan for its week already exists
+func (s *SQLiteDB) insertMealPlan(ctx context.Context, tx *sql.Tx, mealPlan models.MealPlan) error {
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO meal_plans (household_id, week, week_start) VALUES (?, ?, ?)`,
+		s.household, mealPlan.Week, mealPlan.WeekStart.Format(sqliteDateLayout)); err != nil {
+		return err
+	}
+	for i, meal := range mealPlan.Meals {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO meal_plan_meals (household_id, week, day, position) VALUES (?, ?, ?, ?)`,
+			s.household, mealPlan.Week, meal.Day, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetShoppingList retrieves the shopping list in its sort order
+func (s *SQLiteDB) GetShoppingList(ctx context.Context) ([]models.ShoppingListItem, error) {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repairListOrder(ctx, s.household, listID); err != nil {
+		fmt.Printf("Error repairing shopping list order: %s\n", err)
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+shoppingListItemColumns+` FROM shopping_list_items
+		WHERE household_id = ? AND list_id = ? ORDER BY sort_position, rowid`, s.household, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shoppingList []models.ShoppingListItem
+	for rows.Next() {
+		item, err := scanShoppingListItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		shoppingList = append(shoppingList, item)
+	}
+	return shoppingList, rows.Err()
+}
+
+// shoppingListItemColumns lists the shopping_list_items columns in the order
+// scanShoppingListItem reads them
+const shoppingListItemColumns = `id, item, quantity, unit, note, category, ticked, sources, version`
+
+// scanShoppingListItem reads a row of shoppingListItemColumns into a ShoppingListItem
+func scanShoppingListItem(row interface{ Scan(...any) error }) (models.ShoppingListItem, error) {
+	var item models.ShoppingListItem
+	var sources string
+	if err := row.Scan(&item.IDHex, &item.Item, &item.Quantity, &item.Unit, &item.Note, &item.Category, &item.Ticked, &sources, &item.Version); err != nil {
+		return item, err
+	}
+	id, err := primitive.ObjectIDFromHex(item.IDHex)
+	if err != nil {
+		return item, fmt.Errorf("invalid object ID: %s", item.IDHex)
+	}
+	item.ID = id
+	if sources != "" {
+		if err := json.Unmarshal([]byte(sources), &item.Sources); err != nil {
+			return item, err
+		}
+	}
+	return item, nil
+}
+
+// sourcesJSON encodes an item's sources, or "" when it has none
+func sourcesJSON(sources []models.MealSource) (string, error) {
+	if len(sources) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(sources)
+	return string(data), err
+}
+
+// GetShoppingListItemFromIDHex retrieves a shopping list item by its hex ID
+func (s *SQLiteDB) GetShoppingListItemFromIDHex(ctx context.Context, IDHex string) (models.ShoppingListItem, error) {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	row := s.DB.QueryRowContext(ctx, `SELECT `+shoppingListItemColumns+` FROM shopping_list_items
+		WHERE household_id = ? AND list_id = ? AND id = ?`, s.household, listID, IDHex)
+	item, err := scanShoppingListItem(row)
+	if err == sql.ErrNoRows {
+		return models.ShoppingListItem{}, nil
+	}
+	return item, err
+}
+
+// UpdateMeal updates the meal in one slot of a day of the given week
+func (s *SQLiteDB) UpdateMeal(ctx context.Context, week string, day string, slot string, meal string) error {
+	if err := models.ValidateMealSlot(slot); err != nil {
+		return err
+	}
+	// Make sure the week's plan exists before updating one of its days
+	if _, err := s.GetMealPlan(ctx, week); err != nil {
+		return err
+	}
+
+	result, err := s.DB.ExecContext(ctx, `
+		INSERT INTO meal_slots (household_id, week, day, slot, meal, version)
+		SELECT household_id, week, day, ?, ?, 1 FROM meal_plan_meals WHERE household_id = ? AND week = ? AND day = ?
+		ON CONFLICT (household_id, week, day, slot) DO UPDATE SET meal = excluded.meal, version = version + 1`, slot, meal, s.household, week, day)
+	if err != nil {
+		fmt.Printf("Error updating meal: %s\n", err)
+		return err
+	}
+	// Nothing is inserted when the week has no such day
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetMealRecipe links one slot of a day to a recipe, or unlinks it when
+// recipeID is ""
+func (s *SQLiteDB) SetMealRecipe(ctx context.Context, week string, day string, slot string, recipeID string) error {
+	if err := models.ValidateMealSlot(slot); err != nil {
+		return err
+	}
+	if _, err := s.GetMealPlan(ctx, week); err != nil {
+		return err
+	}
+
+	result, err := s.DB.ExecContext(ctx, `
+		INSERT INTO meal_slots (household_id, week, day, slot, recipe_id, version)
+		SELECT household_id, week, day, ?, ?, 1 FROM meal_plan_meals WHERE household_id = ? AND week = ? AND day = ?
+		ON CONFLICT (household_id, week, day, slot) DO UPDATE SET recipe_id = excluded.recipe_id, version = version + 1`, slot, recipeID, s.household, week, day)
+	if err != nil {
+		fmt.Printf("Error linking meal to recipe: %s\n", err)
+		return err
+	}
+	if linked, err := result.RowsAffected(); err != nil || linked == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// EditMeal saves the meal and recipe of one slot of a day of the given week,
+// unless the slot has changed since input was read
+func (s *SQLiteDB) EditMeal(ctx context.Context, week string, input models.MealSlotInput) (models.MealSlotInput, error) {
+	if err := models.ValidateMealSlot(input.Slot); err != nil {
+		return models.MealSlotInput{}, err
+	}
+	if _, err := s.GetMealPlan(ctx, week); err != nil {
+		return models.MealSlotInput{}, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MealSlotInput{}, err
+	}
+	defer tx.Rollback()
+
+	// A slot never filled in has no row, and so is at version 0
+	var version int
+	err = tx.QueryRowContext(ctx, `SELECT version FROM meal_slots WHERE household_id = ? AND week = ? AND day = ? AND slot = ?`,
+		s.household, week, input.Day, input.Slot).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return models.MealSlotInput{}, err
+	}
+	if version != input.Version {
+		tx.Rollback()
+		return mealConflict(ctx, s, week, input.Day, input.Slot)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO meal_slots (household_id, week, day, slot, meal, recipe_id, version)
+		SELECT household_id, week, day, ?, ?, ?, ? FROM meal_plan_meals WHERE household_id = ? AND week = ? AND day = ?
+		ON CONFLICT (household_id, week, day, slot) DO UPDATE SET meal = excluded.meal, recipe_id = excluded.recipe_id, version = excluded.version`,
+		input.Slot, input.Meal, input.RecipeID, version+1, s.household, week, input.Day)
+	if err != nil {
+		fmt.Printf("Error updating meal: %s\n", err)
+		return models.MealSlotInput{}, err
+	}
+	if saved, err := result.RowsAffected(); err != nil || saved == 0 {
+		return models.MealSlotInput{}, ErrNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return models.MealSlotInput{}, err
+	}
+	saved := input
+	saved.Week = week
+	saved.Version++
+	return saved, nil
+}
+
+// AddShoppingListItem adds an item read from text such as "2kg potatoes" to
+// the end of the shopping list, or adds its quantity to a matching unticked
+// item already on the list
+func (s *SQLiteDB) AddShoppingListItem(ctx context.Context, itemName string) (models.ShoppingListItem, error) {
+	return addShoppingListItem(ctx, s, itemName)
+}
+
+// InsertShoppingListItem adds a new item, along with details such as its
+// sources, to the end of the shopping list. The ID is always generated.
+func (s *SQLiteDB) InsertShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	if item.Item == "" {
+		return models.ShoppingListItem{}, fmt.Errorf("item name cannot be empty")
+	}
+
+	newId := primitive.NewObjectID()
+	newItem := item
+	newItem.ID = newId
+	newItem.IDHex = newId.Hex()
+	if len(newItem.Sources) == 0 {
+		newItem.Sources = nil
+	}
+
+	sources, err := sourcesJSON(newItem.Sources)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	// The item, its place at the end of the order and the list's version
+	// change together
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO shopping_list_items (household_id, list_id, id, item, quantity, unit, note, category, ticked, sources, sort_position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(sort_position), 0) + 1 FROM shopping_list_items WHERE household_id = ? AND list_id = ?))`,
+		s.household, listID, newItem.IDHex, newItem.Item, newItem.Quantity, newItem.Unit, newItem.Note, newItem.Category, newItem.Ticked, sources, s.household, listID); err != nil {
+		fmt.Printf("Error adding shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	if err := countListChange(ctx, tx, listID); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+
+	return newItem, nil
+}
+
+// AddShoppingListIdToShoppingListOrder adds an item ID to the end of the sort
+// order, unless it is already in it
+func (s *SQLiteDB) AddShoppingListIdToShoppingListOrder(ctx context.Context, itemId string) error {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, `
+		UPDATE shopping_list_items
+		SET sort_position = (SELECT COALESCE(MAX(sort_position), 0) + 1 FROM shopping_list_items WHERE household_id = ? AND list_id = ?)
+		WHERE household_id = ? AND list_id = ? AND id = ? AND sort_position IS NULL`, s.household, listID, s.household, listID, itemId)
+	if err != nil {
+		fmt.Printf("Error adding shopping list item to order: %s\n", err)
+		return err
+	}
+	return countListChange(ctx, s.DB, listID)
+}
+
+// countListChange counts a change to the items or order of the shopping list
+// with IDHex listID in its version
+func countListChange(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, listID string) error {
+	if _, err := db.ExecContext(ctx, `UPDATE shopping_lists SET version = version + 1 WHERE id = ?`, listID); err != nil {
+		fmt.Printf("Error updating shopping list version: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateShoppingListItem updates an existing shopping list item
+func (s *SQLiteDB) UpdateShoppingListItem(ctx context.Context, itemId string, newItem string) (models.ShoppingListItem, error) {
+	if err := s.execItem(ctx, `UPDATE shopping_list_items SET item = ?, version = version + 1`, itemId, newItem); err != nil {
+		fmt.Printf("Error updating shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// UpdateShoppingListItemDetails replaces the name, quantity, unit and note of
+// the shopping list item with item.IDHex
+func (s *SQLiteDB) UpdateShoppingListItemDetails(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	if err := s.execItem(ctx, `UPDATE shopping_list_items SET item = ?, quantity = ?, unit = ?, note = ?, version = version + 1`, item.IDHex,
+		item.Item, item.Quantity, item.Unit, item.Note); err != nil {
+		fmt.Printf("Error updating shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// EditShoppingListItem replaces the name, quantity, unit and note of the
+// shopping list item with item.IDHex, unless it has changed since item was
+// read
+func (s *SQLiteDB) EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	result, err := s.DB.ExecContext(ctx, `
+		UPDATE shopping_list_items SET item = ?, quantity = ?, unit = ?, note = ?, version = version + 1
+		WHERE household_id = ? AND list_id = ? AND id = ? AND version = ?`,
+		item.Item, item.Quantity, item.Unit, item.Note, s.household, listID, item.IDHex, item.Version)
+	if err != nil {
+		fmt.Printf("Error updating shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	if edited, err := result.RowsAffected(); err != nil || edited == 0 {
+		return itemConflict(ctx, s, item.IDHex)
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// ReplaceShoppingListItem replaces the details, ticked status, category and
+// sources of the shopping list item with item.IDHex, unless it has changed
+// since item was read
+func (s *SQLiteDB) ReplaceShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	sources, err := sourcesJSON(item.Sources)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	result, err := s.DB.ExecContext(ctx, `
+		UPDATE shopping_list_items SET item = ?, quantity = ?, unit = ?, note = ?, ticked = ?, category = ?, sources = ?, version = version + 1
+		WHERE household_id = ? AND list_id = ? AND id = ? AND version = ?`,
+		item.Item, item.Quantity, item.Unit, item.Note, item.Ticked, item.Category, sources, s.household, listID, item.IDHex, item.Version)
+	if err != nil {
+		fmt.Printf("Error replacing shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	if restored, err := result.RowsAffected(); err != nil || restored == 0 {
+		return itemConflict(ctx, s, item.IDHex)
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, item.IDHex)
+}
+
+// SetShoppingListItemSources replaces the meals a shopping list item was
+// generated for
+func (s *SQLiteDB) SetShoppingListItemSources(ctx context.Context, itemId string, sources []models.MealSource) (models.ShoppingListItem, error) {
+	encoded, err := sourcesJSON(sources)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if err := s.execItem(ctx, `UPDATE shopping_list_items SET sources = ?, version = version + 1`, itemId, encoded); err != nil {
+		fmt.Printf("Error updating shopping list item sources: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// execItem runs statement, an UPDATE or DELETE of shopping_list_items with
+// no WHERE clause, against the item with IDHex itemId on this shopping list
+func (s *SQLiteDB) execItem(ctx context.Context, statement string, itemId string, args ...any) error {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, statement+` WHERE household_id = ? AND list_id = ? AND id = ?`, append(args, s.household, listID, itemId)...)
+	return err
+}
+
+// DeleteShoppingListItem removes an item from the shopping list
+func (s *SQLiteDB) DeleteShoppingListItem(ctx context.Context, itemIDHex string) error {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM shopping_list_items WHERE household_id = ? AND list_id = ? AND id = ?`, s.household, listID, itemIDHex); err != nil {
+		fmt.Printf("Error deleting shopping list item: %s\n", err)
+		return err
+	}
+	if err := countListChange(ctx, tx, listID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeShoppingListItems saves the details and sources of the merged items
+// and removes the removed ones in a transaction, which is abandoned if any
+// has changed since it was read
+func (s *SQLiteDB) MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error {
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// changed reports whether a statement matched the item at its version
+	changed := func(result sql.Result, err error) error {
+		if err != nil {
+			fmt.Printf("Error merging shopping list items: %s\n", err)
+			return err
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return ErrConflict
+		}
+		return nil
+	}
+	for _, item := range merged {
+		sources, err := sourcesJSON(item.Sources)
+		if err != nil {
+			return err
+		}
+		if err := changed(tx.ExecContext(ctx, `
+			UPDATE shopping_list_items SET item = ?, quantity = ?, unit = ?, note = ?, sources = ?, version = version + 1
+			WHERE household_id = ? AND list_id = ? AND id = ? AND version = ?`,
+			item.Item, item.Quantity, item.Unit, item.Note, sources, s.household, listID, item.IDHex, item.Version)); err != nil {
+			return err
+		}
+	}
+	for _, item := range removed {
+		if err := changed(tx.ExecContext(ctx, `DELETE FROM shopping_list_items WHERE household_id = ? AND list_id = ? AND id = ? AND version = ?`,
+			s.household, listID, item.IDHex, item.Version)); err != nil {
+			return err
+		}
+	}
+	if err := countListChange(ctx, tx, listID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TickShoppingListItem sets the ticked status of a shopping list item
+func (s *SQLiteDB) TickShoppingListItem(ctx context.Context, itemId string, ticked bool) (models.ShoppingListItem, error) {
+	if err := s.execItem(ctx, `UPDATE shopping_list_items SET ticked = ?, version = version + 1`, itemId, ticked); err != nil {
+		fmt.Printf("Error ticking shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// GetMealPlan retrieves the meal plan for an ISO week, creating an empty plan
+// the first time a week is visited
+func (s *SQLiteDB) GetMealPlan(ctx context.Context, week string) (models.MealPlan, error) {
+	emptyPlan, err := models.NewMealPlan(week)
+	if err != nil {
+		return models.MealPlan{}, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MealPlan{}, err
+	}
+	defer tx.Rollback()
+
+	if err := s.insertMealPlan(ctx, tx, emptyPlan); err != nil {
+		fmt.Printf("Error creating meal plan for %s: %s\n", week, err)
+		return models.MealPlan{}, err
+	}
+
+	mealPlan := models.MealPlan{Week: week}
+	var weekStart string
+	if err := tx.QueryRowContext(ctx, `SELECT week_start FROM meal_plans WHERE household_id = ? AND week = ?`, s.household, week).Scan(&weekStart); err != nil {
+		fmt.Printf("Error finding meal plan: %s\n", err)
+		return models.MealPlan{}, err
+	}
+	if mealPlan.WeekStart, err = time.Parse(sqliteDateLayout, weekStart); err != nil {
+		return models.MealPlan{}, err
+	}
+
+	// One row per filled slot, or a single row with a NULL slot for an empty day
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.day, s.slot, s.meal, s.recipe_id, s.version
+		FROM meal_plan_meals d
+		LEFT JOIN meal_slots s ON s.household_id = d.household_id AND s.week = d.week AND s.day = d.day
+		WHERE d.household_id = ? AND d.week = ?
+		ORDER BY d.position`, s.household, week)
+	if err != nil {
+		fmt.Printf("Error finding meal plan: %s\n", err)
+		return models.MealPlan{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day string
+		var slot, meal, recipeID sql.NullString
+		var version sql.NullInt64
+		if err := rows.Scan(&day, &slot, &meal, &recipeID, &version); err != nil {
+			return models.MealPlan{}, err
+		}
+		if n := len(mealPlan.Meals); n == 0 || mealPlan.Meals[n-1].Day != day {
+			mealPlan.Meals = append(mealPlan.Meals, models.Meal{Day: day})
+		}
+		if slot.Valid {
+			mealPlan.Meals[len(mealPlan.Meals)-1].SetSlotMeal(slot.String, meal.String)
+			mealPlan.Meals[len(mealPlan.Meals)-1].SetSlotRecipe(slot.String, recipeID.String)
+			mealPlan.Meals[len(mealPlan.Meals)-1].SetSlotVersion(slot.String, int(version.Int64))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.MealPlan{}, err
+	}
+	rows.Close()
+
+	return mealPlan, tx.Commit()
+}
+
+// SortShoppingList replaces the order of items in the shopping list
+func (s *SQLiteDB) SortShoppingList(ctx context.Context, newOrder []models.Order) error {
+	return s.sortShoppingList(ctx, newOrder, nil)
+}
+
+// ReorderShoppingList updates the order of items in the shopping list, as
+// long as the list is still at version
+func (s *SQLiteDB) ReorderShoppingList(ctx context.Context, newOrder []models.Order, version int) error {
+	return s.sortShoppingList(ctx, newOrder, &version)
+}
+
+// sortShoppingList saves newOrder as the order of the shopping list, unless
+// version is set and the list has moved on from it
+func (s *SQLiteDB) sortShoppingList(ctx context.Context, newOrder []models.Order, version *int) error {
+	for _, order := range newOrder {
+		if _, err := primitive.ObjectIDFromHex(order.ID); err != nil {
+			return fmt.Errorf("invalid object ID: %s", order.ID)
+		}
+	}
+
+	listID, err := s.listID(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if version == nil {
+		if err := countListChange(ctx, tx, listID); err != nil {
+			return err
+		}
+	} else {
+		// Count the change only while the list is at version, which the
+		// transaction then holds it to
+		result, err := tx.ExecContext(ctx, `UPDATE shopping_lists SET version = version + 1 WHERE id = ? AND version = ?`, listID, *version)
+		if err != nil {
+			fmt.Printf("Error updating shopping list version: %s\n", err)
+			return err
+		}
+		if counted, err := result.RowsAffected(); err != nil || counted == 0 {
+			return ErrConflict
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE shopping_list_items SET sort_position = NULL WHERE household_id = ? AND list_id = ?`, s.household, listID); err != nil {
+		fmt.Printf("Error updating shopping list order: %s\n", err)
+		return err
+	}
+	for i, order := range newOrder {
+		if _, err := tx.ExecContext(ctx, `UPDATE shopping_list_items SET sort_position = ? WHERE household_id = ? AND list_id = ? AND id = ?`, i+1, s.household, listID, order.ID); err != nil {
+			fmt.Printf("Error updating shopping list order: %s\n", err)
+			return err
+		}
+	}
+	// Items left out of newOrder follow the rest
+	ids, drift, err := listOrder(ctx, tx, s.household, listID)
+	if err != nil {
+		return err
+	}
+	if !drift.OK() {
+		if err := setListOrder(ctx, tx, s.household, listID, ids); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// recipeColumns lists the recipes columns in the order scanRecipe reads them
+const recipeColumns = `id, title, servings, ingredients, method, tags`
+
+// scanRecipe reads a row of recipeColumns into a Recipe
+func scanRecipe(row interface{ Scan(...any) error }) (recipes.Recipe, error) {
+	var recipe recipes.Recipe
+	var ingredients, method, tags string
+	if err := row.Scan(&recipe.IDHex, &recipe.Title, &recipe.Servings, &ingredients, &method, &tags); err != nil {
+		return recipe, err
+	}
+	id, err := primitive.ObjectIDFromHex(recipe.IDHex)
+	if err != nil {
+		return recipe, fmt.Errorf("invalid object ID: %s", recipe.IDHex)
+	}
+	recipe.ID = id
+	if err := json.Unmarshal([]byte(ingredients), &recipe.Ingredients); err != nil {
+		return recipe, err
+	}
+	if err := json.Unmarshal([]byte(method), &recipe.Method); err != nil {
+		return recipe, err
+	}
+	if err := json.Unmarshal([]byte(tags), &recipe.Tags); err != nil {
+		return recipe, err
+	}
+	return recipe, nil
+}
+
+// recipeJSON encodes the list columns of a recipe
+func recipeJSON(recipe recipes.Recipe) (ingredients, method, tags string, err error) {
+	values := []any{recipe.Ingredients, recipe.Method, recipe.Tags}
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", "", "", err
+		}
+		encoded[i] = string(data)
+	}
+	return encoded[0], encoded[1], encoded[2], nil
+}
+
+// GetRecipes retrieves every recipe in the library, sorted by title
+func (s *SQLiteDB) GetRecipes(ctx context.Context) ([]recipes.Recipe, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE household_id = ?`, s.household)
+	if err != nil {
+		fmt.Printf("Error finding recipes: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	library := []recipes.Recipe{}
+	for rows.Next() {
+		recipe, err := scanRecipe(rows)
+		if err != nil {
+			return nil, err
+		}
+		library = append(library, recipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	recipes.SortByTitle(library)
+	return library, nil
+}
+
+// GetRecipe retrieves a recipe by its hex ID
+func (s *SQLiteDB) GetRecipe(ctx context.Context, IDHex string) (recipes.Recipe, error) {
+	recipe, err := scanRecipe(s.DB.QueryRowContext(ctx, `SELECT `+recipeColumns+` FROM recipes WHERE household_id = ? AND id = ?`, s.household, IDHex))
+	if err == sql.ErrNoRows {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	return recipe, err
+}
+
+// AddRecipe adds a new recipe to the library
+func (s *SQLiteDB) AddRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return recipes.Recipe{}, err
+	}
+
+	newId := primitive.NewObjectID()
+	recipe.ID = newId
+	recipe.IDHex = newId.Hex()
+
+	ingredients, method, tags, err := recipeJSON(recipe)
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO recipes (household_id, `+recipeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.household, recipe.IDHex, recipe.Title, recipe.Servings, ingredients, method, tags); err != nil {
+		fmt.Printf("Error adding recipe: %s\n", err)
+		return recipes.Recipe{}, err
+	}
+	return recipe, nil
+}
+
+// UpdateRecipe replaces the stored recipe with the same IDHex
+func (s *SQLiteDB) UpdateRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	if err := recipe.Validate(); err != nil {
+		return recipes.Recipe{}, err
+	}
+	id, err := primitive.ObjectIDFromHex(recipe.IDHex)
+	if err != nil {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	recipe.ID = id
+
+	ingredients, method, tags, err := recipeJSON(recipe)
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+	result, err := s.DB.ExecContext(ctx, `UPDATE recipes SET title = ?, servings = ?, ingredients = ?, method = ?, tags = ? WHERE household_id = ? AND id = ?`,
+		recipe.Title, recipe.Servings, ingredients, method, tags, s.household, recipe.IDHex)
+	if err != nil {
+		fmt.Printf("Error updating recipe: %s\n", err)
+		return recipes.Recipe{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return recipes.Recipe{}, ErrNotFound
+	}
+	return recipe, nil
+}
+
+// DeleteRecipe removes a recipe from the library
+func (s *SQLiteDB) DeleteRecipe(ctx context.Context, IDHex string) error {
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM recipes WHERE household_id = ? AND id = ?`, s.household, IDHex)
+	if err != nil {
+		fmt.Printf("Error deleting recipe: %s\n", err)
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetShoppingListItemCategory sets the aisle category of a shopping list item
+func (s *SQLiteDB) SetShoppingListItemCategory(ctx context.Context, itemId string, category string) (models.ShoppingListItem, error) {
+	if err := s.execItem(ctx, `UPDATE shopping_list_items SET category = ?, version = version + 1`, itemId, category); err != nil {
+		fmt.Printf("Error updating shopping list item category: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	return s.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// GetCategoryDictionary retrieves the learned item name to category dictionary
+func (s *SQLiteDB) GetCategoryDictionary(ctx context.Context) (map[string]string, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT name, category FROM categories WHERE household_id = ?`, s.household)
+	if err != nil {
+		fmt.Printf("Error finding categories: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	dictionary := make(map[string]string)
+	for rows.Next() {
+		var name, category string
+		if err := rows.Scan(&name, &category); err != nil {
+			return nil, err
+		}
+		dictionary[name] = category
+	}
+	return dictionary, rows.Err()
+}
+
+// LearnCategory remembers the category of items called itemName. An empty
+// category forgets it.
+func (s *SQLiteDB) LearnCategory(ctx context.Context, itemName string, category string) error {
+	key := models.CategoryKey(itemName)
+	if key == "" {
+		return fmt.Errorf("item name cannot be empty")
+	}
+
+	var err error
+	if category == "" {
+		_, err = s.DB.ExecContext(ctx, `DELETE FROM categories WHERE household_id = ? AND name = ?`, s.household, key)
+	} else {
+		_, err = s.DB.ExecContext(ctx, `
+			INSERT INTO categories (household_id, name, category) VALUES (?, ?, ?)
+			ON CONFLICT (household_id, name) DO UPDATE SET category = excluded.category`, s.household, key, category)
+	}
+	if err != nil {
+		fmt.Printf("Error saving category: %s\n", err)
+	}
+	return err
+}
+
+// GetStores retrieves every store, sorted by name
+func (s *SQLiteDB) GetStores(ctx context.Context) ([]models.Store, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT name, category_order FROM stores WHERE household_id = ?`, s.household)
+	if err != nil {
+		fmt.Printf("Error finding stores: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stores := []models.Store{}
+	for rows.Next() {
+		var store models.Store
+		var order string
+		if err := rows.Scan(&store.Name, &order); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(order), &store.CategoryOrder); err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	models.SortStores(stores)
+	return stores, nil
+}
+
+// SaveStore adds a store, or replaces the store with the same name
+func (s *SQLiteDB) SaveStore(ctx context.Context, store models.Store) (models.Store, error) {
+	if err := store.Validate(); err != nil {
+		return models.Store{}, err
+	}
+	store.Name = strings.TrimSpace(store.Name)
+	if store.CategoryOrder == nil {
+		store.CategoryOrder = []string{}
+	}
+
+	order, err := json.Marshal(store.CategoryOrder)
+	if err != nil {
+		return models.Store{}, err
+	}
+	if _, err := s.DB.ExecContext(ctx, `
+		INSERT INTO stores (household_id, key, name, category_order) VALUES (?, ?, ?, ?)
+		ON CONFLICT (household_id, key) DO UPDATE SET name = excluded.name, category_order = excluded.category_order`,
+		s.household, models.StoreKey(store.Name), store.Name, string(order)); err != nil {
+		fmt.Printf("Error saving store: %s\n", err)
+		return models.Store{}, err
+	}
+	return store, nil
+}
+
+// DeleteStore removes the store called name
+func (s *SQLiteDB) DeleteStore(ctx context.Context, name string) error {
+	result, err := s.DB.ExecContext(ctx, `DELETE FROM stores WHERE household_id = ? AND key = ?`, s.household, models.StoreKey(name))
+	if err != nil {
+		fmt.Printf("Error deleting store: %s\n", err)
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RecordTick remembers that an item was ticked off
+func (s *SQLiteDB) RecordTick(ctx context.Context, event models.TickEvent) error {
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO tick_events (household_id, item, ticked_at) VALUES (?, ?, ?)`,
+		s.household, event.Item, event.TickedAt.UTC().Format(sqliteTimeLayout)); err != nil {
+		fmt.Printf("Error recording tick: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetTickEvents retrieves every recorded tick, oldest first
+func (s *SQLiteDB) GetTickEvents(ctx context.Context) ([]models.TickEvent, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT item, ticked_at FROM tick_events WHERE household_id = ? ORDER BY ticked_at, rowid`, s.household)
+	if err != nil {
+		fmt.Printf("Error finding tick events: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.TickEvent{}
+	for rows.Next() {
+		var event models.TickEvent
+		var tickedAt string
+		if err := rows.Scan(&event.Item, &tickedAt); err != nil {
+			return nil, err
+		}
+		if event.TickedAt, err = time.Parse(sqliteTimeLayout, tickedAt); err != nil {
+			return nil, fmt.Errorf("reading tick time %q: %w", tickedAt, err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// GetWalkOrder retrieves the learned walk order
+func (s *SQLiteDB) GetWalkOrder(ctx context.Context) (map[string]float64, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT item, position FROM walk_order WHERE household_id = ?`, s.household)
+	if err != nil {
+		fmt.Printf("Error finding walk order: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	order := make(map[string]float64)
+	for rows.Next() {
+		var item string
+		var position float64
+		if err := rows.Scan(&item, &position); err != nil {
+			return nil, err
+		}
+		order[item] = position
+	}
+	return order, rows.Err()
+}
+
+// SaveWalkOrder replaces the learned walk order
+func (s *SQLiteDB) SaveWalkOrder(ctx context.Context, order map[string]float64) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM walk_order WHERE household_id = ?`, s.household); err != nil {
+		fmt.Printf("Error saving walk order: %s\n", err)
+		return err
+	}
+	for item, position := range order {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO walk_order (household_id, item, position) VALUES (?, ?, ?)`, s.household, item, position); err != nil {
+			fmt.Printf("Error saving walk order: %s\n", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// changeColumns are the columns read into a models.Change by scanChange
+const changeColumns = "id, list_id, user_id, user_name, changed_at, action, before_value, after_value, undone"
+
+// RecordChange adds a change to the history
+func (s *SQLiteDB) RecordChange(ctx context.Context, change models.Change) (models.Change, error) {
+	change.ID = primitive.NewObjectID()
+	change.IDHex = change.ID.Hex()
+	before, err := json.Marshal(change.Before)
+	if err != nil {
+		return models.Change{}, err
+	}
+	after, err := json.Marshal(change.After)
+	if err != nil {
+		return models.Change{}, err
+	}
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO changes (id, household_id, list_id, user_id, user_name, changed_at, action, before_value, after_value, undone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		change.IDHex, s.household, change.ListID, change.UserID, change.UserName,
+		change.Time.UTC().Format(sqliteTimeLayout), change.Action, string(before), string(after), change.Undone); err != nil {
+		fmt.Printf("Error recording change: %s\n", err)
+		return models.Change{}, err
+	}
+	return change, nil
+}
+
+// UpdateChange saves the values and undone state of a recorded change
+func (s *SQLiteDB) UpdateChange(ctx context.Context, change models.Change) error {
+	before, err := json.Marshal(change.Before)
+	if err != nil {
+		return err
+	}
+	after, err := json.Marshal(change.After)
+	if err != nil {
+		return err
+	}
+	result, err := s.DB.ExecContext(ctx, `UPDATE changes SET before_value = ?, after_value = ?, undone = ? WHERE id = ? AND household_id = ?`,
+		string(before), string(after), change.Undone, change.IDHex, s.household)
+	if err != nil {
+		fmt.Printf("Error updating change: %s\n", err)
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetChange retrieves a recorded change by its hex ID
+func (s *SQLiteDB) GetChange(ctx context.Context, IDHex string) (models.Change, error) {
+	row := s.DB.QueryRowContext(ctx, `SELECT `+changeColumns+` FROM changes WHERE id = ? AND household_id = ?`, IDHex, s.household)
+	change, err := scanChange(row)
+	if err == sql.ErrNoRows {
+		return models.Change{}, ErrNotFound
+	}
+	return change, err
+}
+
+// GetChanges retrieves the latest changes to a list, or to the meal plan when
+// listID is "", newest first
+func (s *SQLiteDB) GetChanges(ctx context.Context, listID string, limit int) ([]models.Change, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+changeColumns+` FROM changes WHERE household_id = ? AND list_id = ?
+		ORDER BY changed_at DESC, rowid DESC LIMIT ?`, s.household, listID, limit)
+	if err != nil {
+		fmt.Printf("Error finding changes: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.Change{}
+	for rows.Next() {
+		change, err := scanChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// scanChange reads a row of changeColumns
+func scanChange(row interface{ Scan(...any) error }) (models.Change, error) {
+	var change models.Change
+	var changedAt, before, after string
+	if err := row.Scan(&change.IDHex, &change.ListID, &change.UserID, &change.UserName, &changedAt,
+		&change.Action, &before, &after, &change.Undone); err != nil {
+		return models.Change{}, err
+	}
+	var err error
+	if change.ID, err = primitive.ObjectIDFromHex(change.IDHex); err != nil {
+		return models.Change{}, err
+	}
+	if change.Time, err = time.Parse(sqliteTimeLayout, changedAt); err != nil {
+		return models.Change{}, fmt.Errorf("reading change time %q: %w", changedAt, err)
+	}
+	if err := json.Unmarshal([]byte(before), &change.Before); err != nil {
+		return models.Change{}, fmt.Errorf("reading change %s: %w", change.IDHex, err)
+	}
+	if err := json.Unmarshal([]byte(after), &change.After); err != nil {
+		return models.Change{}, fmt.Errorf("reading change %s: %w", change.IDHex, err)
+	}
+	return change, nil
+}
+
+// ForHousehold returns the data of the household with IDHex householdID. It
+// shares this store's connection, so only the store from NewSQLiteDB is
+// closed.
+func (s *SQLiteDB) ForHousehold(householdID string) DBInterface {
+	return &SQLiteDB{DB: s.DB, Path: s.Path, household: householdID, view: true}
+}
+
+// ForShoppingList returns a view of this household whose shopping list item
+// calls use the list with IDHex listID
+func (s *SQLiteDB) ForShoppingList(listID string) DBInterface {
+	return &SQLiteDB{DB: s.DB, Path: s.Path, household: s.household, list: listID, view: true}
+}
+
+// listID returns the IDHex of the shopping list item calls use, creating the
+// household's first list if it has none
+func (s *SQLiteDB) listID(ctx context.Context) (string, error) {
+	if s.list == "" {
+		lists, err := s.GetShoppingLists(ctx)
+		if err != nil {
+			return "", err
+		}
+		return lists[0].IDHex, nil
+	}
+	var id string
+	err := s.DB.QueryRowContext(ctx, `SELECT id FROM shopping_lists WHERE household_id = ? AND id = ?`, s.household, s.list).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return id, err
+}
+
+// GetShoppingLists returns every shopping list, in the order they were made,
+// creating the first list when there are none
+func (s *SQLiteDB) GetShoppingLists(ctx context.Context) ([]models.ShoppingList, error) {
+	if _, err := s.DB.ExecContext(ctx, `
+		INSERT INTO shopping_lists (id, household_id, name)
+		SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM shopping_lists WHERE household_id = ?)`,
+		primitive.NewObjectID().Hex(), s.household, models.DefaultShoppingListName, s.household); err != nil {
+		fmt.Printf("Error creating shopping list: %s\n", err)
+		return nil, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+shoppingListColumns+` FROM shopping_lists WHERE household_id = ? ORDER BY rowid`, s.household)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []models.ShoppingList
+	for rows.Next() {
+		list, err := scanShoppingList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// shoppingListColumns lists the shopping_lists columns in the order
+// scanShoppingList reads them
+const shoppingListColumns = `id, name, archived, version`
+
+// scanShoppingList reads a row of shoppingListColumns into a ShoppingList
+func scanShoppingList(row interface{ Scan(...any) error }) (models.ShoppingList, error) {
+	var list models.ShoppingList
+	if err := row.Scan(&list.IDHex, &list.Name, &list.Archived, &list.Version); err != nil {
+		return list, err
+	}
+	id, err := primitive.ObjectIDFromHex(list.IDHex)
+	if err != nil {
+		return list, fmt.Errorf("invalid object ID: %s", list.IDHex)
+	}
+	list.ID = id
+	return list, nil
+}
+
+// CreateShoppingList adds an empty shopping list called name
+func (s *SQLiteDB) CreateShoppingList(ctx context.Context, name string) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	// Make sure the household's first list comes before this one
+	if _, err := s.GetShoppingLists(ctx); err != nil {
+		return models.ShoppingList{}, err
+	}
+
+	list := models.ShoppingList{ID: primitive.NewObjectID(), Name: name}
+	list.IDHex = list.ID.Hex()
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO shopping_lists (id, household_id, name) VALUES (?, ?, ?)`, list.IDHex, s.household, list.Name); err != nil {
+		fmt.Printf("Error creating shopping list: %s\n", err)
+		return models.ShoppingList{}, err
+	}
+	return list, nil
+}
+
+// RenameShoppingList changes the name of the shopping list with IDHex
+func (s *SQLiteDB) RenameShoppingList(ctx context.Context, IDHex string, name string) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	return s.updateShoppingList(ctx, IDHex, `name = ?`, name)
+}
+
+// ArchiveShoppingList archives the shopping list with IDHex, or brings it
+// back when archived is false
+func (s *SQLiteDB) ArchiveShoppingList(ctx context.Context, IDHex string, archived bool) (models.ShoppingList, error) {
+	return s.updateShoppingList(ctx, IDHex, `archived = ?`, archived)
+}
+
+// EditShoppingList replaces the name and archived status of the shopping
+// list with list.IDHex, unless it has changed since list was read
+func (s *SQLiteDB) EditShoppingList(ctx context.Context, list models.ShoppingList) (models.ShoppingList, error) {
+	name, err := models.NormaliseShoppingListName(list.Name)
+	if err != nil {
+		return models.ShoppingList{}, err
+	}
+	result, err := s.DB.ExecContext(ctx, `
+		UPDATE shopping_lists SET name = ?, archived = ?, version = version + 1
+		WHERE household_id = ? AND id = ? AND version = ?`,
+		name, list.Archived, s.household, list.IDHex, list.Version)
+	if err != nil {
+		fmt.Printf("Error updating shopping list: %s\n", err)
+		return models.ShoppingList{}, err
+	}
+	if edited, err := result.RowsAffected(); err != nil || edited == 0 {
+		return listConflict(ctx, s, list.IDHex)
+	}
+	return scanShoppingList(s.DB.QueryRowContext(ctx, `SELECT `+shoppingListColumns+` FROM shopping_lists WHERE household_id = ? AND id = ?`, s.household, list.IDHex))
+}
+
+// updateShoppingList sets the columns in set on the shopping list with IDHex
+// and returns it
+func (s *SQLiteDB) updateShoppingList(ctx context.Context, IDHex string, set string, args ...any) (models.ShoppingList, error) {
+	result, err := s.DB.ExecContext(ctx, `UPDATE shopping_lists SET `+set+` WHERE household_id = ? AND id = ?`, append(args, s.household, IDHex)...)
+	if err != nil {
+		fmt.Printf("Error updating shopping list: %s\n", err)
+		return models.ShoppingList{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return models.ShoppingList{}, ErrNotFound
+	}
+	return scanShoppingList(s.DB.QueryRowContext(ctx, `SELECT `+shoppingListColumns+` FROM shopping_lists WHERE household_id = ? AND id = ?`, s.household, IDHex))
+}
+
+// DeleteShoppingList removes the shopping list with IDHex and its items
+func (s *SQLiteDB) DeleteShoppingList(ctx context.Context, IDHex string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM shopping_lists WHERE household_id = ? AND id = ?`, s.household, IDHex)
+	if err != nil {
+		fmt.Printf("Error deleting shopping list: %s\n", err)
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM shopping_list_items WHERE household_id = ? AND list_id = ?`, s.household, IDHex); err != nil {
+		fmt.Printf("Error deleting shopping list items: %s\n", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+// MoveShoppingListItem moves an item from this shopping list to the end of
+// the list with IDHex listID
+func (s *SQLiteDB) MoveShoppingListItem(ctx context.Context, itemId string, listID string) (models.ShoppingListItem, error) {
+	fromID, err := s.listID(ctx)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	to := &SQLiteDB{DB: s.DB, Path: s.Path, household: s.household, list: listID}
+	if _, err := to.listID(ctx); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if fromID == listID {
+		item, err := s.GetShoppingListItemFromIDHex(ctx, itemId)
+		if err == nil && item.IDHex == "" {
+			err = ErrNotFound
+		}
+		return item, err
+	}
+
+	// The item and both lists' versions change together
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE shopping_list_items
+		SET list_id = ?, sort_position = (SELECT COALESCE(MAX(sort_position), 0) + 1 FROM shopping_list_items WHERE household_id = ? AND list_id = ?)
+		WHERE household_id = ? AND list_id = ? AND id = ?`, listID, s.household, listID, s.household, fromID, itemId)
+	if err != nil {
+		fmt.Printf("Error moving shopping list item: %s\n", err)
+		return models.ShoppingListItem{}, err
+	}
+	if moved, err := result.RowsAffected(); err != nil || moved == 0 {
+		return models.ShoppingListItem{}, ErrNotFound
+	}
+	for _, changed := range []string{fromID, listID} {
+		if err := countListChange(ctx, tx, changed); err != nil {
+			return models.ShoppingListItem{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	return to.GetShoppingListItemFromIDHex(ctx, itemId)
+}
+
+// CreateHousehold adds a household. The first household created takes over
+// the data saved before households existed.
+func (s *SQLiteDB) CreateHousehold(ctx context.Context, household models.Household) (models.Household, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Household{}, err
+	}
+	defer tx.Rollback()
+
+	if household, err = createHousehold(ctx, tx, household); err != nil {
+		return models.Household{}, err
+	}
+	return household, tx.Commit()
+}
+
+// CreateOwnedHousehold adds a household and owner, its first user, in one
+// transaction, so that no household is left without its owner
+func (s *SQLiteDB) CreateOwnedHousehold(ctx context.Context, household models.Household, owner models.User) (models.Household, models.User, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	defer tx.Rollback()
+
+	if household, err = createHousehold(ctx, tx, household); err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	owner.HouseholdID = household.IDHex
+	if owner, err = createUser(ctx, tx, owner); err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Household{}, models.User{}, err
+	}
+	return household, owner, nil
+}
+
+// createHousehold adds a household in tx, handing it the data saved before
+// households existed when it is the first
+func createHousehold(ctx context.Context, tx *sql.Tx, household models.Household) (models.Household, error) {
+	if err := household.Validate(); err != nil {
+		return models.Household{}, err
+	}
+	household.ID = primitive.NewObjectID()
+	household.IDHex = household.ID.Hex()
+	household.Name = strings.TrimSpace(household.Name)
+
+	var households int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM households`).Scan(&households); err != nil {
+		return models.Household{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO households (id, name) VALUES (?, ?)`, household.IDHex, household.Name); err != nil {
+		fmt.Printf("Error adding household: %s\n", err)
+		return models.Household{}, err
+	}
+	if households == 0 {
+		if err := claimUnownedRows(ctx, tx, household.IDHex); err != nil {
+			return models.Household{}, fmt.Errorf("claiming existing data: %w", err)
+		}
+	}
+	return household, nil
+}
+
+// claimUnownedRows gives every row with no household to householdID
+func claimUnownedRows(ctx context.Context, tx *sql.Tx, householdID string) error {
+	// Foreign keys include household_id, so check them once every table moves
+	if _, err := tx.ExecContext(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+		return err
+	}
+	for _, table := range householdDataTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET household_id = ? WHERE household_id = ''`, table), householdID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHousehold retrieves a household by its hex ID
+func (s *SQLiteDB) GetHousehold(ctx context.Context, IDHex string) (models.Household, error) {
+	household := models.Household{IDHex: IDHex}
+	err := s.DB.QueryRowContext(ctx, `SELECT name FROM households WHERE id = ?`, IDHex).Scan(&household.Name)
+	if err == sql.ErrNoRows {
+		return models.Household{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Household{}, err
+	}
+	household.ID, err = primitive.ObjectIDFromHex(IDHex)
+	return household, err
+}
+
+// GetHouseholds retrieves every household, sorted by name
+func (s *SQLiteDB) GetHouseholds(ctx context.Context) ([]models.Household, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT id, name FROM households`)
+	if err != nil {
+		fmt.Printf("Error finding households: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	households := []models.Household{}
+	for rows.Next() {
+		var household models.Household
+		if err := rows.Scan(&household.IDHex, &household.Name); err != nil {
+			return nil, err
+		}
+		if household.ID, err = primitive.ObjectIDFromHex(household.IDHex); err != nil {
+			return nil, err
+		}
+		households = append(households, household)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	models.SortHouseholds(households)
+	return households, nil
+}
+
+// userColumns lists the users columns in the order scanUser reads them
+const userColumns = `id, email, name, password_hash, household_id, role`
+
+// scanUser reads a row of userColumns into a User
+func scanUser(row interface{ Scan(...any) error }) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.IDHex, &user.Email, &user.Name, &user.PasswordHash, &user.HouseholdID, &user.Role)
+	if err == sql.ErrNoRows {
+		return models.User{}, ErrNotFound
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	user.ID, err = primitive.ObjectIDFromHex(user.IDHex)
+	return user, err
+}
+
+// CreateUser adds a user. It returns ErrExists when the email is taken.
+func (s *SQLiteDB) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	if user, err = createUser(ctx, tx, user); err != nil {
+		return models.User{}, err
+	}
+	return user, tx.Commit()
+}
+
+// createUser adds a user in tx, returning ErrExists when the email is taken
+func createUser(ctx context.Context, tx *sql.Tx, user models.User) (models.User, error) {
+	user.Email = models.NormaliseEmail(user.Email)
+	if err := user.Validate(); err != nil {
+		return models.User{}, err
+	}
+	user.ID = primitive.NewObjectID()
+	user.IDHex = user.ID.Hex()
+
+	if _, err := scanUser(tx.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, user.Email)); err == nil {
+		return models.User{}, ErrExists
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		user.IDHex, user.Email, user.Name, user.PasswordHash, user.HouseholdID, user.Role); err != nil {
+		fmt.Printf("Error adding user: %s\n", err)
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// GetUser retrieves a user by their hex ID
+func (s *SQLiteDB) GetUser(ctx context.Context, IDHex string) (models.User, error) {
+	return scanUser(s.DB.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, IDHex))
+}
+
+// GetUserByEmail retrieves the user with an email address, ignoring case
+func (s *SQLiteDB) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return scanUser(s.DB.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, models.NormaliseEmail(email)))
+}
+
+// GetHouseholdUsers retrieves the users in a household, sorted by name
+func (s *SQLiteDB) GetHouseholdUsers(ctx context.Context, householdID string) ([]models.User, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE household_id = ?`, householdID)
+	if err != nil {
+		fmt.Printf("Error finding users: %s\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	models.SortUsers(users)
+	return users, nil
+}
+
+// SetUserRole changes what a user may do in their household
+func (s *SQLiteDB) SetUserRole(ctx context.Context, IDHex string, role models.Role) error {
+	if _, err := models.ParseRole(string(role)); err != nil {
+		return err
+	}
+	result, err := s.DB.ExecContext(ctx, `UPDATE users SET role = ? WHERE id = ?`, role, IDHex)
+	if err != nil {
+		fmt.Printf("Error setting user role: %s\n", err)
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateSession remembers a signed-in browser
+func (s *SQLiteDB) CreateSession(ctx context.Context, session models.Session) error {
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)`,
+		session.TokenHash, session.UserID, session.ExpiresAt.UTC().Format(sqliteTimeLayout)); err != nil {
+		fmt.Printf("Error adding session: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetSession retrieves the session with a token hash
+func (s *SQLiteDB) GetSession(ctx context.Context, tokenHash string) (models.Session, error) {
+	session := models.Session{TokenHash: tokenHash}
+	var expiresAt string
+	err := s.DB.QueryRowContext(ctx, `SELECT user_id, expires_at FROM sessions WHERE token_hash = ?`, tokenHash).Scan(&session.UserID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return models.Session{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Session{}, err
+	}
+	session.ExpiresAt, err = time.Parse(sqliteTimeLayout, expiresAt)
+	return session, err
+}
+
+// DeleteSession signs a browser out. Deleting a missing session is not an
+// error.
+func (s *SQLiteDB) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash); err != nil {
+		fmt.Printf("Error deleting session: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// CreateInvite stores an invite to join a household
+func (s *SQLiteDB) CreateInvite(ctx context.Context, invite models.Invite) error {
+	if _, err := s.DB.ExecContext(ctx, `INSERT INTO invites (code_hash, household_id, role, expires_at) VALUES (?, ?, ?, ?)`,
+		invite.CodeHash, invite.HouseholdID, invite.Role, invite.ExpiresAt.UTC().Format(sqliteTimeLayout)); err != nil {
+		fmt.Printf("Error adding invite: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+// GetInvite retrieves the invite with a code hash
+func (s *SQLiteDB) GetInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	invite := models.Invite{CodeHash: codeHash}
+	var expiresAt string
+	err := s.DB.QueryRowContext(ctx, `SELECT household_id, role, expires_at FROM invites WHERE code_hash = ?`, codeHash).
+		Scan(&invite.HouseholdID, &invite.Role, &expiresAt)
+	if err == sql.ErrNoRows {
+		return models.Invite{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Invite{}, err
+	}
+	invite.ExpiresAt, err = time.Parse(sqliteTimeLayout, expiresAt)
+	return invite, err
+}
+
+// ClaimInvite removes the invite with a code hash and returns it
+func (s *SQLiteDB) ClaimInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	invite := models.Invite{CodeHash: codeHash}
+	var expiresAt string
+	err := s.DB.QueryRowContext(ctx, `DELETE FROM invites WHERE code_hash = ? RETURNING household_id, role, expires_at`, codeHash).
+		Scan(&invite.HouseholdID, &invite.Role, &expiresAt)
+	if err == sql.ErrNoRows {
+		return models.Invite{}, ErrNotFound
+	}
+	if err != nil {
+		fmt.Printf("Error claiming invite: %s\n", err)
+		return models.Invite{}, err
+	}
+	invite.ExpiresAt, err = time.Parse(sqliteTimeLayout, expiresAt)
+	return invite, err
+}
+
+// repairListOrder gives each item of the household's list with IDHex listID
+// its own sort_position, if any are missing or shared, and describes the drift
+func (s *SQLiteDB) repairListOrder(ctx context.Context, household, listID string) (ListIntegrity, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return ListIntegrity{}, err
+	}
+	defer tx.Rollback()
+
+	ids, drift, err := listOrder(ctx, tx, household, listID)
+	if err != nil || drift.OK() {
+		return drift, err
+	}
+	if err := setListOrder(ctx, tx, household, listID, ids); err != nil {
+		return drift, err
+	}
+	return drift, tx.Commit()
+}
+
+// listOrder returns the IDs of a list's items by sort_position, followed by
+// any without one in the order they were added, and describes any items
+// without a position or sharing one
+func listOrder(ctx context.Context, db interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}, household, listID string) ([]string, ListIntegrity, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, sort_position FROM shopping_list_items WHERE household_id = ? AND list_id = ?
+		ORDER BY sort_position IS NULL, sort_position, rowid`, household, listID)
+	if err != nil {
+		return nil, ListIntegrity{}, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	var drift ListIntegrity
+	var previous sql.NullInt64
+	for rows.Next() {
+		var id string
+		var position sql.NullInt64
+		if err := rows.Scan(&id, &position); err != nil {
+			return nil, drift, err
+		}
+		switch {
+		case !position.Valid:
+			drift.Missing = append(drift.Missing, id)
+		case previous.Valid && position.Int64 == previous.Int64:
+			drift.Duplicates = append(drift.Duplicates, id)
+		}
+		previous = position
+		ids = append(ids, id)
+	}
+	return ids, drift, rows.Err()
+}
+
+// setListOrder numbers the items of a list from 1 in the order of ids
+func setListOrder(ctx context.Context, tx *sql.Tx, household, listID string, ids []string) error {
+	for i, id := range ids {
+		if _, err := tx.ExecContext(ctx, `UPDATE shopping_list_items SET sort_position = ? WHERE household_id = ? AND list_id = ? AND id = ?`, i+1, household, listID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyIntegrity checks the order of every household's shopping lists
+func (s *SQLiteDB) VerifyIntegrity(ctx context.Context) (IntegrityReport, error) {
+	return s.checkIntegrity(ctx, false)
+}
+
+// Repair puts right the order of every household's shopping lists
+func (s *SQLiteDB) Repair(ctx context.Context) (IntegrityReport, error) {
+	return s.checkIntegrity(ctx, true)
+}
+
+// checkIntegrity checks, and when repair is set fixes, the order of every
+// list in the database
+func (s *SQLiteDB) checkIntegrity(ctx context.Context, repair bool) (IntegrityReport, error) {
+	var report IntegrityReport
+	rows, err := s.DB.QueryContext(ctx, `SELECT household_id, id, name FROM shopping_lists ORDER BY household_id, rowid`)
+	if err != nil {
+		return report, err
+	}
+	var lists []ListIntegrity
+	for rows.Next() {
+		var list ListIntegrity
+		if err := rows.Scan(&list.Household, &list.ListID, &list.Name); err != nil {
+			rows.Close()
+			return report, err
+		}
+		lists = append(lists, list)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	for _, list := range lists {
+		var drift ListIntegrity
+		if repair {
+			drift, err = s.repairListOrder(ctx, list.Household, list.ListID)
+		} else {
+			_, drift, err = listOrder(ctx, s.DB, list.Household, list.ListID)
+		}
+		if err != nil {
+			return report, fmt.Errorf("checking list %s: %w", list.ListID, err)
+		}
+		report.Lists++
+		if !drift.OK() {
+			list.Missing, list.Duplicates = drift.Missing, drift.Duplicates
+			report.Problems = append(report.Problems, list)
+		}
+	}
+	return report, nil
+}
+
+// Close closes the SQLite database
+func (s *SQLiteDB) Close() {
+	if s.view {
+		return
+	}
+	s.DB.Close()
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLiteDBCreatesDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "mealplanner.db")
+
+	store, err := NewSQLiteDB(context.Background(), path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	assert.FileExists(t, path)
+}
+
+func TestSQLiteDBPersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	store, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	_, err = store.AddShoppingListItem(ctx, "Milk")
+	require.NoError(t, err)
+	require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Friday", "Dinner", "Fish and chips"))
+	store.Close()
+
+	reopened, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	assert.Equal(t, []string{"Milk"}, itemNames(t, reopened))
+
+	mealPlan, err := reopened.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Len(t, mealPlan.Meals, 7, "reopening should not duplicate the days")
+	assert.Equal(t, "Fish and chips", mealPlan.Meals[4].SlotMeal("Dinner"))
+}
+
+func TestSQLiteDBMigratesUndatedMeals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	// Create a database in the layout used before meal plans were dated
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE meals (day TEXT PRIMARY KEY, meal TEXT NOT NULL DEFAULT '', position INTEGER NOT NULL);
+		INSERT INTO meals (day, meal, position) VALUES ('Monday', 'Curry', 0), ('Sunday', 'Roast', 6);`)
+	require.NoError(t, err)
+	legacy.Close()
+
+	store, err := NewSQLiteDB(context.Background(), path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	mealPlan, err := store.GetMealPlan(context.Background(), models.ISOWeek(time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal(models.LegacyMealSlot))
+	assert.Empty(t, mealPlan.Meals[1].Slots)
+	assert.Equal(t, "Roast", mealPlan.Meals[6].SlotMeal(models.LegacyMealSlot))
+
+	var tables int
+	require.NoError(t, store.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'meals'`).Scan(&tables))
+	assert.Zero(t, tables, "the undated meals table should be dropped")
+}
+
+func TestSQLiteDBMigratesSingleSlotMeals(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	// Create a database in the layout used before days had slots
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE meal_plans (week TEXT PRIMARY KEY, week_start TEXT NOT NULL);
+		CREATE TABLE meal_plan_meals (week TEXT NOT NULL, day TEXT NOT NULL, meal TEXT NOT NULL DEFAULT '', position INTEGER NOT NULL, PRIMARY KEY (week, day));
+		INSERT INTO meal_plans VALUES ('2026-W42', '2026-10-12');
+		INSERT INTO meal_plan_meals VALUES ('2026-W42', 'Monday', 'Curry', 0), ('2026-W42', 'Tuesday', '', 1);`)
+	require.NoError(t, err)
+	legacy.Close()
+
+	store, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	require.Len(t, mealPlan.Meals, 7)
+	assert.Equal(t, map[string]string{models.LegacyMealSlot: "Curry"}, mealPlan.Meals[0].Slots)
+	assert.Empty(t, mealPlan.Meals[1].Slots)
+
+	require.NoError(t, store.UpdateMeal(ctx, "2026-W42", "Monday", "Lunch", "Soup"))
+	mealPlan, err = store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal(models.LegacyMealSlot))
+	assert.Equal(t, "Soup", mealPlan.Meals[0].SlotMeal("Lunch"))
+}
+
+func TestSQLiteDBAddsMealRecipeColumn(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	// Create a database in the layout used before meals could link to recipes
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE meal_plans (week TEXT PRIMARY KEY, week_start TEXT NOT NULL);
+		CREATE TABLE meal_plan_meals (week TEXT NOT NULL, day TEXT NOT NULL, position INTEGER NOT NULL, PRIMARY KEY (week, day));
+		CREATE TABLE meal_slots (week TEXT NOT NULL, day TEXT NOT NULL, slot TEXT NOT NULL, meal TEXT NOT NULL DEFAULT '', PRIMARY KEY (week, day, slot));
+		INSERT INTO meal_plans VALUES ('2026-W42', '2026-10-12');
+		INSERT INTO meal_plan_meals VALUES ('2026-W42', 'Monday', 0);
+		INSERT INTO meal_slots VALUES ('2026-W42', 'Monday', 'Dinner', 'Curry');`)
+	require.NoError(t, err)
+	legacy.Close()
+
+	store, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.SetMealRecipe(ctx, "2026-W42", "Monday", "Dinner", "abc123"))
+	mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal("Dinner"))
+	assert.Equal(t, "abc123", mealPlan.Meals[0].SlotRecipe("Dinner"))
+}
+
+func TestSQLiteDBAddsShoppingListItemQuantityColumns(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	// Create a database in the layout used before items had quantities
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE shopping_list_items (id TEXT PRIMARY KEY, item TEXT NOT NULL, ticked INTEGER NOT NULL DEFAULT 0, sort_position INTEGER);
+		INSERT INTO shopping_list_items VALUES ('6513a5c3e4b0a1b2c3d4e5f6', '2 x 1L milk', 0, 1);`)
+	require.NoError(t, err)
+	legacy.Close()
+
+	store, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	item, err := store.GetShoppingListItemFromIDHex(ctx, "6513a5c3e4b0a1b2c3d4e5f6")
+	require.NoError(t, err)
+	assert.Equal(t, "2 x 1L milk", item.Text())
+
+	item.Item, item.Quantity, item.Unit = "milk", 2, "l"
+	updated, err := store.UpdateShoppingListItemDetails(ctx, item)
+	require.NoError(t, err)
+	assert.Equal(t, "2 l milk", updated.Text())
+}
+
+func TestSQLiteDBMigratesToHouseholds(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	// Create a database in the layout used before households existed
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE meal_plans (week TEXT PRIMARY KEY, week_start TEXT NOT NULL);
+		CREATE TABLE meal_plan_meals (week TEXT NOT NULL REFERENCES meal_plans (week) ON DELETE CASCADE, day TEXT NOT NULL, position INTEGER NOT NULL, PRIMARY KEY (week, day));
+		CREATE TABLE meal_slots (week TEXT NOT NULL, day TEXT NOT NULL, slot TEXT NOT NULL, meal TEXT NOT NULL DEFAULT '', recipe_id TEXT NOT NULL DEFAULT '', PRIMARY KEY (week, day, slot), FOREIGN KEY (week, day) REFERENCES meal_plan_meals (week, day) ON DELETE CASCADE);
+		CREATE TABLE categories (name TEXT PRIMARY KEY, category TEXT NOT NULL);
+		INSERT INTO meal_plans VALUES ('2026-W42', '2026-10-12');
+		INSERT INTO meal_plan_meals VALUES ('2026-W42', 'Monday', 0);
+		INSERT INTO meal_slots (week, day, slot, meal) VALUES ('2026-W42', 'Monday', 'Dinner', 'Curry');
+		INSERT INTO categories VALUES ('milk', 'Dairy & eggs');`)
+	require.NoError(t, err)
+	legacy.Close()
+
+	store, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	household, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+	require.NoError(t, err)
+	data := store.ForHousehold(household.IDHex)
+
+	mealPlan, err := data.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal("Dinner"))
+	dictionary, err := data.GetCategoryDictionary(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"milk": "Dairy & eggs"}, dictionary)
+
+	// A second household can plan the same week
+	other, err := store.CreateHousehold(ctx, models.Household{Name: "Allotment"})
+	require.NoError(t, err)
+	require.NoError(t, store.ForHousehold(other.IDHex).UpdateMeal(ctx, "2026-W42", "Monday", "Dinner", "Pizza"))
+	mealPlan, err = data.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal("Dinner"))
+}
+
+func TestSQLiteDBMigratesToNamedShoppingLists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mealplanner.db")
+
+	// Create a database in the layout used before a household had more than
+	// one shopping list
+	legacy, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = legacy.Exec(`
+		CREATE TABLE shopping_list_items (household_id TEXT NOT NULL DEFAULT '', id TEXT PRIMARY KEY, item TEXT NOT NULL, ticked INTEGER NOT NULL DEFAULT 0, sort_position INTEGER);
+		INSERT INTO shopping_list_items (id, item, sort_position) VALUES ('6513a5c3e4b0a1b2c3d4e5f6', 'milk', 2), ('6513a5c3e4b0a1b2c3d4e5f7', 'bread', 1);`)
+	require.NoError(t, err)
+	legacy.Close()
+
+	store, err := NewSQLiteDB(ctx, path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	household, err := store.CreateHousehold(ctx, models.Household{Name: "Home"})
+	require.NoError(t, err)
+	data := store.ForHousehold(household.IDHex)
+
+	lists, err := data.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	require.Len(t, lists, 1)
+	assert.Equal(t, models.DefaultShoppingListName, lists[0].Name)
+	assert.Equal(t, []string{"bread", "milk"}, itemNames(t, data.ForShoppingList(lists[0].IDHex)))
+}
+
+func TestSQLiteDBShoppingListChangesAreAtomic(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteDB(ctx, filepath.Join(t.TempDir(), "mealplanner.db"))
+	require.NoError(t, err)
+	defer store.Close()
+	items := addItems(t, store, "Eggs", "Bread")
+	hardware, err := store.CreateShoppingList(ctx, "Hardware")
+	require.NoError(t, err)
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+
+	// Counting the change in a list's version is the last step of each change,
+	// so failing it fails the change after its first step
+	_, err = store.DB.Exec(`CREATE TRIGGER fail_list_change BEFORE UPDATE OF version ON shopping_lists
+		BEGIN SELECT RAISE(ABORT, 'simulated failure'); END`)
+	require.NoError(t, err)
+
+	_, err = store.InsertShoppingListItem(ctx, models.ShoppingListItem{Item: "Cheese"})
+	assert.ErrorContains(t, err, "simulated failure")
+	assert.ErrorContains(t, store.DeleteShoppingListItem(ctx, items[0].IDHex), "simulated failure")
+	_, err = store.MoveShoppingListItem(ctx, items[1].IDHex, hardware.IDHex)
+	assert.ErrorContains(t, err, "simulated failure")
+
+	_, err = store.DB.Exec(`DROP TRIGGER fail_list_change`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Eggs", "Bread"}, itemNames(t, store), "nothing was added, deleted or moved")
+	assert.Empty(t, itemNames(t, store.ForShoppingList(hardware.IDHex)))
+	after, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, lists[0].Version, after[0].Version)
+	report, err := store.VerifyIntegrity(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+}
@@ -0,0 +1,356 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JonClarke84/mealplannergo/pkg/units"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultSyncBatchSize is how many documents Sync writes at a time
+const DefaultSyncBatchSize = 500
+
+// SyncCollections lists the collections Sync copies by default: the
+// households, their users and all of their data, and the record of the
+// migrations that data has had. Sessions and invites are left behind, so
+// nobody is signed in to the copy by another database's session.
+var SyncCollections = append([]string{"households", "users", "migrations"}, householdCollections...)
+
+// anonymisedFields lists, by collection, the string fields an anonymised
+// sync scrambles. Paths go through arrays to each element, and a path ending
+// at a document scrambles each of its values. Item names are scrambled the
+// same way wherever they appear, so the categories, walk order and ticks of
+// an item still match it.
+var anonymisedFields = map[string][][]string{
+	"shopping-lists": {
+		{"Name"},
+		{"ShoppingList", "Item"}, {"ShoppingList", "Note"}, {"ShoppingList", "Sources", "meal"},
+	},
+	"meal-plans":  {{"meals", "meal"}, {"meals", "slots"}},
+	"recipes":     {{"Title"}, {"Ingredients", "Name"}, {"Method"}},
+	"categories":  {{"_id"}, {"Name"}},
+	"walk-order":  {{"_id"}, {"Item"}},
+	"tick-events": {{"Item"}},
+	"changes": {
+		{"UserName"},
+		{"Before", "Item", "Item"}, {"Before", "Item", "Note"}, {"Before", "Item", "Sources", "meal"},
+		{"After", "Item", "Item"}, {"After", "Item", "Note"}, {"After", "Item", "Sources", "meal"},
+		{"Before", "Meal", "meal"}, {"Before", "Meal", "conflict"},
+		{"After", "Meal", "meal"}, {"After", "Meal", "conflict"},
+	},
+	"households": {{"Name"}},
+	"users":      {{"Name"}, {"Email"}, {"PasswordHash"}},
+}
+
+// anonymisedPasswordHash replaces every password hash in an anonymised sync.
+// It is a bcrypt hash of DemoPassword, so each copied account signs in with
+// that rather than its real password.
+const anonymisedPasswordHash = "$2a$10$Ehn4rtncyVI6VKlygF7Y1O2cKboAjyOyvFFJcOOMQl1CCWdafxVcG"
+
+// SyncOptions chooses what Sync copies and how
+type SyncOptions struct {
+	// Collections to copy, or SyncCollections when empty
+	Collections []string
+	// DryRun only works out what would change
+	DryRun bool
+	// AnonymiseKey, when set, scrambles lists, meals, recipes, households
+	// and user details on the way, and resets passwords to DemoPassword; the
+	// same key scrambles the same text the same way
+	AnonymiseKey string
+	// BatchSize is how many documents are written at a time, or
+	// DefaultSyncBatchSize when 0
+	BatchSize int
+}
+
+// SyncSummary counts what Sync did, or in a dry run would do, to one
+// collection of the destination
+type SyncSummary struct {
+	Collection string
+	Added      int
+	Changed    int
+	Removed    int
+	Unchanged  int
+}
+
+// syncCollection is what Sync needs of a collection, so that it can be
+// tested without a MongoDB server
+type syncCollection interface {
+	// each calls fn with every document in turn
+	each(ctx context.Context, fn func(bson.Raw) error) error
+	// replace saves documents over those with the same _id, adding them
+	// when there are none
+	replace(ctx context.Context, documents []bson.Raw) error
+	// remove deletes the documents with the given _ids
+	remove(ctx context.Context, ids []bson.RawValue) error
+}
+
+// Sync makes the collections of to match those of from, which may be on
+// another deployment. The documents of from are read one at a time, and only
+// those that differ are written, in batches; documents only in to are
+// removed.
+func Sync(ctx context.Context, from, to *mongo.Database, options SyncOptions) ([]SyncSummary, error) {
+	if from.Client() == to.Client() && from.Name() == to.Name() {
+		return nil, fmt.Errorf("cannot sync %s onto itself", from.Name())
+	}
+	collections := options.Collections
+	if len(collections) == 0 {
+		collections = SyncCollections
+	}
+	var summaries []SyncSummary
+	for _, name := range collections {
+		summary, err := syncDocuments(ctx, mongoSyncCollection{from.Collection(name)}, mongoSyncCollection{to.Collection(name)}, name, options)
+		if err != nil {
+			return summaries, fmt.Errorf("syncing %s: %w", name, err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// syncDocuments makes dest match source, both holding the collection name
+func syncDocuments(ctx context.Context, source, dest syncCollection, name string, options SyncOptions) (SyncSummary, error) {
+	summary := SyncSummary{Collection: name}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultSyncBatchSize
+	}
+
+	// Only a hash of each destination document is kept, not the document
+	type destDocument struct {
+		id  bson.RawValue
+		sum [sha256.Size]byte
+	}
+	existing := make(map[string]destDocument)
+	err := dest.each(ctx, func(document bson.Raw) error {
+		id, err := document.LookupErr("_id")
+		if err != nil {
+			return fmt.Errorf("destination document without _id: %w", err)
+		}
+		existing[idKey(id)] = destDocument{id: id, sum: sha256.Sum256(document)}
+		return nil
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	var batch []bson.Raw
+	flush := func() error {
+		if len(batch) == 0 || options.DryRun {
+			batch = batch[:0]
+			return nil
+		}
+		err := dest.replace(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+	err = source.each(ctx, func(document bson.Raw) error {
+		if options.AnonymiseKey != "" {
+			var err error
+			if document, err = anonymiseDocument(document, name, options.AnonymiseKey); err != nil {
+				return err
+			}
+		}
+		id, err := document.LookupErr("_id")
+		if err != nil {
+			return fmt.Errorf("source document without _id: %w", err)
+		}
+		key := idKey(id)
+		current, found := existing[key]
+		delete(existing, key)
+		switch {
+		case !found:
+			summary.Added++
+		case current.sum != sha256.Sum256(document):
+			summary.Changed++
+		default:
+			summary.Unchanged++
+			return nil
+		}
+		// The batch holds copies, as the cursor reuses its buffer
+		batch = append(batch, append(bson.Raw(nil), document...))
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, err
+	}
+	if err := flush(); err != nil {
+		return summary, err
+	}
+
+	var removed []bson.RawValue
+	for _, document := range existing {
+		removed = append(removed, document.id)
+	}
+	summary.Removed = len(removed)
+	if options.DryRun {
+		return summary, nil
+	}
+	for start := 0; start < len(removed); start += batchSize {
+		end := min(start+batchSize, len(removed))
+		if err := dest.remove(ctx, removed[start:end]); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// idKey identifies an _id of any type
+func idKey(id bson.RawValue) string {
+	return string(rune(id.Type)) + string(id.Value)
+}
+
+// anonymiseDocument scrambles the anonymised fields of a document of the
+// collection name
+func anonymiseDocument(document bson.Raw, name, key string) (bson.Raw, error) {
+	paths := anonymisedFields[name]
+	if len(paths) == 0 {
+		return document, nil
+	}
+	var decoded bson.D
+	if err := bson.Unmarshal(document, &decoded); err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		decoded = scrambleField(decoded, path, key).(bson.D)
+	}
+	return bson.Marshal(decoded)
+}
+
+// scrambleField scrambles the strings at path within value
+func scrambleField(value any, path []string, key string) any {
+	switch v := value.(type) {
+	case bson.D:
+		for i := range v {
+			if v[i].Key == path[0] {
+				if len(path) == 1 {
+					v[i].Value = scrambleValue(v[i].Value, path[0], key)
+				} else {
+					v[i].Value = scrambleField(v[i].Value, path[1:], key)
+				}
+			}
+		}
+		return v
+	case bson.A:
+		for i := range v {
+			v[i] = scrambleField(v[i], path, key)
+		}
+		return v
+	}
+	return value
+}
+
+// scrambleValue scrambles a string, or each string of an array or document.
+// Emails keep their shape, at example.com, and password hashes are replaced
+// with anonymisedPasswordHash.
+func scrambleValue(value any, field, key string) any {
+	switch v := value.(type) {
+	case string:
+		switch field {
+		case "Email":
+			local, _, _ := strings.Cut(v, "@")
+			return Scramble(local, key) + "@example.com"
+		case "PasswordHash":
+			return anonymisedPasswordHash
+		}
+		return Scramble(v, key)
+	case bson.A:
+		for i := range v {
+			v[i] = scrambleValue(v[i], field, key)
+		}
+		return v
+	case bson.D:
+		for i := range v {
+			v[i].Value = scrambleValue(v[i].Value, field, key)
+		}
+		return v
+	}
+	return value
+}
+
+// Scramble replaces each letter of s with another, keeping case, spaces,
+// punctuation and shape, so scrambled data looks like the real thing. Each
+// word is scrambled on its own, ignoring case, and words holding digits or
+// naming a unit are kept, so "2 kg Potatoes" and "potatoes" still name the
+// same item. The same word and key always give the same result.
+func Scramble(s, key string) string {
+	var b strings.Builder
+	word := func(w string) {
+		if w == "" || strings.ContainsFunc(w, unicode.IsDigit) || units.DimensionOf(strings.ToLower(w)) != units.Count {
+			b.WriteString(w)
+			return
+		}
+		seed := fnv.New64a()
+		seed.Write([]byte(key))
+		seed.Write([]byte{0})
+		seed.Write([]byte(strings.ToLower(w)))
+		random := rand.New(rand.NewSource(int64(seed.Sum64())))
+		for _, r := range w {
+			switch {
+			case unicode.IsUpper(r):
+				r = rune('A' + random.Intn(26))
+			case unicode.IsLetter(r):
+				r = rune('a' + random.Intn(26))
+			}
+			b.WriteRune(r)
+		}
+	}
+	start := 0
+	for i, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			word(s[start:i])
+			b.WriteRune(r)
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	word(s[start:])
+	return b.String()
+}
+
+// mongoSyncCollection is a syncCollection in MongoDB
+type mongoSyncCollection struct {
+	collection *mongo.Collection
+}
+
+func (c mongoSyncCollection) each(ctx context.Context, fn func(bson.Raw) error) error {
+	cursor, err := c.collection.Find(ctx, bson.D{}, options.Find().SetBatchSize(DefaultSyncBatchSize))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (c mongoSyncCollection) replace(ctx context.Context, documents []bson.Raw) error {
+	writes := make([]mongo.WriteModel, 0, len(documents))
+	for _, document := range documents {
+		id := document.Lookup("_id")
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: id}}).
+			SetReplacement(document).
+			SetUpsert(true))
+	}
+	_, err := c.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (c mongoSyncCollection) remove(ctx context.Context, ids []bson.RawValue) error {
+	_, err := c.collection.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	return err
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fakeSyncCollection is a syncCollection in memory, counting its writes
+type fakeSyncCollection struct {
+	documents map[string]bson.Raw
+	order     []string
+	writes    int
+}
+
+func newFakeSyncCollection(t *testing.T, documents ...bson.D) *fakeSyncCollection {
+	c := &fakeSyncCollection{documents: make(map[string]bson.Raw)}
+	for _, document := range documents {
+		raw, err := bson.Marshal(document)
+		require.NoError(t, err)
+		require.NoError(t, c.replace(context.Background(), []bson.Raw{raw}))
+	}
+	c.writes = 0
+	return c
+}
+
+func (c *fakeSyncCollection) each(ctx context.Context, fn func(bson.Raw) error) error {
+	for _, key := range c.order {
+		if document, ok := c.documents[key]; ok {
+			if err := fn(document); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *fakeSyncCollection) replace(ctx context.Context, documents []bson.Raw) error {
+	c.writes++
+	for _, document := range documents {
+		key := idKey(document.Lookup("_id"))
+		if _, ok := c.documents[key]; !ok {
+			c.order = append(c.order, key)
+		}
+		c.documents[key] = document
+	}
+	return nil
+}
+
+func (c *fakeSyncCollection) remove(ctx context.Context, ids []bson.RawValue) error {
+	c.writes++
+	for _, id := range ids {
+		delete(c.documents, idKey(id))
+	}
+	return nil
+}
+
+// get returns the document with the _id, decoded
+func (c *fakeSyncCollection) get(t *testing.T, id any) bson.M {
+	_, data, err := bson.MarshalValue(id)
+	require.NoError(t, err)
+	for _, document := range c.documents {
+		if string(document.Lookup("_id").Value) == string(data) {
+			var decoded bson.M
+			require.NoError(t, bson.Unmarshal(document, &decoded))
+			return decoded
+		}
+	}
+	return nil
+}
+
+func TestSyncDocuments(t *testing.T) {
+	source := newFakeSyncCollection(t,
+		bson.D{{Key: "_id", Value: 1}, {Key: "Item", Value: "milk"}},
+		bson.D{{Key: "_id", Value: 2}, {Key: "Item", Value: "bread"}},
+		bson.D{{Key: "_id", Value: 3}, {Key: "Item", Value: "eggs"}},
+		bson.D{{Key: "_id", Value: 4}, {Key: "Item", Value: "tea"}},
+	)
+	dest := newFakeSyncCollection(t,
+		bson.D{{Key: "_id", Value: 1}, {Key: "Item", Value: "milk"}},
+		bson.D{{Key: "_id", Value: 2}, {Key: "Item", Value: "brown bread"}},
+		bson.D{{Key: "_id", Value: 9}, {Key: "Item", Value: "coffee"}},
+	)
+
+	summary, err := syncDocuments(context.Background(), source, dest, "walk-order", SyncOptions{DryRun: true})
+	require.NoError(t, err)
+	want := SyncSummary{Collection: "walk-order", Added: 2, Changed: 1, Removed: 1, Unchanged: 1}
+	assert.Equal(t, want, summary)
+	assert.Zero(t, dest.writes, "a dry run changes nothing")
+
+	summary, err = syncDocuments(context.Background(), source, dest, "walk-order", SyncOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, want, summary)
+	assert.Equal(t, 3, dest.writes, "three changed documents in batches of two, then one removal")
+	assert.Len(t, dest.documents, 4)
+	assert.Equal(t, "bread", dest.get(t, 2)["Item"])
+	assert.Nil(t, dest.get(t, 9))
+
+	summary, err = syncDocuments(context.Background(), source, dest, "walk-order", SyncOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, SyncSummary{Collection: "walk-order", Unchanged: 4}, summary)
+}
+
+func TestSyncDocumentsAnonymised(t *testing.T) {
+	source := newFakeSyncCollection(t, bson.D{
+		{Key: "_id", Value: 1},
+		{Key: "Name", Value: "Weekly shop"},
+		{Key: "ShoppingList", Value: bson.A{
+			bson.D{{Key: "Item", Value: "2 kg Potatoes"}, {Key: "Note", Value: "for Sam's party"}, {Key: "Category", Value: "Veg"}},
+		}},
+	})
+	dest := newFakeSyncCollection(t)
+	options := SyncOptions{AnonymiseKey: "key"}
+
+	_, err := syncDocuments(context.Background(), source, dest, "shopping-lists", options)
+	require.NoError(t, err)
+	list := dest.get(t, 1)
+	assert.Equal(t, Scramble("Weekly shop", "key"), list["Name"])
+	item := list["ShoppingList"].(bson.A)[0].(bson.M)
+	assert.Equal(t, "2 kg "+Scramble("Potatoes", "key"), item["Item"])
+	assert.NotContains(t, item["Note"], "Sam")
+	assert.Equal(t, "Veg", item["Category"], "only the fields listed are scrambled")
+
+	summary, err := syncDocuments(context.Background(), source, dest, "shopping-lists", options)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Unchanged, "the same key scrambles the same way, so nothing changes")
+
+	hash, err := auth.HashPassword("sams-real-password")
+	require.NoError(t, err)
+	users := newFakeSyncCollection(t, bson.D{{Key: "_id", Value: 1}, {Key: "Email", Value: "sam@gmail.com"}, {Key: "Name", Value: "Sam"}, {Key: "PasswordHash", Value: hash}})
+	dest = newFakeSyncCollection(t)
+	_, err = syncDocuments(context.Background(), users, dest, "users", options)
+	require.NoError(t, err)
+	user := dest.get(t, 1)
+	assert.Equal(t, Scramble("sam", "key")+"@example.com", user["Email"])
+	assert.Equal(t, Scramble("Sam", "key"), user["Name"])
+	assert.True(t, auth.CheckPassword(user["PasswordHash"].(string), DemoPassword), "copied accounts sign in with the demo password")
+}
+
+func TestSyncDocumentsAnonymisedLeavesNoProductionText(t *testing.T) {
+	// Each collection holds production text in every field that may name
+	// people, places or what a household eats
+	secrets := []string{"Sam", "Smiths", "Kitchen", "Birthday", "Lasagne", "Mince", "Preheat", "Secret"}
+	collections := map[string]bson.D{
+		"households": {{Key: "_id", Value: 1}, {Key: "Name", Value: "The Smiths"}},
+		"users":      {{Key: "_id", Value: 1}, {Key: "Name", Value: "Sam"}, {Key: "Email", Value: "sam@smiths.com"}, {Key: "PasswordHash", Value: "$2a$10$Secret"}},
+		"shopping-lists": {{Key: "_id", Value: 1}, {Key: "Name", Value: "Kitchen"}, {Key: "ShoppingList", Value: bson.A{bson.D{
+			{Key: "Item", Value: "Mince"},
+			{Key: "Note", Value: "Sam's Birthday"},
+			{Key: "Sources", Value: bson.A{bson.D{{Key: "week", Value: "2026-W42"}, {Key: "meal", Value: "Lasagne"}}}},
+		}}}},
+		"meal-plans": {{Key: "_id", Value: 1}, {Key: "week", Value: "2026-W42"}, {Key: "meals", Value: bson.A{bson.D{
+			{Key: "day", Value: "Monday"},
+			{Key: "meal", Value: "Lasagne"},
+			{Key: "slots", Value: bson.D{{Key: "Dinner", Value: "Birthday Lasagne"}}},
+		}}}},
+		"recipes": {
+			{Key: "_id", Value: 1},
+			{Key: "Title", Value: "Lasagne"},
+			{Key: "Ingredients", Value: bson.A{bson.D{{Key: "Name", Value: "Mince"}, {Key: "Quantity", Value: 500.0}}}},
+			{Key: "Method", Value: bson.A{"Preheat the oven"}},
+		},
+		"changes": {
+			{Key: "_id", Value: 1},
+			{Key: "UserName", Value: "Sam"},
+			{Key: "Before", Value: bson.D{{Key: "Meal", Value: bson.D{{Key: "meal", Value: "Lasagne"}, {Key: "conflict", Value: "Birthday"}}}}},
+			{Key: "After", Value: bson.D{{Key: "Item", Value: bson.D{
+				{Key: "Item", Value: "Mince"},
+				{Key: "Sources", Value: bson.A{bson.D{{Key: "meal", Value: "Lasagne"}}}},
+			}}}},
+		},
+	}
+	for name, document := range collections {
+		dest := newFakeSyncCollection(t)
+		_, err := syncDocuments(context.Background(), newFakeSyncCollection(t, document), dest, name, SyncOptions{AnonymiseKey: "key"})
+		require.NoError(t, err)
+		require.Len(t, dest.documents, 1)
+		for _, copied := range dest.documents {
+			text := strings.ToLower(copied.String())
+			for _, secret := range secrets {
+				assert.NotContains(t, text, strings.ToLower(secret), name)
+			}
+		}
+	}
+}
+
+func TestScramble(t *testing.T) {
+	scrambled := Scramble("Sam's Milk, 2 pints", "key")
+	assert.NotEqual(t, "Sam's Milk, 2 pints", scrambled)
+	assert.Regexp(t, `^[A-Z][a-z]{2}'[a-z] [A-Z][a-z]{3}, 2 [a-z]{5}$`, scrambled)
+	assert.Equal(t, scrambled, Scramble("Sam's Milk, 2 pints", "key"))
+	assert.NotEqual(t, scrambled, Scramble("Sam's Milk, 2 pints", "another key"))
+
+	assert.Equal(t, Scramble("milk", "key"), strings.ToLower(Scramble("MILK", "key")), "case is kept but does not change the scrambling")
+	assert.Equal(t, "500 ml "+Scramble("milk", "key"), Scramble("500 ml milk", "key"), "quantities and units are kept")
+}
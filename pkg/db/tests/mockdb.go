@@ -1,80 +1,420 @@
 package tests
 
 import (
+	"context"
+
 	"github.com/JonClarke84/mealplannergo/pkg/db"
 	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// MockDB is a mock implementation of the DBInterface for testing
+// MockDB is a mock implementation of the db.Store for testing. Every
+// household's data is the mock itself.
 type MockDB struct {
 	mock.Mock
 }
 
-// Ensure MockDB implements DBInterface
-var _ db.DBInterface = (*MockDB)(nil)
+// Ensure MockDB implements Store
+var _ db.Store = (*MockDB)(nil)
+
+// ShoppingList is the one shopping list of the household NewMockDB returns
+var ShoppingList = models.ShoppingList{ID: primitive.NewObjectID(), Name: models.DefaultShoppingListName}
+
+// NewMockDB returns a MockDB whose household has just ShoppingList, as every
+// household has at least one list
+func NewMockDB() *MockDB {
+	m := new(MockDB)
+	list := ShoppingList
+	list.IDHex = list.ID.Hex()
+	m.On("GetShoppingLists", mock.Anything).Return([]models.ShoppingList{list}, nil).Maybe()
+	return m
+}
 
 // GetShoppingList mocks the GetShoppingList method
-func (m *MockDB) GetShoppingList() ([]models.ShoppingListItem, error) {
-	args := m.Called()
+func (m *MockDB) GetShoppingList(ctx context.Context) ([]models.ShoppingListItem, error) {
+	args := m.Called(ctx)
 	return args.Get(0).([]models.ShoppingListItem), args.Error(1)
 }
 
 // GetShoppingListItemFromIDHex mocks the GetShoppingListItemFromIDHex method
-func (m *MockDB) GetShoppingListItemFromIDHex(IDHex string) (models.ShoppingListItem, error) {
-	args := m.Called(IDHex)
+func (m *MockDB) GetShoppingListItemFromIDHex(ctx context.Context, IDHex string) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, IDHex)
 	return args.Get(0).(models.ShoppingListItem), args.Error(1)
 }
 
 // UpdateMeal mocks the UpdateMeal method
-func (m *MockDB) UpdateMeal(day string, meal string) error {
-	args := m.Called(day, meal)
+func (m *MockDB) UpdateMeal(ctx context.Context, week string, day string, slot string, meal string) error {
+	args := m.Called(ctx, week, day, slot, meal)
 	return args.Error(0)
 }
 
 // AddShoppingListItem mocks the AddShoppingListItem method
-func (m *MockDB) AddShoppingListItem(itemName string) (models.ShoppingListItem, error) {
-	args := m.Called(itemName)
+func (m *MockDB) AddShoppingListItem(ctx context.Context, itemName string) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, itemName)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// InsertShoppingListItem mocks the InsertShoppingListItem method
+func (m *MockDB) InsertShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, item)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// SetShoppingListItemSources mocks the SetShoppingListItemSources method
+func (m *MockDB) SetShoppingListItemSources(ctx context.Context, itemId string, sources []models.MealSource) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, itemId, sources)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// UpdateShoppingListItemDetails mocks the UpdateShoppingListItemDetails method
+func (m *MockDB) UpdateShoppingListItemDetails(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, item)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// EditShoppingListItem mocks the EditShoppingListItem method
+func (m *MockDB) EditShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, item)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// ReplaceShoppingListItem mocks the ReplaceShoppingListItem method
+func (m *MockDB) ReplaceShoppingListItem(ctx context.Context, item models.ShoppingListItem) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, item)
 	return args.Get(0).(models.ShoppingListItem), args.Error(1)
 }
 
 // AddShoppingListIdToShoppingListOrder mocks the AddShoppingListIdToShoppingListOrder method
-func (m *MockDB) AddShoppingListIdToShoppingListOrder(itemId string) error {
-	args := m.Called(itemId)
+func (m *MockDB) AddShoppingListIdToShoppingListOrder(ctx context.Context, itemId string) error {
+	args := m.Called(ctx, itemId)
 	return args.Error(0)
 }
 
 // UpdateShoppingListItem mocks the UpdateShoppingListItem method
-func (m *MockDB) UpdateShoppingListItem(itemId string, newItem string) (models.ShoppingListItem, error) {
-	args := m.Called(itemId, newItem)
+func (m *MockDB) UpdateShoppingListItem(ctx context.Context, itemId string, newItem string) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, itemId, newItem)
 	return args.Get(0).(models.ShoppingListItem), args.Error(1)
 }
 
 // DeleteShoppingListItem mocks the DeleteShoppingListItem method
-func (m *MockDB) DeleteShoppingListItem(itemIDHex string) error {
-	args := m.Called(itemIDHex)
+func (m *MockDB) DeleteShoppingListItem(ctx context.Context, itemIDHex string) error {
+	args := m.Called(ctx, itemIDHex)
 	return args.Error(0)
 }
 
 // TickShoppingListItem mocks the TickShoppingListItem method
-func (m *MockDB) TickShoppingListItem(itemId string, ticked bool) (models.ShoppingListItem, error) {
-	args := m.Called(itemId, ticked)
+func (m *MockDB) TickShoppingListItem(ctx context.Context, itemId string, ticked bool) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, itemId, ticked)
 	return args.Get(0).(models.ShoppingListItem), args.Error(1)
 }
 
 // GetMealPlan mocks the GetMealPlan method
-func (m *MockDB) GetMealPlan() (models.MealPlan, error) {
-	args := m.Called()
+func (m *MockDB) GetMealPlan(ctx context.Context, week string) (models.MealPlan, error) {
+	args := m.Called(ctx, week)
 	return args.Get(0).(models.MealPlan), args.Error(1)
 }
 
 // SortShoppingList mocks the SortShoppingList method
-func (m *MockDB) SortShoppingList(newOrder []models.Order) error {
-	args := m.Called(newOrder)
+func (m *MockDB) SortShoppingList(ctx context.Context, newOrder []models.Order) error {
+	args := m.Called(ctx, newOrder)
+	return args.Error(0)
+}
+
+// SetMealRecipe mocks the SetMealRecipe method
+func (m *MockDB) SetMealRecipe(ctx context.Context, week string, day string, slot string, recipeID string) error {
+	args := m.Called(ctx, week, day, slot, recipeID)
+	return args.Error(0)
+}
+
+// EditMeal mocks the EditMeal method
+func (m *MockDB) EditMeal(ctx context.Context, week string, input models.MealSlotInput) (models.MealSlotInput, error) {
+	args := m.Called(ctx, week, input)
+	return args.Get(0).(models.MealSlotInput), args.Error(1)
+}
+
+// GetRecipes mocks the GetRecipes method
+func (m *MockDB) GetRecipes(ctx context.Context) ([]recipes.Recipe, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]recipes.Recipe), args.Error(1)
+}
+
+// GetRecipe mocks the GetRecipe method
+func (m *MockDB) GetRecipe(ctx context.Context, IDHex string) (recipes.Recipe, error) {
+	args := m.Called(ctx, IDHex)
+	return args.Get(0).(recipes.Recipe), args.Error(1)
+}
+
+// AddRecipe mocks the AddRecipe method
+func (m *MockDB) AddRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	args := m.Called(ctx, recipe)
+	return args.Get(0).(recipes.Recipe), args.Error(1)
+}
+
+// UpdateRecipe mocks the UpdateRecipe method
+func (m *MockDB) UpdateRecipe(ctx context.Context, recipe recipes.Recipe) (recipes.Recipe, error) {
+	args := m.Called(ctx, recipe)
+	return args.Get(0).(recipes.Recipe), args.Error(1)
+}
+
+// DeleteRecipe mocks the DeleteRecipe method
+func (m *MockDB) DeleteRecipe(ctx context.Context, IDHex string) error {
+	args := m.Called(ctx, IDHex)
 	return args.Error(0)
 }
 
 // Close mocks the Close method
 func (m *MockDB) Close() {
 	m.Called()
-}
\ No newline at end of file
+}
+
+// SetShoppingListItemCategory mocks the SetShoppingListItemCategory method
+func (m *MockDB) SetShoppingListItemCategory(ctx context.Context, itemId string, category string) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, itemId, category)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// GetCategoryDictionary mocks the GetCategoryDictionary method
+func (m *MockDB) GetCategoryDictionary(ctx context.Context) (map[string]string, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]string), args.Error(1)
+}
+
+// LearnCategory mocks the LearnCategory method
+func (m *MockDB) LearnCategory(ctx context.Context, itemName string, category string) error {
+	args := m.Called(ctx, itemName, category)
+	return args.Error(0)
+}
+
+// GetStores mocks the GetStores method
+func (m *MockDB) GetStores(ctx context.Context) ([]models.Store, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Store), args.Error(1)
+}
+
+// SaveStore mocks the SaveStore method
+func (m *MockDB) SaveStore(ctx context.Context, store models.Store) (models.Store, error) {
+	args := m.Called(ctx, store)
+	return args.Get(0).(models.Store), args.Error(1)
+}
+
+// DeleteStore mocks the DeleteStore method
+func (m *MockDB) DeleteStore(ctx context.Context, name string) error {
+	args := m.Called(ctx, name)
+	return args.Error(0)
+}
+
+// RecordTick mocks the RecordTick method
+func (m *MockDB) RecordTick(ctx context.Context, event models.TickEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+// GetTickEvents mocks the GetTickEvents method
+func (m *MockDB) GetTickEvents(ctx context.Context) ([]models.TickEvent, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.TickEvent), args.Error(1)
+}
+
+// GetWalkOrder mocks the GetWalkOrder method
+func (m *MockDB) GetWalkOrder(ctx context.Context) (map[string]float64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]float64), args.Error(1)
+}
+
+// SaveWalkOrder mocks the SaveWalkOrder method
+func (m *MockDB) SaveWalkOrder(ctx context.Context, order map[string]float64) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
+// RecordChange mocks the RecordChange method
+func (m *MockDB) RecordChange(ctx context.Context, change models.Change) (models.Change, error) {
+	args := m.Called(ctx, change)
+	return args.Get(0).(models.Change), args.Error(1)
+}
+
+// UpdateChange mocks the UpdateChange method
+func (m *MockDB) UpdateChange(ctx context.Context, change models.Change) error {
+	args := m.Called(ctx, change)
+	return args.Error(0)
+}
+
+// GetChange mocks the GetChange method
+func (m *MockDB) GetChange(ctx context.Context, IDHex string) (models.Change, error) {
+	args := m.Called(ctx, IDHex)
+	return args.Get(0).(models.Change), args.Error(1)
+}
+
+// GetChanges mocks the GetChanges method
+func (m *MockDB) GetChanges(ctx context.Context, listID string, limit int) ([]models.Change, error) {
+	args := m.Called(ctx, listID, limit)
+	return args.Get(0).([]models.Change), args.Error(1)
+}
+
+// ForHousehold returns the mock itself, so expectations cover every household
+func (m *MockDB) ForHousehold(householdID string) db.DBInterface {
+	return m
+}
+
+// VerifyIntegrity mocks the VerifyIntegrity method
+func (m *MockDB) VerifyIntegrity(ctx context.Context) (db.IntegrityReport, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(db.IntegrityReport), args.Error(1)
+}
+
+// Repair mocks the Repair method
+func (m *MockDB) Repair(ctx context.Context) (db.IntegrityReport, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(db.IntegrityReport), args.Error(1)
+}
+
+// ForShoppingList returns the mock itself, so expectations cover every list
+func (m *MockDB) ForShoppingList(listID string) db.DBInterface {
+	return m
+}
+
+// MergeShoppingListItems mocks the MergeShoppingListItems method
+func (m *MockDB) MergeShoppingListItems(ctx context.Context, merged []models.ShoppingListItem, removed []models.ShoppingListItem) error {
+	args := m.Called(ctx, merged, removed)
+	return args.Error(0)
+}
+
+// ReorderShoppingList mocks the ReorderShoppingList method
+func (m *MockDB) ReorderShoppingList(ctx context.Context, newOrder []models.Order, version int) error {
+	args := m.Called(ctx, newOrder, version)
+	return args.Error(0)
+}
+
+// GetShoppingLists mocks the GetShoppingLists method
+func (m *MockDB) GetShoppingLists(ctx context.Context) ([]models.ShoppingList, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.ShoppingList), args.Error(1)
+}
+
+// CreateShoppingList mocks the CreateShoppingList method
+func (m *MockDB) CreateShoppingList(ctx context.Context, name string) (models.ShoppingList, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(models.ShoppingList), args.Error(1)
+}
+
+// EditShoppingList mocks the EditShoppingList method
+func (m *MockDB) EditShoppingList(ctx context.Context, list models.ShoppingList) (models.ShoppingList, error) {
+	args := m.Called(ctx, list)
+	return args.Get(0).(models.ShoppingList), args.Error(1)
+}
+
+// RenameShoppingList mocks the RenameShoppingList method
+func (m *MockDB) RenameShoppingList(ctx context.Context, IDHex string, name string) (models.ShoppingList, error) {
+	args := m.Called(ctx, IDHex, name)
+	return args.Get(0).(models.ShoppingList), args.Error(1)
+}
+
+// ArchiveShoppingList mocks the ArchiveShoppingList method
+func (m *MockDB) ArchiveShoppingList(ctx context.Context, IDHex string, archived bool) (models.ShoppingList, error) {
+	args := m.Called(ctx, IDHex, archived)
+	return args.Get(0).(models.ShoppingList), args.Error(1)
+}
+
+// DeleteShoppingList mocks the DeleteShoppingList method
+func (m *MockDB) DeleteShoppingList(ctx context.Context, IDHex string) error {
+	args := m.Called(ctx, IDHex)
+	return args.Error(0)
+}
+
+// MoveShoppingListItem mocks the MoveShoppingListItem method
+func (m *MockDB) MoveShoppingListItem(ctx context.Context, itemId string, listID string) (models.ShoppingListItem, error) {
+	args := m.Called(ctx, itemId, listID)
+	return args.Get(0).(models.ShoppingListItem), args.Error(1)
+}
+
+// CreateHousehold mocks the CreateHousehold method
+func (m *MockDB) CreateHousehold(ctx context.Context, household models.Household) (models.Household, error) {
+	args := m.Called(ctx, household)
+	return args.Get(0).(models.Household), args.Error(1)
+}
+
+// GetHousehold mocks the GetHousehold method
+func (m *MockDB) GetHousehold(ctx context.Context, IDHex string) (models.Household, error) {
+	args := m.Called(ctx, IDHex)
+	return args.Get(0).(models.Household), args.Error(1)
+}
+
+// GetHouseholds mocks the GetHouseholds method
+func (m *MockDB) GetHouseholds(ctx context.Context) ([]models.Household, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Household), args.Error(1)
+}
+
+// CreateUser mocks the CreateUser method
+func (m *MockDB) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	args := m.Called(ctx, user)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+// CreateOwnedHousehold mocks the CreateOwnedHousehold method
+func (m *MockDB) CreateOwnedHousehold(ctx context.Context, household models.Household, owner models.User) (models.Household, models.User, error) {
+	args := m.Called(ctx, household, owner)
+	return args.Get(0).(models.Household), args.Get(1).(models.User), args.Error(2)
+}
+
+// GetUser mocks the GetUser method
+func (m *MockDB) GetUser(ctx context.Context, IDHex string) (models.User, error) {
+	args := m.Called(ctx, IDHex)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+// GetUserByEmail mocks the GetUserByEmail method
+func (m *MockDB) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+// CreateSession mocks the CreateSession method
+func (m *MockDB) CreateSession(ctx context.Context, session models.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+// GetSession mocks the GetSession method
+func (m *MockDB) GetSession(ctx context.Context, tokenHash string) (models.Session, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(models.Session), args.Error(1)
+}
+
+// DeleteSession mocks the DeleteSession method
+func (m *MockDB) DeleteSession(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+// GetHouseholdUsers mocks the GetHouseholdUsers method
+func (m *MockDB) GetHouseholdUsers(ctx context.Context, householdID string) ([]models.User, error) {
+	args := m.Called(ctx, householdID)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+// SetUserRole mocks the SetUserRole method
+func (m *MockDB) SetUserRole(ctx context.Context, IDHex string, role models.Role) error {
+	args := m.Called(ctx, IDHex, role)
+	return args.Error(0)
+}
+
+// CreateInvite mocks the CreateInvite method
+func (m *MockDB) CreateInvite(ctx context.Context, invite models.Invite) error {
+	args := m.Called(ctx, invite)
+	return args.Error(0)
+}
+
+// GetInvite mocks the GetInvite method
+func (m *MockDB) GetInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	args := m.Called(ctx, codeHash)
+	return args.Get(0).(models.Invite), args.Error(1)
+}
+
+// ClaimInvite mocks the ClaimInvite method
+func (m *MockDB) ClaimInvite(ctx context.Context, codeHash string) (models.Invite, error) {
+	args := m.Called(ctx, codeHash)
+	return args.Get(0).(models.Invite), args.Error(1)
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// TickItem ticks or unticks a shopping list item. Ticking records when it
+// happened, so the order items are picked up in can be learned (see
+// LearnWalkOrder).
+func TickItem(ctx context.Context, store DBInterface, itemId string, ticked bool, now time.Time) (models.ShoppingListItem, error) {
+	item, err := store.TickShoppingListItem(ctx, itemId, ticked)
+	if err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	if !ticked || item.IDHex == "" {
+		return item, nil
+	}
+	if err := store.RecordTick(ctx, models.TickEvent{Item: models.CategoryKey(item.Item), TickedAt: now}); err != nil {
+		return models.ShoppingListItem{}, err
+	}
+	return item, nil
+}
+
+// LearnWalkOrder derives the walk order from the recorded ticks and saves it
+func LearnWalkOrder(ctx context.Context, store DBInterface) (map[string]float64, error) {
+	events, err := store.GetTickEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order := models.LearnWalkOrder(events)
+	if err := store.SaveWalkOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// StartWalkOrderLearner learns every household's walk order now and then
+// every interval in the background, until ctx is done or stop is called,
+// either of which also cancels any learning under way
+func StartWalkOrderLearner(ctx context.Context, store Store, interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			learnHouseholdWalkOrders(ctx, store)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+// learnHouseholdWalkOrders learns the walk order of each household in store
+func learnHouseholdWalkOrders(ctx context.Context, store Store) {
+	households, err := store.GetHouseholds(ctx)
+	if err != nil {
+		fmt.Printf("Error learning walk order: %s\n", err)
+		return
+	}
+	for _, household := range households {
+		if _, err := LearnWalkOrder(ctx, store.ForHousehold(household.IDHex)); err != nil {
+			fmt.Printf("Error learning walk order for household %s: %s\n", household.IDHex, err)
+		}
+	}
+}
+
+// AutoSort puts the shopping list in the learned walk order. Items that have
+// never been ticked on a trip go after the rest, in their current order.
+func AutoSort(ctx context.Context, store DBInterface) error {
+	order, err := store.GetWalkOrder(ctx)
+	if err != nil {
+		return err
+	}
+	shoppingList, err := store.GetShoppingList(ctx)
+	if err != nil {
+		return err
+	}
+
+	sorted := models.SortByWalkOrder(shoppingList, order)
+	newOrder := make([]models.Order, 0, len(sorted))
+	for i, item := range sorted {
+		newOrder = append(newOrder, models.Order{ID: item.IDHex, Position: i + 1})
+	}
+	return store.SortShoppingList(ctx, newOrder)
+}
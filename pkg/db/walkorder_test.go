@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickItemRecordsTicks(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	milk, err := store.AddShoppingListItem(ctx, "2 l milk")
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	ticked, err := TickItem(ctx, store, milk.IDHex, true, now)
+	require.NoError(t, err)
+	assert.True(t, ticked.Ticked)
+	_, err = TickItem(ctx, store, milk.IDHex, false, now.Add(time.Minute))
+	require.NoError(t, err)
+
+	events, err := store.GetTickEvents(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TickEvent{{Item: "milk", TickedAt: now}}, events, "only ticking is recorded")
+}
+
+func TestAutoSortFollowsLearnedWalkOrder(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDB()
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+
+	// Two trips picking up bananas, then bread, then milk
+	for day := 0; day < 2; day++ {
+		for i, text := range []string{"bananas", "bread", "milk"} {
+			item, err := store.AddShoppingListItem(ctx, text)
+			require.NoError(t, err)
+			_, err = TickItem(ctx, store, item.IDHex, true, start.Add(time.Duration(day)*24*time.Hour+time.Duration(i)*time.Minute))
+			require.NoError(t, err)
+			require.NoError(t, store.DeleteShoppingListItem(ctx, item.IDHex))
+		}
+	}
+	order, err := LearnWalkOrder(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"bananas": 0, "bread": 0.5, "milk": 1}, order)
+
+	addItems(t, store, "candles", "Milk x2", "Bread", "Bananas")
+	require.NoError(t, AutoSort(ctx, store))
+	assert.Equal(t, []string{"Bananas", "Bread", "Milk", "candles"}, itemNames(t, store))
+}
+
+func TestStartWalkOrderLearner(t *testing.T) {
+	ctx := context.Background()
+	accounts := NewMemoryDB()
+	household, err := accounts.CreateHousehold(ctx, models.Household{Name: "Home"})
+	require.NoError(t, err)
+	store := accounts.ForHousehold(household.IDHex)
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, store.RecordTick(ctx, models.TickEvent{Item: "bread", TickedAt: start}))
+	require.NoError(t, store.RecordTick(ctx, models.TickEvent{Item: "milk", TickedAt: start.Add(time.Minute)}))
+
+	stop := StartWalkOrderLearner(ctx, accounts, time.Hour)
+	defer stop()
+	assert.Eventually(t, func() bool {
+		order, err := store.GetWalkOrder(ctx)
+		return err == nil && len(order) == 2
+	}, time.Second, 10*time.Millisecond)
+}
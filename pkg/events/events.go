@@ -0,0 +1,108 @@
+// Package events is an in-process publish/subscribe hub, used to push the
+// changes made in one browser to the others showing the same household's
+// meal plan and shopping list
+package events
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Event is a change to a household's data, sent as a server-sent event
+type Event struct {
+	// Household and List say who is sent the event: the sessions showing
+	// List, or every session of Household when List is ""
+	Household string
+	List      string
+	// Name is the event name that sse-swap and sse: triggers listen for
+	Name string
+	// Data is the rendered fragment, if the event carries one
+	Data string
+	// Origin is the client that made the change, which already shows it
+	Origin string
+}
+
+// subscriberBuffer is how many events a slow subscriber can fall behind by
+// before further events are dropped for it
+const subscriberBuffer = 32
+
+// subscriber is one open session
+type subscriber struct {
+	household string
+	list      string
+	client    string
+	events    chan Event
+}
+
+// Hub passes published events on to the subscribers they are for
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// NewHub creates a Hub with no subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns the events for the session of client showing list in
+// household, and a function to call when the session ends
+func (h *Hub) Subscribe(household, list, client string) (<-chan Event, func()) {
+	sub := &subscriber{household: household, list: list, client: client, events: make(chan Event, subscriberBuffer)}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, sub)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish sends event to every subscriber it is for other than the client
+// that made the change. It never blocks: subscribers that have fallen too far
+// behind miss the event.
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if sub.household != event.Household || (event.List != "" && sub.list != event.List) {
+			continue
+		}
+		if event.Origin != "" && sub.client == event.Origin {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			fmt.Printf("Dropping %s event for a slow subscriber\n", event.Name)
+		}
+	}
+}
+
+// Subscribers returns how many sessions are subscribed
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Write writes event to w in the text/event-stream format
+func Write(w io.Writer, event Event) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event.Name)
+	for _, line := range strings.Split(event.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
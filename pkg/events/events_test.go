@@ -0,0 +1,76 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// received returns the events waiting on events without blocking
+func received(events <-chan Event) []string {
+	var names []string
+	for {
+		select {
+		case event := <-events:
+			names = append(names, event.Name)
+		default:
+			return names
+		}
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	mine, cancelMine := hub.Subscribe("home", "list1", "a")
+	defer cancelMine()
+	partner, cancelPartner := hub.Subscribe("home", "list1", "b")
+	defer cancelPartner()
+	otherList, cancelOtherList := hub.Subscribe("home", "list2", "c")
+	defer cancelOtherList()
+	otherHousehold, cancelOtherHousehold := hub.Subscribe("away", "list1", "d")
+	defer cancelOtherHousehold()
+
+	hub.Publish(Event{Household: "home", List: "list1", Name: "item-1", Origin: "a"})
+	hub.Publish(Event{Household: "home", Name: "meal", Origin: "a"})
+	hub.Publish(Event{Household: "home", List: "list1", Name: "list-changed"})
+
+	assert.Equal(t, []string{"list-changed"}, received(mine), "the client making a change already shows it")
+	assert.Equal(t, []string{"item-1", "meal", "list-changed"}, received(partner))
+	assert.Equal(t, []string{"meal"}, received(otherList), "household events go to every list")
+	assert.Empty(t, received(otherHousehold))
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+	events, cancel := hub.Subscribe("home", "list1", "a")
+	require.Equal(t, 1, hub.Subscribers())
+
+	cancel()
+	cancel()
+	assert.Equal(t, 0, hub.Subscribers())
+	hub.Publish(Event{Household: "home", List: "list1", Name: "list-changed"})
+	assert.Empty(t, received(events))
+}
+
+func TestHubDropsForSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	events, cancel := hub.Subscribe("home", "list1", "a")
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer+10; i++ {
+		hub.Publish(Event{Household: "home", List: "list1", Name: "list-changed"})
+	}
+	assert.Len(t, received(events), subscriberBuffer)
+}
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, Write(&b, Event{Name: "item-1", Data: "<li>\n  eggs\r\n</li>"}))
+	assert.Equal(t, "event: item-1\ndata: <li>\ndata:   eggs\ndata: </li>\n\n", b.String())
+
+	b.Reset()
+	require.NoError(t, Write(&b, Event{Name: "list-changed"}))
+	assert.Equal(t, "event: list-changed\ndata: \n\n", b.String())
+}
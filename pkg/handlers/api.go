@@ -0,0 +1,617 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// APIPrefix is the path every JSON API route starts with
+const APIPrefix = "/api/v1"
+
+// isAPIRequest reports whether r is for the JSON API
+func isAPIRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// writeJSON responds with status and v encoded as JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("Error writing JSON: %s\n", err)
+	}
+}
+
+// apiError responds with status and an APIError holding message
+func apiError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, models.APIError{Error: message})
+}
+
+// apiConflict responds 409 Conflict with current, the resource as it is now
+func apiConflict(w http.ResponseWriter, message string, current any) {
+	writeJSON(w, http.StatusConflict, models.APIError{Error: message, Current: current})
+}
+
+// apiServerError logs err and responds 500 with message
+func apiServerError(w http.ResponseWriter, message string, err error) {
+	fmt.Printf("%s: %s\n", message, err)
+	apiError(w, http.StatusInternalServerError, message)
+}
+
+// readJSON decodes the request body into v, responding 400 Bad Request when
+// it is not a JSON object of v's fields
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		apiError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiAllowed is allowed for the JSON API
+func apiAllowed(w http.ResponseWriter, r *http.Request, check func(models.Role) bool) bool {
+	if can(r, check) {
+		return true
+	}
+	apiError(w, http.StatusForbidden, "Your role does not allow this change")
+	return false
+}
+
+// apiList returns the shopping list at /api/v1/lists/{id} and its data,
+// responding 404 Not Found when the household has no such list
+func (h *Handler) apiList(w http.ResponseWriter, r *http.Request) (models.ShoppingList, db.DBInterface, bool) {
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return models.ShoppingList{}, nil, false
+	}
+	lists, err := store.GetShoppingLists(r.Context())
+	if err != nil {
+		apiServerError(w, "Failed to get shopping lists", err)
+		return models.ShoppingList{}, nil, false
+	}
+	list, found := models.FindShoppingList(lists, r.PathValue("id"))
+	if !found {
+		apiError(w, http.StatusNotFound, "Shopping list not found")
+		return models.ShoppingList{}, nil, false
+	}
+	return list, store.ForShoppingList(list.IDHex), true
+}
+
+// apiItem returns the item at /api/v1/lists/{id}/items/{item}, responding
+// 404 Not Found when the list has no such item
+func (h *Handler) apiItem(w http.ResponseWriter, r *http.Request) (models.ShoppingList, db.DBInterface, models.ShoppingListItem, bool) {
+	list, store, ok := h.apiList(w, r)
+	if !ok {
+		return list, nil, models.ShoppingListItem{}, false
+	}
+	item, err := store.GetShoppingListItemFromIDHex(r.Context(), r.PathValue("item"))
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		apiServerError(w, "Failed to get item", err)
+		return list, nil, item, false
+	}
+	if item.IDHex == "" {
+		apiError(w, http.StatusNotFound, "Item not found")
+		return list, nil, item, false
+	}
+	return list, store, item, true
+}
+
+// APINotFoundHandler answers requests for JSON API paths that do not exist
+func (h *Handler) APINotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	apiError(w, http.StatusNotFound, "No such API route")
+}
+
+// APISessionHandler signs in with the posted email and password, responding
+// 201 Created with a session token for the Authorization header
+func (h *Handler) APISessionHandler(w http.ResponseWriter, r *http.Request) {
+	var input models.APISessionInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+	user, err := db.Login(r.Context(), h.DB, input.Email, input.Password)
+	if errors.Is(err, db.ErrInvalidLogin) {
+		apiError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		apiServerError(w, "Failed to sign in", err)
+		return
+	}
+	token, err := db.StartSession(r.Context(), h.DB, user, time.Now())
+	if err != nil {
+		apiServerError(w, "Failed to sign in", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, models.APISession{Token: token, User: models.NewAPIUser(user)})
+}
+
+// APIEndSessionHandler signs out the session the request was made with
+func (h *Handler) APIEndSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := db.EndSession(r.Context(), h.DB, sessionToken(r)); err != nil {
+		apiServerError(w, "Failed to sign out", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIMealPlanHandler returns the meal plan for the ISO week at
+// /api/v1/meal-plans/{week}
+func (h *Handler) APIMealPlanHandler(w http.ResponseWriter, r *http.Request) {
+	week := r.PathValue("week")
+	if _, err := models.ParseISOWeek(week); err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	mealPlan, err := store.GetMealPlan(r.Context(), week)
+	if err != nil {
+		apiServerError(w, "Failed to get meal plan", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.NewAPIMealPlan(mealPlan, h.mealSlots()))
+}
+
+// APIMealHandler sets the meal at /api/v1/meal-plans/{week}/meals/{day}/{slot}.
+// As with MealHandler, a slot changed since the version sent is not saved,
+// and the response is a 409 holding the slot as it is now.
+func (h *Handler) APIMealHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditMealPlan) {
+		return
+	}
+	week, day, slot := r.PathValue("week"), r.PathValue("day"), r.PathValue("slot")
+	if _, err := models.ParseISOWeek(week); err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.hasMealSlot(slot) {
+		apiError(w, http.StatusNotFound, fmt.Sprintf("Unknown meal slot %q", slot))
+		return
+	}
+	var input models.APIMealInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+	if input.Version == nil {
+		apiError(w, http.StatusBadRequest, "version is required")
+		return
+	}
+
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	var recipeID string
+	if input.RecipeID != nil {
+		recipeID = *input.RecipeID
+		if recipeID != "" {
+			if _, err := store.GetRecipe(r.Context(), recipeID); errors.Is(err, db.ErrNotFound) {
+				apiError(w, http.StatusBadRequest, "Unknown recipe")
+				return
+			} else if err != nil {
+				apiServerError(w, "Failed to get recipe", err)
+				return
+			}
+		}
+	} else {
+		var err error
+		if recipeID, err = h.recipeIDForMeal(r, input.Meal); err != nil {
+			apiServerError(w, "Failed to get recipes", err)
+			return
+		}
+	}
+
+	saved, err := store.EditMeal(r.Context(), week, models.MealSlotInput{
+		Day:      day,
+		Slot:     slot,
+		Meal:     input.Meal,
+		RecipeID: recipeID,
+		Version:  *input.Version,
+	})
+	saved.Week = week
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		apiConflict(w, "The meal has been changed since it was read", models.NewAPIMeal(saved))
+		return
+	case errors.Is(err, db.ErrNotFound):
+		apiError(w, http.StatusNotFound, fmt.Sprintf("Unknown day %q", day))
+		return
+	case err != nil:
+		apiServerError(w, "Failed to update meal", err)
+		return
+	}
+	h.publishMealSlot(r, saved)
+	writeJSON(w, http.StatusOK, models.NewAPIMeal(saved))
+}
+
+// APIListsHandler returns the household's shopping lists
+func (h *Handler) APIListsHandler(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	lists, err := store.GetShoppingLists(r.Context())
+	if err != nil {
+		apiServerError(w, "Failed to get shopping lists", err)
+		return
+	}
+	resources := make([]models.APIShoppingList, 0, len(lists))
+	for _, list := range lists {
+		resources = append(resources, models.NewAPIShoppingList(list))
+	}
+	writeJSON(w, http.StatusOK, resources)
+}
+
+// APICreateListHandler creates a shopping list with the posted name
+func (h *Handler) APICreateListHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	var input models.APIShoppingListInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+	if input.Name == nil {
+		apiError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	name, err := models.NormaliseShoppingListName(*input.Name)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	list, err := store.CreateShoppingList(r.Context(), name)
+	if err != nil {
+		apiServerError(w, "Failed to create shopping list", err)
+		return
+	}
+	if input.Archived != nil && *input.Archived {
+		if list, err = store.ArchiveShoppingList(r.Context(), list.IDHex, true); err != nil {
+			apiServerError(w, "Failed to archive shopping list", err)
+			return
+		}
+	}
+	w.Header().Set("Location", APIPrefix+"/lists/"+list.IDHex)
+	writeJSON(w, http.StatusCreated, models.NewAPIShoppingList(list))
+}
+
+// APIListHandler returns the shopping list at /api/v1/lists/{id}
+func (h *Handler) APIListHandler(w http.ResponseWriter, r *http.Request) {
+	list, _, ok := h.apiList(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, models.NewAPIShoppingList(list))
+}
+
+// APIUpdateListHandler renames or archives the shopping list at
+// /api/v1/lists/{id}, changing only the fields sent. Both are saved together,
+// and only while the list is at the version sent, or else the version just
+// read; otherwise the response is a 409 holding the list as it is now.
+func (h *Handler) APIUpdateListHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, _, ok := h.apiList(w, r)
+	if !ok {
+		return
+	}
+	var input models.APIShoppingListInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	edit := list
+	if input.Name != nil {
+		name, err := models.NormaliseShoppingListName(*input.Name)
+		if err != nil {
+			apiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		edit.Name = name
+	}
+	if input.Archived != nil {
+		edit.Archived = *input.Archived
+	}
+	if input.Version != nil {
+		edit.Version = *input.Version
+	}
+	edited, err := store.EditShoppingList(r.Context(), edit)
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		apiConflict(w, "The list has been changed since it was read", models.NewAPIShoppingList(edited))
+		return
+	case errors.Is(err, db.ErrNotFound):
+		apiError(w, http.StatusNotFound, "Shopping list not found")
+		return
+	case err != nil:
+		apiServerError(w, "Failed to update shopping list", err)
+		return
+	}
+	list = edited
+	h.publish(r, list.IDHex, "list-changed", "")
+	writeJSON(w, http.StatusOK, models.NewAPIShoppingList(list))
+}
+
+// APIDeleteListHandler deletes the shopping list at /api/v1/lists/{id} along
+// with its items
+func (h *Handler) APIDeleteListHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, _, ok := h.apiList(w, r)
+	if !ok {
+		return
+	}
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	if err := store.DeleteShoppingList(r.Context(), list.IDHex); err != nil {
+		apiServerError(w, "Failed to delete shopping list", err)
+		return
+	}
+	h.publish(r, list.IDHex, "list-changed", "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIItemsHandler returns the items of the shopping list at
+// /api/v1/lists/{id}, in order
+func (h *Handler) APIItemsHandler(w http.ResponseWriter, r *http.Request) {
+	list, store, ok := h.apiList(w, r)
+	if !ok {
+		return
+	}
+	shoppingList, err := store.GetShoppingList(r.Context())
+	if err != nil {
+		apiServerError(w, "Failed to get shopping list", err)
+		return
+	}
+	items := make([]models.APIShoppingListItem, 0, len(shoppingList))
+	for _, item := range shoppingList {
+		items = append(items, models.NewAPIShoppingListItem(list.IDHex, item))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// APICreateItemHandler adds the posted text to the shopping list at
+// /api/v1/lists/{id}. As on the page, a matching item is added to rather
+// than duplicated; either way the item is returned with 201 Created.
+func (h *Handler) APICreateItemHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, store, ok := h.apiList(w, r)
+	if !ok {
+		return
+	}
+	var input models.APIShoppingListItemInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+	if input.Text == nil || strings.TrimSpace(*input.Text) == "" {
+		apiError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+
+	item, err := store.AddShoppingListItem(r.Context(), *input.Text)
+	if err != nil {
+		apiServerError(w, "Failed to add item", err)
+		return
+	}
+	if input.Ticked != nil || input.Category != nil {
+		if item, ok = h.apiChangeItem(r.Context(), w, store, item, input); !ok {
+			return
+		}
+	}
+	h.publish(r, list.IDHex, "list-changed", "")
+	w.Header().Set("Location", APIPrefix+"/lists/"+list.IDHex+"/items/"+item.IDHex)
+	writeJSON(w, http.StatusCreated, models.NewAPIShoppingListItem(list.IDHex, item))
+}
+
+// APIItemHandler returns the item at /api/v1/lists/{id}/items/{item}
+func (h *Handler) APIItemHandler(w http.ResponseWriter, r *http.Request) {
+	list, _, item, ok := h.apiItem(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, models.NewAPIShoppingListItem(list.IDHex, item))
+}
+
+// APIUpdateItemHandler changes the fields sent of the item at
+// /api/v1/lists/{id}/items/{item}. New text is read for a quantity, unit and
+// note like a new item, and needs the version the item was read at. The
+// fields are saved together, and if the item has changed since the version
+// sent, or else the version just read, nothing is saved and the response is
+// a 409 holding the item as it is now.
+func (h *Handler) APIUpdateItemHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, store, item, ok := h.apiItem(w, r)
+	if !ok {
+		return
+	}
+	var input models.APIShoppingListItemInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+	if input.Text != nil && input.Version == nil {
+		apiError(w, http.StatusBadRequest, "version is required to change the text")
+		return
+	}
+
+	change := item
+	if input.Version != nil {
+		change.Version = *input.Version
+	}
+	if input.Text != nil {
+		edit := models.ParseShoppingListItem(*input.Text)
+		change.Item, change.Quantity, change.Unit, change.Note = edit.Item, edit.Quantity, edit.Unit, edit.Note
+	}
+	if input.Ticked != nil {
+		change.Ticked = *input.Ticked
+	}
+	if input.Category != nil {
+		change.Category = strings.TrimSpace(*input.Category)
+	}
+	updated, err := store.ReplaceShoppingListItem(r.Context(), change)
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		apiConflict(w, "The item has been changed since it was read", models.NewAPIShoppingListItem(list.IDHex, updated))
+		return
+	case errors.Is(err, db.ErrNotFound):
+		apiError(w, http.StatusNotFound, "Item not found")
+		return
+	case err != nil:
+		apiServerError(w, "Failed to update item", err)
+		return
+	}
+
+	// The walk order and categories learn from the API as from the page
+	if updated.Ticked && !item.Ticked {
+		if err := store.RecordTick(r.Context(), models.TickEvent{Item: models.CategoryKey(updated.Item), TickedAt: time.Now()}); err != nil {
+			apiServerError(w, "Failed to record tick", err)
+			return
+		}
+	}
+	if input.Category != nil {
+		if err := store.LearnCategory(r.Context(), updated.Item, updated.Category); err != nil {
+			apiServerError(w, "Failed to learn category", err)
+			return
+		}
+	}
+
+	// A new category moves the item to another group, which the pages place
+	// by loading the list again; otherwise just the item changes, as when it
+	// is ticked or edited on the page
+	if input.Category != nil {
+		h.publish(r, list.IDHex, "list-changed", "")
+	} else {
+		h.publishShoppingListItem(r, updated)
+	}
+	writeJSON(w, http.StatusOK, models.NewAPIShoppingListItem(list.IDHex, updated))
+}
+
+// apiChangeItem ticks item and sets its category when input has them, as
+// ticking it or choosing its category on the page would
+func (h *Handler) apiChangeItem(ctx context.Context, w http.ResponseWriter, store db.DBInterface, item models.ShoppingListItem, input models.APIShoppingListItemInput) (models.ShoppingListItem, bool) {
+	var err error
+	if input.Ticked != nil && *input.Ticked != item.Ticked {
+		if item, err = db.TickItem(ctx, store, item.IDHex, *input.Ticked, time.Now()); err != nil {
+			apiServerError(w, "Failed to update item", err)
+			return item, false
+		}
+	}
+	if input.Category != nil {
+		if item, err = db.AssignCategory(ctx, store, item.IDHex, *input.Category); err != nil {
+			apiServerError(w, "Failed to set category", err)
+			return item, false
+		}
+	}
+	return item, true
+}
+
+// APIDeleteItemHandler removes the item at /api/v1/lists/{id}/items/{item}
+func (h *Handler) APIDeleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, store, item, ok := h.apiItem(w, r)
+	if !ok {
+		return
+	}
+	if err := store.DeleteShoppingListItem(r.Context(), item.IDHex); err != nil {
+		apiServerError(w, "Failed to delete item", err)
+		return
+	}
+	h.publish(r, list.IDHex, "list-changed", "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIOrderHandler puts the items of the shopping list at
+// /api/v1/lists/{id} in the order of the IDs sent, which must be every item
+// on the list. An order made from an older version of the list is refused
+// with a 409 holding the list as it is now.
+func (h *Handler) APIOrderHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiAllowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, store, ok := h.apiList(w, r)
+	if !ok {
+		return
+	}
+	var input models.APIOrderInput
+	if !readJSON(w, r, &input) {
+		return
+	}
+	if input.Version == nil {
+		apiError(w, http.StatusBadRequest, "version is required")
+		return
+	}
+	if *input.Version != list.Version {
+		apiConflict(w, "The list has been changed since it was read", models.NewAPIShoppingList(list))
+		return
+	}
+
+	shoppingList, err := store.GetShoppingList(r.Context())
+	if err != nil {
+		apiServerError(w, "Failed to get shopping list", err)
+		return
+	}
+	onList := make(map[string]bool, len(shoppingList))
+	for _, item := range shoppingList {
+		onList[item.IDHex] = true
+	}
+	order := make([]models.Order, 0, len(input.Order))
+	for i, IDHex := range input.Order {
+		if !onList[IDHex] {
+			apiError(w, http.StatusBadRequest, "order must list every item on the list once")
+			return
+		}
+		delete(onList, IDHex)
+		order = append(order, models.Order{ID: IDHex, Position: i + 1})
+	}
+	if len(onList) > 0 {
+		apiError(w, http.StatusBadRequest, "order must list every item on the list once")
+		return
+	}
+
+	err = store.ReorderShoppingList(r.Context(), order, *input.Version)
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		if list, _, ok = h.apiList(w, r); ok {
+			apiConflict(w, "The list has been changed since it was read", models.NewAPIShoppingList(list))
+		}
+		return
+	case err != nil:
+		apiServerError(w, "Failed to sort shopping list", err)
+		return
+	}
+	h.publish(r, list.IDHex, "list-changed", "")
+	list, _, ok = h.apiList(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, models.NewAPIShoppingList(list))
+}
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/db/tests"
+	"github.com/JonClarke84/mealplannergo/pkg/events"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonRequest builds a JSON API request, setting the path values given as
+// name, value pairs
+func jsonRequest(method, target, body string, pathValues ...string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(pathValues); i += 2 {
+		req.SetPathValue(pathValues[i], pathValues[i+1])
+	}
+	return req
+}
+
+// decodeJSON decodes the recorded response into v
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(v))
+}
+
+func TestAPIShoppingLists(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.APICreateListHandler(w, asOwner(jsonRequest("POST", "/api/v1/lists", `{"name": " Hardware "}`)))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created models.APIShoppingList
+	decodeJSON(t, w, &created)
+	assert.Equal(t, "Hardware", created.Name)
+	assert.Equal(t, "/api/v1/lists/"+created.ID, w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	handler.APIUpdateListHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x", `{"name": "Tools", "archived": true}`, "id", created.ID)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated models.APIShoppingList
+	decodeJSON(t, w, &updated)
+	assert.Equal(t, "Tools", updated.Name)
+	assert.True(t, updated.Archived)
+
+	w = httptest.NewRecorder()
+	handler.APIListsHandler(w, asOwner(jsonRequest("GET", "/api/v1/lists", "")))
+	require.Equal(t, http.StatusOK, w.Code)
+	var lists []models.APIShoppingList
+	decodeJSON(t, w, &lists)
+	require.Len(t, lists, 2)
+	assert.Equal(t, updated, lists[1])
+
+	// A change from the version first read is refused, with the list as it is
+	w = httptest.NewRecorder()
+	handler.APIUpdateListHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x", `{"archived": false, "version": `+jsonInt(created.Version)+`}`, "id", created.ID)))
+	require.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Error   string
+		Current models.APIShoppingList
+	}
+	decodeJSON(t, w, &conflict)
+	assert.Equal(t, updated, conflict.Current)
+
+	w = httptest.NewRecorder()
+	handler.APIDeleteListHandler(w, asOwner(jsonRequest("DELETE", "/api/v1/lists/x", "", "id", created.ID)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	stored, err := store.GetShoppingLists(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+
+	w = httptest.NewRecorder()
+	handler.APIListHandler(w, asOwner(jsonRequest("GET", "/api/v1/lists/x", "", "id", created.ID)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var apiErr models.APIError
+	decodeJSON(t, w, &apiErr)
+	assert.Equal(t, "Shopping list not found", apiErr.Error)
+
+	for _, body := range []string{`{"name": " "}`, `{}`, `{"title": "Hardware"}`, `not json`} {
+		w = httptest.NewRecorder()
+		handler.APICreateListHandler(w, asOwner(jsonRequest("POST", "/api/v1/lists", body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestAPIShoppingListItems(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(context.Background())
+	require.NoError(t, err)
+	listID := lists[0].IDHex
+
+	w := httptest.NewRecorder()
+	handler.APICreateItemHandler(w, asOwner(jsonRequest("POST", "/api/v1/lists/x/items", `{"text": "2 kg potatoes (baking)", "category": "Veg"}`, "id", listID)))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var item models.APIShoppingListItem
+	decodeJSON(t, w, &item)
+	assert.Equal(t, listID, item.ListID)
+	assert.Equal(t, "potatoes", item.Item)
+	assert.Equal(t, 2.0, item.Quantity)
+	assert.Equal(t, "kg", item.Unit)
+	assert.Equal(t, "baking", item.Note)
+	assert.Equal(t, "Veg", item.Category)
+
+	w = httptest.NewRecorder()
+	handler.APIUpdateItemHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x/items/y", `{"text": "3 kg potatoes", "ticked": true, "version": `+jsonInt(item.Version)+`}`, "id", listID, "item", item.ID)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var edited models.APIShoppingListItem
+	decodeJSON(t, w, &edited)
+	assert.Equal(t, "3 kg potatoes", edited.Text)
+	assert.True(t, edited.Ticked)
+
+	// An edit from the version first read is refused, with the item as it is
+	w = httptest.NewRecorder()
+	handler.APIUpdateItemHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x/items/y", `{"text": "1 kg potatoes", "version": `+jsonInt(item.Version)+`}`, "id", listID, "item", item.ID)))
+	require.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Error   string
+		Current models.APIShoppingListItem
+	}
+	decodeJSON(t, w, &conflict)
+	assert.Equal(t, "3 kg potatoes", conflict.Current.Text)
+
+	w = httptest.NewRecorder()
+	handler.APIUpdateItemHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x/items/y", `{"text": "1 kg potatoes"}`, "id", listID, "item", item.ID)))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "changing the text needs a version")
+
+	w = httptest.NewRecorder()
+	handler.APIItemsHandler(w, asOwner(jsonRequest("GET", "/api/v1/lists/x/items", "", "id", listID)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var items []models.APIShoppingListItem
+	decodeJSON(t, w, &items)
+	assert.Equal(t, []models.APIShoppingListItem{edited}, items)
+
+	w = httptest.NewRecorder()
+	handler.APIDeleteItemHandler(w, asOwner(jsonRequest("DELETE", "/api/v1/lists/x/items/y", "", "id", listID, "item", item.ID)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = httptest.NewRecorder()
+	handler.APIItemHandler(w, asOwner(jsonRequest("GET", "/api/v1/lists/x/items/y", "", "id", listID, "item", item.ID)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.APICreateItemHandler(w, asOwner(jsonRequest("POST", "/api/v1/lists/x/items", `{"text": ""}`, "id", listID)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPIOrder(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	listID := lists[0].IDHex
+	items := addTestItems(t, store, "milk", "eggs")
+	lists, err = store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	version := jsonInt(lists[0].Version)
+
+	for _, order := range []string{`["` + items[1].IDHex + `"]`, `["` + items[1].IDHex + `", "` + items[1].IDHex + `"]`} {
+		w := httptest.NewRecorder()
+		handler.APIOrderHandler(w, asOwner(jsonRequest("PUT", "/api/v1/lists/x/order", `{"order": `+order+`, "version": `+version+`}`, "id", listID)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, "every item must be listed once")
+	}
+
+	w := httptest.NewRecorder()
+	handler.APIOrderHandler(w, asOwner(jsonRequest("PUT", "/api/v1/lists/x/order", `{"order": ["`+items[1].IDHex+`", "`+items[0].IDHex+`"], "version": `+version+`}`, "id", listID)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list models.APIShoppingList
+	decodeJSON(t, w, &list)
+	assert.Greater(t, list.Version, lists[0].Version)
+	shoppingList, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eggs", "milk"}, itemTexts(shoppingList))
+
+	w = httptest.NewRecorder()
+	handler.APIOrderHandler(w, asOwner(jsonRequest("PUT", "/api/v1/lists/x/order", `{"order": ["`+items[0].IDHex+`", "`+items[1].IDHex+`"], "version": `+version+`}`, "id", listID)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAPIOrderConflict(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	listID := tests.ShoppingList.ID.Hex()
+	items := []models.ShoppingListItem{{IDHex: "123"}, {IDHex: "456"}}
+	orders := []models.Order{{ID: "456", Position: 1}, {ID: "123", Position: 2}}
+	mockDB.On("GetShoppingList", mock.Anything).Return(items, nil)
+	// Someone else changed the list after the handler read version 0
+	mockDB.On("ReorderShoppingList", mock.Anything, orders, 0).Return(db.ErrConflict)
+	handler := &Handler{DB: mockDB}
+
+	w := httptest.NewRecorder()
+	handler.APIOrderHandler(w, asOwner(jsonRequest("PUT", "/api/v1/lists/x/order", `{"order": ["456", "123"], "version": 0}`, "id", listID)))
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Error   string
+		Current models.APIShoppingList
+	}
+	decodeJSON(t, w, &conflict)
+	assert.Equal(t, listID, conflict.Current.ID)
+}
+
+func TestAPIUpdateListNotFound(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	list := tests.ShoppingList
+	list.IDHex = list.ID.Hex()
+	// The list was deleted after the handler read it
+	mockDB.On("EditShoppingList", mock.Anything, mock.Anything).Return(models.ShoppingList{}, db.ErrNotFound)
+	handler := &Handler{DB: mockDB}
+
+	w := httptest.NewRecorder()
+	handler.APIUpdateListHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x", `{"name": "Tools"}`, "id", list.IDHex)))
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAPIUpdateItemConflict(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	listID := tests.ShoppingList.ID.Hex()
+	item := models.ShoppingListItem{IDHex: "123", Item: "eggs", Version: 3}
+	mockDB.On("GetShoppingListItemFromIDHex", mock.Anything, "123").Return(item, nil)
+	// Someone else changed the item after the handler read it, so neither
+	// the text nor the tick is saved
+	current := item
+	current.Version = 4
+	change := models.ShoppingListItem{IDHex: "123", Item: "free range eggs", Ticked: true, Version: 3}
+	mockDB.On("ReplaceShoppingListItem", mock.Anything, change).Return(current, db.ErrConflict)
+	handler := &Handler{DB: mockDB}
+
+	w := httptest.NewRecorder()
+	handler.APIUpdateItemHandler(w, asOwner(jsonRequest("PATCH", "/api/v1/lists/x/items/y", `{"text": "free range eggs", "ticked": true, "version": 3}`, "id", listID, "item", "123")))
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Error   string
+		Current models.APIShoppingListItem
+	}
+	decodeJSON(t, w, &conflict)
+	assert.Equal(t, 4, conflict.Current.Version)
+}
+
+func TestAPIListChangesPublish(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+	handler.Events = events.NewHub()
+	list, err := store.CreateShoppingList(context.Background(), "Hardware")
+	require.NoError(t, err)
+	stream, cancel := handler.Events.Subscribe("", list.IDHex, "b")
+	defer cancel()
+
+	published := func(action string) {
+		t.Helper()
+		select {
+		case event := <-stream:
+			assert.Equal(t, "list-changed", event.Name, action)
+		default:
+			t.Fatalf("no event was published for the %s", action)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.APIUpdateListHandler(w, asOwner(asClient(jsonRequest("PATCH", "/api/v1/lists/x", `{"name": "Tools"}`, "id", list.IDHex), "a")))
+	require.Equal(t, http.StatusOK, w.Code)
+	published("rename")
+
+	// Ticking an item sends just the item, which stays where it is
+	item, err := store.ForShoppingList(list.IDHex).AddShoppingListItem(context.Background(), "nails")
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handler.APIUpdateItemHandler(w, asOwner(asClient(jsonRequest("PATCH", "/api/v1/lists/x/items/y", `{"ticked": true}`, "id", list.IDHex, "item", item.IDHex), "a")))
+	require.Equal(t, http.StatusOK, w.Code)
+	select {
+	case event := <-stream:
+		assert.Equal(t, "item-"+item.IDHex, event.Name)
+		assert.Contains(t, event.Data, "nails")
+	default:
+		t.Fatal("no event was published for the tick")
+	}
+
+	w = httptest.NewRecorder()
+	handler.APIUpdateItemHandler(w, asOwner(asClient(jsonRequest("PATCH", "/api/v1/lists/x/items/y", `{"category": "Hardware"}`, "id", list.IDHex, "item", item.IDHex), "a")))
+	require.Equal(t, http.StatusOK, w.Code)
+	published("category")
+
+	w = httptest.NewRecorder()
+	handler.APIDeleteListHandler(w, asOwner(asClient(jsonRequest("DELETE", "/api/v1/lists/x", "", "id", list.IDHex), "a")))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	published("delete")
+}
+
+func TestAPIMealPlan(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+	recipe, err := store.AddRecipe(context.Background(), recipes.Recipe{Title: "Fish pie", Ingredients: []recipes.Ingredient{{Name: "fish"}}})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.APIMealHandler(w, asOwner(jsonRequest("PUT", "/api/v1/meal-plans/2026-W42/meals/Monday/Dinner", `{"meal": "Fish pie", "version": 0}`, "week", "2026-W42", "day", "Monday", "slot", "Dinner")))
+	require.Equal(t, http.StatusOK, w.Code)
+	var meal models.APIMeal
+	decodeJSON(t, w, &meal)
+	assert.Equal(t, models.APIMeal{Week: "2026-W42", Day: "Monday", Slot: "Dinner", Meal: "Fish pie", RecipeID: recipe.IDHex, Version: 1}, meal,
+		"the meal is linked to the recipe with its title")
+
+	w = httptest.NewRecorder()
+	handler.APIMealHandler(w, asOwner(jsonRequest("PUT", "/api/v1/meal-plans/2026-W42/meals/Monday/Dinner", `{"meal": "Curry", "version": 0}`, "week", "2026-W42", "day", "Monday", "slot", "Dinner")))
+	require.Equal(t, http.StatusConflict, w.Code)
+	var conflict struct {
+		Error   string
+		Current models.APIMeal
+	}
+	decodeJSON(t, w, &conflict)
+	assert.Equal(t, meal, conflict.Current)
+
+	w = httptest.NewRecorder()
+	handler.APIMealPlanHandler(w, asOwner(jsonRequest("GET", "/api/v1/meal-plans/2026-W42", "", "week", "2026-W42")))
+	require.Equal(t, http.StatusOK, w.Code)
+	var plan models.APIMealPlan
+	decodeJSON(t, w, &plan)
+	assert.Equal(t, "2026-10-12", plan.WeekStart)
+	assert.Len(t, plan.Meals, len(models.WeekDays)*len(models.DefaultMealSlots))
+	assert.Contains(t, plan.Meals, meal)
+
+	for _, test := range []struct {
+		week, day, slot, body string
+		status                int
+	}{
+		{"2026-42", "Monday", "Dinner", `{"meal": "Toast", "version": 0}`, http.StatusBadRequest},
+		{"2026-W42", "Someday", "Dinner", `{"meal": "Toast", "version": 0}`, http.StatusNotFound},
+		{"2026-W42", "Monday", "Elevenses", `{"meal": "Toast", "version": 0}`, http.StatusNotFound},
+		{"2026-W42", "Tuesday", "Dinner", `{"meal": "Toast"}`, http.StatusBadRequest},
+		{"2026-W42", "Tuesday", "Dinner", `{"meal": "Toast", "recipeId": "000000000000000000000000", "version": 0}`, http.StatusBadRequest},
+	} {
+		w = httptest.NewRecorder()
+		handler.APIMealHandler(w, asOwner(jsonRequest("PUT", "/api/v1/meal-plans/x/meals/y/z", test.body, "week", test.week, "day", test.day, "slot", test.slot)))
+		assert.Equal(t, test.status, w.Code, test)
+	}
+}
+
+func TestAPIPermissions(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+	viewer := join(t, handler, invite(t, handler, owner, models.RoleViewer), "kim@example.com")
+	sam, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	lists, err := store.ForHousehold(sam.HouseholdID).GetShoppingLists(ctx)
+	require.NoError(t, err)
+
+	w := signedIn(handler, viewer, handler.APIItemsHandler, jsonRequest("GET", "/api/v1/lists/x/items", "", "id", lists[0].IDHex))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = signedIn(handler, viewer, handler.APICreateItemHandler, jsonRequest("POST", "/api/v1/lists/x/items", `{"text": "milk"}`, "id", lists[0].IDHex))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = signedIn(handler, viewer, handler.APIMealHandler, jsonRequest("PUT", "/api/v1/meal-plans/x/meals/y/z", `{"meal": "Toast", "version": 0}`, "week", "2026-W42", "day", "Monday", "slot", "Dinner"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Lists of other households are not found
+	w = signedIn(handler, viewer, handler.APIListHandler, jsonRequest("GET", "/api/v1/lists/x", "", "id", lists[0].IDHex))
+	assert.Equal(t, http.StatusOK, w.Code)
+	unowned, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	w = signedIn(handler, owner, handler.APIListHandler, jsonRequest("GET", "/api/v1/lists/x", "", "id", unowned[0].IDHex))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// jsonInt formats n for a JSON request body
+func jsonInt(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
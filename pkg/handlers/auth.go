@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// sessionCookie holds the session token of a signed-in browser
+const sessionCookie = "session"
+
+// userContextKey is the request context key RequireLogin stores the user under
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx in which user is signed in, as RequireLogin
+// leaves the requests it lets through
+func WithUser(ctx context.Context, user models.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// currentUser returns the user RequireLogin signed the request in as
+func currentUser(r *http.Request) (models.User, bool) {
+	user, ok := r.Context().Value(userContextKey{}).(models.User)
+	return user, ok
+}
+
+// errNotSignedIn is returned by household for requests that did not pass
+// through RequireLogin
+var errNotSignedIn = errors.New("the request is not signed in")
+
+// household returns the data of the signed-in user's household. Requests
+// that did not pass through RequireLogin get errNotSignedIn, unless Unscoped
+// is set, when they use the data saved before households existed. With
+// History set, the changes made through it are recorded as made by the user.
+func (h *Handler) household(r *http.Request) (db.DBInterface, error) {
+	var store db.DBInterface = h.DB
+	user, ok := currentUser(r)
+	switch {
+	case ok:
+		store = h.DB.ForHousehold(user.HouseholdID)
+	case !h.Unscoped:
+		return nil, errNotSignedIn
+	}
+	if h.History {
+		store = db.WithHistory(store, user)
+	}
+	return store, nil
+}
+
+// householdStore is household for handlers: it responds 500 Internal Server
+// Error when the request is not signed in, as only a route missing
+// RequireLogin lets that happen
+func (h *Handler) householdStore(w http.ResponseWriter, r *http.Request) (db.DBInterface, bool) {
+	store, err := h.household(r)
+	if err == nil {
+		return store, true
+	}
+	if isAPIRequest(r) {
+		apiServerError(w, "Failed to find your household", err)
+	} else {
+		fmt.Printf("Failed to find your household: %s\n", err)
+		http.Error(w, "Failed to find your household", http.StatusInternalServerError)
+	}
+	return nil, false
+}
+
+// can reports whether the signed-in user's role passes check. Requests that
+// did not pass through RequireLogin may do nothing.
+func can(r *http.Request, check func(models.Role) bool) bool {
+	user, ok := currentUser(r)
+	return ok && check(user.Role)
+}
+
+// allowed is can for handlers: it responds 403 Forbidden when the user's role
+// does not pass check
+func allowed(w http.ResponseWriter, r *http.Request, check func(models.Role) bool) bool {
+	if can(r, check) {
+		return true
+	}
+	http.Error(w, "You do not have permission to do that", http.StatusForbidden)
+	return false
+}
+
+// sessionToken returns the session token a request was made with: the
+// bearer token of JSON API clients, or else the session cookie
+func sessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// RequireLogin only lets signed-in requests through to next. Others are sent
+// to the sign in page; HTMX requests are told to load it with HX-Redirect,
+// and JSON API requests are answered 401 Unauthorized.
+func (h *Handler) RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := db.Authenticate(r.Context(), h.DB, sessionToken(r), time.Now())
+		if errors.Is(err, db.ErrNotFound) {
+			if isAPIRequest(r) {
+				apiError(w, http.StatusUnauthorized, "Sign in required")
+				return
+			}
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", "/login")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error checking session: %s\n", err)
+			http.Error(w, "Failed to check session", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// LoginHandler shows the sign in form on GET and signs in with the email and
+// password posted to it
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	data := models.LoginPageData{Hint: h.LoginHint}
+	switch r.Method {
+	case http.MethodGet:
+		h.renderLogin(w, http.StatusOK, data)
+	case http.MethodPost:
+		data.Email = r.PostFormValue("email")
+		user, err := db.Login(r.Context(), h.DB, data.Email, r.PostFormValue("password"))
+		if errors.Is(err, db.ErrInvalidLogin) {
+			data.Error = err.Error()
+			h.renderLogin(w, http.StatusUnauthorized, data)
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error signing in: %s\n", err)
+			http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+			return
+		}
+		h.startSession(w, r, user)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// RegisterHandler shows the form for creating an account on GET, and on POST
+// creates a household and its first user, then signs them in
+func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	data := models.LoginPageData{Register: true}
+	switch r.Method {
+	case http.MethodGet:
+		h.renderLogin(w, http.StatusOK, data)
+	case http.MethodPost:
+		data.Email = r.PostFormValue("email")
+		data.Name = r.PostFormValue("name")
+		data.Household = r.PostFormValue("household")
+		user, err := db.Register(r.Context(), h.DB, data.Email, data.Name, r.PostFormValue("password"), data.Household)
+		if errors.Is(err, db.ErrExists) {
+			data.Error = "An account with that email already exists"
+			h.renderLogin(w, http.StatusConflict, data)
+			return
+		}
+		if err != nil {
+			data.Error = err.Error()
+			h.renderLogin(w, http.StatusBadRequest, data)
+			return
+		}
+		h.startSession(w, r, user)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// JoinHandler shows the form for joining a household with an invite code on
+// GET, filled in from ?code=, and on POST creates a user in the household the
+// code is for, then signs them in
+func (h *Handler) JoinHandler(w http.ResponseWriter, r *http.Request) {
+	data := models.LoginPageData{Register: true, Join: true, InviteCode: r.URL.Query().Get("code")}
+	switch r.Method {
+	case http.MethodGet:
+		h.renderLogin(w, http.StatusOK, data)
+	case http.MethodPost:
+		data.Email = r.PostFormValue("email")
+		data.Name = r.PostFormValue("name")
+		data.InviteCode = r.PostFormValue("code")
+		user, err := db.Join(r.Context(), h.DB, data.InviteCode, data.Email, data.Name, r.PostFormValue("password"), time.Now())
+		if errors.Is(err, db.ErrExists) {
+			data.Error = "An account with that email already exists"
+			h.renderLogin(w, http.StatusConflict, data)
+			return
+		}
+		if err != nil {
+			data.Error = err.Error()
+			h.renderLogin(w, http.StatusBadRequest, data)
+			return
+		}
+		h.startSession(w, r, user)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LogoutHandler signs the browser out
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := db.EndSession(r.Context(), h.DB, cookie.Value); err != nil {
+			fmt.Printf("Error signing out: %s\n", err)
+			http.Error(w, "Failed to sign out", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.setSessionCookie(w, "", -1)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// startSession signs user in and sends them to the home page
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user models.User) {
+	token, err := db.StartSession(r.Context(), h.DB, user, time.Now())
+	if err != nil {
+		fmt.Printf("Error signing in: %s\n", err)
+		http.Error(w, "Failed to sign in", http.StatusInternalServerError)
+		return
+	}
+	h.setSessionCookie(w, token, int(models.SessionLifetime.Seconds()))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// setSessionCookie stores token in the session cookie, or removes the cookie
+// when maxAge is negative
+func (h *Handler) setSessionCookie(w http.ResponseWriter, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// renderLogin executes the sign in template with the given status
+func (h *Handler) renderLogin(w http.ResponseWriter, status int, data models.LoginPageData) {
+	tmpl, err := template.ParseFiles(h.LoginTemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.LoginTemplatePath)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	if err := tmpl.Execute(w, data); err != nil {
+		fmt.Printf("Error executing template: %s\n", err)
+	}
+}
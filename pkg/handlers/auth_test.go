@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// register creates an account through RegisterHandler and returns its
+// session cookie
+func register(t *testing.T, handler *Handler, email, household string) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.RegisterHandler(w, formRequest("/register", "email="+email+"&name=Sam&password=correct-horse&household="+household))
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0]
+}
+
+// asOwner signs req in as an owner with no household, as RequireLogin would
+// for handlers called on their own
+func asOwner(req *http.Request) *http.Request {
+	return req.WithContext(WithUser(req.Context(), models.User{Role: models.RoleOwner}))
+}
+
+// shoppingListFor runs ShoppingListHandler behind RequireLogin with cookie
+func shoppingListFor(handler *Handler, cookie *http.Cookie, body string) *httptest.ResponseRecorder {
+	req := formRequest("/shopping-list", body)
+	if body == "" {
+		req = httptest.NewRequest("GET", "/shopping-list", nil)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	handler.RequireLogin(http.HandlerFunc(handler.ShoppingListHandler)).ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	handler.SecureCookies = true
+
+	cookie := register(t, handler, "sam@example.com", "The+Smiths")
+	assert.Equal(t, sessionCookie, cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	user, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	household, err := store.GetHousehold(ctx, user.HouseholdID)
+	require.NoError(t, err)
+	assert.Equal(t, "The Smiths", household.Name)
+
+	w := httptest.NewRecorder()
+	handler.RegisterHandler(w, formRequest("/register", "email=sam@example.com&password=correct-horse&household=Another"))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.RegisterHandler(w, formRequest("/register", "email=alex@example.com&password=short&household=Another"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at least 8 characters")
+}
+
+func TestLoginHandler(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	register(t, handler, "sam@example.com", "Home")
+
+	w := httptest.NewRecorder()
+	handler.LoginHandler(w, formRequest("/login", "email=Sam@Example.com&password=wrong-password"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "incorrect email or password")
+	assert.Empty(t, w.Result().Cookies())
+
+	w = httptest.NewRecorder()
+	handler.LoginHandler(w, formRequest("/login", "email=Sam@Example.com&password=correct-horse"))
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/", w.Header().Get("Location"))
+	require.Len(t, w.Result().Cookies(), 1)
+}
+
+func TestRequireLogin(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+
+	w := shoppingListFor(handler, nil, "")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/login", w.Header().Get("Location"))
+
+	req := httptest.NewRequest("GET", "/shopping-list", nil)
+	req.Header.Set("HX-Request", "true")
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: "forged"})
+	w = httptest.NewRecorder()
+	handler.RequireLogin(http.HandlerFunc(handler.ShoppingListHandler)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "/login", w.Header().Get("HX-Redirect"))
+
+	cookie := register(t, handler, "sam@example.com", "Home")
+	w = shoppingListFor(handler, cookie, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRequireLoginCancelled(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	cookie := register(t, handler, "sam@example.com", "Home")
+
+	// The session is looked up with the request context, so a request that
+	// has gone away is not signed in
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/shopping-list", nil).WithContext(ctx)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.RequireLogin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("next should not be called")
+	})).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHouseholdsHaveSeparateData(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	smiths := register(t, handler, "sam@example.com", "Smiths")
+	joneses := register(t, handler, "alex@example.com", "Joneses")
+
+	w := shoppingListFor(handler, smiths, "item=potatoes")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "potatoes")
+
+	w = shoppingListFor(handler, joneses, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "potatoes")
+
+	user, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	list, err := store.ForHousehold(user.HouseholdID).GetShoppingList(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "potatoes", list[0].Item)
+}
+
+func TestHandlersWithoutUserFail(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	_, err := store.AddShoppingListItem(ctx, "potatoes")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListHandler(w, httptest.NewRequest("GET", "/shopping-list", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "potatoes", "a route missing RequireLogin shows no data")
+
+	w = httptest.NewRecorder()
+	handler.APIListsHandler(w, httptest.NewRequest("GET", "/api/v1/lists", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	// Changes are refused outright, even when the data could be used
+	handler.Unscoped = true
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, formRequest("/shopping-list", "item=candles"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	list, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestLogoutHandler(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	cookie := register(t, handler, "sam@example.com", "Home")
+
+	req := httptest.NewRequest("POST", "/logout", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.LogoutHandler(w, req)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	require.Len(t, w.Result().Cookies(), 1)
+	assert.True(t, w.Result().Cookies()[0].MaxAge < 0, "the cookie is removed")
+
+	w = shoppingListFor(handler, cookie, "")
+	assert.Equal(t, http.StatusSeeOther, w.Code, "the old session no longer signs in")
+}
+
+func TestHomeHandlerShowsUser(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	cookie := register(t, handler, "sam@example.com", "Home")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.RequireLogin(http.HandlerFunc(handler.HomeHandler)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Signed in as Sam")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/events"
+)
+
+// clientHeader names the browser tab that made a request, so that the
+// changes it makes are pushed to every other tab but not back to it
+const clientHeader = "X-Client-ID"
+
+// keepAliveInterval is how often an idle event stream is sent a comment, so
+// that proxies do not close it
+const keepAliveInterval = 30 * time.Second
+
+// newClientID returns a random ID for a newly opened page
+func newClientID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		fmt.Printf("Error generating client ID: %s\n", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// publish sends a change made by r to the other open sessions of the
+// household showing list, or all of them when list is ""
+func (h *Handler) publish(r *http.Request, list, name, data string) {
+	if h.Events == nil {
+		return
+	}
+	user, _ := currentUser(r)
+	h.Events.Publish(events.Event{
+		Household: user.HouseholdID,
+		List:      list,
+		Name:      name,
+		Data:      data,
+		Origin:    r.Header.Get(clientHeader),
+	})
+}
+
+// publishListChange tells the other sessions showing the shopping list r is
+// for to load it again
+func (h *Handler) publishListChange(r *http.Request) {
+	view, err := h.listsView(r)
+	if err != nil {
+		fmt.Printf("Error getting shopping lists: %s\n", err)
+		return
+	}
+	h.publish(r, view.List.IDHex, "list-changed", "")
+}
+
+// ShoppingListEventsHandler streams the changes other people make to the
+// shopping list at /lists/{id} and to the meal plan, as server-sent events
+// for the htmx SSE extension. The client parameter names the page listening.
+func (h *Handler) ShoppingListEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Events == nil {
+		http.Error(w, "Live updates are not available", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	if _, ok := h.shoppingList(w, r); !ok {
+		return
+	}
+
+	user, _ := currentUser(r)
+	stream, cancel := h.Events.Subscribe(user.HouseholdID, r.PathValue("id"), r.URL.Query().Get("client"))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event := <-stream:
+			if err := events.Write(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/events"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads the next event from an event stream, skipping comments
+func readEvent(t *testing.T, stream *bufio.Reader) (name, data string) {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := stream.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, strings.Join(lines, "\n")
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			lines = append(lines, strings.TrimPrefix(line, "data: "))
+		}
+	}
+}
+
+// asClient marks req as made by the page named client
+func asClient(req *http.Request, client string) *http.Request {
+	req.Header.Set(clientHeader, client)
+	return req
+}
+
+func TestShoppingListEventsHandler(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+	handler.Events = events.NewHub()
+	lists, err := store.GetShoppingLists(context.Background())
+	require.NoError(t, err)
+	list := lists[0]
+	items := addTestItems(t, store, "milk")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lists/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		handler.ShoppingListEventsHandler(w, asOwner(r))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/lists/" + list.IDHex + "/events?client=a")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	stream := bufio.NewReader(resp.Body)
+	line, err := stream.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+
+	// Another page ticking an item sends the rendered item
+	w := httptest.NewRecorder()
+	handler.ShoppingListTickHandler(w, asOwner(asClient(listRequest(formRequest("/lists/x/items/tick", items[0].IDHex+"=on"), list), "b")))
+	require.Equal(t, http.StatusOK, w.Code)
+	name, data := readEvent(t, stream)
+	assert.Equal(t, "item-"+items[0].IDHex, name)
+	assert.Contains(t, data, "milk (checked)")
+
+	// Changes made by the listening page are not sent back to it
+	w = httptest.NewRecorder()
+	handler.ShoppingListEditHandler(w, asOwner(asClient(listRequest(formRequest("/lists/x/items/edit", items[0].IDHex+"=oat+milk&version=1"), list), "a")))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(asClient(listRequest(formRequest("/lists/x/items", "item=eggs"), list), "b")))
+	require.Equal(t, http.StatusOK, w.Code)
+	name, data = readEvent(t, stream)
+	assert.Equal(t, "list-changed", name)
+	assert.Empty(t, data)
+}
+
+func TestShoppingListEventsHandlerErrors(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	unknown := models.ShoppingList{IDHex: "000000000000000000000000"}
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListEventsHandler(w, asOwner(listRequest(httptest.NewRequest("GET", "/lists/x/events", nil), unknown)))
+	assert.Equal(t, http.StatusNotFound, w.Code, "live updates are off without a hub")
+
+	handler.Events = events.NewHub()
+	w = httptest.NewRecorder()
+	handler.ShoppingListEventsHandler(w, asOwner(listRequest(httptest.NewRequest("GET", "/lists/x/events", nil), unknown)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, 0, handler.Events.Subscribers())
+}
+
+func TestMealHandlerPublishes(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	handler.Events = events.NewHub()
+	stream, cancel := handler.Events.Subscribe("", "any list", "b")
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	handler.MealHandler(w, asOwner(asClient(formRequest("/meal", "week=2026-W42&day=Monday&slot=Dinner&meal=Fish+pie&version=0"), "a")))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	select {
+	case event := <-stream:
+		assert.Equal(t, "meal-monday-dinner-2026-w42", event.Name)
+		assert.Contains(t, event.Data, "Monday Dinner: Fish pie")
+	default:
+		t.Fatal("no meal event was published")
+	}
+}
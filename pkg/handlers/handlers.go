@@ -1,47 +1,136 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/events"
 	"github.com/JonClarke84/mealplannergo/pkg/models"
 )
 
 // Handler contains all the dependencies needed for handling HTTP requests
 type Handler struct {
-	DB db.DBInterface
+	DB db.Store
 	// Template path relative to where the app is run from
 	TemplatePath string
+	// Recipe library template path, relative like TemplatePath
+	RecipesTemplatePath string
+	// Stores template path, relative like TemplatePath
+	StoresTemplatePath string
+	// Sign in and register template path, relative like TemplatePath
+	LoginTemplatePath string
+	// Household members and invites template path, relative like TemplatePath
+	HouseholdTemplatePath string
+	// Shopping lists template path, relative like TemplatePath
+	ListsTemplatePath string
+	// Change history template path, relative like TemplatePath
+	HistoryTemplatePath string
+	// Meal slots shown for each day, in display order
+	MealSlots []string
+	// SecureCookies marks the session cookie as HTTPS only
+	SecureCookies bool
+	// LoginHint is shown on the sign in page, e.g. the demo account
+	LoginHint string
+	// Events carries changes to the other open pages; nil turns live updates off
+	Events *events.Hub
+	// History records the changes made through the handlers, so that they
+	// can be undone and shown on the history page
+	History bool
+	// RequestTimeout is how long WithDeadline lets a request take; zero sets
+	// no limit
+	RequestTimeout time.Duration
+	// Unscoped lets requests that did not pass through RequireLogin use the
+	// data saved before households existed, for tests of handlers on their
+	// own. Otherwise such requests fail.
+	Unscoped bool
 }
 
 // New creates a new Handler with the given database connection
-func New(db db.DBInterface) *Handler {
+func New(db db.Store) *Handler {
 	return &Handler{
-		DB:           db,
-		TemplatePath: "./pkg/templates/index.html",
+		DB:                    db,
+		TemplatePath:          "./pkg/templates/index.html",
+		RecipesTemplatePath:   "./pkg/templates/recipes.html",
+		StoresTemplatePath:    "./pkg/templates/stores.html",
+		LoginTemplatePath:     "./pkg/templates/login.html",
+		HouseholdTemplatePath: "./pkg/templates/household.html",
+		ListsTemplatePath:     "./pkg/templates/lists.html",
+		HistoryTemplatePath:   "./pkg/templates/history.html",
+		MealSlots:             models.DefaultMealSlots,
+		SecureCookies:         true,
+		Events:                events.NewHub(),
+		History:               true,
 	}
 }
 
-// HomeHandler handles the root path request
+// WithDeadline gives requests to next RequestTimeout to finish. The request
+// context, which handlers pass to every database call, is cancelled when it
+// runs out, and the request is answered 503 Service Unavailable.
+func (h *Handler) WithDeadline(next http.Handler) http.Handler {
+	if h.RequestTimeout <= 0 {
+		return next
+	}
+	return http.TimeoutHandler(next, h.RequestTimeout, "The request took too long, please try again")
+}
+
+// HomeHandler handles the root path request. The meal plan shown is the ISO
+// week given by ?week=2026-W42, or the current week if none is given, and the
+// shopping list the one given by ?list=, or the first one not archived.
 func (h *Handler) HomeHandler(w http.ResponseWriter, r *http.Request) {
-	shoppingList, err := h.DB.GetShoppingList()
+	week := r.URL.Query().Get("week")
+	if week == "" {
+		week = models.ISOWeek(time.Now())
+	}
+	weekStart, err := models.ParseISOWeek(week)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+	shoppingList, err := list.GetShoppingList(r.Context())
 	if err != nil {
 		fmt.Printf("Error getting this week's meals: %s\n", err)
 		http.Error(w, "Failed to get shopping list", http.StatusInternalServerError)
 		return
 	}
 
-	mealPlan, err := h.DB.GetMealPlan()
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	mealPlan, err := store.GetMealPlan(r.Context(), week)
 	if err != nil {
 		fmt.Printf("Error getting meal plan: %s\n", err)
 		http.Error(w, "Failed to get meal plan", http.StatusInternalServerError)
 		return
 	}
 
+	// Recipe titles are offered as suggestions in the meal inputs
+	library, err := store.GetRecipes(r.Context())
+	if err != nil {
+		fmt.Printf("Error getting recipes: %s\n", err)
+		http.Error(w, "Failed to get recipes", http.StatusInternalServerError)
+		return
+	}
+
+	view, err := h.shoppingListView(r, shoppingList)
+	if err != nil {
+		fmt.Printf("Error getting stores: %s\n", err)
+		http.Error(w, "Failed to get stores", http.StatusInternalServerError)
+		return
+	}
+
 	tmpl, err := template.ParseFiles(h.TemplatePath)
 	if err != nil {
 		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.TemplatePath)
@@ -50,113 +139,245 @@ func (h *Handler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	prevWeek, _ := models.AddWeeks(week, -1)
+	nextWeek, _ := models.AddWeeks(week, 1)
+
 	pageData := models.PageData{
-		MealPlan:     mealPlan.Meals,
-		ShoppingList: shoppingList,
+		Week:             week,
+		WeekStart:        weekStart,
+		PrevWeek:         prevWeek,
+		NextWeek:         nextWeek,
+		MealSlots:        h.mealSlots(),
+		MealPlan:         mealPlan.Meals,
+		ShoppingList:     shoppingList,
+		Recipes:          library,
+		ShoppingListView: view,
 	}
+	pageData.User, _ = currentUser(r)
+	pageData.CanEditMeals = can(r, models.Role.CanEditMealPlan)
+	pageData.ClientID = newClientID()
 
 	tmpl.Execute(w, pageData)
 }
 
-// MealHandler handles updating a meal
+// MealHandler handles updating a meal. The form holds the day, slot and meal,
+// the version of the slot that was edited, the ISO week being edited (defaults
+// to this week) and the shopping list being shown. A meal matching the title
+// of a recipe is linked to that recipe. If someone else has changed the slot
+// since, nothing is saved and the slot as it is now is returned with a 409.
 func (h *Handler) MealHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowed(w, r, models.Role.CanEditMealPlan) {
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		fmt.Printf("Error parsing form: %s\n", err)
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
-	var key string
-	var value string
-	for k, v := range r.PostForm {
-		key = k
-		value = v[0]
-		break
+	week, err := formWeek(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if err := h.DB.UpdateMeal(key, value); err != nil {
+	version, err := models.ParseVersion(r.PostFormValue("version"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	day := r.PostFormValue("day")
+	slot := r.PostFormValue("slot")
+	value := r.PostFormValue("meal")
+	if day == "" {
+		// Pages from before slots existed post one field named after the day
+		for k, v := range r.PostForm {
+			if k == "week" || k == "list" || k == "version" {
+				continue
+			}
+			day = k
+			value = v[0]
+			break
+		}
+		slot = models.LegacyMealSlot
+	} else if !h.hasMealSlot(slot) {
+		http.Error(w, fmt.Sprintf("Unknown meal slot %q", slot), http.StatusBadRequest)
+		return
+	}
+
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	recipeID, err := h.recipeIDForMeal(r, value)
+	if err != nil {
+		fmt.Printf("Error getting recipes: %s\n", err)
+		http.Error(w, "Failed to update meal", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := store.EditMeal(r.Context(), week, models.MealSlotInput{
+		Day:      day,
+		Slot:     slot,
+		Meal:     value,
+		RecipeID: recipeID,
+		Version:  version,
+	})
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		saved.Week = week
+		saved.Conflict = value
+		tmpl := template.Must(template.ParseFiles(h.TemplatePath))
+		w.WriteHeader(http.StatusConflict)
+		tmpl.ExecuteTemplate(w, "meal-input", saved)
+		return
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, fmt.Sprintf("Unknown day %q", day), http.StatusBadRequest)
+		return
+	case err != nil:
+		fmt.Printf("Error updating meal: %s\n", err)
 		http.Error(w, "Failed to update meal", http.StatusInternalServerError)
 		return
 	}
 
-	updatedMeal := models.Meal{
-		Day:  key,
-		Meal: value,
+	// Offer to remove ingredients added for a recipe this slot no longer uses
+	var offer *models.IngredientOffer
+	if shoppingList, err := store.ForShoppingList(listID(r)).GetShoppingList(r.Context()); err != nil {
+		fmt.Printf("Error getting shopping list: %s\n", err)
+	} else {
+		offer = models.FindIngredientOffer(shoppingList, week, day, slot, recipeID)
 	}
 
+	updatedMeal := saved
+	updatedMeal.Week = week
+	updatedMeal.Offer = offer
+
 	tmpl := template.Must(template.ParseFiles(h.TemplatePath))
 	tmpl.ExecuteTemplate(w, "meal-input", updatedMeal)
+
+	// Other pages are sent the meal without the offer, which is for whoever
+	// changed it
+	var shared bytes.Buffer
+	updatedMeal.Offer = nil
+	if err := tmpl.ExecuteTemplate(&shared, "meal-input", updatedMeal); err != nil {
+		fmt.Printf("Error rendering meal: %s\n", err)
+		return
+	}
+	h.publish(r, "", updatedMeal.EventName(), shared.String())
 }
 
-// ShoppingListHandler handles operations on the shopping list
+// formWeek returns the ISO week posted in the form, defaulting to this week
+func formWeek(r *http.Request) (string, error) {
+	week := r.PostFormValue("week")
+	if week == "" {
+		week = models.ISOWeek(time.Now())
+	}
+	if _, err := models.ParseISOWeek(week); err != nil {
+		return "", err
+	}
+	return week, nil
+}
+
+// mealSlots returns the configured meal slots, falling back to the defaults
+func (h *Handler) mealSlots() []string {
+	if len(h.MealSlots) == 0 {
+		return models.DefaultMealSlots
+	}
+	return h.MealSlots
+}
+
+// hasMealSlot reports whether slot is one of the configured meal slots
+func (h *Handler) hasMealSlot(slot string) bool {
+	for _, s := range h.mealSlots() {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// ShoppingListHandler handles operations on the items of a shopping list
 func (h *Handler) ShoppingListHandler(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		fmt.Printf("Error parsing shopping list: %s\n", err)
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
 
-	// CREATE
+	// READ, choosing the store whose aisle order the list follows
+	if r.Method == "GET" {
+		if r.URL.Query().Has("store") {
+			setStoreCookie(w, r.URL.Query().Get("store"))
+		}
+		h.renderShoppingList(w, r, list)
+	}
+
+	// Anyone in the household may read the list, but not change it
+	if r.Method != "GET" && !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	// CREATE, or add to the quantity of a matching item. The whole list is
+	// rendered, as the item belongs under its category heading.
 	if r.Method == "POST" {
 		item := r.PostFormValue("item")
-		newItem, err := h.DB.AddShoppingListItem(item)
-		if err != nil {
+		if _, err := list.AddShoppingListItem(r.Context(), item); err != nil {
 			fmt.Printf("Error adding shopping list item: %s\n", err)
 			http.Error(w, "Failed to add item", http.StatusInternalServerError)
 			return
 		}
-		tmpl := template.Must(template.ParseFiles(h.TemplatePath))
-		tmpl.ExecuteTemplate(w, "shopping-list-item", newItem)
+		h.renderShoppingList(w, r, list)
 	}
 
 	// DELETE
 	if r.Method == "DELETE" {
 		item := r.URL.Query().Get("item")
-		if err := h.DB.DeleteShoppingListItem(item); err != nil {
+		if err := list.DeleteShoppingListItem(r.Context(), item); err != nil {
 			http.Error(w, "Failed to delete item", http.StatusInternalServerError)
 			return
 		}
-		shoppingList, err := h.DB.GetShoppingList()
-		if err != nil {
-			fmt.Printf("Error getting shopping list: %s\n", err)
-			http.Error(w, "Failed to get updated shopping list", http.StatusInternalServerError)
-			return
-		}
-
-		tmpl := template.Must(template.ParseFiles(h.TemplatePath))
-		tmpl.ExecuteTemplate(w, "shopping-list", shoppingList)
+		h.renderShoppingList(w, r, list)
 	}
 }
 
 // ShoppingListTickHandler handles toggling a shopping list item's ticked state
 func (h *Handler) ShoppingListTickHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		fmt.Printf("Error parsing shopping list: %s\n", err)
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
 	var itemId string
 	var ticked bool
 
 	for k, v := range r.PostForm {
+		if k == "list" {
+			continue
+		}
 		itemId = k
 		ticked = v[0] == "on"
 		break
 	}
 
-	shoppingListItem, err := h.DB.TickShoppingListItem(itemId, ticked)
+	shoppingListItem, err := db.TickItem(r.Context(), list, itemId, ticked, time.Now())
 	if err != nil {
 		fmt.Printf("Error ticking shopping list item: %s\n", err)
 		http.Error(w, "Failed to update item", http.StatusInternalServerError)
 		return
 	}
-
-	tmpl, err := template.ParseFiles(h.TemplatePath)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	tmpl.ExecuteTemplate(w, "shopping-list-item", shoppingListItem)
+	h.renderShoppingListItem(w, r, shoppingListItem)
 }
 
 // ShoppingListSortHandler handles reordering shopping list items
@@ -165,6 +386,14 @@ func (h *Handler) ShoppingListSortHandler(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
 
 	var updates models.OrderUpdate
 	err := json.NewDecoder(r.Body).Decode(&updates)
@@ -172,42 +401,309 @@ func (h *Handler) ShoppingListSortHandler(w http.ResponseWriter, r *http.Request
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if updates.Version == nil {
+		http.Error(w, "version is required", http.StatusBadRequest)
+		return
+	}
+
+	// An order made before items were added, removed or reordered elsewhere
+	// would lose those changes, so it is only saved while the list is still
+	// at the version the page read
+	err = list.ReorderShoppingList(r.Context(), updates.Order, *updates.Version)
+	if errors.Is(err, db.ErrConflict) {
+		view, err := h.listsView(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{"status": "conflict", "version": view.List.Version})
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.publishListChange(r)
 
-	// Update the order in the database
-	err = h.DB.SortShoppingList(updates.Order)
+	// The page sends the new version with its next order
+	view, err := h.listsView(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	// Respond with OK status
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{"status": "success", "version": view.List.Version})
 }
 
-// ShoppingListEditHandler handles editing shopping list items
+// ShoppingListEditHandler handles editing shopping list items. The text is
+// read for a quantity, unit and note the same way as a new item. The form
+// holds the version of the item that was edited; if someone else has changed
+// the item since, nothing is saved and the item as it is now is returned with
+// a 409.
 func (h *Handler) ShoppingListEditHandler(w http.ResponseWriter, r *http.Request) {
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		fmt.Printf("Error parsing form: %s\n", err)
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := models.ParseVersion(r.PostFormValue("version"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var itemId string
 	var updatedItem string
 	for k, v := range r.PostForm {
+		if k == "list" || k == "version" {
+			continue
+		}
 		itemId = k
 		updatedItem = v[0]
 		break
 	}
 
-	shoppingListItem, err := h.DB.UpdateShoppingListItem(itemId, updatedItem)
-	if err != nil {
+	item := models.ParseShoppingListItem(updatedItem)
+	item.IDHex = itemId
+	item.Version = version
+	shoppingListItem, err := list.EditShoppingListItem(r.Context(), item)
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		h.renderShoppingListItemConflict(w, r, shoppingListItem, updatedItem)
+		return
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, "Item not found", http.StatusNotFound)
+		return
+	case err != nil:
 		fmt.Printf("Error updating shopping list item: %s\n", err)
 		http.Error(w, "Failed to update item", http.StatusInternalServerError)
 		return
 	}
+	h.renderShoppingListItem(w, r, shoppingListItem)
+}
+
+// ShoppingListBuildHandler adds the ingredients of the recipes planned for the
+// posted week to the shopping list, then renders the whole list
+func (h *Handler) ShoppingListBuildHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		fmt.Printf("Error parsing form: %s\n", err)
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	week, err := formWeek(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := db.GenerateShoppingList(r.Context(), list, week); err != nil {
+		fmt.Printf("Error building shopping list: %s\n", err)
+		http.Error(w, "Failed to build shopping list", http.StatusInternalServerError)
+		return
+	}
+	h.renderShoppingList(w, r, list)
+}
+
+// ShoppingListMergeHandler combines duplicate items on the shopping list, then
+// renders the whole list
+func (h *Handler) ShoppingListMergeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := db.MergeDuplicates(r.Context(), list); err != nil {
+		fmt.Printf("Error merging shopping list items: %s\n", err)
+		http.Error(w, "Failed to merge shopping list items", http.StatusInternalServerError)
+		return
+	}
+	h.renderShoppingList(w, r, list)
+}
+
+// ShoppingListAutoSortHandler puts the shopping list in the order items are
+// usually picked up, learned from past trips, then renders the whole list
+func (h *Handler) ShoppingListAutoSortHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.AutoSort(r.Context(), list); err != nil {
+		fmt.Printf("Error sorting shopping list: %s\n", err)
+		http.Error(w, "Failed to sort shopping list", http.StatusInternalServerError)
+		return
+	}
+	h.renderShoppingList(w, r, list)
+}
+
+// ShoppingListRemoveMealHandler removes the items generated for a meal that
+// has been changed. Items also needed by other meals are kept, and just stop
+// listing the changed meal as a source.
+func (h *Handler) ShoppingListRemoveMealHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		fmt.Printf("Error parsing form: %s\n", err)
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	week, err := formWeek(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	source := models.MealSource{
+		Week:     week,
+		Day:      r.PostFormValue("day"),
+		Slot:     r.PostFormValue("slot"),
+		RecipeID: r.PostFormValue("recipe"),
+	}
+
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	shoppingList, err := list.GetShoppingList(r.Context())
+	if err != nil {
+		fmt.Printf("Error getting shopping list: %s\n", err)
+		http.Error(w, "Failed to get shopping list", http.StatusInternalServerError)
+		return
+	}
+	for _, item := range shoppingList {
+		if !item.HasSource(source) {
+			continue
+		}
+		remaining := item.WithoutSource(source)
+		if len(remaining) == 0 {
+			err = list.DeleteShoppingListItem(r.Context(), item.IDHex)
+		} else {
+			_, err = list.SetShoppingListItemSources(r.Context(), item.IDHex, remaining)
+		}
+		if err != nil {
+			fmt.Printf("Error removing %q from shopping list: %s\n", item.Item, err)
+			http.Error(w, "Failed to remove meal ingredients", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.renderShoppingList(w, r, list)
+}
+
+// renderShoppingList renders the shopping-list block with the items of list,
+// grouped for the store chosen in r
+func (h *Handler) renderShoppingList(w http.ResponseWriter, r *http.Request, list db.DBInterface) {
+	h.renderShoppingListStatus(w, r, list, http.StatusOK, "")
+}
+
+// renderShoppingListStatus is renderShoppingList responding with status,
+// and with undoFailed shown as why a change could not be undone
+func (h *Handler) renderShoppingListStatus(w http.ResponseWriter, r *http.Request, list db.DBInterface, status int, undoFailed string) {
+	shoppingList, err := list.GetShoppingList(r.Context())
+	if err != nil {
+		fmt.Printf("Error getting shopping list: %s\n", err)
+		http.Error(w, "Failed to get updated shopping list", http.StatusInternalServerError)
+		return
+	}
+	view, err := h.shoppingListView(r, shoppingList)
+	if err != nil {
+		fmt.Printf("Error getting stores: %s\n", err)
+		http.Error(w, "Failed to get stores", http.StatusInternalServerError)
+		return
+	}
+
+	// Offer to undo deleting an item
+	if r.Method == http.MethodDelete {
+		if change := db.LastChange(list); change != nil && change.Destructive() {
+			view.Undo = change
+		}
+	}
+	view.UndoFailed = undoFailed
 
 	tmpl := template.Must(template.ParseFiles(h.TemplatePath))
-	tmpl.ExecuteTemplate(w, "shopping-list-item", shoppingListItem)
-}
\ No newline at end of file
+	w.WriteHeader(status)
+	tmpl.ExecuteTemplate(w, "shopping-list", models.PageData{ShoppingList: shoppingList, ShoppingListView: view})
+
+	// Other pages load the list again, grouped for their own store
+	if r.Method != http.MethodGet {
+		h.publish(r, view.List.IDHex, "list-changed", "")
+	}
+}
+
+// ShoppingListCategoryHandler moves a shopping list item to the posted
+// category, which is remembered for items with the same name, then renders
+// the whole list
+func (h *Handler) ShoppingListCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		fmt.Printf("Error parsing form: %s\n", err)
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := db.AssignCategory(r.Context(), list, r.PostFormValue("item"), r.PostFormValue("category")); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Item not found", http.StatusNotFound)
+			return
+		}
+		fmt.Printf("Error setting shopping list item category: %s\n", err)
+		http.Error(w, "Failed to set category", http.StatusInternalServerError)
+		return
+	}
+	h.renderShoppingList(w, r, list)
+}
@@ -1,22 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/JonClarke84/mealplannergo/pkg/db"
 	"github.com/JonClarke84/mealplannergo/pkg/db/tests"
 	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestNew(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	handler := New(mockDB)
 	
 	assert.Equal(t, mockDB, handler.DB, "Handler should use the provided DB")
@@ -25,7 +30,7 @@ func TestNew(t *testing.T) {
 
 func TestHomeHandler(t *testing.T) {
 	// Setup mock DB
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	// Create test data
 	shoppingList := []models.ShoppingListItem{
@@ -47,8 +52,10 @@ func TestHomeHandler(t *testing.T) {
 	}
 	
 	// Set expectations on mock
-	mockDB.On("GetShoppingList").Return(shoppingList, nil)
-	mockDB.On("GetMealPlan").Return(mealPlan, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return(shoppingList, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
+	mockDB.On("GetMealPlan", mock.Anything, models.ISOWeek(time.Now())).Return(mealPlan, nil)
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
 	
 	// Create handler with mock
 	handler := &Handler{
@@ -61,7 +68,7 @@ func TestHomeHandler(t *testing.T) {
 	w := httptest.NewRecorder()
 	
 	// Call handler
-	handler.HomeHandler(w, req)
+	handler.HomeHandler(w, asOwner(req))
 	
 	// Assert expectations were met
 	mockDB.AssertExpectations(t)
@@ -73,8 +80,8 @@ func TestHomeHandler(t *testing.T) {
 }
 
 func TestHomeHandlerShoppingListError(t *testing.T) {
-	mockDB := new(tests.MockDB)
-	mockDB.On("GetShoppingList").Return([]models.ShoppingListItem{}, errors.New("database error"))
+	mockDB := tests.NewMockDB()
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, errors.New("database error"))
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -84,7 +91,7 @@ func TestHomeHandlerShoppingListError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
 	w := httptest.NewRecorder()
 	
-	handler.HomeHandler(w, req)
+	handler.HomeHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -93,7 +100,7 @@ func TestHomeHandlerShoppingListError(t *testing.T) {
 }
 
 func TestHomeHandlerMealPlanError(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	shoppingList := []models.ShoppingListItem{
 		{
@@ -104,8 +111,8 @@ func TestHomeHandlerMealPlanError(t *testing.T) {
 		},
 	}
 	
-	mockDB.On("GetShoppingList").Return(shoppingList, nil)
-	mockDB.On("GetMealPlan").Return(models.MealPlan{}, errors.New("database error"))
+	mockDB.On("GetShoppingList", mock.Anything).Return(shoppingList, nil)
+	mockDB.On("GetMealPlan", mock.Anything, models.ISOWeek(time.Now())).Return(models.MealPlan{}, errors.New("database error"))
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -115,7 +122,7 @@ func TestHomeHandlerMealPlanError(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
 	w := httptest.NewRecorder()
 	
-	handler.HomeHandler(w, req)
+	handler.HomeHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -123,9 +130,57 @@ func TestHomeHandlerMealPlanError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 }
 
+func TestWithDeadline(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	deadline := make(chan bool, 1)
+	mockDB.On("GetShoppingList", mock.Anything).Run(func(args mock.Arguments) {
+		// A slow database gives up once the request context is done
+		ctx := args.Get(0).(context.Context)
+		_, ok := ctx.Deadline()
+		deadline <- ok
+		<-ctx.Done()
+	}).Return([]models.ShoppingListItem{}, context.DeadlineExceeded)
+
+	handler := &Handler{
+		DB:             mockDB,
+		TemplatePath:   "../../cmd/server/testdata/test_template.html",
+		RequestTimeout: 20 * time.Millisecond,
+	}
+	done := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		handler.HomeHandler(w, asOwner(r))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	handler.WithDeadline(next).ServeHTTP(w, req)
+	<-done
+
+	mockDB.AssertExpectations(t)
+	assert.True(t, <-deadline, "the database call should be given the request deadline")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestWithDeadlineDisabled(t *testing.T) {
+	handler := &Handler{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		assert.False(t, ok, "no deadline should be set")
+	})
+
+	w := httptest.NewRecorder()
+	handler.WithDeadline(next).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestMealHandler(t *testing.T) {
-	mockDB := new(tests.MockDB)
-	mockDB.On("UpdateMeal", "Monday", "New Meal").Return(nil)
+	mockDB := tests.NewMockDB()
+	edit := models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "New Meal", Version: 2}
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+	mockDB.On("EditMeal", mock.Anything, "2026-W42", edit).Return(models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "New Meal", Version: 3}, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, nil)
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -133,12 +188,12 @@ func TestMealHandler(t *testing.T) {
 	}
 	
 	// Create form data
-	formData := "Monday=New+Meal"
+	formData := "week=2026-W42&day=Monday&slot=Lunch&meal=New+Meal&version=2"
 	req := httptest.NewRequest("POST", "/meal", strings.NewReader(formData))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
 	
-	handler.MealHandler(w, req)
+	handler.MealHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -146,20 +201,21 @@ func TestMealHandler(t *testing.T) {
 }
 
 func TestMealHandlerUpdateError(t *testing.T) {
-	mockDB := new(tests.MockDB)
-	mockDB.On("UpdateMeal", "Monday", "New Meal").Return(errors.New("update error"))
+	mockDB := tests.NewMockDB()
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+	mockDB.On("EditMeal", mock.Anything, "2026-W42", models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "New Meal"}).Return(models.MealSlotInput{}, errors.New("update error"))
 	
 	handler := &Handler{
 		DB:           mockDB,
 		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
 	}
 	
-	formData := "Monday=New+Meal"
+	formData := "week=2026-W42&day=Monday&slot=Lunch&meal=New+Meal&version=0"
 	req := httptest.NewRequest("POST", "/meal", strings.NewReader(formData))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
 	
-	handler.MealHandler(w, req)
+	handler.MealHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -168,7 +224,7 @@ func TestMealHandlerUpdateError(t *testing.T) {
 }
 
 func TestShoppingListHandler_Post(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	newItem := models.ShoppingListItem{
 		ID:     primitive.NewObjectID(),
@@ -177,7 +233,9 @@ func TestShoppingListHandler_Post(t *testing.T) {
 		Ticked: false,
 	}
 	
-	mockDB.On("AddShoppingListItem", "New Item").Return(newItem, nil)
+	mockDB.On("AddShoppingListItem", mock.Anything, "New Item").Return(newItem, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{newItem}, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -189,7 +247,7 @@ func TestShoppingListHandler_Post(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
 	
-	handler.ShoppingListHandler(w, req)
+	handler.ShoppingListHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -197,7 +255,7 @@ func TestShoppingListHandler_Post(t *testing.T) {
 }
 
 func TestShoppingListHandler_Delete(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	shoppingList := []models.ShoppingListItem{
 		{
@@ -208,8 +266,9 @@ func TestShoppingListHandler_Delete(t *testing.T) {
 		},
 	}
 	
-	mockDB.On("DeleteShoppingListItem", "123").Return(nil)
-	mockDB.On("GetShoppingList").Return(shoppingList, nil)
+	mockDB.On("DeleteShoppingListItem", mock.Anything, "123").Return(nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return(shoppingList, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -219,7 +278,7 @@ func TestShoppingListHandler_Delete(t *testing.T) {
 	req := httptest.NewRequest("DELETE", "/shopping-list?item=123", nil)
 	w := httptest.NewRecorder()
 	
-	handler.ShoppingListHandler(w, req)
+	handler.ShoppingListHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -227,7 +286,7 @@ func TestShoppingListHandler_Delete(t *testing.T) {
 }
 
 func TestShoppingListTickHandler(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	updatedItem := models.ShoppingListItem{
 		ID:     primitive.NewObjectID(),
@@ -236,7 +295,8 @@ func TestShoppingListTickHandler(t *testing.T) {
 		Ticked: true,
 	}
 	
-	mockDB.On("TickShoppingListItem", "123", true).Return(updatedItem, nil)
+	mockDB.On("TickShoppingListItem", mock.Anything, "123", true).Return(updatedItem, nil)
+	mockDB.On("RecordTick", mock.Anything, mock.MatchedBy(func(event models.TickEvent) bool { return event.Item == "test item" })).Return(nil)
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -248,7 +308,7 @@ func TestShoppingListTickHandler(t *testing.T) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
 	
-	handler.ShoppingListTickHandler(w, req)
+	handler.ShoppingListTickHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -256,7 +316,7 @@ func TestShoppingListTickHandler(t *testing.T) {
 }
 
 func TestShoppingListSortHandler(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	orders := []models.Order{
 		{
@@ -269,9 +329,9 @@ func TestShoppingListSortHandler(t *testing.T) {
 		},
 	}
 	
-	mockDB.On("SortShoppingList", mock.MatchedBy(func(o []models.Order) bool {
+	mockDB.On("ReorderShoppingList", mock.Anything, mock.MatchedBy(func(o []models.Order) bool {
 		return len(o) == 2 && o[0].ID == "123" && o[1].ID == "456"
-	})).Return(nil)
+	}), 0).Return(nil)
 	
 	handler := &Handler{
 		DB:           mockDB,
@@ -279,14 +339,15 @@ func TestShoppingListSortHandler(t *testing.T) {
 	}
 	
 	// Create JSON payload
-	orderUpdate := models.OrderUpdate{Order: orders}
+	version := 0
+	orderUpdate := models.OrderUpdate{Order: orders, Version: &version}
 	jsonData, _ := json.Marshal(orderUpdate)
 	
 	req := httptest.NewRequest("POST", "/shopping-list/sort", strings.NewReader(string(jsonData)))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	
-	handler.ShoppingListSortHandler(w, req)
+	handler.ShoppingListSortHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
@@ -294,8 +355,32 @@ func TestShoppingListSortHandler(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestShoppingListSortHandlerConflict(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	orders := []models.Order{{ID: "123", Position: 1}, {ID: "456", Position: 2}}
+	// Someone else changed the list after the page read version 3
+	mockDB.On("ReorderShoppingList", mock.Anything, orders, 3).Return(db.ErrConflict)
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html",
+	}
+
+	version := 3
+	jsonData, _ := json.Marshal(models.OrderUpdate{Order: orders, Version: &version})
+	req := httptest.NewRequest("POST", "/shopping-list/sort", strings.NewReader(string(jsonData)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.ShoppingListSortHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"conflict"`)
+}
+
 func TestShoppingListEditHandler(t *testing.T) {
-	mockDB := new(tests.MockDB)
+	mockDB := tests.NewMockDB()
 	
 	updatedItem := models.ShoppingListItem{
 		ID:     primitive.NewObjectID(),
@@ -304,21 +389,425 @@ func TestShoppingListEditHandler(t *testing.T) {
 		Ticked: false,
 	}
 	
-	mockDB.On("UpdateShoppingListItem", "123", "Updated Item").Return(updatedItem, nil)
+	mockDB.On("EditShoppingListItem", mock.Anything, models.ShoppingListItem{IDHex: "123", Item: "Updated Item", Version: 1}).Return(updatedItem, nil)
 	
 	handler := &Handler{
 		DB:           mockDB,
 		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
 	}
 	
-	formData := "123=Updated+Item"
+	formData := "123=Updated+Item&version=1"
 	req := httptest.NewRequest("POST", "/shopping-list/edit", strings.NewReader(formData))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
 	
-	handler.ShoppingListEditHandler(w, req)
+	handler.ShoppingListEditHandler(w, asOwner(req))
 	
 	mockDB.AssertExpectations(t)
 	
 	// Note: We can't easily test the template execution without implementing a custom template
-}
\ No newline at end of file
+}
+func TestShoppingListFlowWithMemoryDB(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryDB()
+	handler := &Handler{
+		DB:           store,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	post := func(handlerFunc http.HandlerFunc, target, body string) *http.Response {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handlerFunc(w, asOwner(req))
+		return w.Result()
+	}
+
+	// Add
+	for _, item := range []string{"Eggs", "Bread", "Cheese"} {
+		resp := post(handler.ShoppingListHandler, "/shopping-list", "item="+item)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	list, err := store.GetShoppingList(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	// Tick
+	resp := post(handler.ShoppingListTickHandler, "/shopping-list/tick", list[1].IDHex+"=on")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	ticked, err := store.GetShoppingListItemFromIDHex(ctx, list[1].IDHex)
+	assert.NoError(t, err)
+	assert.True(t, ticked.Ticked)
+
+	// Sort
+	lists, err := store.GetShoppingLists(ctx)
+	assert.NoError(t, err)
+	orderUpdate := models.OrderUpdate{Order: []models.Order{
+		{ID: list[2].IDHex, Position: 1},
+		{ID: list[1].IDHex, Position: 2},
+		{ID: list[0].IDHex, Position: 3},
+	}, Version: &lists[0].Version}
+	jsonData, _ := json.Marshal(orderUpdate)
+	req := httptest.NewRequest("POST", "/shopping-list/sort", strings.NewReader(string(jsonData)))
+	w := httptest.NewRecorder()
+	handler.ShoppingListSortHandler(w, asOwner(req))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	// Delete
+	req = httptest.NewRequest("DELETE", "/shopping-list?item="+list[1].IDHex, nil)
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(req))
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "Cheese")
+	assert.NotContains(t, w.Body.String(), "Bread")
+
+	list, err = store.GetShoppingList(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "Cheese", list[0].Item)
+	assert.Equal(t, "Eggs", list[1].Item)
+}
+
+func TestMealHandlerWithMemoryDB(t *testing.T) {
+	ctx := context.Background()
+	store := db.NewMemoryDB()
+	handler := &Handler{
+		DB:           store,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	req := httptest.NewRequest("POST", "/meal", strings.NewReader("week=2026-W42&day=Wednesday&slot=Dinner&meal=Fish+pie&version=0"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler.MealHandler(w, asOwner(req))
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `<div id="meal-wednesday-dinner">Wednesday Dinner: Fish pie</div>`)
+
+	mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+	assert.NoError(t, err)
+	assert.Equal(t, "Fish pie", mealPlan.Meals[2].SlotMeal("Dinner"))
+
+	otherWeek, err := store.GetMealPlan(ctx, "2026-W43")
+	assert.NoError(t, err)
+	assert.Empty(t, otherWeek.Meals[2].SlotMeal("Dinner"), "other weeks should be untouched")
+}
+
+func TestMealHandlerConflict(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/meal", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.MealHandler(w, asOwner(req))
+		return w
+	}
+
+	w := post("week=2026-W42&day=Wednesday&slot=Dinner&meal=Fish+pie&version=0")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A second page still showing the empty slot does not overwrite it
+	w = post("week=2026-W42&day=Wednesday&slot=Dinner&meal=Curry&version=0")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Wednesday Dinner: Fish pie")
+	assert.Contains(t, w.Body.String(), "Not saved: Curry")
+	mealPlan, err := store.GetMealPlan(context.Background(), "2026-W42")
+	assert.NoError(t, err)
+	assert.Equal(t, "Fish pie", mealPlan.Meals[2].SlotMeal("Dinner"))
+
+	w = post("week=2026-W42&day=Wednesday&slot=Dinner&meal=Curry")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = post("week=2026-W42&day=Wednesday&slot=Dinner&meal=Curry&version=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Wednesday Dinner: Curry")
+}
+
+func TestMealHandlerDefaultsToCurrentWeek(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	edit := models.MealSlotInput{Day: "Monday", Slot: "Lunch", Meal: "New Meal"}
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+	mockDB.On("EditMeal", mock.Anything, models.ISOWeek(time.Now()), edit).Return(edit, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, nil)
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	req := httptest.NewRequest("POST", "/meal", strings.NewReader("day=Monday&slot=Lunch&meal=New+Meal&version=0"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler.MealHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestMealHandlerSingleFieldForm(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	edit := models.MealSlotInput{Day: "Monday", Slot: models.LegacyMealSlot, Meal: "New Meal"}
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+	mockDB.On("EditMeal", mock.Anything, "2026-W42", edit).Return(edit, nil)
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, nil)
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	// Pages rendered before slots existed post a single field named after the day
+	req := httptest.NewRequest("POST", "/meal", strings.NewReader("week=2026-W42&Monday=New+Meal&version=0"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler.MealHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestMealHandlerUnknownSlot(t *testing.T) {
+	mockDB := tests.NewMockDB()
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+		MealSlots:    []string{"Lunch", "Dinner"},
+	}
+
+	req := httptest.NewRequest("POST", "/meal", strings.NewReader("week=2026-W42&day=Monday&slot=Breakfast&meal=Toast"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler.MealHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHomeHandlerMealGrid(t *testing.T) {
+	mealPlan := models.MealPlan{Week: "2026-W42", Meals: []models.Meal{
+		{Day: "Monday", Slots: map[string]string{"Breakfast": "Porridge", "Dinner": "Lasagne"}},
+	}}
+
+	mockDB := tests.NewMockDB()
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
+	mockDB.On("GetMealPlan", mock.Anything, "2026-W42").Return(mealPlan, nil)
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+		MealSlots:    []string{"Breakfast", "Lunch", "Dinner"},
+	}
+
+	req := httptest.NewRequest("GET", "/?week=2026-W42", nil)
+	w := httptest.NewRecorder()
+
+	handler.HomeHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	body := w.Body.String()
+	assert.Contains(t, body, "Monday Breakfast: Porridge")
+	assert.Contains(t, body, "Monday Lunch: </div>")
+	assert.Contains(t, body, "Monday Dinner: Lasagne")
+	assert.NotContains(t, body, "Snacks")
+}
+
+func TestMealHandlerInvalidWeek(t *testing.T) {
+	mockDB := tests.NewMockDB()
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	req := httptest.NewRequest("POST", "/meal", strings.NewReader("week=2026-W99&day=Monday&slot=Lunch&meal=New+Meal"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler.MealHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHomeHandlerWeekNavigation(t *testing.T) {
+	mockDB := tests.NewMockDB()
+	mockDB.On("GetShoppingList", mock.Anything).Return([]models.ShoppingListItem{}, nil)
+	mockDB.On("GetStores", mock.Anything).Return([]models.Store{}, nil)
+	mockDB.On("GetMealPlan", mock.Anything, "2026-W01").Return(models.MealPlan{Week: "2026-W01"}, nil)
+	mockDB.On("GetRecipes", mock.Anything).Return([]recipes.Recipe{}, nil)
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	req := httptest.NewRequest("GET", "/?week=2026-W01", nil)
+	w := httptest.NewRecorder()
+
+	handler.HomeHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), "2025-W52 | 2026-W01 2025-12-29 | 2026-W02")
+}
+
+func TestHomeHandlerInvalidWeek(t *testing.T) {
+	mockDB := tests.NewMockDB()
+
+	handler := &Handler{
+		DB:           mockDB,
+		TemplatePath: "../../cmd/server/testdata/test_template.html", // Use test template
+	}
+
+	req := httptest.NewRequest("GET", "/?week=next-tuesday", nil)
+	w := httptest.NewRecorder()
+
+	handler.HomeHandler(w, asOwner(req))
+
+	mockDB.AssertExpectations(t)
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestShoppingListBuildAndRemoveMeal(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	chilli, err := store.AddRecipe(ctx, recipes.Recipe{Title: "Chilli", Ingredients: []recipes.Ingredient{
+		{Name: "mince", Quantity: 500, Unit: "g"},
+		{Name: "kidney beans", Quantity: 1, Unit: "tin"},
+	}})
+	assert.NoError(t, err)
+	pie, err := store.AddRecipe(ctx, recipes.Recipe{Title: "Cottage pie", Ingredients: []recipes.Ingredient{
+		{Name: "mince", Quantity: 500, Unit: "g"},
+	}})
+	assert.NoError(t, err)
+
+	post := func(handlerFunc http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handlerFunc(w, asOwner(req))
+		return w
+	}
+	post(handler.MealHandler, "/meal", "week=2026-W42&day=Monday&slot=Dinner&meal=Chilli&version=0")
+	post(handler.MealHandler, "/meal", "week=2026-W42&day=Tuesday&slot=Dinner&meal=Cottage+pie&version=0")
+
+	w := post(handler.ShoppingListBuildHandler, "/shopping-list/build", "week=2026-W42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "1 kg mince  for Chilli, Cottage pie")
+	assert.Contains(t, w.Body.String(), "1 tin kidney beans  for Chilli")
+
+	// Changing Monday's meal offers to remove the chilli ingredients
+	w = post(handler.MealHandler, "/meal", "week=2026-W42&day=Monday&slot=Dinner&meal=Takeaway&version=1")
+	assert.Contains(t, w.Body.String(), "Remove 2 Chilli ingredients?")
+
+	w = post(handler.ShoppingListRemoveMealHandler, "/shopping-list/remove-meal", "week=2026-W42&day=Monday&slot=Dinner&recipe="+chilli.IDHex)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The beans were only for the chilli; the mince is still needed for the pie
+	list, err := store.GetShoppingList(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, "1 kg mince", list[0].Text())
+		assert.Equal(t, []models.MealSource{{Week: "2026-W42", Day: "Tuesday", Slot: "Dinner", RecipeID: pie.IDHex, Meal: "Cottage pie"}}, list[0].Sources)
+	}
+}
+
+func TestShoppingListAddAndEditParseQuantities(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+
+	req := httptest.NewRequest("POST", "/shopping-list", strings.NewReader("item=2kg+potatoes+(for+roasting)"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(req))
+	assert.Contains(t, w.Body.String(), "2 kg potatoes (for roasting)")
+
+	list, err := store.GetShoppingList(ctx)
+	assert.NoError(t, err)
+	if !assert.Len(t, list, 1) {
+		return
+	}
+	assert.Equal(t, "potatoes", list[0].Item)
+	assert.Equal(t, "for roasting", list[0].Note)
+
+	// Adding more updates the existing item, which is shown once
+	req = httptest.NewRequest("POST", "/shopping-list", strings.NewReader("item=eggs"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ShoppingListHandler(httptest.NewRecorder(), req)
+	req = httptest.NewRequest("POST", "/shopping-list", strings.NewReader("item=500g+potatoes"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(req))
+	assert.Contains(t, w.Body.String(), "2.5 kg potatoes (for roasting)")
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "potatoes"))
+
+	potatoes, err := store.GetShoppingListItemFromIDHex(ctx, list[0].IDHex)
+	assert.NoError(t, err)
+	req = httptest.NewRequest("POST", "/shopping-list/edit", strings.NewReader(fmt.Sprintf("%s=potatoes+x3&version=%d", potatoes.IDHex, potatoes.Version)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	handler.ShoppingListEditHandler(w, asOwner(req))
+	assert.Contains(t, w.Body.String(), "3 potatoes")
+
+	item, err := store.GetShoppingListItemFromIDHex(ctx, list[0].IDHex)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, item.Quantity)
+	assert.Empty(t, item.Unit)
+	assert.Empty(t, item.Note)
+}
+
+func TestShoppingListMergeHandler(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+	for _, item := range []models.ShoppingListItem{{Item: "flour", Quantity: 500, Unit: "g"}, {Item: "Eggs"}, {Item: "Flour", Quantity: 1, Unit: "kg"}} {
+		_, err := store.InsertShoppingListItem(context.Background(), item)
+		assert.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListMergeHandler(w, asOwner(httptest.NewRequest("POST", "/shopping-list/merge", nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "1.5 kg flour")
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "flour"))
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListMergeHandler(w, asOwner(httptest.NewRequest("GET", "/shopping-list/merge", nil)))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestShoppingListAutoSortHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	for i, name := range []string{"bread", "milk"} {
+		assert.NoError(t, store.RecordTick(ctx, models.TickEvent{Item: name, TickedAt: start.Add(time.Duration(i) * time.Minute)}))
+	}
+	_, err := db.LearnWalkOrder(ctx, store)
+	assert.NoError(t, err)
+	for _, text := range []string{"candles", "milk", "bread"} {
+		_, err := store.AddShoppingListItem(ctx, text)
+		assert.NoError(t, err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListAutoSortHandler(w, asOwner(httptest.NewRequest("POST", "/shopping-list/auto-sort", nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	list, err := store.GetShoppingList(ctx)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, item := range list {
+		names = append(names, item.Item)
+	}
+	assert.Equal(t, []string{"bread", "milk", "candles"}, names)
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListAutoSortHandler(w, asOwner(httptest.NewRequest("GET", "/shopping-list/auto-sort", nil)))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
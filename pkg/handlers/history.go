@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// ShoppingListHistoryHandler shows who changed what on the shopping list at
+// /lists/{id} and on the meal plan, newest first
+func (h *Handler) ShoppingListHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := h.shoppingList(w, r); !ok {
+		return
+	}
+	h.renderHistory(w, r, "", http.StatusOK, "")
+}
+
+// ShoppingListUndoHandler undoes the change at
+// /lists/{id}/history/{change}, then renders the shopping list when the
+// request came from its undo toast, or else the history block
+func (h *Handler) ShoppingListUndoHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUndone(w, r, "undo", db.Undo)
+}
+
+// ShoppingListRedoHandler makes an undone change again, responding like
+// ShoppingListUndoHandler
+func (h *Handler) ShoppingListRedoHandler(w http.ResponseWriter, r *http.Request) {
+	h.setUndone(w, r, "redo", db.Redo)
+}
+
+// setUndone applies undo, which is db.Undo or db.Redo, to the change at
+// /lists/{id}/history/{change}. Changes to the meal plan need permission to
+// edit it rather than the list.
+func (h *Handler) setUndone(w http.ResponseWriter, r *http.Request, verb string, undo func(context.Context, db.DBInterface, string) (models.Change, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	change, err := store.GetChange(r.Context(), r.PathValue("change"))
+	if errors.Is(err, db.ErrNotFound) || (err == nil && change.ListID != "" && change.ListID != r.PathValue("id")) {
+		http.Error(w, "Change not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error getting change: %s\n", err)
+		http.Error(w, "Failed to get change", http.StatusInternalServerError)
+		return
+	}
+	check := models.Role.CanEditShoppingList
+	if change.ListID == "" {
+		check = models.Role.CanEditMealPlan
+	}
+	if !allowed(w, r, check) {
+		return
+	}
+
+	status, failed := http.StatusOK, ""
+	change, err = undo(r.Context(), store, change.IDHex)
+	switch {
+	case errors.Is(err, db.ErrConflict):
+		status = http.StatusConflict
+		failed = fmt.Sprintf("Could not %s %q, as it has been changed since", verb, change.Summary())
+	case err != nil:
+		fmt.Printf("Error changing %s: %s\n", verb, err)
+		http.Error(w, "Failed to "+verb+" change", http.StatusInternalServerError)
+		return
+	case change.ListID == "":
+		h.publishMeal(r, change)
+	}
+
+	if r.Header.Get("HX-Target") == "shopping-list" {
+		// renderShoppingListStatus tells the other pages showing the list
+		h.renderShoppingListStatus(w, r, list, status, failed)
+		return
+	}
+	if status == http.StatusOK && change.ListID != "" {
+		h.publish(r, change.ListID, "list-changed", "")
+	}
+	h.renderHistory(w, r, "history", status, failed)
+}
+
+// publishMeal sends the meal slot a change left behind to the other open
+// pages
+func (h *Handler) publishMeal(r *http.Request, change models.Change) {
+	meal := change.After.Meal
+	if change.Undone {
+		meal = change.Before.Meal
+	}
+	if meal != nil {
+		h.publishMealSlot(r, *meal)
+	}
+}
+
+// publishMealSlot sends a meal slot of the week meal.Week, as saved, to the
+// other open pages
+func (h *Handler) publishMealSlot(r *http.Request, meal models.MealSlotInput) {
+	tmpl, err := template.ParseFiles(h.TemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s\n", err)
+		return
+	}
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "meal-input", meal); err != nil {
+		fmt.Printf("Error rendering meal: %s\n", err)
+		return
+	}
+	h.publish(r, "", meal.EventName(), b.String())
+}
+
+// renderHistory executes the named block of the history template, or the
+// whole page when name is "", responding with status
+func (h *Handler) renderHistory(w http.ResponseWriter, r *http.Request, name string, status int, failed string) {
+	view, err := h.listsView(r)
+	if err != nil {
+		fmt.Printf("Error getting shopping lists: %s\n", err)
+		http.Error(w, "Failed to get shopping list", http.StatusInternalServerError)
+		return
+	}
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	changes, err := store.GetChanges(r.Context(), view.List.IDHex, models.HistoryLength)
+	if err != nil {
+		fmt.Printf("Error getting changes: %s\n", err)
+		http.Error(w, "Failed to get history", http.StatusInternalServerError)
+		return
+	}
+	mealChanges, err := store.GetChanges(r.Context(), "", models.HistoryLength)
+	if err != nil {
+		fmt.Printf("Error getting changes: %s\n", err)
+		http.Error(w, "Failed to get history", http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.ParseFiles(h.HistoryTemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.HistoryTemplatePath)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	data := models.HistoryPageData{
+		List:            view.List,
+		Changes:         changes,
+		MealChanges:     mealChanges,
+		CanEdit:         view.CanEdit,
+		CanEditMealPlan: can(r, models.Role.CanEditMealPlan),
+		Failed:          failed,
+	}
+	w.WriteHeader(status)
+	if name == "" {
+		err = tmpl.Execute(w, data)
+	} else {
+		err = tmpl.ExecuteTemplate(w, name, data)
+	}
+	if err != nil {
+		fmt.Printf("Error executing template: %s\n", err)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyRequest builds a request for /lists/{id}/history, or for undoing or
+// redoing the change with IDHex when action is "undo" or "redo"
+func historyRequest(list models.ShoppingList, change, action string) *http.Request {
+	target := "/lists/" + list.IDHex + "/history"
+	method := "GET"
+	if action != "" {
+		target += "/" + change + "/" + action
+		method = "POST"
+	}
+	req := listRequest(httptest.NewRequest(method, target, nil), list)
+	req.SetPathValue("change", change)
+	return req
+}
+
+func TestShoppingListDeleteOffersUndo(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	list := lists[0]
+	items := addTestItems(t, store, "milk", "eggs", "bread")
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(listRequest(httptest.NewRequest("DELETE", "/lists/x/items?item="+items[1].IDHex, nil), list)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Someone deleted eggs")
+	changes, err := store.GetChanges(ctx, list.IDHex, models.HistoryLength)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Contains(t, w.Body.String(), "[undo "+changes[0].IDHex+"]")
+
+	// Undoing from the toast renders the list with the item back in place
+	w = httptest.NewRecorder()
+	req := historyRequest(list, changes[0].IDHex, "undo")
+	req.Header.Set("HX-Target", "shopping-list")
+	handler.ShoppingListUndoHandler(w, asOwner(req))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "eggs")
+	assert.NotContains(t, w.Body.String(), "undo-toast")
+	shoppingList, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"milk", "eggs", "bread"}, itemTexts(shoppingList))
+
+	// Ticking is not offered to undo
+	w = httptest.NewRecorder()
+	handler.ShoppingListTickHandler(w, asOwner(listRequest(formRequest("/lists/x/items/tick", shoppingList[0].IDHex+"=on"), list)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "undo-toast")
+}
+
+func TestShoppingListHistoryHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	list := lists[0]
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(listRequest(formRequest("/lists/x/items", "item=milk"), list)))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	handler.MealHandler(w, asOwner(formRequest("/meal", "week=2026-W42&day=Monday&slot=Dinner&meal=Fish+pie&version=0")))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListHistoryHandler(w, asOwner(historyRequest(list, "", "")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "History of "+list.Name)
+	assert.Contains(t, w.Body.String(), "Someone added milk")
+	assert.Contains(t, w.Body.String(), "Someone set Monday Dinner to Fish pie")
+
+	// Undoing the meal from the history page renders the history block
+	mealChanges, err := store.GetChanges(ctx, "", models.HistoryLength)
+	require.NoError(t, err)
+	require.Len(t, mealChanges, 1)
+	w = httptest.NewRecorder()
+	handler.ShoppingListUndoHandler(w, asOwner(historyRequest(list, mealChanges[0].IDHex, "undo")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Fish pie (undone)")
+	assert.NotContains(t, w.Body.String(), "History of")
+	mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Empty(t, mealPlan.Meals[0].SlotMeal("Dinner"))
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListRedoHandler(w, asOwner(historyRequest(list, mealChanges[0].IDHex, "redo")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "(undone)")
+	mealPlan, err = store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, "Fish pie", mealPlan.Meals[0].SlotMeal("Dinner"))
+}
+
+func TestShoppingListUndoConflict(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	list := lists[0]
+	items := addTestItems(t, store, "milk")
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListTickHandler(w, asOwner(listRequest(formRequest("/lists/x/items/tick", items[0].IDHex+"=on"), list)))
+	require.Equal(t, http.StatusOK, w.Code)
+	_, err = store.TickShoppingListItem(ctx, items[0].IDHex, false)
+	require.NoError(t, err)
+	changes, err := store.GetChanges(ctx, list.IDHex, models.HistoryLength)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListUndoHandler(w, asOwner(historyRequest(list, changes[0].IDHex, "undo")))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Could not undo &#34;ticked milk&#34;, as it has been changed since")
+
+	w = httptest.NewRecorder()
+	req := historyRequest(list, changes[0].IDHex, "undo")
+	req.Header.Set("HX-Target", "shopping-list")
+	handler.ShoppingListUndoHandler(w, asOwner(req))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "milk")
+	assert.Contains(t, w.Body.String(), "Could not undo")
+}
+
+func TestShoppingListUndoErrors(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	list := lists[0]
+	other, err := store.CreateShoppingList(ctx, "Hardware")
+	require.NoError(t, err)
+	items := addTestItems(t, store.ForShoppingList(other.IDHex), "nails")
+	_, err = db.WithHistory(store, models.User{}).ForShoppingList(other.IDHex).TickShoppingListItem(ctx, items[0].IDHex, true)
+	require.NoError(t, err)
+	changes, err := store.GetChanges(ctx, other.IDHex, models.HistoryLength)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListUndoHandler(w, asOwner(historyRequest(list, changes[0].IDHex, "undo")))
+	assert.Equal(t, http.StatusNotFound, w.Code, "the change is to another list")
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListUndoHandler(w, asOwner(historyRequest(list, "000000000000000000000000", "undo")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListUndoHandler(w, asOwner(historyRequest(list, changes[0].IDHex, "")))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestShoppingListHistoryPermissions(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+	viewer := join(t, handler, invite(t, handler, owner, models.RoleViewer), "kim@example.com")
+	sam, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	lists, err := store.ForHousehold(sam.HouseholdID).GetShoppingLists(ctx)
+	require.NoError(t, err)
+	list := lists[0]
+
+	w := signedIn(handler, owner, handler.ShoppingListHandler, listRequest(formRequest("/lists/x/items", "item=milk"), list))
+	require.Equal(t, http.StatusOK, w.Code)
+	changes, err := store.ForHousehold(sam.HouseholdID).GetChanges(ctx, list.IDHex, models.HistoryLength)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, sam.IDHex, changes[0].UserID)
+
+	// Anyone in the household may see who changed what, but not undo it
+	w = signedIn(handler, viewer, handler.ShoppingListHistoryHandler, historyRequest(list, "", ""))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), sam.DisplayName()+" added milk")
+	assert.NotContains(t, w.Body.String(), changes[0].IDHex)
+	w = signedIn(handler, viewer, handler.ShoppingListUndoHandler, historyRequest(list, changes[0].IDHex, "undo"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Changes are kept with the household's data
+	_, err = store.GetChange(ctx, changes[0].IDHex)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
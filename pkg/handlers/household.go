@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// HouseholdHandler shows the signed-in user's household: who is in it, their
+// roles and, for owners, forms to invite people and change roles
+func (h *Handler) HouseholdHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	data, ok := h.householdPageData(w, r)
+	if !ok {
+		return
+	}
+	h.renderHousehold(w, "", data)
+}
+
+// HouseholdInvitesHandler makes an invite code for the posted role and
+// renders the invite block with it. Only owners may invite people.
+func (h *Handler) HouseholdInvitesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditMealPlan) {
+		return
+	}
+	data, ok := h.householdPageData(w, r)
+	if !ok {
+		return
+	}
+
+	role, err := models.ParseRole(r.PostFormValue("role"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	code, err := db.CreateInvite(r.Context(), h.DB, data.Household.IDHex, role, time.Now())
+	if err != nil {
+		fmt.Printf("Error creating invite: %s\n", err)
+		http.Error(w, "Failed to create invite", http.StatusInternalServerError)
+		return
+	}
+	data.InviteCode = code
+	data.InviteRole = role
+	data.InviteLink = h.inviteLink(r, code)
+	h.renderHousehold(w, "invite", data)
+}
+
+// HouseholdMemberHandler changes the role of the household member at
+// /household/members/{id} to the posted role, then renders the member list.
+// Only owners may change roles, and the last owner cannot stop being one.
+func (h *Handler) HouseholdMemberHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditMealPlan) {
+		return
+	}
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Not signed in", http.StatusUnauthorized)
+		return
+	}
+
+	role, err := models.ParseRole(r.PostFormValue("role"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := db.SetRole(r.Context(), h.DB, user.HouseholdID, r.PathValue("id"), role); err != nil {
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			http.Error(w, "Member not found", http.StatusNotFound)
+		case errors.Is(err, db.ErrLastOwner):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			fmt.Printf("Error changing role: %s\n", err)
+			http.Error(w, "Failed to change role", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	data, ok := h.householdPageData(w, r)
+	if !ok {
+		return
+	}
+	h.renderHousehold(w, "members", data)
+}
+
+// householdPageData loads the signed-in user's household and its members,
+// responding with an error when it cannot
+func (h *Handler) householdPageData(w http.ResponseWriter, r *http.Request) (models.HouseholdPageData, bool) {
+	user, ok := currentUser(r)
+	if !ok {
+		http.Error(w, "Not signed in", http.StatusUnauthorized)
+		return models.HouseholdPageData{}, false
+	}
+	// Read the user again, as an owner may have just changed their own role
+	user, err := h.DB.GetUser(r.Context(), user.IDHex)
+	if err != nil {
+		fmt.Printf("Error getting user: %s\n", err)
+		http.Error(w, "Failed to get household", http.StatusInternalServerError)
+		return models.HouseholdPageData{}, false
+	}
+	household, err := h.DB.GetHousehold(r.Context(), user.HouseholdID)
+	if err != nil {
+		fmt.Printf("Error getting household: %s\n", err)
+		http.Error(w, "Failed to get household", http.StatusInternalServerError)
+		return models.HouseholdPageData{}, false
+	}
+	members, err := h.DB.GetHouseholdUsers(r.Context(), user.HouseholdID)
+	if err != nil {
+		fmt.Printf("Error getting household members: %s\n", err)
+		http.Error(w, "Failed to get household", http.StatusInternalServerError)
+		return models.HouseholdPageData{}, false
+	}
+	return models.HouseholdPageData{Household: household, User: user, Members: members, Roles: models.Roles}, true
+}
+
+// inviteLink returns the address of the join page with code filled in
+func (h *Handler) inviteLink(r *http.Request, code string) string {
+	scheme := "http"
+	if r.TLS != nil || h.SecureCookies {
+		scheme = "https"
+	}
+	return (&url.URL{Scheme: scheme, Host: r.Host, Path: "/join", RawQuery: "code=" + url.QueryEscape(code)}).String()
+}
+
+// renderHousehold executes the named block of the household template, or the
+// whole page when name is ""
+func (h *Handler) renderHousehold(w http.ResponseWriter, name string, data models.HouseholdPageData) {
+	tmpl, err := template.ParseFiles(h.HouseholdTemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.HouseholdTemplatePath)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	if name == "" {
+		err = tmpl.Execute(w, data)
+	} else {
+		err = tmpl.ExecuteTemplate(w, name, data)
+	}
+	if err != nil {
+		fmt.Printf("Error executing template: %s\n", err)
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedIn runs next behind RequireLogin with cookie
+func signedIn(handler *Handler, cookie *http.Cookie, next http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.RequireLogin(next).ServeHTTP(w, req)
+	return w
+}
+
+// invite makes an invite code for role as the owner signed in with cookie
+func invite(t *testing.T, handler *Handler, cookie *http.Cookie, role models.Role) string {
+	t.Helper()
+	w := signedIn(handler, cookie, handler.HouseholdInvitesHandler, formRequest("/household/invites", "role="+string(role)))
+	require.Equal(t, http.StatusOK, w.Code)
+	code := regexp.MustCompile(`[A-Z0-9]{5}-[A-Z0-9]{5}`).FindString(w.Body.String())
+	require.NotEmpty(t, code)
+	return code
+}
+
+// join creates an account with an invite code through JoinHandler and
+// returns its session cookie
+func join(t *testing.T, handler *Handler, code, email string) *http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler.JoinHandler(w, formRequest("/join", "email="+email+"&name=Kim&password=correct-horse&code="+code))
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	require.Len(t, w.Result().Cookies(), 1)
+	return w.Result().Cookies()[0]
+}
+
+func TestHouseholdInvitesHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+
+	req := formRequest("/household/invites", "role=viewer")
+	req.Host = "meals.example.com"
+	handler.SecureCookies = true
+	w := signedIn(handler, owner, handler.HouseholdInvitesHandler, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "viewer")
+	assert.Contains(t, w.Body.String(), "https://meals.example.com/join?code=")
+
+	w = signedIn(handler, owner, handler.HouseholdInvitesHandler, formRequest("/household/invites", "role=admin"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	viewer := join(t, handler, invite(t, handler, owner, models.RoleViewer), "kim@example.com")
+	w = signedIn(handler, viewer, handler.HouseholdInvitesHandler, formRequest("/household/invites", "role=owner"))
+	assert.Equal(t, http.StatusForbidden, w.Code, "only owners invite people")
+
+	sam, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	kim, err := store.GetUserByEmail(ctx, "kim@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, sam.HouseholdID, kim.HouseholdID)
+	assert.Equal(t, models.RoleViewer, kim.Role)
+
+	w = signedIn(handler, owner, handler.HouseholdHandler, httptest.NewRequest("GET", "/household", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Sam: owner")
+	assert.Contains(t, w.Body.String(), "Kim: viewer")
+}
+
+func TestHouseholdMemberHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+	member := join(t, handler, invite(t, handler, owner, models.RoleMember), "kim@example.com")
+	sam, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	kim, err := store.GetUserByEmail(ctx, "kim@example.com")
+	require.NoError(t, err)
+
+	roleRequest := func(id, role string) *http.Request {
+		req := formRequest("/household/members/"+id, "role="+role)
+		req.SetPathValue("id", id)
+		return req
+	}
+
+	w := signedIn(handler, member, handler.HouseholdMemberHandler, roleRequest(kim.IDHex, "owner"))
+	assert.Equal(t, http.StatusForbidden, w.Code, "members cannot promote themselves")
+
+	w = signedIn(handler, owner, handler.HouseholdMemberHandler, roleRequest(sam.IDHex, "viewer"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at least one owner")
+
+	register(t, handler, "alex@example.com", "Elsewhere")
+	alex, err := store.GetUserByEmail(ctx, "alex@example.com")
+	require.NoError(t, err)
+	w = signedIn(handler, owner, handler.HouseholdMemberHandler, roleRequest(alex.IDHex, "viewer"))
+	assert.Equal(t, http.StatusNotFound, w.Code, "owners only manage their own household")
+
+	w = signedIn(handler, owner, handler.HouseholdMemberHandler, roleRequest(kim.IDHex, "viewer"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Kim: viewer")
+}
+
+func TestJoinHandler(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+	code := invite(t, handler, owner, models.RoleMember)
+
+	w := httptest.NewRecorder()
+	handler.JoinHandler(w, httptest.NewRequest("GET", "/join?code="+code, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Join with "+code)
+
+	w = httptest.NewRecorder()
+	handler.JoinHandler(w, formRequest("/join", "email=kim@example.com&password=correct-horse&code=AAAAA-AAAAA"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "not valid")
+
+	w = httptest.NewRecorder()
+	handler.JoinHandler(w, formRequest("/join", "email=sam@example.com&password=correct-horse&code="+code))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	join(t, handler, strings.ToLower(code), "kim@example.com")
+}
+
+func TestRolePermissions(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+	member := join(t, handler, invite(t, handler, owner, models.RoleMember), "kim@example.com")
+	viewer := join(t, handler, invite(t, handler, owner, models.RoleViewer), "lou@example.com")
+
+	w := signedIn(handler, owner, handler.ShoppingListHandler, formRequest("/shopping-list", "item=milk"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	requests := map[string]func() (http.HandlerFunc, *http.Request){
+		"MealHandler": func() (http.HandlerFunc, *http.Request) {
+			return handler.MealHandler, formRequest("/meal", "week=2026-W42&day=Monday&slot=Dinner&meal=Curry&version=0")
+		},
+		"ShoppingListHandler": func() (http.HandlerFunc, *http.Request) {
+			return handler.ShoppingListHandler, formRequest("/shopping-list", "item=bread")
+		},
+		"ShoppingListTickHandler": func() (http.HandlerFunc, *http.Request) {
+			return handler.ShoppingListTickHandler, formRequest("/shopping-list/tick", "missing=on")
+		},
+		"ShoppingListSortHandler": func() (http.HandlerFunc, *http.Request) {
+			return handler.ShoppingListSortHandler, httptest.NewRequest("POST", "/shopping-list/sort", strings.NewReader(`{"order":[],"version":0}`))
+		},
+		"ShoppingListEditHandler": func() (http.HandlerFunc, *http.Request) {
+			return handler.ShoppingListEditHandler, formRequest("/shopping-list/edit", "missing=bread&version=0")
+		},
+	}
+	for name, request := range requests {
+		next, req := request()
+		w := signedIn(handler, viewer, next, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, "viewer: %s", name)
+	}
+
+	next, req := requests["MealHandler"]()
+	w = signedIn(handler, member, next, req)
+	assert.Equal(t, http.StatusForbidden, w.Code, "members cannot plan meals")
+	next, req = requests["ShoppingListHandler"]()
+	w = signedIn(handler, member, next, req)
+	assert.Equal(t, http.StatusOK, w.Code, "members add items")
+	next, req = requests["MealHandler"]()
+	w = signedIn(handler, owner, next, req)
+	assert.Equal(t, http.StatusOK, w.Code, "owners plan meals")
+
+	w = signedIn(handler, viewer, handler.ShoppingListHandler, httptest.NewRequest("GET", "/shopping-list", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "viewers read the list")
+	assert.Contains(t, w.Body.String(), "milk")
+	assert.Contains(t, w.Body.String(), "read-only")
+
+	w = signedIn(handler, viewer, handler.HomeHandler, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Meal plan is read only")
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// listID returns the IDHex of the shopping list a request is for: the one at
+// /lists/{id}, or else the one named by the list parameter, or "" for the
+// household's first list
+func listID(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.FormValue("list")
+}
+
+// shoppingList returns the data of the shopping list a request is for,
+// responding 404 Not Found when the household has no such list
+func (h *Handler) shoppingList(w http.ResponseWriter, r *http.Request) (db.DBInterface, bool) {
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return nil, false
+	}
+	lists, err := store.GetShoppingLists(r.Context())
+	if err != nil {
+		fmt.Printf("Error getting shopping lists: %s\n", err)
+		http.Error(w, "Failed to get shopping list", http.StatusInternalServerError)
+		return nil, false
+	}
+	list, found := models.ChooseShoppingList(lists, listID(r))
+	if !found {
+		http.Error(w, "Shopping list not found", http.StatusNotFound)
+		return nil, false
+	}
+	return store.ForShoppingList(list.IDHex), true
+}
+
+// listsView returns a ShoppingListView holding just the list a request is for
+// and the household's lists
+func (h *Handler) listsView(r *http.Request) (models.ShoppingListView, error) {
+	store, err := h.household(r)
+	if err != nil {
+		return models.ShoppingListView{}, err
+	}
+	lists, err := store.GetShoppingLists(r.Context())
+	if err != nil {
+		return models.ShoppingListView{}, err
+	}
+	list, _ := models.ChooseShoppingList(lists, listID(r))
+	return models.ShoppingListView{List: list, Lists: lists, CanEdit: can(r, models.Role.CanEditShoppingList)}, nil
+}
+
+// renderShoppingListItem renders the shopping-list-item block for item on
+// the list a request is for, and sends it to the other pages showing the list
+func (h *Handler) renderShoppingListItem(w http.ResponseWriter, r *http.Request, item models.ShoppingListItem) {
+	listID, fragment, err := h.shoppingListItemFragment(r, item)
+	if err != nil {
+		fmt.Printf("Error rendering shopping list item: %s\n", err)
+		http.Error(w, "Failed to render shopping list item", http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(fragment))
+	h.publish(r, listID, "item-"+item.IDHex, fragment)
+}
+
+// publishShoppingListItem sends the shopping-list-item block for item to the
+// pages showing the list a request is for, so that they change just the item
+func (h *Handler) publishShoppingListItem(r *http.Request, item models.ShoppingListItem) {
+	listID, fragment, err := h.shoppingListItemFragment(r, item)
+	if err != nil {
+		fmt.Printf("Error rendering shopping list item: %s\n", err)
+		return
+	}
+	h.publish(r, listID, "item-"+item.IDHex, fragment)
+}
+
+// shoppingListItemFragment renders the shopping-list-item block for item,
+// returning it with the IDHex of the list a request is for
+func (h *Handler) shoppingListItemFragment(r *http.Request, item models.ShoppingListItem) (string, string, error) {
+	view, err := h.listsView(r)
+	if err != nil {
+		return "", "", err
+	}
+	tmpl, err := template.ParseFiles(h.TemplatePath)
+	if err != nil {
+		return "", "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, "shopping-list-item", view.Item(item)); err != nil {
+		return "", "", err
+	}
+	return view.List.IDHex, b.String(), nil
+}
+
+// renderShoppingListItemConflict responds with a 409 and the
+// shopping-list-item block for item as it is now, showing the edit text that
+// was not saved
+func (h *Handler) renderShoppingListItemConflict(w http.ResponseWriter, r *http.Request, item models.ShoppingListItem, text string) {
+	view, err := h.listsView(r)
+	if err != nil {
+		fmt.Printf("Error getting shopping lists: %s\n", err)
+		http.Error(w, "Failed to get shopping list", http.StatusInternalServerError)
+		return
+	}
+	tmpl, err := template.ParseFiles(h.TemplatePath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	listItem := view.Item(item)
+	listItem.Conflict = text
+	w.WriteHeader(http.StatusConflict)
+	tmpl.ExecuteTemplate(w, "shopping-list-item", listItem)
+}
+
+// ShoppingListsHandler shows the household's shopping lists, or creates one
+// with the posted name and renders the lists block
+func (h *Handler) ShoppingListsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.renderLists(w, r, "")
+	case http.MethodPost:
+		if !allowed(w, r, models.Role.CanEditShoppingList) {
+			return
+		}
+		name, err := models.NormaliseShoppingListName(r.PostFormValue("name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		store, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		if _, err := store.CreateShoppingList(r.Context(), name); err != nil {
+			fmt.Printf("Error creating shopping list: %s\n", err)
+			http.Error(w, "Failed to create shopping list", http.StatusInternalServerError)
+			return
+		}
+		h.renderLists(w, r, "lists")
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// ShoppingListManageHandler renames the shopping list at /lists/{id} to the
+// posted name, or deletes it along with its items, then renders the lists
+// block
+func (h *Handler) ShoppingListManageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	var err error
+	if r.Method == http.MethodDelete {
+		err = store.DeleteShoppingList(r.Context(), r.PathValue("id"))
+	} else {
+		var name string
+		if name, err = models.NormaliseShoppingListName(r.PostFormValue("name")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err = store.RenameShoppingList(r.Context(), r.PathValue("id"), name)
+	}
+	if !listChanged(w, err) {
+		return
+	}
+	h.publish(r, r.PathValue("id"), "list-changed", "")
+	h.renderLists(w, r, "lists")
+}
+
+// ShoppingListArchiveHandler archives the shopping list at /lists/{id}, or
+// brings it back when archived=false is posted, then renders the lists block
+func (h *Handler) ShoppingListArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	archived := true
+	if value := r.PostFormValue("archived"); value != "" {
+		var err error
+		if archived, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "archived must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	_, err := store.ArchiveShoppingList(r.Context(), r.PathValue("id"), archived)
+	if !listChanged(w, err) {
+		return
+	}
+	h.publish(r, r.PathValue("id"), "list-changed", "")
+	h.renderLists(w, r, "lists")
+}
+
+// ShoppingListMoveHandler moves the posted item from the shopping list at
+// /lists/{id} to the end of the list posted as to, then renders the list it
+// was moved from
+func (h *Handler) ShoppingListMoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+	list, ok := h.shoppingList(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := list.MoveShoppingListItem(r.Context(), r.PostFormValue("item"), r.PostFormValue("to")); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Item or shopping list not found", http.StatusNotFound)
+			return
+		}
+		fmt.Printf("Error moving shopping list item: %s\n", err)
+		http.Error(w, "Failed to move item", http.StatusInternalServerError)
+		return
+	}
+	// The pages showing either list load it again; renderShoppingList tells
+	// those showing the list it was moved from
+	h.publish(r, r.PostFormValue("to"), "list-changed", "")
+	h.renderShoppingList(w, r, list)
+}
+
+// listChanged responds with an error when changing a shopping list failed
+func listChanged(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, "Shopping list not found", http.StatusNotFound)
+	default:
+		fmt.Printf("Error changing shopping list: %s\n", err)
+		http.Error(w, "Failed to change shopping list", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// renderLists executes the named block of the shopping lists template, or the
+// whole page when name is ""
+func (h *Handler) renderLists(w http.ResponseWriter, r *http.Request, name string) {
+	store, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	lists, err := store.GetShoppingLists(r.Context())
+	if err != nil {
+		fmt.Printf("Error getting shopping lists: %s\n", err)
+		http.Error(w, "Failed to get shopping lists", http.StatusInternalServerError)
+		return
+	}
+	tmpl, err := template.ParseFiles(h.ListsTemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.ListsTemplatePath)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	data := models.ListsPageData{Lists: lists, CanEdit: can(r, models.Role.CanEditShoppingList)}
+	if name == "" {
+		err = tmpl.Execute(w, data)
+	} else {
+		err = tmpl.ExecuteTemplate(w, name, data)
+	}
+	if err != nil {
+		fmt.Printf("Error executing template: %s\n", err)
+	}
+}
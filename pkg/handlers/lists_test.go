@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/events"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listRequest sets the {id} path value of req to the IDHex of list
+func listRequest(req *http.Request, list models.ShoppingList) *http.Request {
+	req.SetPathValue("id", list.IDHex)
+	return req
+}
+
+// assertPublished checks that the next event sent to stream is name
+func assertPublished(t *testing.T, stream <-chan events.Event, name, action string) {
+	t.Helper()
+	select {
+	case event := <-stream:
+		assert.Equal(t, name, event.Name, action)
+	default:
+		t.Errorf("no event was published for the %s", action)
+	}
+}
+
+func TestShoppingListsHandler(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+	handler.Events = events.NewHub()
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListsHandler(w, asOwner(formRequest("/lists", "name=+Hardware+")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), models.DefaultShoppingListName)
+	assert.Contains(t, w.Body.String(), "Hardware")
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListsHandler(w, asOwner(formRequest("/lists", "name=")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	lists, err := store.GetShoppingLists(context.Background())
+	require.NoError(t, err)
+	require.Len(t, lists, 2)
+	hardware := lists[1]
+	stream, cancel := handler.Events.Subscribe("", hardware.IDHex, "b")
+	defer cancel()
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListManageHandler(w, asOwner(listRequest(formRequest("/lists/"+hardware.IDHex, "name=DIY"), hardware)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "DIY")
+	assertPublished(t, stream, "list-changed", "rename")
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListArchiveHandler(w, asOwner(listRequest(formRequest("/lists/"+hardware.IDHex+"/archive", "archived=true"), hardware)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "DIY (archived)")
+	assertPublished(t, stream, "list-changed", "archive")
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListsHandler(w, asOwner(httptest.NewRequest("GET", "/lists", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "DIY (archived)")
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListManageHandler(w, asOwner(listRequest(httptest.NewRequest("DELETE", "/lists/"+hardware.IDHex, nil), hardware)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "DIY")
+	assertPublished(t, stream, "list-changed", "delete")
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListManageHandler(w, asOwner(listRequest(httptest.NewRequest("DELETE", "/lists/"+hardware.IDHex, nil), hardware)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShoppingListItemsByList(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	hardware, err := store.CreateShoppingList(ctx, "Hardware")
+	require.NoError(t, err)
+	_, err = store.AddShoppingListItem(ctx, "milk")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(listRequest(formRequest("/lists/"+hardware.IDHex+"/items", "item=nails"), hardware)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<h2>Hardware</h2>")
+	assert.Contains(t, w.Body.String(), "nails")
+	assert.NotContains(t, w.Body.String(), "milk")
+
+	// The home page shows the first list, or the one asked for
+	w = httptest.NewRecorder()
+	handler.HomeHandler(w, asOwner(httptest.NewRequest("GET", "/", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "milk")
+	assert.NotContains(t, w.Body.String(), "nails")
+	w = httptest.NewRecorder()
+	handler.HomeHandler(w, asOwner(httptest.NewRequest("GET", "/?list="+hardware.IDHex, nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "nails")
+
+	unknown := models.ShoppingList{IDHex: "000000000000000000000000"}
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(listRequest(httptest.NewRequest("GET", "/lists/"+unknown.IDHex+"/items", nil), unknown)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	handler.HomeHandler(w, asOwner(httptest.NewRequest("GET", "/?list="+unknown.IDHex, nil)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShoppingListMoveHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	supermarket := lists[0]
+	hardware, err := store.CreateShoppingList(ctx, "Hardware")
+	require.NoError(t, err)
+	items := addTestItems(t, store, "milk", "nails", "bread")
+	handler.Events = events.NewHub()
+	from, cancelFrom := handler.Events.Subscribe("", supermarket.IDHex, "b")
+	defer cancelFrom()
+	to, cancelTo := handler.Events.Subscribe("", hardware.IDHex, "b")
+	defer cancelTo()
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListMoveHandler(w, asOwner(listRequest(formRequest("/lists/"+supermarket.IDHex+"/items/move", "item="+items[1].IDHex+"&to="+hardware.IDHex), supermarket)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "milk")
+	assert.Contains(t, w.Body.String(), "[move to Hardware]")
+	assert.NotContains(t, w.Body.String(), "nails")
+	assertPublished(t, from, "list-changed", "move from")
+	assertPublished(t, to, "list-changed", "move to")
+
+	moved, err := store.ForShoppingList(hardware.IDHex).GetShoppingList(ctx)
+	require.NoError(t, err)
+	require.Len(t, moved, 1)
+	assert.Equal(t, "nails", moved[0].Item)
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListMoveHandler(w, asOwner(listRequest(formRequest("/lists/"+supermarket.IDHex+"/items/move", "item="+items[0].IDHex+"&to=000000000000000000000000"), supermarket)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShoppingListsPermissions(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	owner := register(t, handler, "sam@example.com", "Home")
+	viewer := join(t, handler, invite(t, handler, owner, models.RoleViewer), "kim@example.com")
+
+	w := signedIn(handler, viewer, handler.ShoppingListsHandler, formRequest("/lists", "name=Hardware"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = signedIn(handler, viewer, handler.ShoppingListsHandler, httptest.NewRequest("GET", "/lists", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Shopping lists are read only")
+
+	w = signedIn(handler, owner, handler.ShoppingListsHandler, formRequest("/lists", "name=Hardware"))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Lists belong to the household, not to the data saved before households
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	assert.Len(t, lists, 1)
+	sam, err := store.GetUserByEmail(ctx, "sam@example.com")
+	require.NoError(t, err)
+	lists, err = store.ForHousehold(sam.HouseholdID).GetShoppingLists(ctx)
+	require.NoError(t, err)
+	require.Len(t, lists, 2)
+
+	w = signedIn(handler, owner, handler.ShoppingListHandler, listRequest(httptest.NewRequest("GET", "/lists/x/items", nil), lists[1]))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = signedIn(handler, viewer, handler.ShoppingListArchiveHandler, listRequest(formRequest("/lists/x/archive", ""), lists[1]))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// addTestItems adds each named item to the first shopping list of store
+func addTestItems(t *testing.T, store db.DBInterface, names ...string) []models.ShoppingListItem {
+	t.Helper()
+	var items []models.ShoppingListItem
+	for _, name := range names {
+		item, err := store.AddShoppingListItem(context.Background(), name)
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestShoppingListEditConflict(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	items := addTestItems(t, store, "milk")
+
+	edit := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ShoppingListEditHandler(w, asOwner(listRequest(formRequest("/lists/x/items/edit", body), lists[0])))
+		return w
+	}
+
+	w := edit(items[0].IDHex + "=oat+milk&version=0")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// A second page still showing the first version does not overwrite it
+	w = edit(items[0].IDHex + "=soya+milk&version=0")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "oat milk  [not saved: soya milk]")
+	item, err := store.GetShoppingListItemFromIDHex(ctx, items[0].IDHex)
+	require.NoError(t, err)
+	assert.Equal(t, "oat milk", item.Item)
+
+	w = edit(items[0].IDHex + "=soya+milk")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = edit("000000000000000000000000=soya+milk&version=0")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShoppingListSortConflict(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	items := addTestItems(t, store, "milk", "bread")
+	lists, err := store.GetShoppingLists(ctx)
+	require.NoError(t, err)
+	list := lists[0]
+
+	sort := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ShoppingListSortHandler(w, asOwner(listRequest(httptest.NewRequest("POST", "/lists/x/sort", strings.NewReader(body)), list)))
+		return w
+	}
+	order := fmt.Sprintf(`{"order":[{"id":%q,"position":1},{"id":%q,"position":2}]`, items[1].IDHex, items[0].IDHex)
+
+	// An item added since the page was loaded would lose its place
+	addTestItems(t, store, "eggs")
+	w := sort(order + fmt.Sprintf(`,"version":%d}`, list.Version))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"conflict"`)
+	current, err := store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"milk", "bread", "eggs"}, itemTexts(current))
+
+	w = sort(order + "}")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The reloaded page sends an order with the new item and version
+	order = fmt.Sprintf(`{"order":[{"id":%q,"position":1},{"id":%q,"position":2},{"id":%q,"position":3}]`, items[1].IDHex, current[2].IDHex, items[0].IDHex)
+	w = sort(order + fmt.Sprintf(`,"version":%d}`, list.Version+1))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"version":%d`, list.Version+2))
+	current, err = store.GetShoppingList(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bread", "eggs", "milk"}, itemTexts(current))
+}
+
+// itemTexts returns the text of each item in order
+func itemTexts(items []models.ShoppingListItem) []string {
+	var texts []string
+	for _, item := range items {
+		texts = append(texts, item.Text())
+	}
+	return texts
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/openapi"
+)
+
+// apiOperations describes every JSON API route, with the pkg/models types of
+// its bodies. The router registers the same routes; a test in cmd/server
+// fails when the two differ.
+func (h *Handler) apiOperations() []openapi.Route {
+	errorBody := models.APIError{}
+	list := models.APIShoppingList{}
+	item := models.APIShoppingListItem{}
+	meal := models.APIMeal{}
+
+	return []openapi.Route{
+		{Method: "POST", Path: "/session", Name: "createSession", Summary: "Sign in, for a bearer token", Public: true,
+			Request: models.APISessionInput{}, Responses: map[int]any{201: models.APISession{}, 400: errorBody, 401: errorBody}},
+		{Method: "DELETE", Path: "/session", Name: "deleteSession", Summary: "Sign out",
+			Responses: map[int]any{204: nil, 401: errorBody}},
+		{Method: "GET", Path: "/meal-plans/{week}", Name: "getMealPlan", Summary: "Get the meal plan for an ISO week",
+			Responses: map[int]any{200: models.APIMealPlan{}, 400: errorBody, 401: errorBody}},
+		{Method: "PUT", Path: "/meal-plans/{week}/meals/{day}/{slot}", Name: "setMeal", Summary: "Set the meal in a slot",
+			Request: models.APIMealInput{}, Responses: map[int]any{200: meal, 400: errorBody, 401: errorBody, 403: errorBody, 404: errorBody, 409: errorBody}},
+		{Method: "GET", Path: "/lists", Name: "listShoppingLists", Summary: "List the household's shopping lists",
+			Responses: map[int]any{200: []models.APIShoppingList{}, 401: errorBody}},
+		{Method: "POST", Path: "/lists", Name: "createShoppingList", Summary: "Create a shopping list",
+			Request: models.APIShoppingListInput{}, Responses: map[int]any{201: list, 400: errorBody, 401: errorBody, 403: errorBody}},
+		{Method: "GET", Path: "/lists/{id}", Name: "getShoppingList", Summary: "Get a shopping list",
+			Responses: map[int]any{200: list, 401: errorBody, 404: errorBody}},
+		{Method: "PATCH", Path: "/lists/{id}", Name: "updateShoppingList", Summary: "Rename or archive a shopping list",
+			Request: models.APIShoppingListInput{}, Responses: map[int]any{200: list, 400: errorBody, 401: errorBody, 403: errorBody, 404: errorBody, 409: errorBody}},
+		{Method: "DELETE", Path: "/lists/{id}", Name: "deleteShoppingList", Summary: "Delete a shopping list and its items",
+			Responses: map[int]any{204: nil, 401: errorBody, 403: errorBody, 404: errorBody}},
+		{Method: "GET", Path: "/lists/{id}/items", Name: "listItems", Summary: "List a shopping list's items in order",
+			Responses: map[int]any{200: []models.APIShoppingListItem{}, 401: errorBody, 404: errorBody}},
+		{Method: "POST", Path: "/lists/{id}/items", Name: "createItem", Summary: "Add an item to a shopping list",
+			Request: models.APIShoppingListItemInput{}, Responses: map[int]any{201: item, 400: errorBody, 401: errorBody, 403: errorBody, 404: errorBody}},
+		{Method: "GET", Path: "/lists/{id}/items/{item}", Name: "getItem", Summary: "Get an item",
+			Responses: map[int]any{200: item, 401: errorBody, 404: errorBody}},
+		{Method: "PATCH", Path: "/lists/{id}/items/{item}", Name: "updateItem", Summary: "Change an item's text, tick or category",
+			Request: models.APIShoppingListItemInput{}, Responses: map[int]any{200: item, 400: errorBody, 401: errorBody, 403: errorBody, 404: errorBody, 409: errorBody}},
+		{Method: "DELETE", Path: "/lists/{id}/items/{item}", Name: "deleteItem", Summary: "Remove an item",
+			Responses: map[int]any{204: nil, 401: errorBody, 403: errorBody, 404: errorBody}},
+		{Method: "PUT", Path: "/lists/{id}/order", Name: "orderItems", Summary: "Put a shopping list's items in order",
+			Request: models.APIOrderInput{}, Responses: map[int]any{200: list, 400: errorBody, 401: errorBody, 403: errorBody, 404: errorBody, 409: errorBody}},
+	}
+}
+
+// OpenAPI returns the OpenAPI document of the JSON API
+func (h *Handler) OpenAPI() *openapi.Document {
+	builder := openapi.NewBuilder("Meal Planner API",
+		"Meal plans and shopping lists of the signed-in user's household.", "1")
+	// Path parameters that are not listed here may be any string
+	params := map[string]*openapi.Schema{
+		"week": {Type: "string", Pattern: `^\d{4}-W\d{2}$`},
+		"day":  {Type: "string", Enum: models.WeekDays},
+		"slot": {Type: "string", Enum: h.mealSlots()},
+	}
+	for _, route := range h.apiOperations() {
+		route.Path = APIPrefix + route.Path
+		route.Params = params
+		builder.Add(route)
+	}
+	return builder.Document()
+}
+
+// OpenAPIHandler serves the OpenAPI document of the JSON API
+func (h *Handler) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.OpenAPI())
+}
+
+// ValidateAPI checks requests to the JSON API route with pattern, such as
+// "GET /api/v1/lists", against its operation in doc before passing them to
+// next, responding 400 Bad Request to those that do not match. Routes that
+// are not in doc respond 500, so that they are not served undocumented.
+func (h *Handler) ValidateAPI(doc *openapi.Document, pattern string, next http.HandlerFunc) http.HandlerFunc {
+	method, path, _ := strings.Cut(pattern, " ")
+	op := doc.Operation(method, path)
+	if op == nil {
+		fmt.Printf("Error: API route %s is not in the OpenAPI document\n", pattern)
+		return func(w http.ResponseWriter, r *http.Request) {
+			apiError(w, http.StatusInternalServerError, "Undocumented API route")
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := doc.ValidateRequest(op, r); err != nil {
+			apiError(w, http.StatusBadRequest, "Invalid request: "+err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	doc := handler.OpenAPI()
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			assert.NotEmpty(t, op.OperationID, "%s %s", method, path)
+			if op.Security == nil {
+				assert.Contains(t, op.Responses, "401", "%s %s can be refused for not signing in", method, path)
+			}
+			if op.RequestBody != nil {
+				schema, err := doc.Resolve(op.RequestBody.Content["application/json"].Schema)
+				require.NoError(t, err)
+				require.NotNil(t, schema.AdditionalProperties, "%s %s refuses unknown fields", method, path)
+				assert.False(t, *schema.AdditionalProperties)
+			}
+		}
+	}
+
+	item, err := doc.Resolve(doc.Operation("GET", "/api/v1/lists/{id}/items/{item}").Responses["200"].Content["application/json"].Schema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "item", "listId", "text", "ticked", "version"}, item.Required)
+	assert.Equal(t, "#/components/schemas/MealSource", item.Properties["sources"].Items.Ref)
+
+	w := httptest.NewRecorder()
+	handler.OpenAPIHandler(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestValidateAPI(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	doc := handler.OpenAPI()
+
+	var body string
+	next := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	validated := handler.ValidateAPI(doc, "POST /api/v1/lists", next)
+
+	w := httptest.NewRecorder()
+	validated(w, jsonRequest("POST", "/api/v1/lists", `{"name": "Hardware"}`))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, `{"name": "Hardware"}`, body, "the handler can still read the body")
+
+	w = httptest.NewRecorder()
+	validated(w, jsonRequest("POST", "/api/v1/lists", `{"name": true}`))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var apiErr models.APIError
+	decodeJSON(t, w, &apiErr)
+	assert.Equal(t, "Invalid request: body.name must be a string", apiErr.Error)
+
+	w = httptest.NewRecorder()
+	validated(w, jsonRequest("POST", "/api/v1/lists", ``))
+	assert.Equal(t, http.StatusBadRequest, w.Code, "the body is required")
+
+	w = httptest.NewRecorder()
+	handler.ValidateAPI(doc, "POST /api/v1/recipes", next)(w, jsonRequest("POST", "/api/v1/recipes", `{}`))
+	assert.Equal(t, http.StatusInternalServerError, w.Code, "undocumented routes are not served")
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+)
+
+// RecipesHandler handles the recipe library: GET lists every recipe and POST
+// adds one from the recipe form
+func (h *Handler) RecipesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		store, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		library, err := store.GetRecipes(r.Context())
+		if err != nil {
+			fmt.Printf("Error getting recipes: %s\n", err)
+			http.Error(w, "Failed to get recipes", http.StatusInternalServerError)
+			return
+		}
+		h.renderRecipes(w, "", models.RecipePageData{Recipes: library})
+
+	case http.MethodPost:
+		if !allowed(w, r, models.Role.CanEditMealPlan) {
+			return
+		}
+		recipe, err := recipeFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		store, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		newRecipe, err := store.AddRecipe(r.Context(), recipe)
+		if err != nil {
+			fmt.Printf("Error adding recipe: %s\n", err)
+			http.Error(w, "Failed to add recipe", http.StatusInternalServerError)
+			return
+		}
+		h.renderRecipes(w, "recipe-card", newRecipe)
+
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// RecipeHandler handles a single recipe at /recipes/{id}: GET shows it, POST
+// saves the edit form and DELETE removes it from the library
+func (h *Handler) RecipeHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		store, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		recipe, err := store.GetRecipe(r.Context(), id)
+		if err != nil {
+			recipeError(w, "Failed to get recipe", err)
+			return
+		}
+		h.renderRecipes(w, "", models.RecipePageData{Recipe: &recipe})
+
+	case http.MethodPost, http.MethodPut:
+		if !allowed(w, r, models.Role.CanEditMealPlan) {
+			return
+		}
+		recipe, err := recipeFromForm(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		recipe.IDHex = id
+		store, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		updatedRecipe, err := store.UpdateRecipe(r.Context(), recipe)
+		if err != nil {
+			recipeError(w, "Failed to update recipe", err)
+			return
+		}
+		h.renderRecipes(w, "recipe", updatedRecipe)
+
+	case http.MethodDelete:
+		if !allowed(w, r, models.Role.CanEditMealPlan) {
+			return
+		}
+		store, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		if err := store.DeleteRecipe(r.Context(), id); err != nil {
+			recipeError(w, "Failed to delete recipe", err)
+			return
+		}
+		// Send the browser back to the library once the recipe has gone
+		w.Header().Set("HX-Redirect", "/recipes")
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// recipeFromForm reads the recipe form fields from the request
+func recipeFromForm(r *http.Request) (recipes.Recipe, error) {
+	if err := r.ParseForm(); err != nil {
+		fmt.Printf("Error parsing form: %s\n", err)
+		return recipes.Recipe{}, fmt.Errorf("failed to parse form")
+	}
+	return recipes.FromForm(
+		r.PostFormValue("title"),
+		r.PostFormValue("servings"),
+		r.PostFormValue("ingredients"),
+		r.PostFormValue("method"),
+		r.PostFormValue("tags"),
+	)
+}
+
+// recipeError responds 404 when the recipe does not exist, otherwise 500
+func recipeError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	fmt.Printf("%s: %s\n", message, err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
+
+// renderRecipes executes the named block of the recipes template, or the
+// whole page when name is ""
+func (h *Handler) renderRecipes(w http.ResponseWriter, name string, data any) {
+	tmpl, err := template.ParseFiles(h.RecipesTemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.RecipesTemplatePath)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	if name == "" {
+		err = tmpl.Execute(w, data)
+	} else {
+		err = tmpl.ExecuteTemplate(w, name, data)
+	}
+	if err != nil {
+		fmt.Printf("Error rendering recipes template: %s\n", err)
+	}
+}
+
+// recipeIDForMeal returns the IDHex of the recipe whose title matches the
+// meal typed into a slot, or "" when the meal is free text
+func (h *Handler) recipeIDForMeal(r *http.Request, meal string) (string, error) {
+	if meal == "" {
+		return "", nil
+	}
+	store, err := h.household(r)
+	if err != nil {
+		return "", err
+	}
+	library, err := store.GetRecipes(r.Context())
+	if err != nil {
+		return "", err
+	}
+	recipe, _ := recipes.FindByTitle(library, meal)
+	return recipe.IDHex, nil
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRecipeTestHandler returns a handler over an empty in-memory store
+func newRecipeTestHandler() (*Handler, *db.MemoryDB) {
+	store := db.NewMemoryDB()
+	return &Handler{
+		DB:                    store,
+		TemplatePath:          "../../cmd/server/testdata/test_template.html",           // Use test template
+		RecipesTemplatePath:   "../../cmd/server/testdata/test_recipes_template.html",   // Use test template
+		StoresTemplatePath:    "../../cmd/server/testdata/test_stores_template.html",    // Use test template
+		LoginTemplatePath:     "../../cmd/server/testdata/test_login_template.html",     // Use test template
+		HouseholdTemplatePath: "../../cmd/server/testdata/test_household_template.html", // Use test template
+		ListsTemplatePath:     "../../cmd/server/testdata/test_lists_template.html",     // Use test template
+		HistoryTemplatePath:   "../../cmd/server/testdata/test_history_template.html",   // Use test template
+		History:               true,
+	}, store
+}
+
+// recipeRequest builds a request for /recipes/{id}, or /recipes when id is ""
+func recipeRequest(method, id, body string) *http.Request {
+	target := "/recipes"
+	if id != "" {
+		target += "/" + id
+	}
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", id)
+	return req
+}
+
+func TestRecipesHandlerCreateAndList(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.RecipesHandler(w, asOwner(recipeRequest("POST", "", "title=Fish+pie&servings=4&ingredients=400g+fish%0A1+kg+potatoes&tags=Fish")))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	library, err := store.GetRecipes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, library, 1)
+	assert.Equal(t, "Fish pie", library[0].Title)
+	assert.Equal(t, []string{"fish"}, library[0].Tags)
+	assert.Contains(t, w.Body.String(), `<li id="recipe-`+library[0].IDHex+`">Fish pie</li>`)
+
+	w = httptest.NewRecorder()
+	handler.RecipesHandler(w, asOwner(recipeRequest("GET", "", "")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Fish pie")
+}
+
+func TestRecipesHandlerCreateInvalid(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.RecipesHandler(w, asOwner(recipeRequest("POST", "", "title=&servings=2")))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	library, err := store.GetRecipes(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, library)
+}
+
+func TestRecipeHandlerUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	recipe, err := store.AddRecipe(ctx, testLibraryRecipe("Chilli"))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.RecipeHandler(w, asOwner(recipeRequest("POST", recipe.IDHex, "title=Veggie+chilli&servings=6&ingredients=2+tins+beans&method=Simmer")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Veggie chilli serves 6")
+	assert.Contains(t, w.Body.String(), "<li>2 tin beans</li>")
+
+	w = httptest.NewRecorder()
+	handler.RecipeHandler(w, asOwner(recipeRequest("DELETE", recipe.IDHex, "")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/recipes", w.Header().Get("HX-Redirect"))
+
+	_, err = store.GetRecipe(ctx, recipe.IDHex)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func TestRecipeHandlerNotFound(t *testing.T) {
+	handler, _ := newRecipeTestHandler()
+	missing := "000000000000000000000000"
+
+	for _, req := range []*http.Request{
+		recipeRequest("GET", missing, ""),
+		recipeRequest("POST", missing, "title=Chilli"),
+		recipeRequest("DELETE", missing, ""),
+	} {
+		w := httptest.NewRecorder()
+		handler.RecipeHandler(w, asOwner(req))
+		assert.Equal(t, http.StatusNotFound, w.Code, "%s should be not found", req.Method)
+	}
+}
+
+func TestMealHandlerLinksRecipe(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	recipe, err := store.AddRecipe(ctx, testLibraryRecipe("Fish pie"))
+	require.NoError(t, err)
+
+	post := func(meal, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/meal", strings.NewReader("week=2026-W42&day=Friday&slot=Dinner&meal="+meal+"&version="+version))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.MealHandler(w, asOwner(req))
+		return w
+	}
+
+	w := post("fish+pie", "0")
+	assert.Contains(t, w.Body.String(), `<a href="/recipes/`+recipe.IDHex+`">`)
+	mealPlan, err := store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, recipe.IDHex, mealPlan.Meals[4].SlotRecipe("Dinner"))
+
+	// Free text replaces the link
+	w = post("Takeaway", "1")
+	assert.NotContains(t, w.Body.String(), "/recipes/")
+	mealPlan, err = store.GetMealPlan(ctx, "2026-W42")
+	require.NoError(t, err)
+	assert.Empty(t, mealPlan.Meals[4].SlotRecipe("Dinner"))
+}
+
+// testLibraryRecipe returns a valid recipe with the given title
+func testLibraryRecipe(title string) recipes.Recipe {
+	return recipes.Recipe{
+		Title:       title,
+		Servings:    4,
+		Ingredients: []recipes.Ingredient{{Name: "potatoes", Quantity: 1, Unit: "kg"}},
+		Method:      []string{"Bake"},
+	}
+}
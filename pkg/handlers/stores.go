@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/db"
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+)
+
+// storeCookie remembers the store the shopping list is being walked in
+const storeCookie = "store"
+
+// StoresHandler handles the stores page: GET lists every store and POST saves
+// one from the store form, replacing any store with the same name
+func (h *Handler) StoresHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.renderStores(w, r, "")
+
+	case http.MethodPost:
+		if !allowed(w, r, models.Role.CanEditShoppingList) {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			fmt.Printf("Error parsing form: %s\n", err)
+			http.Error(w, "Failed to parse form", http.StatusBadRequest)
+			return
+		}
+		store := models.Store{
+			Name:          r.PostFormValue("name"),
+			CategoryOrder: models.ParseCategoryOrder(r.PostFormValue("categories")),
+		}
+		if err := store.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		household, ok := h.householdStore(w, r)
+		if !ok {
+			return
+		}
+		if _, err := household.SaveStore(r.Context(), store); err != nil {
+			fmt.Printf("Error saving store: %s\n", err)
+			http.Error(w, "Failed to save store", http.StatusInternalServerError)
+			return
+		}
+		h.renderStores(w, r, "store-list")
+
+	default:
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// StoreHandler handles a single store at /stores/{name}: DELETE removes it
+func (h *Handler) StoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !allowed(w, r, models.Role.CanEditShoppingList) {
+		return
+	}
+
+	household, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	if err := household.DeleteStore(r.Context(), r.PathValue("name")); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			http.Error(w, "Store not found", http.StatusNotFound)
+			return
+		}
+		fmt.Printf("Error deleting store: %s\n", err)
+		http.Error(w, "Failed to delete store", http.StatusInternalServerError)
+		return
+	}
+	h.renderStores(w, r, "store-list")
+}
+
+// renderStores executes the named block of the stores template, or the whole
+// page when name is ""
+func (h *Handler) renderStores(w http.ResponseWriter, r *http.Request, name string) {
+	household, ok := h.householdStore(w, r)
+	if !ok {
+		return
+	}
+	stores, err := household.GetStores(r.Context())
+	if err != nil {
+		fmt.Printf("Error getting stores: %s\n", err)
+		http.Error(w, "Failed to get stores", http.StatusInternalServerError)
+		return
+	}
+	data := models.StorePageData{Stores: stores, Categories: models.Categories}
+
+	tmpl, err := template.ParseFiles(h.StoresTemplatePath)
+	if err != nil {
+		fmt.Printf("Error parsing template: %s (path: %s)\n", err, h.StoresTemplatePath)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	if name == "" {
+		err = tmpl.Execute(w, data)
+	} else {
+		err = tmpl.ExecuteTemplate(w, name, data)
+	}
+	if err != nil {
+		fmt.Printf("Error executing template: %s\n", err)
+	}
+}
+
+// shoppingListView groups shoppingList in the aisle order of the store chosen
+// by the ?store= parameter, or else the store cookie
+func (h *Handler) shoppingListView(r *http.Request, shoppingList []models.ShoppingListItem) (models.ShoppingListView, error) {
+	view, err := h.listsView(r)
+	if err != nil {
+		return models.ShoppingListView{}, err
+	}
+	household, err := h.household(r)
+	if err != nil {
+		return models.ShoppingListView{}, err
+	}
+	stores, err := household.GetStores(r.Context())
+	if err != nil {
+		return models.ShoppingListView{}, err
+	}
+
+	name := r.URL.Query().Get("store")
+	if !r.URL.Query().Has("store") {
+		if cookie, err := r.Cookie(storeCookie); err == nil {
+			name = cookie.Value
+		}
+	}
+	view.Stores = stores
+	store, found := models.FindStore(stores, name)
+	if found {
+		view.Store = store.Name
+	}
+	view.Groups = models.GroupByCategory(shoppingList, store.CategoryOrder)
+
+	// Offer the store's categories, the defaults and any already in use
+	seen := make(map[string]bool)
+	for _, category := range append(append([]string{}, store.CategoryOrder...), models.Categories...) {
+		if !seen[strings.ToLower(category)] {
+			seen[strings.ToLower(category)] = true
+			view.Categories = append(view.Categories, category)
+		}
+	}
+	for _, item := range shoppingList {
+		if item.Category != "" && !seen[strings.ToLower(item.Category)] {
+			seen[strings.ToLower(item.Category)] = true
+			view.Categories = append(view.Categories, item.Category)
+		}
+	}
+	return view, nil
+}
+
+// setStoreCookie remembers the chosen store, or forgets it when name is ""
+func setStoreCookie(w http.ResponseWriter, name string) {
+	cookie := &http.Cookie{Name: storeCookie, Value: name, Path: "/", MaxAge: 365 * 24 * 60 * 60, SameSite: http.SameSiteLaxMode}
+	if name == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
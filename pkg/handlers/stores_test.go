@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JonClarke84/mealplannergo/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formRequest builds a form POST to target
+func formRequest(target, body string) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestStoresHandler(t *testing.T) {
+	handler, store := newRecipeTestHandler()
+
+	w := httptest.NewRecorder()
+	handler.StoresHandler(w, asOwner(formRequest("/stores", "name=Corner+shop&categories=Drinks%0ABakery%0A%0Adrinks")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Corner shop: Drinks, Bakery,")
+
+	stores, err := store.GetStores(context.Background())
+	require.NoError(t, err)
+	require.Len(t, stores, 1)
+	assert.Equal(t, []string{"Drinks", "Bakery"}, stores[0].CategoryOrder)
+
+	w = httptest.NewRecorder()
+	handler.StoresHandler(w, asOwner(formRequest("/stores", "name=&categories=Drinks")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.StoresHandler(w, asOwner(httptest.NewRequest("GET", "/stores", nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Corner shop")
+
+	req := httptest.NewRequest("DELETE", "/stores/Corner%20shop", nil)
+	req.SetPathValue("name", "Corner shop")
+	w = httptest.NewRecorder()
+	handler.StoreHandler(w, asOwner(req))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Corner shop")
+
+	w = httptest.NewRecorder()
+	handler.StoreHandler(w, asOwner(req))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestShoppingListGroupedByStore(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	for _, text := range []string{"bread", "milk", "widgets"} {
+		_, err := store.AddShoppingListItem(ctx, text)
+		require.NoError(t, err)
+	}
+	_, err := store.SaveStore(ctx, models.Store{Name: "Corner shop", CategoryOrder: []string{"Dairy & eggs", "Bakery"}})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(httptest.NewRequest("GET", "/shopping-list", nil)))
+	body := w.Body.String()
+	assert.Less(t, strings.Index(body, "Bakery"), strings.Index(body, "Dairy &amp; eggs"))
+	assert.Less(t, strings.Index(body, "Dairy &amp; eggs"), strings.Index(body, "Other"))
+
+	// Choosing a store follows its order and is remembered in a cookie
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(httptest.NewRequest("GET", "/shopping-list?store=corner+shop", nil)))
+	body = w.Body.String()
+	assert.Less(t, strings.Index(body, "Dairy &amp; eggs"), strings.Index(body, "Bakery"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "corner shop", cookies[0].Value)
+
+	req := httptest.NewRequest("GET", "/shopping-list", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ShoppingListHandler(w, asOwner(req))
+	body = w.Body.String()
+	assert.Less(t, strings.Index(body, "Dairy &amp; eggs"), strings.Index(body, "Bakery"))
+}
+
+func TestShoppingListCategoryHandler(t *testing.T) {
+	ctx := context.Background()
+	handler, store := newRecipeTestHandler()
+	item, err := store.AddShoppingListItem(ctx, "widgets")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ShoppingListCategoryHandler(w, asOwner(formRequest("/shopping-list/category", "item="+item.IDHex+"&category=Household")))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<h3>Household</h3>")
+
+	// The category is learned for the next time the item is added
+	require.NoError(t, store.DeleteShoppingListItem(ctx, item.IDHex))
+	added, err := store.AddShoppingListItem(ctx, "2 widgets")
+	require.NoError(t, err)
+	assert.Equal(t, "Household", added.Category)
+
+	w = httptest.NewRecorder()
+	handler.ShoppingListCategoryHandler(w, asOwner(formRequest("/shopping-list/category", "item=missing&category=Household")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
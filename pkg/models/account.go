@@ -0,0 +1,155 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionLifetime is how long a sign-in lasts
+const SessionLifetime = 30 * 24 * time.Hour
+
+// MinPasswordLength is the shortest password accepted when signing up
+const MinPasswordLength = 8
+
+// InviteLifetime is how long an invite code can be used for
+const InviteLifetime = 7 * 24 * time.Hour
+
+// Role is what a user may change in their household
+type Role string
+
+// Roles, from most to least able. Owners plan meals and manage the household,
+// members keep the shopping list and viewers can only look.
+const (
+	RoleOwner  Role = "owner"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+// Roles lists every role, from most to least able
+var Roles = []Role{RoleOwner, RoleMember, RoleViewer}
+
+// ParseRole returns the role called name
+func ParseRole(name string) (Role, error) {
+	for _, role := range Roles {
+		if string(role) == strings.ToLower(strings.TrimSpace(name)) {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role %q", name)
+}
+
+// CanEditMealPlan reports whether the role may change meal plans and recipes
+// and manage the household
+func (r Role) CanEditMealPlan() bool {
+	return r == RoleOwner
+}
+
+// CanEditShoppingList reports whether the role may add, tick and rearrange
+// shopping list items, and set up stores
+func (r Role) CanEditShoppingList() bool {
+	return r == RoleOwner || r == RoleMember
+}
+
+// Household owns a set of meal plans, shopping lists, recipes and stores,
+// shared by the users in it
+type Household struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty" json:"ID,omitempty"`
+	IDHex string             `bson:"IDHex,omitempty" json:"IDHex,omitempty"`
+	Name  string             `bson:"Name" json:"Name"`
+}
+
+// Validate returns an error describing the first problem with the household
+func (h Household) Validate() error {
+	if strings.TrimSpace(h.Name) == "" {
+		return fmt.Errorf("household name cannot be empty")
+	}
+	return nil
+}
+
+// SortHouseholds sorts households by name, ignoring case
+func SortHouseholds(households []Household) {
+	sort.SliceStable(households, func(i, j int) bool {
+		return strings.ToLower(households[i].Name) < strings.ToLower(households[j].Name)
+	})
+}
+
+// User is someone who can sign in. Email is stored normalised (see
+// NormaliseEmail) and PasswordHash is never shown or sent to clients.
+type User struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"ID,omitempty"`
+	IDHex        string             `bson:"IDHex,omitempty" json:"IDHex,omitempty"`
+	Email        string             `bson:"Email" json:"Email"`
+	Name         string             `bson:"Name" json:"Name"`
+	PasswordHash string             `bson:"PasswordHash" json:"-"`
+	HouseholdID  string             `bson:"HouseholdID" json:"HouseholdID"`
+	Role         Role               `bson:"Role" json:"Role"`
+}
+
+// Validate returns an error describing the first problem with the user
+func (u User) Validate() error {
+	if !strings.Contains(u.Email, "@") {
+		return fmt.Errorf("a valid email address is required")
+	}
+	if u.PasswordHash == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+	if u.HouseholdID == "" {
+		return fmt.Errorf("user must belong to a household")
+	}
+	if _, err := ParseRole(string(u.Role)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SortUsers sorts users by display name, ignoring case
+func SortUsers(users []User) {
+	sort.SliceStable(users, func(i, j int) bool {
+		return strings.ToLower(users[i].DisplayName()) < strings.ToLower(users[j].DisplayName())
+	})
+}
+
+// DisplayName returns the user's name, or their email when they have none
+func (u User) DisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return u.Email
+}
+
+// NormaliseEmail returns email trimmed and lower case, so addresses match
+// however they are typed
+func NormaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Session is a signed-in browser. Only a hash of the session token is
+// stored, so a leaked database cannot be used to sign in.
+type Session struct {
+	TokenHash string    `bson:"_id" json:"-"`
+	UserID    string    `bson:"UserID" json:"UserID"`
+	ExpiresAt time.Time `bson:"ExpiresAt" json:"ExpiresAt"`
+}
+
+// Expired reports whether the session has ended at now
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Invite lets someone join a household with a role. Like sessions, only a
+// hash of the invite code is stored.
+type Invite struct {
+	CodeHash    string    `bson:"_id" json:"-"`
+	HouseholdID string    `bson:"HouseholdID" json:"HouseholdID"`
+	Role        Role      `bson:"Role" json:"Role"`
+	ExpiresAt   time.Time `bson:"ExpiresAt" json:"ExpiresAt"`
+}
+
+// Expired reports whether the invite can no longer be used at now
+func (i Invite) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
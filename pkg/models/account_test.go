@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole(" Member ")
+	require.NoError(t, err)
+	assert.Equal(t, RoleMember, role)
+
+	_, err = ParseRole("admin")
+	assert.Error(t, err)
+	_, err = ParseRole("")
+	assert.Error(t, err)
+}
+
+func TestRolePermissions(t *testing.T) {
+	assert.True(t, RoleOwner.CanEditMealPlan())
+	assert.True(t, RoleOwner.CanEditShoppingList())
+	assert.False(t, RoleMember.CanEditMealPlan())
+	assert.True(t, RoleMember.CanEditShoppingList())
+	assert.False(t, RoleViewer.CanEditMealPlan())
+	assert.False(t, RoleViewer.CanEditShoppingList())
+}
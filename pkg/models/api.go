@@ -0,0 +1,158 @@
+package models
+
+// The types below are the resources and request bodies of the JSON API at
+// /api/v1. They are kept apart from the stored types so that the API does
+// not change shape when storage does.
+
+// APIError is the body of every JSON API error. Current is sent with a 409
+// Conflict and holds the resource as it is now.
+type APIError struct {
+	Error   string `json:"error"`
+	Current any    `json:"current,omitempty"`
+}
+
+// APISessionInput signs in to the JSON API
+type APISessionInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// APISession is a signed-in session. Token is sent back as
+// "Authorization: Bearer <token>".
+type APISession struct {
+	Token string  `json:"token"`
+	User  APIUser `json:"user"`
+}
+
+// APIUser is the signed-in user
+type APIUser struct {
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	HouseholdID string `json:"householdId"`
+	Role        Role   `json:"role"`
+}
+
+// NewAPIUser returns the API resource for user
+func NewAPIUser(user User) APIUser {
+	return APIUser{ID: user.IDHex, Email: user.Email, Name: user.Name, HouseholdID: user.HouseholdID, Role: user.Role}
+}
+
+// APIShoppingList is one of the household's shopping lists
+type APIShoppingList struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Archived bool   `json:"archived"`
+	Version  int    `json:"version"`
+}
+
+// NewAPIShoppingList returns the API resource for list
+func NewAPIShoppingList(list ShoppingList) APIShoppingList {
+	return APIShoppingList{ID: list.IDHex, Name: list.Name, Archived: list.Archived, Version: list.Version}
+}
+
+// APIShoppingListInput creates a list, or changes the fields given. A change
+// sent with the Version the list was read at is refused if the list has
+// changed since.
+type APIShoppingListInput struct {
+	Name     *string `json:"name"`
+	Archived *bool   `json:"archived"`
+	Version  *int    `json:"version"`
+}
+
+// APIShoppingListItem is an item on a shopping list. Text is the item as it
+// is shown, e.g. "2 kg potatoes (baking)".
+type APIShoppingListItem struct {
+	ID       string       `json:"id"`
+	ListID   string       `json:"listId"`
+	Text     string       `json:"text"`
+	Item     string       `json:"item"`
+	Quantity float64      `json:"quantity,omitempty"`
+	Unit     string       `json:"unit,omitempty"`
+	Note     string       `json:"note,omitempty"`
+	Category string       `json:"category,omitempty"`
+	Ticked   bool         `json:"ticked"`
+	Sources  []MealSource `json:"sources,omitempty"`
+	Version  int          `json:"version"`
+}
+
+// NewAPIShoppingListItem returns the API resource for item on the list with
+// IDHex listID
+func NewAPIShoppingListItem(listID string, item ShoppingListItem) APIShoppingListItem {
+	return APIShoppingListItem{
+		ID:       item.IDHex,
+		ListID:   listID,
+		Text:     item.Text(),
+		Item:     item.Item,
+		Quantity: item.Quantity,
+		Unit:     item.Unit,
+		Note:     item.Note,
+		Category: item.Category,
+		Ticked:   item.Ticked,
+		Sources:  item.Sources,
+		Version:  item.Version,
+	}
+}
+
+// APIShoppingListItemInput adds an item from Text, or changes the fields
+// given. Changing the text needs the Version it was read at.
+type APIShoppingListItemInput struct {
+	Text     *string `json:"text"`
+	Ticked   *bool   `json:"ticked"`
+	Category *string `json:"category"`
+	Version  *int    `json:"version"`
+}
+
+// APIOrderInput puts a list's items in order, by ID. Version is the list's
+// version when the order was read.
+type APIOrderInput struct {
+	Order   []string `json:"order"`
+	Version *int     `json:"version"`
+}
+
+// APIMealPlan is the meal plan for one ISO week, with a meal for every day
+// and slot in display order
+type APIMealPlan struct {
+	Week      string    `json:"week"`
+	WeekStart string    `json:"weekStart"`
+	Meals     []APIMeal `json:"meals"`
+}
+
+// NewAPIMealPlan returns the API resource for mealPlan, with the given
+// slots of each day
+func NewAPIMealPlan(mealPlan MealPlan, slots []string) APIMealPlan {
+	plan := APIMealPlan{Week: mealPlan.Week, WeekStart: mealPlan.WeekStart.Format("2006-01-02"), Meals: []APIMeal{}}
+	for _, meal := range mealPlan.Meals {
+		for _, slot := range slots {
+			input := meal.Input(slot)
+			input.Week = mealPlan.Week
+			plan.Meals = append(plan.Meals, NewAPIMeal(input))
+		}
+	}
+	return plan
+}
+
+// APIMeal is the meal in one slot of a day. Meal is "" when nothing is
+// planned.
+type APIMeal struct {
+	Week     string `json:"week"`
+	Day      string `json:"day"`
+	Slot     string `json:"slot"`
+	Meal     string `json:"meal"`
+	RecipeID string `json:"recipeId,omitempty"`
+	Version  int    `json:"version"`
+}
+
+// NewAPIMeal returns the API resource for a meal slot
+func NewAPIMeal(input MealSlotInput) APIMeal {
+	return APIMeal{Week: input.Week, Day: input.Day, Slot: input.Slot, Meal: input.Meal, RecipeID: input.RecipeID, Version: input.Version}
+}
+
+// APIMealInput sets the meal in a slot. Without a RecipeID the meal is
+// linked to the recipe with its title, as on the meal plan page. Version is
+// the slot's version when it was read.
+type APIMealInput struct {
+	Meal     string  `json:"meal"`
+	RecipeID *string `json:"recipeId"`
+	Version  *int    `json:"version"`
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// OtherCategory is the heading for items without a category
+const OtherCategory = "Other"
+
+// Categories lists the aisle categories offered for items, in the order
+// used when no store is chosen
+var Categories = []string{"Produce", "Bakery", "Meat & fish", "Dairy & eggs", "Frozen", "Cupboard", "Drinks", "Household"}
+
+// defaultCategories is the built-in dictionary used to guess the category
+// of an item nobody has categorised yet
+var defaultCategories = map[string]string{
+	"apple": "Produce", "banana": "Produce", "onion": "Produce", "potato": "Produce",
+	"potatoes": "Produce", "tomatoes": "Produce", "carrot": "Produce", "garlic": "Produce",
+	"lemon": "Produce", "lettuce": "Produce", "pepper": "Produce", "mushroom": "Produce",
+	"spinach": "Produce", "leek": "Produce", "coriander": "Produce", "broccoli": "Produce",
+	"bread": "Bakery", "rolls": "Bakery", "bagels": "Bakery", "croissants": "Bakery",
+	"mince": "Meat & fish", "chicken": "Meat & fish", "bacon": "Meat & fish", "sausages": "Meat & fish",
+	"fish": "Meat & fish", "salmon": "Meat & fish", "beef": "Meat & fish", "pork": "Meat & fish",
+	"milk": "Dairy & eggs", "eggs": "Dairy & eggs", "butter": "Dairy & eggs", "cheese": "Dairy & eggs",
+	"yoghurt": "Dairy & eggs", "yogurt": "Dairy & eggs", "cream": "Dairy & eggs",
+	"peas": "Frozen", "ice cream": "Frozen", "chips": "Frozen",
+	"pasta": "Cupboard", "spaghetti": "Cupboard", "rice": "Cupboard", "flour": "Cupboard",
+	"sugar": "Cupboard", "passata": "Cupboard", "beans": "Cupboard", "oil": "Cupboard",
+	"cumin": "Cupboard", "salt": "Cupboard", "stock": "Cupboard", "cereal": "Cupboard",
+	"juice": "Drinks", "coffee": "Drinks", "tea": "Drinks", "water": "Drinks", "wine": "Drinks",
+	"beer": "Drinks", "squash": "Drinks",
+	"toilet roll": "Household", "bin bags": "Household", "washing up liquid": "Household",
+	"soap": "Household", "foil": "Household", "kitchen roll": "Household",
+}
+
+// Store is a shop with the order its aisles are walked in
+type Store struct {
+	Name          string   `bson:"Name" json:"Name"`
+	CategoryOrder []string `bson:"CategoryOrder" json:"CategoryOrder"`
+}
+
+// Validate returns an error describing the first problem with the store
+func (s Store) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("store name cannot be empty")
+	}
+	return nil
+}
+
+// CategoryOrderText returns the store's categories one per line, for editing
+func (s Store) CategoryOrderText() string {
+	return strings.Join(s.CategoryOrder, "\n")
+}
+
+// Path returns the URL of the store, for deleting it
+func (s Store) Path() string {
+	return "/stores/" + url.PathEscape(s.Name)
+}
+
+// ParseCategoryOrder reads categories one per non-blank line, dropping
+// repeats
+func ParseCategoryOrder(text string) []string {
+	order := []string{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		category := strings.TrimSpace(line)
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
+		}
+		seen[strings.ToLower(category)] = true
+		order = append(order, category)
+	}
+	return order
+}
+
+// StoreKey returns the key a store is saved under; store names ignore case
+func StoreKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// SortStores sorts stores by name, ignoring case
+func SortStores(stores []Store) {
+	sort.SliceStable(stores, func(i, j int) bool {
+		return StoreKey(stores[i].Name) < StoreKey(stores[j].Name)
+	})
+}
+
+// FindStore returns the store called name, ignoring case
+func FindStore(stores []Store, name string) (Store, bool) {
+	for _, store := range stores {
+		if StoreKey(store.Name) == StoreKey(name) {
+			return store, true
+		}
+	}
+	return Store{}, false
+}
+
+// CategoryKey returns the key an item name's learned category and walk order
+// are kept under
+func CategoryKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(ShoppingListItem{Item: name}.Structured().Item))
+}
+
+// GuessCategory returns the category for an item called name, preferring the
+// learned dictionary over the built-in one. Names are tried whole, then by
+// their last word ("oat milk" is milk), with and without a plural "s". It
+// returns "" when no category is known.
+func GuessCategory(name string, learned map[string]string) string {
+	key := CategoryKey(name)
+	candidates := []string{key}
+	if words := strings.Fields(key); len(words) > 1 {
+		candidates = append(candidates, words[len(words)-1])
+	}
+	for _, candidate := range candidates {
+		for _, form := range []string{candidate, strings.TrimSuffix(candidate, "s")} {
+			if category, known := learned[form]; known {
+				return category
+			}
+			if category, known := defaultCategories[form]; known {
+				return category
+			}
+		}
+	}
+	return ""
+}
+
+// CategoryGroup is the items of one category, in list order
+type CategoryGroup struct {
+	Category string
+	Items    []ShoppingListItem
+}
+
+// GroupByCategory groups items by category. Groups follow order, then the
+// default Categories, then any other categories alphabetically, with items
+// without a category last under OtherCategory.
+func GroupByCategory(items []ShoppingListItem, order []string) []CategoryGroup {
+	rank := make(map[string]int)
+	for _, category := range append(append([]string{}, order...), Categories...) {
+		if _, seen := rank[strings.ToLower(category)]; !seen {
+			rank[strings.ToLower(category)] = len(rank)
+		}
+	}
+
+	var groups []CategoryGroup
+	index := make(map[string]int)
+	for _, item := range items {
+		category := strings.TrimSpace(item.Category)
+		if category == "" {
+			category = OtherCategory
+		}
+		key := strings.ToLower(category)
+		i, exists := index[key]
+		if !exists {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, CategoryGroup{Category: category})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+
+	position := func(category string) (int, string) {
+		key := strings.ToLower(category)
+		if r, ranked := rank[key]; ranked {
+			return r, ""
+		}
+		if key == strings.ToLower(OtherCategory) {
+			return len(rank) + 1, ""
+		}
+		return len(rank), key
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		ri, ni := position(groups[i].Category)
+		rj, nj := position(groups[j].Category)
+		if ri != rj {
+			return ri < rj
+		}
+		return ni < nj
+	})
+	return groups
+}
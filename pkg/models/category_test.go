@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuessCategory(t *testing.T) {
+	learned := map[string]string{"oat milk": "Drinks", "halloumi": "Dairy & eggs"}
+
+	tests := map[string]string{
+		"Milk":              "Dairy & eggs",
+		"2 l milk":          "Dairy & eggs",
+		"Oat milk":          "Drinks",
+		"semi-skimmed milk": "Dairy & eggs",
+		"Halloumi":          "Dairy & eggs",
+		"bananas":           "Produce",
+		"3 onions":          "Produce",
+		"light bulbs":       "",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, GuessCategory(name, learned), "name %q", name)
+	}
+	assert.Equal(t, "Produce", GuessCategory("carrots", nil))
+}
+
+func TestGroupByCategory(t *testing.T) {
+	items := []ShoppingListItem{
+		{Item: "milk", Category: "Dairy & eggs"},
+		{Item: "light bulbs"},
+		{Item: "bananas", Category: "Produce"},
+		{Item: "candles", Category: "Seasonal"},
+		{Item: "eggs", Category: "dairy & eggs"},
+		{Item: "bread", Category: "Bakery"},
+	}
+
+	categoriesOf := func(groups []CategoryGroup) []string {
+		names := []string{}
+		for _, group := range groups {
+			names = append(names, group.Category)
+		}
+		return names
+	}
+
+	groups := GroupByCategory(items, nil)
+	assert.Equal(t, []string{"Produce", "Bakery", "Dairy & eggs", "Seasonal", OtherCategory}, categoriesOf(groups))
+	assert.Equal(t, []ShoppingListItem{items[0], items[4]}, groups[2].Items)
+
+	// A store's order comes first, then the default order
+	groups = GroupByCategory(items, []string{"Seasonal", "Dairy & eggs"})
+	assert.Equal(t, []string{"Seasonal", "Dairy & eggs", "Produce", "Bakery", OtherCategory}, categoriesOf(groups))
+
+	assert.Empty(t, GroupByCategory(nil, nil))
+}
+
+func TestStoreHelpers(t *testing.T) {
+	assert.Error(t, Store{Name: " "}.Validate())
+	assert.NoError(t, Store{Name: "Corner shop"}.Validate())
+
+	order := ParseCategoryOrder("Produce\n\n  Bakery \nproduce\nFrozen")
+	assert.Equal(t, []string{"Produce", "Bakery", "Frozen"}, order)
+	assert.Equal(t, "Produce\nBakery\nFrozen", Store{CategoryOrder: order}.CategoryOrderText())
+
+	stores := []Store{{Name: "Corner shop"}, {Name: "Big Supermarket"}}
+	store, found := FindStore(stores, "big supermarket")
+	assert.True(t, found)
+	assert.Equal(t, "Big Supermarket", store.Name)
+	_, found = FindStore(stores, "Market")
+	assert.False(t, found)
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseVersion reads the version a client says it edited. Edits of items and
+// meals must send one, so that an edit made to an out of date copy is
+// rejected rather than overwriting someone else's change.
+func ParseVersion(value string) (int, error) {
+	if value == "" {
+		return 0, fmt.Errorf("version is required")
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid version %q", value)
+	}
+	return version, nil
+}
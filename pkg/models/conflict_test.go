@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion("3")
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	version, err = ParseVersion("0")
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+
+	for _, value := range []string{"", "x", "-1"} {
+		_, err := ParseVersion(value)
+		assert.Error(t, err, value)
+	}
+}
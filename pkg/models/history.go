@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HistoryLength is how many changes the history page shows
+const HistoryLength = 100
+
+// The kinds of change recorded in a household's history
+const (
+	ChangeAdd    = "add"
+	ChangeEdit   = "edit"
+	ChangeTick   = "tick"
+	ChangeDelete = "delete"
+	ChangeSort   = "sort"
+	ChangeMeal   = "meal"
+)
+
+// Change records one change made to a shopping list or the meal plan: who
+// made it, when, and the values before and after. ListID is "" for changes
+// to the meal plan. Undone is set while the change has been undone.
+type Change struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	IDHex    string             `bson:"-" json:"IDHex"`
+	ListID   string             `bson:"ListID,omitempty" json:"ListID,omitempty"`
+	UserID   string             `bson:"UserID,omitempty" json:"UserID,omitempty"`
+	UserName string             `bson:"UserName,omitempty" json:"UserName,omitempty"`
+	Time     time.Time          `bson:"Time" json:"Time"`
+	Action   string             `bson:"Action" json:"Action"`
+	Before   ChangeValue        `bson:"Before" json:"Before"`
+	After    ChangeValue        `bson:"After" json:"After"`
+	Undone   bool               `bson:"Undone" json:"Undone"`
+}
+
+// ChangeValue is what a change changed, as it was before or after: an item,
+// which is nil when it was not on the list, the order of the list's items by
+// IDHex, or one meal slot
+type ChangeValue struct {
+	Item  *ShoppingListItem `bson:"Item,omitempty" json:"Item,omitempty"`
+	Order []string          `bson:"Order,omitempty" json:"Order,omitempty"`
+	Meal  *MealSlotInput    `bson:"Meal,omitempty" json:"Meal,omitempty"`
+}
+
+// Who returns the name of the person who made the change
+func (c Change) Who() string {
+	if c.UserName == "" {
+		return "Someone"
+	}
+	return c.UserName
+}
+
+// Destructive reports whether the change lost something that the page
+// should offer to undo
+func (c Change) Destructive() bool {
+	return c.Action == ChangeDelete
+}
+
+// Summary describes the change, e.g. "ticked milk" or "set Monday Dinner to
+// Fish pie"
+func (c Change) Summary() string {
+	before, after := c.Before.Item, c.After.Item
+	switch {
+	case c.Action == ChangeSort:
+		return "reordered the list"
+	case c.Action == ChangeMeal && c.After.Meal != nil:
+		meal := c.After.Meal
+		if meal.Meal == "" {
+			return fmt.Sprintf("cleared %s %s", meal.Day, meal.Slot)
+		}
+		return fmt.Sprintf("set %s %s to %s", meal.Day, meal.Slot, meal.Meal)
+	case c.Action == ChangeTick && after != nil:
+		if after.Ticked {
+			return "ticked " + after.Text()
+		}
+		return "unticked " + after.Text()
+	case c.Action == ChangeDelete && before != nil:
+		return "deleted " + before.Text()
+	case c.Action == ChangeAdd && before == nil && after != nil:
+		return "added " + after.Text()
+	case c.Action == ChangeAdd && before != nil && after != nil:
+		return fmt.Sprintf("added to %s, making %s", before.Text(), after.Text())
+	case before != nil && after != nil && before.Text() != after.Text():
+		return fmt.Sprintf("changed %s to %s", before.Text(), after.Text())
+	case before != nil && after != nil && before.Category != after.Category:
+		return fmt.Sprintf("moved %s to %s", after.Text(), after.Category)
+	case after != nil:
+		return "changed " + after.Text()
+	}
+	return "made a change"
+}
+
+// HistoryPageData is passed to the history template
+type HistoryPageData struct {
+	// List is the shopping list whose changes are shown
+	List ShoppingList
+	// Changes are the list's changes and MealChanges the meal plan's, newest
+	// first
+	Changes     []Change
+	MealChanges []Change
+	// CanEdit and CanEditMealPlan say which changes the user may undo
+	CanEdit         bool
+	CanEditMealPlan bool
+	// Failed says why undoing or redoing a change did not work
+	Failed string
+}
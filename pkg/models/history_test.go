@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeSummary(t *testing.T) {
+	milk := &ShoppingListItem{Item: "milk"}
+	oatMilk := &ShoppingListItem{Item: "oat milk"}
+	ticked := &ShoppingListItem{Item: "milk", Ticked: true}
+	dairy := &ShoppingListItem{Item: "milk", Category: "Dairy"}
+	twoMilk := &ShoppingListItem{Item: "milk", Quantity: 2}
+
+	tests := []struct {
+		change Change
+		want   string
+	}{
+		{Change{Action: ChangeAdd, After: ChangeValue{Item: milk}}, "added milk"},
+		{Change{Action: ChangeAdd, Before: ChangeValue{Item: milk}, After: ChangeValue{Item: twoMilk}}, "added to milk, making 2 milk"},
+		{Change{Action: ChangeEdit, Before: ChangeValue{Item: milk}, After: ChangeValue{Item: oatMilk}}, "changed milk to oat milk"},
+		{Change{Action: ChangeEdit, Before: ChangeValue{Item: milk}, After: ChangeValue{Item: dairy}}, "moved milk to Dairy"},
+		{Change{Action: ChangeTick, Before: ChangeValue{Item: milk}, After: ChangeValue{Item: ticked}}, "ticked milk"},
+		{Change{Action: ChangeTick, Before: ChangeValue{Item: ticked}, After: ChangeValue{Item: milk}}, "unticked milk"},
+		{Change{Action: ChangeDelete, Before: ChangeValue{Item: milk}}, "deleted milk"},
+		{Change{Action: ChangeSort}, "reordered the list"},
+		{Change{Action: ChangeMeal, After: ChangeValue{Meal: &MealSlotInput{Day: "Monday", Slot: "Dinner", Meal: "Chilli"}}}, "set Monday Dinner to Chilli"},
+		{Change{Action: ChangeMeal, After: ChangeValue{Meal: &MealSlotInput{Day: "Monday", Slot: "Dinner"}}}, "cleared Monday Dinner"},
+		{Change{Action: ChangeEdit}, "made a change"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.change.Summary())
+	}
+
+	assert.Equal(t, "Someone", Change{}.Who())
+	assert.Equal(t, "Sam", Change{UserName: "Sam"}.Who())
+	assert.True(t, Change{Action: ChangeDelete}.Destructive())
+	assert.False(t, Change{Action: ChangeEdit}.Destructive())
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/quantity"
+	"github.com/JonClarke84/mealplannergo/pkg/units"
+)
+
+// ParseShoppingListItem reads text typed into the shopping list, such as
+// "2kg potatoes" or "milk x3", into an item's name, quantity, unit and note
+func ParseShoppingListItem(text string) ShoppingListItem {
+	parsed := quantity.Parse(text)
+	return ShoppingListItem{
+		Item:     parsed.Name,
+		Quantity: parsed.Quantity,
+		Unit:     parsed.Unit,
+		Note:     parsed.Note,
+	}
+}
+
+// Amount returns the item's quantity and unit as text, e.g. "2 kg", or ""
+// when it has no quantity
+func (i ShoppingListItem) Amount() string {
+	return quantity.Format(i.Quantity, i.Unit)
+}
+
+// Text returns the item the way it is typed, e.g. "2 kg potatoes (new)".
+// Items saved as a single string show that string unchanged.
+func (i ShoppingListItem) Text() string {
+	return quantity.Text(i.Item, i.Quantity, i.Unit, i.Note)
+}
+
+// Structured returns the item with its quantity, unit and note read from
+// Item when it was saved as a single string, such as "2 x 1L milk"
+func (i ShoppingListItem) Structured() ShoppingListItem {
+	if i.Quantity != 0 || i.Unit != "" || i.Note != "" {
+		return i
+	}
+	parsed := ParseShoppingListItem(i.Item)
+	if parsed.Item == "" {
+		return i
+	}
+	i.Item, i.Quantity, i.Unit, i.Note = parsed.Item, parsed.Quantity, parsed.Unit, parsed.Note
+	return i
+}
+
+// SameItem reports whether i and other name the same thing, ignoring case
+func (i ShoppingListItem) SameItem(other ShoppingListItem) bool {
+	return strings.EqualFold(strings.TrimSpace(i.Structured().Item), strings.TrimSpace(other.Structured().Item))
+}
+
+// MergeShoppingListItems returns a and b combined into a, with their
+// quantities added (converting units where possible, see the units package),
+// their notes joined and their sources combined. a's category is kept unless
+// it has none. It reports false when the
+// items are different things or their amounts cannot be added, e.g. 1 tin
+// and 400 g of tomatoes. An item with no quantity adds nothing to a measured
+// one, and counts as one of an item counted without a unit.
+func MergeShoppingListItems(a, b ShoppingListItem) (ShoppingListItem, bool) {
+	if !a.SameItem(b) {
+		return ShoppingListItem{}, false
+	}
+	a, b = a.Structured(), b.Structured()
+
+	merged := a
+	switch {
+	case a.Quantity == 0 && b.Quantity == 0:
+	case a.Unit == "" && b.Unit == "":
+		merged.Quantity = max(a.Quantity, 1) + max(b.Quantity, 1)
+	case b.Quantity == 0:
+	case a.Quantity == 0:
+		merged.Quantity, merged.Unit = b.Quantity, b.Unit
+	default:
+		total, ok := units.Add(units.Amount{Quantity: a.Quantity, Unit: a.Unit}, units.Amount{Quantity: b.Quantity, Unit: b.Unit}, a.Item)
+		if !ok {
+			return ShoppingListItem{}, false
+		}
+		merged.Quantity, merged.Unit = total.Quantity, total.Unit
+	}
+
+	switch {
+	case merged.Note == "":
+		merged.Note = b.Note
+	case b.Note != "" && !strings.EqualFold(b.Note, merged.Note):
+		merged.Note += "; " + b.Note
+	}
+
+	if merged.Category == "" {
+		merged.Category = b.Category
+	}
+
+	merged.Sources = append([]MealSource{}, a.Sources...)
+	for _, source := range b.Sources {
+		if !merged.HasSource(source) {
+			merged.Sources = append(merged.Sources, source)
+		}
+	}
+	if len(merged.Sources) == 0 {
+		merged.Sources = nil
+	}
+	return merged, true
+}
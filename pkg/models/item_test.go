@@ -0,0 +1,67 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseShoppingListItem(t *testing.T) {
+	item := ParseShoppingListItem("2kg potatoes (for roasting)")
+	assert.Equal(t, ShoppingListItem{Item: "potatoes", Quantity: 2, Unit: "kg", Note: "for roasting"}, item)
+	assert.Equal(t, "2 kg", item.Amount())
+	assert.Equal(t, "2 kg potatoes (for roasting)", item.Text())
+
+	assert.Equal(t, ShoppingListItem{Item: "milk", Quantity: 3}, ParseShoppingListItem("milk x3"))
+}
+
+func TestShoppingListItemTextKeepsLegacyItems(t *testing.T) {
+	item := ShoppingListItem{Item: "2 x 1L milk"}
+	assert.Equal(t, "", item.Amount())
+	assert.Equal(t, "2 x 1L milk", item.Text())
+}
+
+func TestMergeShoppingListItems(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"500g flour", "1 kg Flour", "1.5 kg flour"},
+		{"2 tbsp cumin", "1/4 cup cumin", "6.167 tbsp cumin"},
+		{"milk x2", "milk", "3 milk"},
+		{"milk", "milk", "milk"},
+		{"milk", "2 l milk", "2 l milk"},
+		{"1 l milk", "milk", "1 l milk"},
+		{"bread (wholemeal)", "bread (sliced)", "bread (wholemeal; sliced)"},
+		{"bread (sliced)", "Bread (Sliced)", "bread (sliced)"},
+	}
+	for _, test := range tests {
+		merged, ok := MergeShoppingListItems(ParseShoppingListItem(test.a), ParseShoppingListItem(test.b))
+		assert.True(t, ok, "%q + %q", test.a, test.b)
+		assert.Equal(t, test.want, merged.Text(), "%q + %q", test.a, test.b)
+	}
+
+	for _, pair := range [][2]string{{"1 tin tomatoes", "400g tomatoes"}, {"eggs", "milk"}} {
+		_, ok := MergeShoppingListItems(ParseShoppingListItem(pair[0]), ParseShoppingListItem(pair[1]))
+		assert.False(t, ok, "%q + %q", pair[0], pair[1])
+	}
+}
+
+func TestMergeShoppingListItemsKeepsFirstAndCombinesSources(t *testing.T) {
+	chilli := MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "abc"}
+	pie := MealSource{Week: "2026-W42", Day: "Tuesday", Slot: "Dinner", RecipeID: "def"}
+	a := ShoppingListItem{IDHex: "1", Item: "mince", Quantity: 500, Unit: "g", Sources: []MealSource{chilli}}
+	b := ShoppingListItem{IDHex: "2", Item: "Mince", Quantity: 500, Unit: "g", Sources: []MealSource{chilli, pie}}
+
+	merged, ok := MergeShoppingListItems(a, b)
+	assert.True(t, ok)
+	assert.Equal(t, "1", merged.IDHex)
+	assert.Equal(t, "1 kg mince", merged.Text())
+	assert.Equal(t, []MealSource{chilli, pie}, merged.Sources)
+	assert.Len(t, a.Sources, 1, "a should not be changed")
+
+	// Items saved as a single string are read before merging
+	merged, ok = MergeShoppingListItems(ShoppingListItem{Item: "2 x 1L milk"}, ShoppingListItem{Item: "milk", Quantity: 500, Unit: "ml"})
+	assert.True(t, ok)
+	assert.Equal(t, "2.5 l milk", merged.Text())
+}
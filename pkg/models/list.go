@@ -0,0 +1,85 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultShoppingListName names the list each household starts with, which
+// holds the items saved before households could have more than one
+const DefaultShoppingListName = "Shopping list"
+
+// MaxShoppingListNameLength is the longest name a shopping list can have
+const MaxShoppingListNameLength = 60
+
+// NormaliseShoppingListName returns name as it is stored, or an error when it
+// cannot name a list
+func NormaliseShoppingListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("shopping list name cannot be empty")
+	}
+	if len(name) > MaxShoppingListNameLength {
+		return "", fmt.Errorf("shopping list name must be at most %d characters", MaxShoppingListNameLength)
+	}
+	return name, nil
+}
+
+// FindShoppingList returns the list in lists with IDHex, reporting whether
+// there is one
+func FindShoppingList(lists []ShoppingList, IDHex string) (ShoppingList, bool) {
+	for _, list := range lists {
+		if list.IDHex == IDHex {
+			return list, true
+		}
+	}
+	return ShoppingList{}, false
+}
+
+// ActiveShoppingLists returns the lists in lists that are not archived, in
+// the same order
+func ActiveShoppingLists(lists []ShoppingList) []ShoppingList {
+	var active []ShoppingList
+	for _, list := range lists {
+		if !list.Archived {
+			active = append(active, list)
+		}
+	}
+	return active
+}
+
+// ChooseShoppingList returns the list in lists with IDHex or, when IDHex is
+// "", the first one not archived. It reports false if there is no such list.
+func ChooseShoppingList(lists []ShoppingList, IDHex string) (ShoppingList, bool) {
+	if IDHex != "" {
+		return FindShoppingList(lists, IDHex)
+	}
+	if active := ActiveShoppingLists(lists); len(active) > 0 {
+		return active[0], true
+	}
+	if len(lists) > 0 {
+		return lists[0], true
+	}
+	return ShoppingList{}, false
+}
+
+// ListItem is a shopping list item along with the list it is on and the
+// lists it could be moved to, which its controls need. Conflict is the text
+// of an edit that was not saved because someone else changed the item first.
+type ListItem struct {
+	ShoppingListItem
+	ListID   string
+	MoveTo   []ShoppingList
+	Conflict string
+}
+
+// Item returns item as a ListItem on the view's list
+func (v ShoppingListView) Item(item ShoppingListItem) ListItem {
+	listItem := ListItem{ShoppingListItem: item, ListID: v.List.IDHex}
+	for _, list := range ActiveShoppingLists(v.Lists) {
+		if list.IDHex != v.List.IDHex {
+			listItem.MoveTo = append(listItem.MoveTo, list)
+		}
+	}
+	return listItem
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormaliseShoppingListName(t *testing.T) {
+	name, err := NormaliseShoppingListName("  Butcher ")
+	require.NoError(t, err)
+	assert.Equal(t, "Butcher", name)
+
+	_, err = NormaliseShoppingListName("   ")
+	assert.Error(t, err)
+	_, err = NormaliseShoppingListName(strings.Repeat("x", MaxShoppingListNameLength+1))
+	assert.Error(t, err)
+}
+
+func TestActiveShoppingLists(t *testing.T) {
+	lists := []ShoppingList{
+		{IDHex: "a", Name: "Supermarket"},
+		{IDHex: "b", Name: "Christmas", Archived: true},
+		{IDHex: "c", Name: "Hardware"},
+	}
+	active := ActiveShoppingLists(lists)
+	require.Len(t, active, 2)
+	assert.Equal(t, "Supermarket", active[0].Name)
+	assert.Equal(t, "Hardware", active[1].Name)
+
+	list, found := FindShoppingList(lists, "b")
+	assert.True(t, found)
+	assert.Equal(t, "Christmas", list.Name)
+	_, found = FindShoppingList(lists, "z")
+	assert.False(t, found)
+}
+
+func TestChooseShoppingList(t *testing.T) {
+	lists := []ShoppingList{
+		{IDHex: "a", Name: "Christmas", Archived: true},
+		{IDHex: "b", Name: "Supermarket"},
+		{IDHex: "c", Name: "Hardware"},
+	}
+	list, found := ChooseShoppingList(lists, "")
+	assert.True(t, found)
+	assert.Equal(t, "Supermarket", list.Name, "archived lists are not chosen by default")
+	list, found = ChooseShoppingList(lists, "a")
+	assert.True(t, found)
+	assert.Equal(t, "Christmas", list.Name)
+	_, found = ChooseShoppingList(lists, "z")
+	assert.False(t, found)
+
+	list, found = ChooseShoppingList(lists[:1], "")
+	assert.True(t, found)
+	assert.Equal(t, "Christmas", list.Name)
+}
+
+func TestShoppingListViewItem(t *testing.T) {
+	lists := []ShoppingList{
+		{IDHex: "a", Name: "Supermarket"},
+		{IDHex: "b", Name: "Christmas", Archived: true},
+		{IDHex: "c", Name: "Hardware"},
+	}
+	view := ShoppingListView{List: lists[0], Lists: lists}
+
+	item := view.Item(ShoppingListItem{IDHex: "1", Item: "milk"})
+	assert.Equal(t, "milk", item.Item)
+	assert.Equal(t, "a", item.ListID)
+	require.Len(t, item.MoveTo, 1)
+	assert.Equal(t, "Hardware", item.MoveTo[0].Name)
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DefaultMealSlots are the slots planned for each day unless configured otherwise
+var DefaultMealSlots = []string{"Breakfast", "Lunch", "Dinner", "Snacks"}
+
+// LegacyMealSlot is the slot that single-meal days from before slots existed are read into
+const LegacyMealSlot = "Dinner"
+
+// mealSlotPattern restricts slot names to characters that are safe as
+// MongoDB field names and in HTML element IDs once spaces are replaced
+var mealSlotPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 '-]*$`)
+
+// ValidateMealSlot returns an error if slot cannot be used as a slot name
+func ValidateMealSlot(slot string) error {
+	if !mealSlotPattern.MatchString(slot) {
+		return fmt.Errorf("invalid meal slot %q", slot)
+	}
+	return nil
+}
+
+// SlotMeal returns the meal planned for slot, or "" if there is none
+func (m Meal) SlotMeal(slot string) string {
+	return m.Slots[slot]
+}
+
+// SetSlotMeal sets the meal planned for slot
+func (m *Meal) SetSlotMeal(slot string, meal string) {
+	m.Normalise()
+	if m.Slots == nil {
+		m.Slots = make(map[string]string)
+	}
+	m.Slots[slot] = meal
+}
+
+// SlotRecipe returns the IDHex of the recipe linked to slot, or "" if the
+// slot is free text
+func (m Meal) SlotRecipe(slot string) string {
+	return m.Recipes[slot]
+}
+
+// SlotVersion returns the number of times slot has been changed
+func (m Meal) SlotVersion(slot string) int {
+	return m.Versions[slot]
+}
+
+// SetSlotVersion sets the number of times slot has been changed
+func (m *Meal) SetSlotVersion(slot string, version int) {
+	if version == 0 {
+		delete(m.Versions, slot)
+		return
+	}
+	if m.Versions == nil {
+		m.Versions = make(map[string]int)
+	}
+	m.Versions[slot] = version
+}
+
+// NextSlotVersion counts a change to slot
+func (m *Meal) NextSlotVersion(slot string) {
+	m.SetSlotVersion(slot, m.SlotVersion(slot)+1)
+}
+
+// SetSlotRecipe links slot to a recipe, or unlinks it when recipeID is ""
+func (m *Meal) SetSlotRecipe(slot string, recipeID string) {
+	if recipeID == "" {
+		delete(m.Recipes, slot)
+		return
+	}
+	if m.Recipes == nil {
+		m.Recipes = make(map[string]string)
+	}
+	m.Recipes[slot] = recipeID
+}
+
+// Normalise moves a single-slot Meal value into LegacyMealSlot, unless that
+// slot has already been filled in
+func (m *Meal) Normalise() {
+	if m.Meal == "" {
+		return
+	}
+	if m.Slots[LegacyMealSlot] == "" {
+		if m.Slots == nil {
+			m.Slots = make(map[string]string)
+		}
+		m.Slots[LegacyMealSlot] = m.Meal
+	}
+	m.Meal = ""
+}
+
+// UnmarshalBSON decodes a day and normalises documents written before slots existed
+func (m *Meal) UnmarshalBSON(data []byte) error {
+	// Decode into an alias type so this method is not called recursively
+	type rawMeal Meal
+	var raw rawMeal
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Meal(raw)
+	m.Normalise()
+	return nil
+}
+
+// MealSlotInput is the data rendered by the meal-input template block
+type MealSlotInput struct {
+	// Week is the ISO week of the meal plan the slot belongs to
+	Week     string
+	Day      string
+	Slot     string
+	Meal     string
+	RecipeID string
+	// Version is the slot's version when it was read
+	Version int
+	// Offer is set when the meal changed away from a recipe whose
+	// ingredients are on the shopping list
+	Offer *IngredientOffer
+	// Conflict is the meal that was not saved because someone else changed
+	// the slot first
+	Conflict string
+}
+
+// Input returns the template data for one slot of the day
+func (m Meal) Input(slot string) MealSlotInput {
+	return MealSlotInput{Day: m.Day, Slot: slot, Meal: m.SlotMeal(slot), RecipeID: m.SlotRecipe(slot), Version: m.SlotVersion(slot)}
+}
+
+// MealInput returns the template data for one slot of a day of the week shown
+func (p PageData) MealInput(meal Meal, slot string) MealSlotInput {
+	input := meal.Input(slot)
+	input.Week = p.Week
+	return input
+}
+
+// ElementID returns an HTML element ID unique to this day and slot
+func (i MealSlotInput) ElementID() string {
+	slug := strings.NewReplacer(" ", "-", "'", "").Replace(strings.ToLower(i.Slot))
+	return fmt.Sprintf("meal-%s-%s", strings.ToLower(i.Day), slug)
+}
+
+// EventName returns the name of the live update sent when the slot changes,
+// unique to the week as well as the day and slot
+func (i MealSlotInput) EventName() string {
+	return fmt.Sprintf("%s-%s", i.ElementID(), strings.ToLower(i.Week))
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMealUnmarshalBSONSingleSlot(t *testing.T) {
+	// A day as stored before slots existed
+	data, err := bson.Marshal(bson.D{{Key: "day", Value: "Monday"}, {Key: "meal", Value: "Lasagne"}})
+	require.NoError(t, err)
+
+	var meal Meal
+	require.NoError(t, bson.Unmarshal(data, &meal))
+
+	assert.Equal(t, "Monday", meal.Day)
+	assert.Empty(t, meal.Meal)
+	assert.Equal(t, map[string]string{LegacyMealSlot: "Lasagne"}, meal.Slots)
+}
+
+func TestMealUnmarshalBSONPlan(t *testing.T) {
+	data, err := bson.Marshal(bson.D{
+		{Key: "week", Value: "2026-W42"},
+		{Key: "meals", Value: bson.A{
+			bson.D{{Key: "day", Value: "Monday"}, {Key: "meal", Value: "Curry"}},
+			bson.D{{Key: "day", Value: "Tuesday"}, {Key: "slots", Value: bson.D{{Key: "Lunch", Value: "Soup"}}}},
+		}},
+	})
+	require.NoError(t, err)
+
+	var mealPlan MealPlan
+	require.NoError(t, bson.Unmarshal(data, &mealPlan))
+
+	require.Len(t, mealPlan.Meals, 2)
+	assert.Equal(t, "Curry", mealPlan.Meals[0].SlotMeal(LegacyMealSlot))
+	assert.Equal(t, "Soup", mealPlan.Meals[1].SlotMeal("Lunch"))
+	assert.Empty(t, mealPlan.Meals[1].SlotMeal(LegacyMealSlot))
+}
+
+func TestMealNormaliseKeepsSlottedValue(t *testing.T) {
+	meal := Meal{Day: "Monday", Meal: "Old", Slots: map[string]string{LegacyMealSlot: "New"}}
+
+	meal.Normalise()
+
+	assert.Empty(t, meal.Meal)
+	assert.Equal(t, "New", meal.SlotMeal(LegacyMealSlot))
+}
+
+func TestMealSetSlotMeal(t *testing.T) {
+	meal := Meal{Day: "Monday", Meal: "Lasagne"}
+
+	meal.SetSlotMeal("Breakfast", "Porridge")
+
+	assert.Equal(t, map[string]string{"Breakfast": "Porridge", LegacyMealSlot: "Lasagne"}, meal.Slots)
+	assert.Empty(t, meal.Meal)
+}
+
+func TestValidateMealSlot(t *testing.T) {
+	for _, slot := range []string{"Breakfast", "Afternoon tea", "Kids' tea", "Pre-workout"} {
+		assert.NoError(t, ValidateMealSlot(slot), "slot %q should be valid", slot)
+	}
+	for _, slot := range []string{"", " Lunch", "$where", "meals.day", "<b>"} {
+		assert.Error(t, ValidateMealSlot(slot), "slot %q should be rejected", slot)
+	}
+}
+
+func TestMealSlotInput(t *testing.T) {
+	meal := Meal{Day: "Friday", Slots: map[string]string{"Afternoon tea": "Scones"}}
+
+	input := meal.Input("Afternoon tea")
+
+	assert.Equal(t, MealSlotInput{Day: "Friday", Slot: "Afternoon tea", Meal: "Scones"}, input)
+	assert.Equal(t, "meal-friday-afternoon-tea", input.ElementID())
+	assert.Empty(t, meal.Input("Lunch").Meal)
+
+	meal.NextSlotVersion("Afternoon tea")
+	meal.NextSlotVersion("Afternoon tea")
+	assert.Equal(t, 2, meal.Input("Afternoon tea").Version)
+	assert.Equal(t, 0, meal.SlotVersion("Lunch"))
+
+	input = PageData{Week: "2026-W42"}.MealInput(meal, "Afternoon tea")
+	assert.Equal(t, "2026-W42", input.Week)
+	assert.Equal(t, "meal-friday-afternoon-tea-2026-w42", input.EventName())
+}
+
+func TestMealSetSlotRecipe(t *testing.T) {
+	meal := Meal{Day: "Monday"}
+
+	meal.SetSlotRecipe("Dinner", "abc123")
+	assert.Equal(t, "abc123", meal.SlotRecipe("Dinner"))
+	assert.Equal(t, "abc123", meal.Input("Dinner").RecipeID)
+
+	meal.SetSlotRecipe("Dinner", "")
+	assert.Empty(t, meal.SlotRecipe("Dinner"))
+	assert.Empty(t, meal.Recipes)
+}
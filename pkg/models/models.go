@@ -1,22 +1,44 @@
 package models
 
 import (
+	"time"
+
+	"github.com/JonClarke84/mealplannergo/pkg/recipes"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// ShoppingListItem represents a single item in a shopping list
+// ShoppingListItem represents a single item in a shopping list. Item is the
+// name; Quantity, Unit and Note are empty for items saved before they existed
+// (see item.go). Category is the aisle the item is found in (see category.go).
+// Sources lists the planned meals an item generated from recipes was needed
+// for. Version counts the changes made to the item, so that an edit made to
+// an out of date copy can be detected (see conflict.go).
 type ShoppingListItem struct {
-	ID     primitive.ObjectID `bson:"_id,omitempty" json:"ID,omitempty"`
-	IDHex  string             `bson:"IDHex,omitempty" json:"IDHex,omitempty"`
-	Item   string             `bson:"Item" json:"Item"`
-	Ticked bool               `bson:"Ticked" json:"Ticked"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"ID,omitempty"`
+	IDHex    string             `bson:"IDHex,omitempty" json:"IDHex,omitempty"`
+	Item     string             `bson:"Item" json:"Item"`
+	Quantity float64            `bson:"Quantity,omitempty" json:"Quantity,omitempty"`
+	Unit     string             `bson:"Unit,omitempty" json:"Unit,omitempty"`
+	Note     string             `bson:"Note,omitempty" json:"Note,omitempty"`
+	Category string             `bson:"Category,omitempty" json:"Category,omitempty"`
+	Ticked   bool               `bson:"Ticked" json:"Ticked"`
+	Sources  []MealSource       `bson:"Sources,omitempty" json:"Sources,omitempty"`
+	Version  int                `bson:"Version,omitempty" json:"Version,omitempty"`
 }
 
-// ShoppingList represents the entire shopping list
+// ShoppingList is one of a household's named shopping lists, e.g. one for
+// the supermarket and one for the butcher. Archived lists are kept but not
+// offered for shopping. ShoppingList and SortOrder hold its items where a
+// backend stores them with the list. Version counts the changes to which items
+// are on the list and their order, and edits of the list made through the API.
 type ShoppingList struct {
-	ShoppingList []ShoppingListItem
-	ID           primitive.ObjectID
-	SortOrder    []primitive.ObjectID
+	ID           primitive.ObjectID   `bson:"_id" json:"ID"`
+	IDHex        string               `bson:"-" json:"IDHex"`
+	Name         string               `bson:"Name" json:"Name"`
+	Archived     bool                 `bson:"Archived" json:"Archived"`
+	ShoppingList []ShoppingListItem   `bson:"ShoppingList" json:"-"`
+	SortOrder    []primitive.ObjectID `bson:"SortOrder" json:"-"`
+	Version      int                  `bson:"Version,omitempty" json:"Version"`
 }
 
 // ShoppingListDocument represents how the shopping list is stored in MongoDB
@@ -26,21 +48,112 @@ type ShoppingListDocument struct {
 	SortOrder    []primitive.ObjectID `bson:"SortOrder" json:"SortOrder"`
 }
 
-// Meal represents a single meal for a day
+// Meal represents the meals for a day, keyed by slot name (see meal.go).
+// Meal holds the single value stored before days had slots; it is moved into
+// LegacyMealSlot when the day is read. Recipes maps a slot to the IDHex of
+// the recipe its meal was picked from, and Versions to the number of times
+// the slot has been changed.
 type Meal struct {
-	Day  string
-	Meal string
+	Day      string
+	Meal     string            `bson:"meal,omitempty" json:"Meal,omitempty"`
+	Slots    map[string]string `bson:"slots,omitempty" json:"Slots,omitempty"`
+	Recipes  map[string]string `bson:"recipes,omitempty" json:"Recipes,omitempty"`
+	Versions map[string]int    `bson:"versions,omitempty" json:"Versions,omitempty"`
 }
 
-// MealPlan represents a collection of meals for a period
+// WeekDays lists the days of a meal plan in display order
+var WeekDays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// MealPlan represents the meals for one ISO week
 type MealPlan struct {
-	Meals []Meal
+	Week      string    `bson:"week,omitempty" json:"week,omitempty"`
+	WeekStart time.Time `bson:"weekStart,omitempty" json:"weekStart,omitempty"`
+	Meals     []Meal
 }
 
 // PageData represents the data structure passed to the HTML template
 type PageData struct {
+	Week         string
+	WeekStart    time.Time
+	PrevWeek     string
+	NextWeek     string
+	MealSlots    []string
 	MealPlan     []Meal
 	ShoppingList []ShoppingListItem
+	Recipes      []recipes.Recipe
+	// User is who is signed in, if anyone
+	User User
+	// CanEditMeals is false for users whose role may only look at the plan
+	CanEditMeals bool
+	// ClientID names this page, so live updates are not sent back to it
+	ClientID string
+	ShoppingListView
+}
+
+// ShoppingListView is what the shopping-list template block needs to show the
+// list grouped by category in the chosen store's aisle order
+type ShoppingListView struct {
+	Groups     []CategoryGroup
+	Categories []string
+	Stores     []Store
+	Store      string
+	// List is the list being shown, and Lists all of the household's lists
+	List  ShoppingList
+	Lists []ShoppingList
+	// CanEdit is false for users whose role may only look at the list
+	CanEdit bool
+	// Undo is the change just made that the page offers to undo, if any, and
+	// UndoFailed says why undoing a change did not work
+	Undo       *Change
+	UndoFailed string
+}
+
+// ListsPageData is passed to the shopping lists template
+type ListsPageData struct {
+	Lists []ShoppingList
+	// CanEdit is false for users whose role may only look at the lists
+	CanEdit bool
+}
+
+// StorePageData is passed to the stores template
+type StorePageData struct {
+	Stores     []Store
+	Categories []string
+}
+
+// LoginPageData is passed to the sign in and register template
+type LoginPageData struct {
+	// Register shows the form for creating an account instead of signing in
+	Register  bool
+	Error     string
+	Email     string
+	Name      string
+	Household string
+	Hint      string
+	// Join shows the form for joining a household with InviteCode instead of
+	// creating one
+	Join       bool
+	InviteCode string
+}
+
+// HouseholdPageData is passed to the household template
+type HouseholdPageData struct {
+	Household Household
+	User      User
+	Members   []User
+	Roles     []Role
+	// InviteCode and InviteLink are shown once, straight after an invite is
+	// made
+	InviteCode string
+	InviteLink string
+	InviteRole Role
+}
+
+// RecipePageData is passed to the recipes template. Recipe is set when a
+// single recipe is shown, otherwise the whole library is listed.
+type RecipePageData struct {
+	Recipes []recipes.Recipe
+	Recipe  *recipes.Recipe
 }
 
 // Order represents the position of an item in the shopping list
@@ -49,7 +162,9 @@ type Order struct {
 	Position int    `json:"position"`
 }
 
-// OrderUpdate represents a collection of orders for updating positions
+// OrderUpdate represents a collection of orders for updating positions.
+// Version is the version of the list the order was made from.
 type OrderUpdate struct {
-	Order []Order `json:"order"`
+	Order   []Order `json:"order"`
+	Version *int    `json:"version"`
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package models
+
+import "strings"
+
+// MealSource identifies the planned meal a generated shopping list item was
+// needed for. Meal is the meal's text at the time, kept for display.
+type MealSource struct {
+	Week     string `bson:"week" json:"week"`
+	Day      string `bson:"day" json:"day"`
+	Slot     string `bson:"slot" json:"slot"`
+	RecipeID string `bson:"recipeId" json:"recipeId"`
+	Meal     string `bson:"meal,omitempty" json:"meal,omitempty"`
+}
+
+// SameMeal reports whether s and other refer to the same recipe in the same
+// slot, ignoring the display text
+func (s MealSource) SameMeal(other MealSource) bool {
+	return s.Week == other.Week && s.Day == other.Day && s.Slot == other.Slot && s.RecipeID == other.RecipeID
+}
+
+// HasSource reports whether the item was generated for source
+func (i ShoppingListItem) HasSource(source MealSource) bool {
+	for _, s := range i.Sources {
+		if s.SameMeal(source) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutSource returns the item's sources other than source
+func (i ShoppingListItem) WithoutSource(source MealSource) []MealSource {
+	remaining := []MealSource{}
+	for _, s := range i.Sources {
+		if !s.SameMeal(source) {
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining
+}
+
+// SourceSummary lists the meals the item was generated for, e.g.
+// "Chilli, Fish pie"
+func (i ShoppingListItem) SourceSummary() string {
+	seen := make(map[string]bool)
+	var meals []string
+	for _, s := range i.Sources {
+		if s.Meal != "" && !seen[s.Meal] {
+			seen[s.Meal] = true
+			meals = append(meals, s.Meal)
+		}
+	}
+	return strings.Join(meals, ", ")
+}
+
+// IngredientOffer offers to remove the shopping list items generated for a
+// meal that has since been changed
+type IngredientOffer struct {
+	Source MealSource
+	Items  []ShoppingListItem
+}
+
+// FindIngredientOffer returns an offer for the items generated for the given
+// slot from a recipe other than recipeID, or nil if there are none
+func FindIngredientOffer(shoppingList []ShoppingListItem, week, day, slot, recipeID string) *IngredientOffer {
+	var offer *IngredientOffer
+	for _, item := range shoppingList {
+		for _, source := range item.Sources {
+			if source.Week != week || source.Day != day || source.Slot != slot || source.RecipeID == recipeID {
+				continue
+			}
+			if offer == nil {
+				offer = &IngredientOffer{Source: source}
+			}
+			if source.SameMeal(offer.Source) {
+				offer.Items = append(offer.Items, item)
+				break
+			}
+		}
+	}
+	return offer
+}
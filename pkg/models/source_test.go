@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShoppingListItemSources(t *testing.T) {
+	chilli := MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "a", Meal: "Chilli"}
+	bolognese := MealSource{Week: "2026-W42", Day: "Friday", Slot: "Dinner", RecipeID: "b", Meal: "Bolognese"}
+	item := ShoppingListItem{Item: "500 g mince", Sources: []MealSource{chilli, bolognese}}
+
+	// The display text does not affect which meal a source refers to
+	assert.True(t, item.HasSource(MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "a"}))
+	assert.False(t, item.HasSource(MealSource{Week: "2026-W43", Day: "Monday", Slot: "Dinner", RecipeID: "a"}))
+	assert.Equal(t, []MealSource{bolognese}, item.WithoutSource(chilli))
+	assert.Equal(t, "Chilli, Bolognese", item.SourceSummary())
+}
+
+func TestFindIngredientOffer(t *testing.T) {
+	chilli := MealSource{Week: "2026-W42", Day: "Monday", Slot: "Dinner", RecipeID: "a", Meal: "Chilli"}
+	shoppingList := []ShoppingListItem{
+		{Item: "Mince", Sources: []MealSource{chilli}},
+		{Item: "Milk"},
+		{Item: "Beans", Sources: []MealSource{chilli}},
+	}
+
+	offer := FindIngredientOffer(shoppingList, "2026-W42", "Monday", "Dinner", "")
+	require.NotNil(t, offer)
+	assert.Equal(t, chilli, offer.Source)
+	assert.Len(t, offer.Items, 2)
+
+	assert.Nil(t, FindIngredientOffer(shoppingList, "2026-W42", "Monday", "Dinner", "a"), "the meal still uses the recipe")
+	assert.Nil(t, FindIngredientOffer(shoppingList, "2026-W42", "Monday", "Lunch", ""), "nothing was generated for lunch")
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// TripGap is the longest pause between ticks on the same shopping trip
+const TripGap = 2 * time.Hour
+
+// WalkOrderTrips is how many of the most recent trips the walk order is
+// learned from
+const WalkOrderTrips = 10
+
+// TickEvent records an item being ticked off the shopping list. Item is the
+// CategoryKey of the item's name, so "2 kg potatoes" and "potatoes" match.
+type TickEvent struct {
+	Item     string    `bson:"Item" json:"Item"`
+	TickedAt time.Time `bson:"TickedAt" json:"TickedAt"`
+}
+
+// SplitTrips groups tick events, sorted by time, into shopping trips: a new
+// trip starts after a pause of more than TripGap
+func SplitTrips(events []TickEvent) [][]TickEvent {
+	var trips [][]TickEvent
+	for i, event := range events {
+		if i == 0 || event.TickedAt.Sub(events[i-1].TickedAt) > TripGap {
+			trips = append(trips, nil)
+		}
+		trips[len(trips)-1] = append(trips[len(trips)-1], event)
+	}
+	return trips
+}
+
+// LearnWalkOrder derives where each item is picked up on a walk round the
+// shop from tick events sorted by time. Within each of the last
+// WalkOrderTrips trips an item's position runs from 0 for the first item
+// ticked to 1 for the last, using the last time it was ticked; the learned
+// position is the average over the trips it was ticked on. Trips with only
+// one item say nothing about order and are ignored.
+func LearnWalkOrder(events []TickEvent) map[string]float64 {
+	trips := SplitTrips(events)
+	if len(trips) > WalkOrderTrips {
+		trips = trips[len(trips)-WalkOrderTrips:]
+	}
+
+	totals := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, trip := range trips {
+		last := make(map[string]int)
+		for i, event := range trip {
+			last[event.Item] = i
+		}
+		walk := make([]string, 0, len(last))
+		for item := range last {
+			walk = append(walk, item)
+		}
+		if len(walk) < 2 {
+			continue
+		}
+		sort.Slice(walk, func(i, j int) bool { return last[walk[i]] < last[walk[j]] })
+		for i, item := range walk {
+			totals[item] += float64(i) / float64(len(walk)-1)
+			counts[item]++
+		}
+	}
+
+	order := make(map[string]float64, len(totals))
+	for item, total := range totals {
+		order[item] = total / float64(counts[item])
+	}
+	return order
+}
+
+// SortByWalkOrder returns items in the order they are picked up, according
+// to order from LearnWalkOrder. Items never ticked on a trip keep their order
+// after the rest.
+func SortByWalkOrder(items []ShoppingListItem, order map[string]float64) []ShoppingListItem {
+	sorted := append([]ShoppingListItem{}, items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		pi, iKnown := order[CategoryKey(sorted[i].Item)]
+		pj, jKnown := order[CategoryKey(sorted[j].Item)]
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return iKnown && pi < pj
+	})
+	return sorted
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trip returns tick events for items ticked a minute apart from start
+func trip(start time.Time, items ...string) []TickEvent {
+	events := []TickEvent{}
+	for i, item := range items {
+		events = append(events, TickEvent{Item: item, TickedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	return events
+}
+
+func TestSplitTrips(t *testing.T) {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	events := append(trip(start, "milk", "bread"), trip(start.Add(3*time.Hour), "eggs")...)
+
+	trips := SplitTrips(events)
+	assert.Len(t, trips, 2)
+	assert.Len(t, trips[0], 2)
+	assert.Equal(t, "eggs", trips[1][0].Item)
+	assert.Empty(t, SplitTrips(nil))
+}
+
+func TestLearnWalkOrder(t *testing.T) {
+	start := time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)
+	var events []TickEvent
+	events = append(events, trip(start, "bananas", "bread", "milk")...)
+	// Milk was ticked by mistake, then again at the end of the shop
+	events = append(events, trip(start.Add(24*time.Hour), "milk", "bananas", "eggs", "milk")...)
+	// A single item says nothing about order
+	events = append(events, trip(start.Add(48*time.Hour), "eggs")...)
+
+	order := LearnWalkOrder(events)
+	assert.Equal(t, map[string]float64{"bananas": 0, "bread": 0.5, "milk": 1, "eggs": 0.5}, order)
+	assert.Empty(t, LearnWalkOrder(nil))
+}
+
+func TestLearnWalkOrderUsesRecentTrips(t *testing.T) {
+	start := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	var events []TickEvent
+	events = append(events, trip(start, "milk", "bread")...)
+	for day := 1; day <= WalkOrderTrips; day++ {
+		events = append(events, trip(start.Add(time.Duration(day)*24*time.Hour), "bread", "milk")...)
+	}
+
+	assert.Equal(t, map[string]float64{"bread": 0, "milk": 1}, LearnWalkOrder(events))
+}
+
+func TestSortByWalkOrder(t *testing.T) {
+	items := []ShoppingListItem{{Item: "light bulbs"}, {Item: "2 l milk"}, {Item: "candles"}, {Item: "Bananas"}}
+	order := map[string]float64{"bananas": 0, "milk": 0.8}
+
+	sorted := SortByWalkOrder(items, order)
+	names := []string{}
+	for _, item := range sorted {
+		names = append(names, item.Item)
+	}
+	assert.Equal(t, []string{"Bananas", "2 l milk", "light bulbs", "candles"}, names)
+	assert.Equal(t, "light bulbs", items[0].Item, "the list passed in is not reordered")
+}
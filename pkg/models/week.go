@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// isoWeekPattern matches ISO 8601 week identifiers such as "2026-W42"
+var isoWeekPattern = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+
+// ISOWeek returns the ISO 8601 week containing t, formatted like "2026-W42"
+func ISOWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// ParseISOWeek returns midnight UTC on the Monday that starts the given week
+func ParseISOWeek(week string) (time.Time, error) {
+	match := isoWeekPattern.FindStringSubmatch(week)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("invalid week %q, expected a value like 2026-W42", week)
+	}
+	year, _ := strconv.Atoi(match[1])
+	number, _ := strconv.Atoi(match[2])
+
+	// 4th January is always in week 1, so step back to its Monday and count on
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	daysSinceMonday := (int(jan4.Weekday()) + 6) % 7
+	monday := jan4.AddDate(0, 0, -daysSinceMonday+(number-1)*7)
+
+	// Reject week numbers the year does not have (W00, W53 in a 52-week year)
+	if ISOWeek(monday) != week {
+		return time.Time{}, fmt.Errorf("invalid week %q, %d has no week %d", week, year, number)
+	}
+	return monday, nil
+}
+
+// AddWeeks returns the ISO week n weeks after week (or before, if n is negative)
+func AddWeeks(week string, n int) (string, error) {
+	monday, err := ParseISOWeek(week)
+	if err != nil {
+		return "", err
+	}
+	return ISOWeek(monday.AddDate(0, 0, 7*n)), nil
+}
+
+// NewMealPlan returns an empty meal plan for the given ISO week
+func NewMealPlan(week string) (MealPlan, error) {
+	weekStart, err := ParseISOWeek(week)
+	if err != nil {
+		return MealPlan{}, err
+	}
+
+	mealPlan := MealPlan{Week: week, WeekStart: weekStart}
+	for _, day := range WeekDays {
+		mealPlan.Meals = append(mealPlan.Meals, Meal{Day: day})
+	}
+	return mealPlan, nil
+}
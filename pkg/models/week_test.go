@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestISOWeek(t *testing.T) {
+	assert.Equal(t, "2026-W42", ISOWeek(time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)))
+	// 1st January 2027 is a Friday, so it belongs to the last week of 2026
+	assert.Equal(t, "2026-W53", ISOWeek(time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestParseISOWeek(t *testing.T) {
+	monday, err := ParseISOWeek("2026-W42")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC), monday)
+
+	// Week 1 can start in the previous calendar year
+	monday, err = ParseISOWeek("2026-W01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.December, 29, 0, 0, 0, 0, time.UTC), monday)
+}
+
+func TestParseISOWeekInvalid(t *testing.T) {
+	for _, week := range []string{"", "2026-42", "2026-W4", "2026-W00", "2025-W53", "next week"} {
+		_, err := ParseISOWeek(week)
+		assert.Error(t, err, "week %q should be rejected", week)
+	}
+}
+
+func TestAddWeeks(t *testing.T) {
+	next, err := AddWeeks("2026-W53", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "2027-W01", next)
+
+	prev, err := AddWeeks("2026-W01", -1)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-W52", prev)
+
+	_, err = AddWeeks("bad", 1)
+	assert.Error(t, err)
+}
+
+func TestNewMealPlan(t *testing.T) {
+	mealPlan, err := NewMealPlan("2026-W42")
+	require.NoError(t, err)
+
+	assert.Equal(t, "2026-W42", mealPlan.Week)
+	assert.Equal(t, time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC), mealPlan.WeekStart)
+	require.Len(t, mealPlan.Meals, 7)
+	assert.Equal(t, "Monday", mealPlan.Meals[0].Day)
+	assert.Equal(t, "Sunday", mealPlan.Meals[6].Day)
+}
@@ -0,0 +1,306 @@
+// Package openapi describes an HTTP API as an OpenAPI 3 document, taking the
+// JSON schemas of request and response bodies from Go types, and checks
+// requests against the document.
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of OpenAPI the documents are written in
+const Version = "3.0.3"
+
+// Document is an OpenAPI document. Only the parts this app uses are
+// modelled.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations on one path, by lower case method
+type PathItem map[string]*Operation
+
+// Operation is one method on one path. Security is nil for operations that
+// need the document's security, and empty for public ones.
+type Operation struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary"`
+	Parameters  []Parameter            `json:"parameters,omitempty"`
+	RequestBody *RequestBody           `json:"requestBody,omitempty"`
+	Responses   map[string]Response    `json:"responses"`
+	Security    *[]map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the JSON body an operation takes
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one of the responses an operation gives
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas that others refer to by name, and how
+// requests are authenticated
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way of authenticating requests
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+// Schema is a JSON schema, as OpenAPI 3.0 writes them. Ref names a schema in
+// the document's components. AdditionalProperties is false for objects that
+// may not have properties other than those listed.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// refPrefix starts the Ref of every component schema
+const refPrefix = "#/components/schemas/"
+
+// Builder builds a Document route by route
+type Builder struct {
+	doc Document
+}
+
+// NewBuilder starts a document for the API named title, whose operations
+// need a bearer token unless they are added as public
+func NewBuilder(title, description, version string) *Builder {
+	return &Builder{doc: Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Description: description, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{"bearer": {Type: "http", Scheme: "bearer"}},
+		},
+		Security: []map[string][]string{{"bearer": {}}},
+	}}
+}
+
+// Route describes one operation for Add. Request and the values of
+// Responses are example values of the Go types of the bodies, or nil for no
+// body. Params gives the schema of each path parameter.
+type Route struct {
+	Method    string
+	Path      string
+	Name      string
+	Summary   string
+	Public    bool
+	Params    map[string]*Schema
+	Request   any
+	Responses map[int]any
+}
+
+// Add adds the operation described by route
+func (b *Builder) Add(route Route) {
+	op := &Operation{
+		OperationID: route.Name,
+		Summary:     route.Summary,
+		Responses:   make(map[string]Response),
+	}
+	for _, name := range PathParams(route.Path) {
+		schema := route.Params[name]
+		if schema == nil {
+			schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: b.SchemaOf(route.Request, true)}},
+		}
+	}
+	for status, body := range route.Responses {
+		response := Response{Description: statusDescription(status)}
+		if body != nil {
+			response.Content = map[string]MediaType{"application/json": {Schema: b.SchemaOf(body, false)}}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+	}
+	if route.Public {
+		op.Security = &[]map[string][]string{}
+	}
+
+	item := b.doc.Paths[route.Path]
+	if item == nil {
+		item = make(PathItem)
+		b.doc.Paths[route.Path] = item
+	}
+	item[strings.ToLower(route.Method)] = op
+}
+
+// Document returns the document built so far
+func (b *Builder) Document() *Document {
+	return &b.doc
+}
+
+// SchemaOf returns the schema of v's type. Named struct types are added to
+// the components, named without any "API" prefix, and referred to. Request
+// bodies are strict: they may not have properties their type does not have.
+func (b *Builder) SchemaOf(v any, strict bool) *Schema {
+	return b.schemaOf(reflect.TypeOf(v), strict)
+}
+
+func (b *Builder) schemaOf(t reflect.Type, strict bool) *Schema {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := b.schemaOf(t.Elem(), strict)
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schemaOf(t.Elem(), strict)}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		name := strings.TrimPrefix(t.Name(), "API")
+		if name == "" {
+			return b.structSchema(t, strict)
+		}
+		if _, ok := b.doc.Components.Schemas[name]; !ok {
+			// Added before its fields, in case they refer back to it
+			b.doc.Components.Schemas[name] = &Schema{}
+			*b.doc.Components.Schemas[name] = *b.structSchema(t, strict)
+		}
+		return &Schema{Ref: refPrefix + name}
+	}
+	// Interfaces, such as any, may hold anything
+	return &Schema{}
+}
+
+// structSchema returns the schema of an object with t's exported JSON
+// fields. Fields that are not omitempty and not pointers are required.
+func (b *Builder) structSchema(t reflect.Type, strict bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = b.schemaOf(field.Type, strict)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer && field.Type.Kind() != reflect.Interface {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+	if strict {
+		closed := false
+		schema.AdditionalProperties = &closed
+	}
+	return schema
+}
+
+// PathParams returns the names of the {parameters} of a path, in order
+func PathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"))
+		}
+	}
+	return names
+}
+
+// Operation returns the operation for method on path, or nil
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][strings.ToLower(method)]
+}
+
+// Resolve returns the component schema s refers to, or s itself
+func (d *Document) Resolve(s *Schema) (*Schema, error) {
+	if s == nil || s.Ref == "" {
+		return s, nil
+	}
+	resolved, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %s", s.Ref)
+	}
+	return resolved, nil
+}
+
+// statusDescription describes a response by its status
+func statusDescription(status int) string {
+	switch status {
+	case 200:
+		return "OK"
+	case 201:
+		return "Created"
+	case 204:
+		return "No content"
+	case 400:
+		return "The request is not valid"
+	case 401:
+		return "Not signed in"
+	case 403:
+		return "The user's role does not allow this"
+	case 404:
+		return "Not found"
+	case 409:
+		return "Changed since it was read; nothing was saved"
+	}
+	return "Status " + strconv.Itoa(status)
+}
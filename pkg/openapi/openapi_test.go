@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTag struct {
+	Name string `json:"name"`
+}
+
+type APITestInput struct {
+	Title    string    `json:"title"`
+	Count    *int      `json:"count"`
+	Price    float64   `json:"price,omitempty"`
+	Tags     []testTag `json:"tags"`
+	Extra    any       `json:"extra,omitempty"`
+	Internal string    `json:"-"`
+	hidden   string
+}
+
+func TestSchemaOf(t *testing.T) {
+	builder := NewBuilder("Test", "", "1")
+	schema := builder.SchemaOf(APITestInput{}, true)
+	assert.Equal(t, "#/components/schemas/TestInput", schema.Ref, "named without the API prefix")
+
+	doc := builder.Document()
+	input := doc.Components.Schemas["TestInput"]
+	require.NotNil(t, input)
+	assert.Equal(t, "object", input.Type)
+	assert.Equal(t, []string{"tags", "title"}, input.Required)
+	assert.Len(t, input.Properties, 5)
+	assert.Equal(t, &Schema{Type: "integer", Nullable: true}, input.Properties["count"])
+	assert.Equal(t, &Schema{Type: "number"}, input.Properties["price"])
+	assert.Equal(t, "#/components/schemas/testTag", input.Properties["tags"].Items.Ref)
+	assert.Equal(t, &Schema{}, input.Properties["extra"])
+	require.NotNil(t, input.AdditionalProperties)
+	assert.False(t, *input.AdditionalProperties)
+
+	list := builder.SchemaOf([]testTag{}, false)
+	assert.Equal(t, "array", list.Type)
+}
+
+func TestAdd(t *testing.T) {
+	builder := NewBuilder("Test", "", "1")
+	builder.Add(Route{Method: "POST", Path: "/things/{id}", Name: "addThing", Public: true,
+		Params: map[string]*Schema{"id": {Type: "string", Pattern: "^[0-9]+$"}}, Request: APITestInput{},
+		Responses: map[int]any{201: testTag{}, 204: nil}})
+	doc := builder.Document()
+
+	op := doc.Operation("POST", "/things/{id}")
+	require.NotNil(t, op)
+	assert.Equal(t, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: "^[0-9]+$"}}}, op.Parameters)
+	assert.Equal(t, "Created", op.Responses["201"].Description)
+	assert.Nil(t, op.Responses["204"].Content)
+	require.NotNil(t, op.Security)
+	assert.Empty(t, *op.Security, "public")
+	assert.Nil(t, doc.Operation("GET", "/things/{id}"))
+
+	encoded, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"openapi":"3.0.3"`)
+	assert.Contains(t, string(encoded), `"$ref":"#/components/schemas/TestInput"`)
+}
+
+func TestValidate(t *testing.T) {
+	builder := NewBuilder("Test", "", "1")
+	schema := builder.SchemaOf(APITestInput{}, true)
+	doc := builder.Document()
+
+	tests := []struct {
+		name string
+		body string
+		err  string
+	}{
+		{"valid", `{"title": "Soup", "count": 2, "price": 1.5, "tags": [{"name": "hot"}], "extra": [1, "a"]}`, ""},
+		{"null pointer", `{"title": "Soup", "count": null, "tags": []}`, ""},
+		{"missing field", `{"tags": []}`, "body.title is required"},
+		{"null field", `{"title": null, "tags": []}`, "body.title must not be null"},
+		{"wrong type", `{"title": "Soup", "tags": {}}`, "body.tags must be an array"},
+		{"fraction", `{"title": "Soup", "count": 2.5, "tags": []}`, "body.count must be an integer"},
+		{"nested", `{"title": "Soup", "tags": [{"name": 1}]}`, "body.tags[0].name must be a string"},
+		{"unknown field", `{"title": "Soup", "tags": [], "colour": "red"}`, `body has unknown field "colour"`},
+		{"not an object", `"Soup"`, "body must be an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(tt.body))
+			decoder.UseNumber()
+			var value any
+			require.NoError(t, decoder.Decode(&value))
+
+			err := doc.Validate(value, schema, "body")
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+
+	assert.NoError(t, doc.Validate("Monday", &Schema{Type: "string", Enum: []string{"Monday"}}, "day"))
+	assert.EqualError(t, doc.Validate("Someday", &Schema{Type: "string", Enum: []string{"Monday"}}, "day"), "day must be one of [Monday]")
+	assert.EqualError(t, doc.Validate("x", &Schema{Ref: "#/components/schemas/Missing"}, "x"), "unknown schema #/components/schemas/Missing")
+}
@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// maxBodySize is the largest request body that is read for validation
+const maxBodySize = 1 << 20
+
+// ValidateRequest checks r's path parameters and JSON body against op,
+// returning an error saying what does not match. The body is put back for the
+// handler to read.
+func (d *Document) ValidateRequest(op *Operation, r *http.Request) error {
+	for _, param := range op.Parameters {
+		if param.In != "path" {
+			continue
+		}
+		if err := d.Validate(r.PathValue(param.Name), param.Schema, param.Name); err != nil {
+			return err
+		}
+	}
+	if op.RequestBody == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return fmt.Errorf("could not read body: %w", err)
+	}
+	if len(body) > maxBodySize {
+		return fmt.Errorf("body is larger than %d bytes", maxBodySize)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		if err == io.EOF && !op.RequestBody.Required {
+			return nil
+		}
+		return fmt.Errorf("body is not JSON: %w", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("body has more than one JSON value")
+	}
+	return d.Validate(value, op.RequestBody.Content["application/json"].Schema, "body")
+}
+
+// Validate checks value, as decoded from JSON with numbers kept as
+// json.Number, against schema. Errors name the part of the value at fault,
+// starting from at.
+func (d *Document) Validate(value any, schema *Schema, at string) error {
+	schema, err := d.Resolve(schema)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", at)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", at)
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s.%s is required", at, name)
+			}
+		}
+		// Checked in order, so the same body always gives the same error
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fmt.Errorf("%s has unknown field %q", at, name)
+				}
+				continue
+			}
+			if err := d.Validate(object[name], property, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", at)
+		}
+		for i, element := range array {
+			if err := d.Validate(element, schema.Items, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", at)
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			return fmt.Errorf("%s must be one of %v", at, schema.Enum)
+		}
+		if schema.Pattern != "" {
+			pattern, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s has an invalid pattern: %w", at, err)
+			}
+			if !pattern.MatchString(s) {
+				return fmt.Errorf("%s must match %s", at, schema.Pattern)
+			}
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			return fmt.Errorf("%s must be an integer", at)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s must be a number", at)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", at)
+		}
+	}
+	return nil
+}
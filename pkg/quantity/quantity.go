@@ -0,0 +1,252 @@
+// Package quantity reads amounts such as "2kg potatoes", "milk x3" or
+// "½ tsp cumin" out of free text, and formats them back.
+package quantity
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parsed is free text split into its parts. Quantity is 0 when no amount
+// was given.
+type Parsed struct {
+	Name     string
+	Quantity float64
+	Unit     string
+	Note     string
+}
+
+// units maps the spellings recognised after a quantity to a canonical unit
+var units = map[string]string{
+	"g": "g", "gram": "g", "grams": "g",
+	"kg": "kg", "kilo": "kg", "kilos": "kg",
+	"ml": "ml", "l": "l", "litre": "l", "litres": "l", "liter": "l", "liters": "l",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"oz": "oz", "lb": "lb", "lbs": "lb",
+	"pint": "pint", "pints": "pint", "pt": "pint",
+	"tin": "tin", "tins": "tin", "can": "tin", "cans": "tin",
+	"pack": "pack", "packs": "pack", "bag": "bag", "bags": "bag",
+	"jar": "jar", "jars": "jar", "bottle": "bottle", "bottles": "bottle",
+	"bunch": "bunch", "bunches": "bunch",
+	"clove": "clove", "cloves": "clove",
+	"pinch": "pinch", "handful": "handful",
+}
+
+// fractions maps the single-character vulgar fractions to their value
+var fractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅕': 1.0 / 5, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+// Unit returns the canonical spelling of unit, and whether it is known
+func Unit(unit string) (string, bool) {
+	canonical, known := units[strings.ToLower(unit)]
+	return canonical, known
+}
+
+// Parse splits text into a name, quantity, unit and note. It understands a
+// leading amount with an optional unit ("2kg potatoes", "½ tsp cumin",
+// "1 1/2 cups flour"), a count before or after the name ("3 x lemons",
+// "milk x3", "2 x 1L milk" is 2 l of milk) and a note in trailing brackets or
+// after a comma ("bread (wholemeal)", "apples, for the crumble").
+func Parse(text string) Parsed {
+	var parsed Parsed
+	text, parsed.Note = splitNote(strings.TrimSpace(text))
+	fields := strings.Fields(text)
+
+	count := 1.0
+	hasCount := false
+	fields, count, hasCount = trailingCount(fields)
+	if !hasCount {
+		fields, count, hasCount = leadingCount(fields)
+	}
+
+	rest := fields
+	if len(fields) > 0 {
+		if amount, unit, ok := parseAmount(fields[0]); ok {
+			parsed.Quantity, parsed.Unit = amount, unit
+			rest = fields[1:]
+			// A mixed number such as "1 1/2"
+			if len(rest) > 0 && amount == math.Trunc(amount) && strings.Contains(rest[0], "/") && unit == "" {
+				if part, partUnit, ok := parseAmount(rest[0]); ok && part < 1 {
+					parsed.Quantity += part
+					parsed.Unit = partUnit
+					rest = rest[1:]
+				}
+			}
+			if parsed.Unit == "" && len(rest) > 1 {
+				if unit, known := Unit(rest[0]); known {
+					parsed.Unit = unit
+					rest = rest[1:]
+				}
+			}
+		}
+	}
+
+	if len(rest) == 0 {
+		// Nothing is left for the name, e.g. "3 cloves": read it all as the name
+		parsed.Name = strings.Join(fields, " ")
+		if len(fields) > 1 {
+			parsed.Name = strings.Join(fields[1:], " ")
+			parsed.Unit = ""
+		} else {
+			parsed.Quantity, parsed.Unit = 0, ""
+		}
+	} else {
+		parsed.Name = strings.Join(rest, " ")
+	}
+
+	if hasCount {
+		if parsed.Quantity == 0 {
+			parsed.Quantity = count
+		} else {
+			parsed.Quantity *= count
+		}
+	}
+	if parsed.Name == "" {
+		return Parsed{Note: parsed.Note}
+	}
+	return parsed
+}
+
+// splitNote separates a note in trailing brackets, or after the first comma
+func splitNote(text string) (string, string) {
+	if strings.HasSuffix(text, ")") {
+		if open := strings.LastIndex(text, "("); open > 0 {
+			return strings.TrimSpace(text[:open]), strings.TrimSpace(text[open+1 : len(text)-1])
+		}
+	}
+	if before, after, found := strings.Cut(text, ","); found && strings.TrimSpace(before) != "" {
+		return strings.TrimSpace(before), strings.TrimSpace(after)
+	}
+	return text, ""
+}
+
+// trailingCount reads a count after the name: "milk x3" or "milk x 3"
+func trailingCount(fields []string) ([]string, float64, bool) {
+	n := len(fields)
+	if n >= 2 {
+		if count, ok := countSuffix(fields[n-1]); ok {
+			return fields[:n-1], count, true
+		}
+	}
+	if n >= 3 && isTimes(fields[n-2]) {
+		if count, err := strconv.ParseFloat(fields[n-1], 64); err == nil && count > 0 {
+			return fields[:n-2], count, true
+		}
+	}
+	return fields, 1, false
+}
+
+// leadingCount reads a count before the name: "3x lemons" or "3 x lemons"
+func leadingCount(fields []string) ([]string, float64, bool) {
+	if len(fields) >= 2 {
+		if number, ok := strings.CutSuffix(strings.ToLower(fields[0]), "x"); ok {
+			if count, err := strconv.ParseFloat(number, 64); err == nil && count > 0 {
+				return fields[1:], count, true
+			}
+		}
+	}
+	if len(fields) >= 3 && isTimes(fields[1]) {
+		if count, err := strconv.ParseFloat(fields[0], 64); err == nil && count > 0 {
+			return fields[2:], count, true
+		}
+	}
+	return fields, 1, false
+}
+
+// countSuffix reads "x3" or "×3"
+func countSuffix(field string) (float64, bool) {
+	r, size := utf8.DecodeRuneInString(field)
+	if r != 'x' && r != 'X' && r != '×' {
+		return 0, false
+	}
+	count, err := strconv.ParseFloat(field[size:], 64)
+	return count, err == nil && count > 0
+}
+
+// isTimes reports whether field is a multiplication sign
+func isTimes(field string) bool {
+	return field == "x" || field == "X" || field == "×"
+}
+
+// parseAmount reads a number, optionally followed directly by a known unit
+// ("200g", "1L"). Decimals, simple fractions ("1/2") and vulgar fractions,
+// alone or after a whole number ("½", "1½"), are accepted.
+func parseAmount(field string) (float64, string, bool) {
+	end := 0
+	for end < len(field) && (field[end] >= '0' && field[end] <= '9' || field[end] == '.' || field[end] == '/') {
+		end++
+	}
+	number, suffix := field[:end], field[end:]
+
+	amount := 0.0
+	if number != "" {
+		var ok bool
+		if amount, ok = parseNumber(number); !ok {
+			return 0, "", false
+		}
+	}
+	if r, size := utf8.DecodeRuneInString(suffix); size > 0 {
+		if fraction, isFraction := fractions[r]; isFraction {
+			amount += fraction
+			suffix = suffix[size:]
+			number += string(r)
+		}
+	}
+	if number == "" {
+		return 0, "", false
+	}
+
+	if suffix == "" {
+		return amount, "", true
+	}
+	unit, known := Unit(suffix)
+	if !known {
+		return 0, "", false
+	}
+	return amount, unit, true
+}
+
+// parseNumber reads a decimal or a simple fraction such as "3/4"
+func parseNumber(number string) (float64, bool) {
+	if numerator, denominator, isFraction := strings.Cut(number, "/"); isFraction {
+		n, err1 := strconv.ParseFloat(numerator, 64)
+		d, err2 := strconv.ParseFloat(denominator, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	return value, err == nil
+}
+
+// Format returns an amount as text, e.g. "2 kg" or "3", or "" when quantity
+// is 0. Quantities are rounded to three decimal places.
+func Format(quantity float64, unit string) string {
+	if quantity == 0 {
+		return ""
+	}
+	text := strconv.FormatFloat(math.Round(quantity*1000)/1000, 'f', -1, 64)
+	if unit != "" {
+		text += " " + unit
+	}
+	return text
+}
+
+// Text formats parts the way Parse reads them, e.g. "2 kg potatoes (new)"
+func Text(name string, quantity float64, unit string, note string) string {
+	text := name
+	if amount := Format(quantity, unit); amount != "" {
+		text = amount + " " + name
+	}
+	if note != "" {
+		text += " (" + note + ")"
+	}
+	return text
+}
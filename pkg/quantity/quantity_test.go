@@ -0,0 +1,63 @@
+package quantity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]Parsed{
+		"milk":                      {Name: "milk"},
+		"  Oat milk ":               {Name: "Oat milk"},
+		"2kg potatoes":              {Name: "potatoes", Quantity: 2, Unit: "kg"},
+		"2 kg potatoes":             {Name: "potatoes", Quantity: 2, Unit: "kg"},
+		"500 grams mince":           {Name: "mince", Quantity: 500, Unit: "g"},
+		"milk x3":                   {Name: "milk", Quantity: 3},
+		"milk x 3":                  {Name: "milk", Quantity: 3},
+		"3x lemons":                 {Name: "lemons", Quantity: 3},
+		"3 x lemons":                {Name: "lemons", Quantity: 3},
+		"2 x 1L milk":               {Name: "milk", Quantity: 2, Unit: "l"},
+		"2 tins tomatoes x2":        {Name: "tomatoes", Quantity: 4, Unit: "tin"},
+		"½ tsp cumin":               {Name: "cumin", Quantity: 0.5, Unit: "tsp"},
+		"1½ cups flour":             {Name: "flour", Quantity: 1.5, Unit: "cup"},
+		"1 1/2 cups flour":          {Name: "flour", Quantity: 1.5, Unit: "cup"},
+		"3/4 pint cream":            {Name: "cream", Quantity: 0.75, Unit: "pint"},
+		"6 eggs":                    {Name: "eggs", Quantity: 6},
+		"3 cloves":                  {Name: "cloves", Quantity: 3},
+		"bread (wholemeal)":         {Name: "bread", Note: "wholemeal"},
+		"2 apples, for the crumble": {Name: "apples", Quantity: 2, Note: "for the crumble"},
+		"7up":                       {Name: "7up"},
+		"2 x 1L":                    {Name: "1L", Quantity: 2},
+		"":                          {},
+		"(just a note)":             {Name: "(just a note)"},
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, Parse(text), "text %q", text)
+	}
+}
+
+func TestUnit(t *testing.T) {
+	unit, known := Unit("Litres")
+	assert.True(t, known)
+	assert.Equal(t, "l", unit)
+
+	_, known = Unit("potatoes")
+	assert.False(t, known)
+}
+
+func TestFormatAndText(t *testing.T) {
+	assert.Equal(t, "", Format(0, "kg"))
+	assert.Equal(t, "2 kg", Format(2, "kg"))
+	assert.Equal(t, "3", Format(3, ""))
+	assert.Equal(t, "0.333 cup", Format(1.0/3, "cup"))
+
+	assert.Equal(t, "milk", Text("milk", 0, "", ""))
+	assert.Equal(t, "2 kg potatoes (new)", Text("potatoes", 2, "kg", "new"))
+
+	// Text is read back by Parse
+	for _, text := range []string{"2 kg potatoes (new)", "3 lemons", "0.5 tsp cumin", "bread"} {
+		parsed := Parse(text)
+		assert.Equal(t, text, Text(parsed.Name, parsed.Quantity, parsed.Unit, parsed.Note))
+	}
+}
@@ -0,0 +1,218 @@
+// Package recipes defines the recipe library: recipes with their
+// ingredients, method and tags, and how they are read from form input.
+package recipes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/JonClarke84/mealplannergo/pkg/quantity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ingredient is one line of a recipe's ingredient list, e.g. 200 g mince
+type Ingredient struct {
+	Name     string  `bson:"Name" json:"Name"`
+	Quantity float64 `bson:"Quantity,omitempty" json:"Quantity,omitempty"`
+	Unit     string  `bson:"Unit,omitempty" json:"Unit,omitempty"`
+}
+
+// Recipe is a stored recipe that meals can refer to
+type Recipe struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"ID,omitempty"`
+	IDHex       string             `bson:"IDHex,omitempty" json:"IDHex,omitempty"`
+	Title       string             `bson:"Title" json:"Title"`
+	Servings    int                `bson:"Servings" json:"Servings"`
+	Ingredients []Ingredient       `bson:"Ingredients" json:"Ingredients"`
+	Method      []string           `bson:"Method" json:"Method"`
+	Tags        []string           `bson:"Tags" json:"Tags"`
+}
+
+// Validate returns an error describing the first problem with the recipe
+func (r Recipe) Validate() error {
+	if strings.TrimSpace(r.Title) == "" {
+		return fmt.Errorf("recipe title cannot be empty")
+	}
+	if r.Servings < 0 {
+		return fmt.Errorf("servings cannot be negative")
+	}
+	for _, ingredient := range r.Ingredients {
+		if ingredient.Name == "" {
+			return fmt.Errorf("ingredient name cannot be empty")
+		}
+	}
+	return nil
+}
+
+// String formats an ingredient the way ParseIngredient reads it
+func (i Ingredient) String() string {
+	if i.Quantity == 0 {
+		return i.Name
+	}
+	return quantity.Format(i.Quantity, i.Unit) + " " + i.Name
+}
+
+// ParseIngredient reads a line such as "200 g mince", "200g mince",
+// "2 onions" or "salt" into an Ingredient
+func ParseIngredient(line string) Ingredient {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Ingredient{}
+	}
+
+	amount, unit, ok := splitQuantity(fields[0])
+	if !ok {
+		return Ingredient{Name: strings.Join(fields, " ")}
+	}
+	rest := fields[1:]
+	if unit == "" && len(rest) > 1 {
+		if u, known := quantity.Unit(rest[0]); known {
+			unit = u
+			rest = rest[1:]
+		}
+	}
+	if len(rest) == 0 {
+		return Ingredient{Name: strings.Join(fields, " ")}
+	}
+	return Ingredient{Name: strings.Join(rest, " "), Quantity: amount, Unit: unit}
+}
+
+// splitQuantity reads a leading number, optionally followed directly by a
+// known unit ("200g"). Simple fractions such as "1/2" are accepted.
+func splitQuantity(field string) (float64, string, bool) {
+	end := 0
+	for end < len(field) && (field[end] >= '0' && field[end] <= '9' || field[end] == '.' || field[end] == '/') {
+		end++
+	}
+	if end == 0 {
+		return 0, "", false
+	}
+
+	number, suffix := field[:end], strings.ToLower(field[end:])
+	unit := ""
+	if suffix != "" {
+		u, known := quantity.Unit(suffix)
+		if !known {
+			return 0, "", false
+		}
+		unit = u
+	}
+
+	if numerator, denominator, isFraction := strings.Cut(number, "/"); isFraction {
+		n, err1 := strconv.ParseFloat(numerator, 64)
+		d, err2 := strconv.ParseFloat(denominator, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, "", false
+		}
+		return n / d, unit, true
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return value, unit, true
+}
+
+// ParseIngredients reads one ingredient per non-blank line
+func ParseIngredients(text string) []Ingredient {
+	ingredients := []Ingredient{}
+	for _, line := range strings.Split(text, "\n") {
+		if ingredient := ParseIngredient(line); ingredient.Name != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	return ingredients
+}
+
+// ParseMethod reads one method step per non-blank line
+func ParseMethod(text string) []string {
+	steps := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if step := strings.TrimSpace(line); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// ParseTags reads comma-separated tags, lower-cased, de-duplicated and sorted
+func ParseTags(text string) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// FromForm builds a recipe from the fields of the recipe form
+func FromForm(title, servings, ingredients, method, tags string) (Recipe, error) {
+	recipe := Recipe{
+		Title:       strings.TrimSpace(title),
+		Servings:    1,
+		Ingredients: ParseIngredients(ingredients),
+		Method:      ParseMethod(method),
+		Tags:        ParseTags(tags),
+	}
+	if servings = strings.TrimSpace(servings); servings != "" {
+		n, err := strconv.Atoi(servings)
+		if err != nil {
+			return recipe, fmt.Errorf("servings must be a whole number")
+		}
+		recipe.Servings = n
+	}
+	return recipe, recipe.Validate()
+}
+
+// Clone returns a copy of the recipe that shares no slices with r
+func (r Recipe) Clone() Recipe {
+	r.Ingredients = append([]Ingredient{}, r.Ingredients...)
+	r.Method = append([]string{}, r.Method...)
+	r.Tags = append([]string{}, r.Tags...)
+	return r
+}
+
+// IngredientsText formats the ingredients one per line, for editing
+func (r Recipe) IngredientsText() string {
+	lines := make([]string, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		lines[i] = ingredient.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MethodText formats the method one step per line, for editing
+func (r Recipe) MethodText() string {
+	return strings.Join(r.Method, "\n")
+}
+
+// TagsText formats the tags as a comma-separated list, for editing
+func (r Recipe) TagsText() string {
+	return strings.Join(r.Tags, ", ")
+}
+
+// SortByTitle sorts recipes alphabetically by title, ignoring case
+func SortByTitle(list []Recipe) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return strings.ToLower(list[i].Title) < strings.ToLower(list[j].Title)
+	})
+}
+
+// FindByTitle returns the recipe whose title matches title, ignoring case and
+// surrounding spaces
+func FindByTitle(list []Recipe, title string) (Recipe, bool) {
+	title = strings.TrimSpace(title)
+	for _, recipe := range list {
+		if title != "" && strings.EqualFold(recipe.Title, title) {
+			return recipe, true
+		}
+	}
+	return Recipe{}, false
+}
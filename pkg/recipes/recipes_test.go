@@ -0,0 +1,75 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIngredient(t *testing.T) {
+	cases := map[string]Ingredient{
+		"200 g mince":        {Name: "mince", Quantity: 200, Unit: "g"},
+		"200g mince":         {Name: "mince", Quantity: 200, Unit: "g"},
+		"1 Tin chopped toms": {Name: "chopped toms", Quantity: 1, Unit: "tin"},
+		"1/2 tsp chilli":     {Name: "chilli", Quantity: 0.5, Unit: "tsp"},
+		"2 onions":           {Name: "onions", Quantity: 2},
+		"  salt  ":           {Name: "salt"},
+		"3 cloves":           {Name: "cloves", Quantity: 3},
+		"2x4 timber":         {Name: "2x4 timber"},
+		"":                   {},
+	}
+	for line, want := range cases {
+		assert.Equal(t, want, ParseIngredient(line), "line %q", line)
+	}
+}
+
+func TestIngredientStringRoundTrip(t *testing.T) {
+	for _, line := range []string{"200 g mince", "2 onions", "salt", "0.5 tsp chilli"} {
+		assert.Equal(t, line, ParseIngredient(line).String())
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"quick", "vegetarian"}, ParseTags("Vegetarian, quick,, vegetarian "))
+	assert.Empty(t, ParseTags(""))
+}
+
+func TestFromForm(t *testing.T) {
+	recipe, err := FromForm(" Chilli ", "4", "500g mince\n\n1 tin kidney beans\n", "Brown the mince\r\n Simmer ", "spicy, batch")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Chilli", recipe.Title)
+	assert.Equal(t, 4, recipe.Servings)
+	assert.Equal(t, []Ingredient{
+		{Name: "mince", Quantity: 500, Unit: "g"},
+		{Name: "kidney beans", Quantity: 1, Unit: "tin"},
+	}, recipe.Ingredients)
+	assert.Equal(t, []string{"Brown the mince", "Simmer"}, recipe.Method)
+	assert.Equal(t, []string{"batch", "spicy"}, recipe.Tags)
+}
+
+func TestFromFormInvalid(t *testing.T) {
+	_, err := FromForm("", "4", "", "", "")
+	assert.Error(t, err, "a title is required")
+
+	_, err = FromForm("Chilli", "lots", "", "", "")
+	assert.Error(t, err, "servings must be a number")
+
+	recipe, err := FromForm("Chilli", "", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, recipe.Servings, "servings default to one")
+}
+
+func TestFindByTitle(t *testing.T) {
+	library := []Recipe{{IDHex: "a", Title: "Chilli"}, {IDHex: "b", Title: "Fish pie"}}
+
+	recipe, found := FindByTitle(library, " fish PIE ")
+	assert.True(t, found)
+	assert.Equal(t, "b", recipe.IDHex)
+
+	_, found = FindByTitle(library, "Fish")
+	assert.False(t, found)
+	_, found = FindByTitle(library, "")
+	assert.False(t, found)
+}
@@ -0,0 +1,144 @@
+// Package units converts between metric, imperial and cooking measures, and
+// adds amounts given in different units. Units are the canonical spellings
+// used by the quantity package, e.g. "g", "tbsp" or "cup".
+package units
+
+import "strings"
+
+// Dimension is what a unit measures
+type Dimension int
+
+const (
+	// Count is a number of things: no unit, or packaging such as tins
+	Count Dimension = iota
+	Mass
+	Volume
+)
+
+// unit describes a measurable unit. size is in grams for Mass and
+// millilitres for Volume; family lists the units a total is shown in, from
+// smallest to largest.
+type unit struct {
+	dimension Dimension
+	size      float64
+	family    []string
+}
+
+var (
+	metricMass     = []string{"g", "kg"}
+	metricVolume   = []string{"ml", "l"}
+	imperialMass   = []string{"oz", "lb"}
+	imperialVolume = []string{"pint"}
+	cooking        = []string{"tsp", "tbsp", "cup"}
+)
+
+// measures are the units that can be converted. Cups are metric cups; any
+// other unit, such as "tin" or "clove", only adds to the same unit.
+var measures = map[string]unit{
+	"g":    {Mass, 1, metricMass},
+	"kg":   {Mass, 1000, metricMass},
+	"oz":   {Mass, 28.349523125, imperialMass},
+	"lb":   {Mass, 453.59237, imperialMass},
+	"ml":   {Volume, 1, metricVolume},
+	"l":    {Volume, 1000, metricVolume},
+	"pint": {Volume, 568.26125, imperialVolume},
+	"tsp":  {Volume, 5, cooking},
+	"tbsp": {Volume, 15, cooking},
+	"cup":  {Volume, 250, cooking},
+}
+
+// densities are approximate grams per millilitre, used to convert between
+// mass and volume for common ingredients
+var densities = map[string]float64{
+	"water": 1, "milk": 1.03, "cream": 1.01, "yoghurt": 1.03, "yogurt": 1.03,
+	"stock": 1, "oil": 0.92, "butter": 0.96, "honey": 1.42, "syrup": 1.37,
+	"flour": 0.53, "sugar": 0.85, "salt": 1.2, "rice": 0.85, "oats": 0.41,
+	"cocoa": 0.42, "breadcrumbs": 0.25, "cheese": 0.45, "peas": 0.6,
+}
+
+// Amount is a quantity in a unit
+type Amount struct {
+	Quantity float64
+	Unit     string
+}
+
+// DimensionOf returns what unit measures. Unknown units, and no unit, are
+// counts.
+func DimensionOf(unitName string) Dimension {
+	if m, known := measures[unitName]; known {
+		return m.dimension
+	}
+	return Count
+}
+
+// Density returns the grams per millilitre of ingredient, matching either
+// its whole name or its last word ("plain flour" is flour), and whether one
+// is known
+func Density(ingredient string) (float64, bool) {
+	name := strings.ToLower(strings.TrimSpace(ingredient))
+	if density, known := densities[name]; known {
+		return density, true
+	}
+	if words := strings.Fields(name); len(words) > 1 {
+		density, known := densities[words[len(words)-1]]
+		return density, known
+	}
+	return 0, false
+}
+
+// Convert returns quantity in from as a quantity in to. Mass and volume are
+// converted using the density of ingredient when it is known. It reports
+// false when the units cannot be converted.
+func Convert(quantity float64, from, to, ingredient string) (float64, bool) {
+	if from == to {
+		return quantity, true
+	}
+	source, sourceKnown := measures[from]
+	target, targetKnown := measures[to]
+	if !sourceKnown || !targetKnown {
+		return 0, false
+	}
+
+	base := quantity * source.size
+	if source.dimension != target.dimension {
+		density, known := Density(ingredient)
+		if !known {
+			return 0, false
+		}
+		if source.dimension == Volume {
+			base *= density
+		} else {
+			base /= density
+		}
+	}
+	return base / target.size, true
+}
+
+// Add returns a + b of ingredient, shown in the unit family of a: 500 g and
+// 1 kg of flour is 1.5 kg. It reports false when b cannot be converted to
+// a's unit.
+func Add(a, b Amount, ingredient string) (Amount, bool) {
+	converted, ok := Convert(b.Quantity, b.Unit, a.Unit, ingredient)
+	if !ok {
+		return Amount{}, false
+	}
+	return Best(Amount{Quantity: a.Quantity + converted, Unit: a.Unit}), true
+}
+
+// Best returns amount in the largest unit of its family that keeps the
+// quantity at least 1, e.g. 1500 g is 1.5 kg and 3 tsp is 1 tbsp
+func Best(amount Amount) Amount {
+	m, known := measures[amount.Unit]
+	if !known {
+		return amount
+	}
+	base := amount.Quantity * m.size
+	best := amount
+	for _, name := range m.family {
+		size := measures[name].size
+		if base/size >= 1 || name == m.family[0] {
+			best = Amount{Quantity: base / size, Unit: name}
+		}
+	}
+	return best
+}
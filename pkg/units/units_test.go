@@ -0,0 +1,85 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		quantity   float64
+		from, to   string
+		ingredient string
+		want       float64
+	}{
+		{1, "kg", "g", "", 1000},
+		{16, "oz", "lb", "", 1},
+		{1, "cup", "tbsp", "", 250.0 / 15},
+		{1, "pint", "ml", "", 568.26125},
+		{2, "tin", "tin", "", 2},
+		{250, "ml", "g", "milk", 257.5},
+		{1, "cup", "g", "plain flour", 132.5},
+		{530, "g", "l", "flour", 1},
+	}
+	for _, test := range tests {
+		got, ok := Convert(test.quantity, test.from, test.to, test.ingredient)
+		assert.True(t, ok, "%v %s to %s", test.quantity, test.from, test.to)
+		assert.InDelta(t, test.want, got, 1e-9, "%v %s to %s", test.quantity, test.from, test.to)
+	}
+}
+
+func TestConvertIncompatible(t *testing.T) {
+	for _, units := range [][2]string{{"g", "ml"}, {"tin", "g"}, {"", "kg"}, {"clove", "bunch"}} {
+		_, ok := Convert(1, units[0], units[1], "garlic")
+		assert.False(t, ok, "%s to %s", units[0], units[1])
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		a, b       Amount
+		ingredient string
+		want       Amount
+	}{
+		{Amount{500, "g"}, Amount{1, "kg"}, "flour", Amount{1.5, "kg"}},
+		{Amount{1, "kg"}, Amount{500, "g"}, "flour", Amount{1.5, "kg"}},
+		{Amount{2, "tbsp"}, Amount{0.25, "cup"}, "cumin", Amount{92.5 / 15, "tbsp"}},
+		{Amount{2, "tin"}, Amount{1, "tin"}, "tomatoes", Amount{3, "tin"}},
+		{Amount{600, "g"}, Amount{400, "g"}, "mince", Amount{1, "kg"}},
+		{Amount{3, ""}, Amount{2, ""}, "lemons", Amount{5, ""}},
+		{Amount{1, "lb"}, Amount{8, "oz"}, "mince", Amount{1.5, "lb"}},
+		{Amount{500, "ml"}, Amount{515, "g"}, "milk", Amount{1, "l"}},
+	}
+	for _, test := range tests {
+		got, ok := Add(test.a, test.b, test.ingredient)
+		assert.True(t, ok, "%v + %v", test.a, test.b)
+		assert.Equal(t, test.want.Unit, got.Unit, "%v + %v", test.a, test.b)
+		assert.InDelta(t, test.want.Quantity, got.Quantity, 1e-9, "%v + %v", test.a, test.b)
+	}
+
+	_, ok := Add(Amount{200, "g"}, Amount{1, "tin"}, "tomatoes")
+	assert.False(t, ok)
+	_, ok = Add(Amount{200, "g"}, Amount{1, "cup"}, "spinach")
+	assert.False(t, ok, "no density is known for spinach")
+}
+
+func TestBest(t *testing.T) {
+	assert.Equal(t, Amount{1.5, "kg"}, Best(Amount{1500, "g"}))
+	assert.Equal(t, Amount{500, "g"}, Best(Amount{0.5, "kg"}))
+	assert.Equal(t, Amount{1, "tbsp"}, Best(Amount{3, "tsp"}))
+	assert.Equal(t, Amount{2, "tin"}, Best(Amount{2, "tin"}))
+}
+
+func TestDimensionOfAndDensity(t *testing.T) {
+	assert.Equal(t, Mass, DimensionOf("oz"))
+	assert.Equal(t, Volume, DimensionOf("cup"))
+	assert.Equal(t, Count, DimensionOf("tin"))
+	assert.Equal(t, Count, DimensionOf(""))
+
+	density, ok := Density("Caster sugar")
+	assert.True(t, ok)
+	assert.Equal(t, 0.85, density)
+	_, ok = Density("spinach")
+	assert.False(t, ok)
+}